@@ -9,8 +9,9 @@ import (
 	"github.com/glynternet/go-accounting/account"
 	"github.com/glynternet/go-accounting/balance"
 	"github.com/glynternet/go-money/currency"
+	"github.com/glynternet/mon/internal/client"
+	"github.com/glynternet/mon/internal/router"
 	"github.com/glynternet/mon/pkg/date"
-	"github.com/glynternet/mon/pkg/filter"
 	"github.com/glynternet/mon/pkg/storage"
 	"github.com/glynternet/mon/pkg/table"
 	"github.com/pkg/errors"
@@ -31,11 +32,18 @@ const (
 	keyOpeningBalanceNote = "opening-balance-note"
 	keyClosingBalance     = "closing-balance"
 	keyClosingBalanceNote = "closing-balance-note"
+	keyCascade            = "cascade"
+	keyForce              = "force"
+	keyFrom               = "from"
+	keyTo                 = "to"
+	keyIdempotencyKey     = "idempotency-key"
 )
 
 var (
 	accountOpened = date.Flag()
 	accountClosed = date.Flag()
+	balancesFrom  = date.Flag()
+	balancesTo    = date.Flag()
 )
 
 var accountCmd = &cobra.Command{
@@ -117,23 +125,13 @@ var accountOpenCmd = &cobra.Command{
 			return errors.Wrap(err, "creating new account for insert")
 		}
 
-		c := newClient()
-
-		i, err := c.InsertAccount(*a)
-		if err != nil {
-			return errors.Wrap(err, "inserting new account")
-		}
-
-		b, err := c.InsertBalance(
-			(*i).ID,
-			balance.Balance{
-				Date:   i.Account.Opened(),
-				Amount: viper.GetInt(keyOpeningBalance),
-			},
+		i, b, err := newClient().OpenAccount(
+			*a,
+			viper.GetInt(keyOpeningBalance),
 			viper.GetString(keyOpeningBalanceNote),
 		)
 		if err != nil {
-			return errors.Wrap(err, "inserting balance")
+			return errors.Wrap(err, "opening account")
 		}
 
 		table.Accounts(storage.Accounts{*i}, os.Stdout)
@@ -153,26 +151,34 @@ var accountReopenCmd = &cobra.Command{
 			return errors.Wrap(err, "parsing account id")
 		}
 
-		c := newClient()
-
-		a, err := c.SelectAccount(uint(id))
+		b, err := newClient().V2().PatchAccount(uint(id), router.AccountPatchBody{Closed: &time.Time{}})
 		if err != nil {
-			return errors.Wrap(err, "selecting account")
+			return errors.Wrap(err, "applying updates")
 		}
 
-		aux := a.Account
-		us, err := account.New(aux.Name(), aux.CurrencyCode(), aux.Opened())
+		fmt.Println("Reopened:")
+		table.Accounts(storage.Accounts{*b}, os.Stdout)
+		return nil
+	},
+}
+
+var accountUndeleteCmd = &cobra.Command{
+	Use:   "undelete [ID]",
+	Short: "restore a deleted account",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
 		if err != nil {
-			return errors.Wrap(err, "creating updates account from selected account")
+			return errors.Wrap(err, "parsing account id")
 		}
 
-		b, err := c.UpdateAccount(a.ID, *us)
+		a, err := newClient().UndeleteAccount(uint(id))
 		if err != nil {
-			return errors.Wrap(err, "applying updates")
+			return errors.Wrap(err, "undeleting account")
 		}
 
-		fmt.Println("Reopened:")
-		table.Accounts(storage.Accounts{*b}, os.Stdout)
+		fmt.Println("Restored:")
+		table.Accounts(storage.Accounts{*a}, os.Stdout)
 		return nil
 	},
 }
@@ -180,23 +186,35 @@ var accountReopenCmd = &cobra.Command{
 var accountDeleteCmd = &cobra.Command{
 	Use:   "delete [ID]",
 	Short: "delete an account",
-	Args:  cobra.ExactArgs(1),
+	Long: `delete an account.
+An account that has balances will not be deleted unless either --cascade or
+--force is given. --cascade deletes the balances along with the account.
+--force deletes only the account, archiving its balances so that they are
+restored if the account is restored.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := parseID(args[0])
 		if err != nil {
 			return errors.Wrap(err, "parsing account id")
 		}
 
-		c := newClient()
-
-		a, err := c.SelectAccount(uint(id))
+		policy, err := deletionPolicy(viper.GetBool(keyCascade), viper.GetBool(keyForce))
 		if err != nil {
-			return errors.Wrap(err, "selecting account")
+			return err
 		}
 
-		err = c.DeleteAccount(a.ID)
+		c := newClient()
+
+		var a *storage.Account
+		err = client.RetryStale(staleAttempts, func() error {
+			a, err = c.SelectAccount(uint(id))
+			if err != nil {
+				return errors.Wrap(err, "selecting account")
+			}
+			return errors.Wrap(c.IfMatch(a.Version).DeleteAccount(a.ID, policy), "deleting account")
+		})
 		if err != nil {
-			return errors.Wrap(err, "deleting account")
+			return err
 		}
 
 		fmt.Println("Deleted:")
@@ -220,15 +238,8 @@ var accountCloseCmd = &cobra.Command{
 			return errors.Wrap(err, "parsing account id")
 		}
 
-		c := newClient()
-
-		a, err := c.SelectAccount(uint(id))
-		if err != nil {
-			return errors.Wrap(err, "selecting account")
-		}
-
-		b, err := c.InsertBalance(
-			(*a).ID,
+		u, b, err := newClient().CloseAccount(
+			uint(id),
 			balance.Balance{
 				Date:   closed,
 				Amount: viper.GetInt(keyClosingBalance),
@@ -236,22 +247,7 @@ var accountCloseCmd = &cobra.Command{
 			viper.GetString(keyClosingBalanceNote),
 		)
 		if err != nil {
-			return errors.Wrap(err, "inserting balance")
-		}
-
-		us, err := account.New(
-			a.Account.Name(),
-			a.Account.CurrencyCode(),
-			a.Account.Opened(),
-			account.CloseTime(b.Date),
-		)
-		if err != nil {
-			return errors.Wrap(err, "creating updates account")
-		}
-
-		u, err := c.UpdateAccount(a.ID, *us)
-		if err != nil {
-			return errors.Wrap(err, "updating account")
+			return errors.Wrap(err, "closing account")
 		}
 
 		table.Accounts(storage.Accounts{*u}, os.Stdout)
@@ -263,9 +259,8 @@ var accountCloseCmd = &cobra.Command{
 var accountUpdateCmd = &cobra.Command{
 	Use:   "update [ID]",
 	Short: "update an account",
-	Long: `update an account with the given details. 
-All of the details of an account must be provided, even if they are exactly 
-the same as the original account`,
+	Long: `update an account with the given details.
+Only the details that are given will be changed.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := parseID(args[0])
@@ -273,43 +268,22 @@ the same as the original account`,
 			return errors.Wrap(err, "parsing account id")
 		}
 
-		c := newClient()
-		a, err := c.SelectAccount(uint(id))
-		if err != nil {
-			return errors.Wrap(err, "selecting account to update")
-		}
-
-		opened := time.Now()
-		if accountOpened.Time != nil {
-			opened = *accountOpened.Time
-		}
-
-		var ops []account.Option
-		if accountClosed.Time != nil {
-			ops = append(ops, account.CloseTime(*accountClosed.Time))
+		var p router.AccountPatchBody
+		if cmd.Flag(keyName).Changed {
+			name := viper.GetString(keyName)
+			p.Name = &name
 		}
-
-		cc, err := currency.NewCode(viper.GetString(keyCurrency))
-		if err != nil {
-			return errors.Wrap(err, "creating new currency code")
+		if cmd.Flag(keyCurrency).Changed {
+			code := viper.GetString(keyCurrency)
+			p.Currency = &code
 		}
-
-		us, err := account.New(viper.GetString(keyName), *cc, opened, ops...)
-		if err != nil {
-			return errors.Wrap(err, "creating account for update")
+		if cmd.Flag(keyOpened).Changed {
+			p.Opened = accountOpened.Time
 		}
-
-		u, err := c.UpdateAccount(a.ID, *us)
-		if err != nil {
-			return errors.Wrap(err, "updating account")
+		if cmd.Flag(keyClosed).Changed {
+			p.Closed = accountClosed.Time
 		}
-
-		fmt.Println("ORIGINAL")
-		table.Accounts(storage.Accounts{*a}, os.Stdout)
-
-		fmt.Println("UPDATED")
-		table.Accounts(storage.Accounts{*u}, os.Stdout)
-		return nil
+		return patchAccount(uint(id), p)
 	},
 }
 
@@ -322,40 +296,34 @@ var accountRenameCmd = &cobra.Command{
 		if err != nil {
 			return errors.Wrap(err, "parsing account id")
 		}
+		return patchAccount(uint(id), router.AccountPatchBody{Name: &args[1]})
+	},
+}
 
-		c := newClient()
-		a, err := c.SelectAccount(uint(id))
-		if err != nil {
-			return errors.Wrap(err, "selecting account")
-		}
-
-		var ops []account.Option
-		if a.Account.Closed().Valid {
-			ops = append(ops, account.CloseTime(a.Account.Closed().Time))
-		}
-
-		us, err := account.New(
-			args[1],
-			a.Account.CurrencyCode(),
-			a.Account.Opened(),
-			ops...,
-		)
-		if err != nil {
-			return errors.Wrap(err, "creating new account for update")
-		}
-
-		u, err := c.UpdateAccount(a.ID, *us)
+// patchAccount applies the given patch to the account with the given id,
+// printing the account before and after it has been changed.
+func patchAccount(id uint, p router.AccountPatchBody) error {
+	c := newClient()
+	var a, u *storage.Account
+	err := client.RetryStale(staleAttempts, func() error {
+		var err error
+		a, err = c.SelectAccount(id)
 		if err != nil {
-			return errors.Wrap(err, "updating account")
+			return errors.Wrap(err, "selecting account to update")
 		}
+		u, err = c.IfMatch(a.Version).V2().PatchAccount(a.ID, p)
+		return errors.Wrap(err, "updating account")
+	})
+	if err != nil {
+		return err
+	}
 
-		fmt.Println("ORIGINAL")
-		table.Accounts(storage.Accounts{*a}, os.Stdout)
+	fmt.Println("ORIGINAL")
+	table.Accounts(storage.Accounts{*a}, os.Stdout)
 
-		fmt.Println("UPDATED")
-		table.Accounts(storage.Accounts{*u}, os.Stdout)
-		return nil
-	},
+	fmt.Println("UPDATED")
+	table.Accounts(storage.Accounts{*u}, os.Stdout)
+	return nil
 }
 
 var accountBalancesCmd = &cobra.Command{
@@ -375,17 +343,24 @@ var accountBalancesCmd = &cobra.Command{
 
 		table.Accounts(storage.Accounts{*a}, os.Stdout)
 
-		bs, err := c.SelectAccountBalances((*a).ID)
+		// the latest balances are selected when limited, so are selected in
+		// reverse chronological order and then put back into order
+		q := router.BalancesQuery{
+			From:  balancesFrom.Time,
+			To:    balancesTo.Time,
+			Limit: uint(viper.GetInt(keyLimit)),
+		}
+		if q.Limit > 0 {
+			q.Order = storage.OrderDescending
+		}
+		bs, _, err := c.SelectAccountBalancesPage((*a).ID, q)
 		if err != nil {
 			return errors.Wrap(err, "selecting account balances")
 		}
-
-		limit := viper.GetInt(keyLimit)
-		if limit > len(*bs) {
-			limit = len(*bs)
-		}
-		if limit != 0 {
-			*bs = (*bs)[len(*bs)-limit:]
+		if q.Order == storage.OrderDescending {
+			for i, j := 0, len(*bs)-1; i < j; i, j = i+1, j-1 {
+				(*bs)[i], (*bs)[j] = (*bs)[j], (*bs)[i]
+			}
 		}
 
 		table.Balances(*bs, os.Stdout)
@@ -414,6 +389,14 @@ var accountBalanceInsertCmd = &cobra.Command{
 			t = *balanceDate.Time
 		}
 
+		if key := viper.GetString(keyIdempotencyKey); key != "" {
+			// a balance without a date is inserted at the current time,
+			// which would make each run of the command a different request
+			if balanceDate.Time == nil {
+				return fmt.Errorf("--%s must be given along with --%s", keyDate, keyIdempotencyKey)
+			}
+			c = c.WithIdempotencyKey(key)
+		}
 		b, err := c.InsertBalance(
 			(*a).ID,
 			balance.Balance{
@@ -441,37 +424,108 @@ var accountBalanceCmd = &cobra.Command{
 			return errors.Wrap(err, "parsing account id")
 		}
 
+		t := time.Now()
+		if balanceDate.Time != nil {
+			t = *balanceDate.Time
+		}
+
 		c := newClient()
-		a, err := c.SelectAccount(uint(id))
-		if err != nil {
+		if _, err := c.SelectAccount(uint(id)); err != nil {
 			return errors.Wrap(err, "selecting account")
 		}
+		s, err := c.SelectAccountsSummary(router.AccountsQuery{
+			AtDate: &t,
+			IDs:    []uint{uint(id)},
+		})
+		if err != nil {
+			return errors.Wrapf(err, "selecting summary at time:%+v for account:%d", t, id)
+		}
+		var amount int
+		for _, ab := range s.Accounts {
+			amount += ab.Balance.Amount
+		}
+		fmt.Println(amount)
+		return nil
+	},
+}
 
-		t := time.Now()
-		if balanceDate.Time != nil {
-			t = *balanceDate.Time
+var accountShareCmd = &cobra.Command{
+	Use:   "share [ID] [USER]",
+	Short: "share an account with another user",
+	Long: `share an account with another user.
+The user will be able to see the account and its balances but will not be able
+to change them.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return errors.Wrap(err, "parsing account id")
 		}
 
-		bs, err := accountBalancesAtTime(c, *a, t)
+		err = newClient().V2().ShareAccount(uint(id), args[1])
 		if err != nil {
-			return errors.Wrapf(err, "getting balance at time:%+v for account:%+v", t, a)
+			return errors.Wrap(err, "sharing account")
 		}
-		fmt.Println(bs.InnerBalances().Sum())
+
+		fmt.Printf("Shared account %d with %s\n", id, args[1])
 		return nil
 	},
 }
 
-// accountBalancesAtTime retrieves the balances that existed for the account at
-// a given time.
-func accountBalancesAtTime(store storage.Storage, a storage.Account, at time.Time) (storage.Balances, error) {
-	bs, err := store.SelectAccountBalances(a.ID)
-	if err != nil {
-		return storage.Balances{}, errors.Wrapf(err, "selecting balances for account: %+v", a)
-	}
-	c := filter.BalanceNot(filter.BalanceAfter(at))
-	filtered := c.Filter(*bs)
+var accountUnshareCmd = &cobra.Command{
+	Use:   "unshare [ID] [USER]",
+	Short: "stop sharing an account with another user",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return errors.Wrap(err, "parsing account id")
+		}
+
+		err = newClient().V2().UnshareAccount(uint(id), args[1])
+		if err != nil {
+			return errors.Wrap(err, "unsharing account")
+		}
+
+		fmt.Printf("Stopped sharing account %d with %s\n", id, args[1])
+		return nil
+	},
+}
+
+var accountSharesCmd = &cobra.Command{
+	Use:   "shares [ID]",
+	Short: "list the users that an account has been shared with",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return errors.Wrap(err, "parsing account id")
+		}
 
-	return filtered, err
+		users, err := newClient().V2().SelectAccountShares(uint(id))
+		if err != nil {
+			return errors.Wrap(err, "selecting account shares")
+		}
+
+		for _, u := range users {
+			fmt.Println(u)
+		}
+		return nil
+	},
+}
+
+// deletionPolicy returns the storage.DeletionPolicy selected by the cascade
+// and force flags of the account delete command.
+func deletionPolicy(cascade, force bool) (storage.DeletionPolicy, error) {
+	switch {
+	case cascade && force:
+		return "", fmt.Errorf("only one of --%s and --%s can be given", keyCascade, keyForce)
+	case cascade:
+		return storage.DeletionCascade, nil
+	case force:
+		return storage.DeletionArchive, nil
+	}
+	return storage.DeletionRefuse, nil
 }
 
 func init() {
@@ -493,6 +547,9 @@ func init() {
 	accountOpenCmd.Flags().IntP(keyOpeningBalance, "b", 0, "account opening balance")
 	accountOpenCmd.Flags().String(keyOpeningBalanceNote, "", "note to attach to account opening balance")
 
+	accountDeleteCmd.Flags().Bool(keyCascade, false, "delete the balances of the account along with it")
+	accountDeleteCmd.Flags().Bool(keyForce, false, "delete the account, archiving its balances with it")
+
 	accountCloseCmd.Flags().VarP(balanceDate, keyDate, "d", "account closed date")
 	accountCloseCmd.Flags().IntP(keyClosingBalance, "b", 0, "account closing balance")
 	accountCloseCmd.Flags().String(keyClosingBalanceNote, "", "note to attach to account closing balance")
@@ -501,12 +558,15 @@ func init() {
 	accountUpdateCmd.Flags().VarP(accountOpened, keyOpened, "o", "account opened date")
 	accountUpdateCmd.Flags().VarP(accountClosed, keyClosed, "c", "account closed date")
 
-	accountBalancesCmd.Flags().UintP(keyLimit, "l", 0, "limit results")
+	accountBalancesCmd.Flags().UintP(keyLimit, "l", 0, "show only the latest balances, up to this number")
+	accountBalancesCmd.Flags().Var(balancesFrom, keyFrom, "show only balances on or after a date")
+	accountBalancesCmd.Flags().Var(balancesTo, keyTo, "show only balances on or before a date")
 
 	// TODO: Stop multiple usage of the flag like in this article: http://blog.ralch.com/tutorial/golang-custom-flags/
 	accountBalanceInsertCmd.Flags().VarP(balanceDate, keyDate, "d", "date of balance to insert")
 	accountBalanceInsertCmd.Flags().IntP(keyAmount, "a", 0, "amount of balance to insert")
 	accountBalanceInsertCmd.Flags().String(keyNote, "", "note to attach to balance")
+	accountBalanceInsertCmd.Flags().String(keyIdempotencyKey, "", "unique key for the insert, so that running the command again with the same key does not insert the balance again")
 
 	accountBalanceCmd.Flags().VarP(balanceDate, keyDate, "d", "date at which to retrieve balance")
 
@@ -517,10 +577,14 @@ func init() {
 		accountCloseCmd,
 		accountUpdateCmd,
 		accountDeleteCmd,
+		accountUndeleteCmd,
 		accountRenameCmd,
 		accountBalancesCmd,
 		accountBalanceInsertCmd,
 		accountBalanceCmd,
+		accountShareCmd,
+		accountUnshareCmd,
+		accountSharesCmd,
 	} {
 		err := viper.BindPFlags(c.Flags())
 		if err != nil {
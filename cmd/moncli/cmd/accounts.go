@@ -8,13 +8,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/glynternet/go-accounting/balance"
-	"github.com/glynternet/go-money/currency"
-	"github.com/glynternet/mon/internal/accountbalance"
-	"github.com/glynternet/mon/internal/client"
+	"github.com/glynternet/mon/internal/router"
 	"github.com/glynternet/mon/internal/sort"
 	"github.com/glynternet/mon/pkg/date"
-	"github.com/glynternet/mon/pkg/filter"
 	"github.com/glynternet/mon/pkg/storage"
 	"github.com/glynternet/mon/pkg/table"
 	"github.com/pkg/errors"
@@ -30,10 +26,12 @@ const (
 	keyQuiet      = "quiet"
 	keyAtDate     = "at-date"
 	keySortBy     = "sort-by"
+	keyAsOf       = "as-of"
 )
 
 var (
 	atDate          = date.Flag()
+	asOf            = date.Flag()
 	sortBy          = sort.NewKey()
 	ids, excludeIDs []uint
 	currencies      []string
@@ -48,8 +46,7 @@ var accountsCmd = &cobra.Command{
 			atDate.Time = &now
 		}
 
-		c := client.Client(viper.GetString(keyServerHost))
-		as, err := accounts(c)
+		as, err := accounts()
 		if err != nil {
 			return errors.Wrap(err, "getting accounts")
 		}
@@ -75,151 +72,52 @@ var accountsBalancesCmd = &cobra.Command{
 			atDate.Time = &now
 		}
 
-		c := client.Client(viper.GetString(keyServerHost))
-		as, err := accounts(c)
+		q := accountsQuery()
+		q.SortBy = sortBy.String()
+		s, err := newClient().SelectAccountsSummary(q)
 		if err != nil {
-			return errors.Wrap(err, "getting accounts")
-		}
-
-		abs, err := accountsBalances(c, as, *atDate.Time)
-		if err != nil {
-			return errors.Wrap(err, "getting balances for all accounts")
+			return errors.Wrap(err, "selecting accounts summary")
 		}
 
-		table.AccountsWithBalance(abs, os.Stdout)
+		table.AccountsWithBalance(s.Accounts, os.Stdout)
 
-		cbs := currencyBalances(abs)
-		if len(cbs) == 0 {
+		if len(s.Totals) == 0 {
 			return nil
 		}
 
 		totals := [][]string{{"Currency", "Amount"}}
-		for crncy, bs := range cbs {
-			totals = append(totals, []string{crncy.String(), strconv.Itoa(bs.Sum())})
+		for crncy, amount := range s.Totals {
+			totals = append(totals, []string{crncy, strconv.Itoa(amount)})
 		}
 		return errors.Wrap(table.Basic(totals, os.Stdout), "printing basic table for totals")
 	},
 }
 
-func accounts(store storage.Storage) (storage.Accounts, error) {
-	as, err := store.SelectAccounts()
-	if err != nil {
-		return nil, errors.Wrap(err, "selecting accounts")
-	}
-
-	ac, err := prepareAccountCondition()
-	if err != nil {
-		return nil, errors.Wrap(err, "preparing conditions")
-	}
-
-	*as = ac.Filter(*as)
-
-	if s, ok := sort.AccountSorts()[sortBy.String()]; ok {
-		s(*as)
-	}
-
-	return *as, nil
-}
-
-func accountsBalances(s storage.Storage, as storage.Accounts, at time.Time) ([]accountbalance.AccountBalance, error) {
-	var abs []accountbalance.AccountBalance
-	for _, a := range as {
-		bs, err := accountBalancesAtTime(s, a, at)
-		if err != nil {
-			return nil, errors.Wrapf(err, "getting balances at time:%+v for account:%+v", at, a)
-		}
-		l, err := bs.InnerBalances().Latest()
-		if err != nil {
-			return nil, errors.Wrapf(err, "getting latest balance for account:%+v balances:%+v", a, bs)
-		}
-		abs = append(abs, accountbalance.AccountBalance{
-			Account: a,
-			Balance: balance.Balance{
-				Date:   l.Date,
-				Amount: bs.InnerBalances().Sum(),
-			},
-		})
-	}
-
-	if s, ok := sort.AccountbalanceSorts()[sortBy.String()]; ok {
-		s(abs)
-	}
-
-	return abs, nil
-}
-
-func currencyBalances(abs []accountbalance.AccountBalance) map[currency.Code]balance.Balances {
-	cbs := make(map[currency.Code]balance.Balances)
-	for _, ab := range abs {
-		crncy := ab.Account.Account.CurrencyCode()
-		if _, ok := cbs[crncy]; !ok {
-			cbs[crncy] = balance.Balances{}
-		}
-		cbs[crncy] = append(cbs[crncy], ab.Balance)
-	}
-	return cbs
-}
-
-func prepareAccountCondition() (filter.AccountCondition, error) {
-	cs := filter.AccountConditions{
-		filter.Existed(*atDate.Time),
-	}
-
-	if viper.GetBool(keyOpen) {
-		cs = append(cs, filter.OpenAt(*atDate.Time))
-	}
-
-	if len(ids) > 0 {
-		cs = append(cs, idsCondition(ids))
-	}
-
-	if len(excludeIDs) > 0 {
-		cs = append(cs, filter.AccountNot(idsCondition(excludeIDs)))
-	}
-
-	if len(currencies) > 0 {
-		ccs, err := currenciesCondition(currencies)
-		if err != nil {
-			return nil, errors.Wrap(err, "creating currencies condition")
-		}
-		cs = append(cs, ccs)
+// accountsQuery returns the router.AccountsQuery that selects the accounts
+// that match the filtering flags of the accounts command.
+func accountsQuery() router.AccountsQuery {
+	return router.AccountsQuery{
+		AsOf:       asOf.Time,
+		Open:       viper.GetBool(keyOpen),
+		AtDate:     atDate.Time,
+		IDs:        ids,
+		ExcludeIDs: excludeIDs,
+		Currencies: currencies,
 	}
-
-	// Account must meet all AccountConditions
-	return cs.And, nil
 }
 
-func idsCondition(ids []uint) filter.AccountCondition {
-	var cs filter.AccountConditions
-	for _, id := range ids {
-		cs = append(cs, filter.ID(id))
+// accounts selects the accounts that match the flags of the accounts command,
+// which are filtered and sorted by the mon server.
+func accounts() (storage.Accounts, error) {
+	q := accountsQuery()
+	if _, ok := sort.AccountSorts()[sortBy.String()]; ok {
+		q.SortBy = sortBy.String()
 	}
-	return cs.Or
-}
-
-func currenciesCondition(cs []string) (filter.AccountCondition, error) {
-	ccs, err := currencyStringsToCodes(cs...)
+	as, _, err := newClient().SelectAccountsPage(q)
 	if err != nil {
-		return nil, errors.Wrap(err, "converting currency string to currency codes")
-	}
-	var acs filter.AccountConditions
-	for _, c := range ccs {
-		acs = append(acs, filter.Currency(c))
-	}
-	return acs.Or, nil
-}
-
-// TODO: this should be handled as a flags type perhaps?
-func currencyStringsToCodes(css ...string) ([]currency.Code, error) {
-	var codes []currency.Code
-	for _, cs := range css {
-		c, err := currency.NewCode(cs)
-		if err != nil {
-			return nil, errors.Wrap(err, "creating new code")
-		}
-		codes = append(codes, *c)
+		return nil, errors.Wrap(err, "selecting accounts")
 	}
-	return codes, nil
+	return *as, nil
 }
 
 func init() {
@@ -230,6 +128,7 @@ func init() {
 	accountsCmd.PersistentFlags().StringSliceVar(&currencies, keyCurrencies, []string{}, "filter by currencies")
 	accountsCmd.Flags().BoolP(keyQuiet, "q", false, "show only account ids")
 	accountsCmd.PersistentFlags().Var(atDate, keyAtDate, "show balances at a certain date")
+	accountsCmd.PersistentFlags().Var(asOf, keyAsOf, "show accounts and balances as they were stored at the start of a certain date")
 	sortByKeys := strings.Join(sort.AllKeys(), ",")
 	accountsCmd.PersistentFlags().Var(sortBy, keySortBy, fmt.Sprintf("sort by one of %s", sortByKeys))
 
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/glynternet/mon/internal/router"
+	"github.com/glynternet/mon/pkg/date"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	keyRoute     = "route"
+	keyAccountID = "account-id"
+	keyBalanceID = "balance-id"
+	keyDetails   = "details"
+)
+
+// the flags of the audit command are held in variables rather than retrieved
+// with viper, as some share their names with flags of other commands.
+var (
+	auditFrom      = date.Flag()
+	auditTo        = date.Flag()
+	auditRoute     string
+	auditAccountID uint
+	auditBalanceID uint
+	auditLimit     uint
+	auditDetails   bool
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "list the recorded changes to accounts and balances",
+	Long: `list the requests made by the user of the API token that changed, or
+attempted to change, accounts and balances, in the order that they were made.
+Each entry records the route that served the request, the account and balance
+that it targeted and the status of its response. With --details, the body of
+each request and its target as it was before and after the request are also
+shown.
+
+Only tokens with the admin role can list the audit log.`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		// the latest entries are selected when limited, so are selected in
+		// reverse order and then put back into order
+		q := router.AuditQuery{
+			Route:     auditRoute,
+			AccountID: auditAccountID,
+			BalanceID: auditBalanceID,
+			From:      auditFrom.Time,
+			To:        auditTo.Time,
+			Limit:     auditLimit,
+		}
+		if q.Limit > 0 {
+			q.Order = storage.OrderDescending
+		}
+		page, _, err := newClient().SelectAuditEntriesPage(q)
+		if err != nil {
+			return errors.Wrap(err, "selecting audit entries")
+		}
+		es := *page
+		if q.Order == storage.OrderDescending {
+			for i, j := 0, len(es)-1; i < j; i, j = i+1, j-1 {
+				es[i], es[j] = es[j], es[i]
+			}
+		}
+
+		table.AuditEntries(es, os.Stdout)
+		if auditDetails {
+			for _, e := range es {
+				fmt.Printf("\nENTRY %d\n", e.ID)
+				fmt.Printf("REQUEST %s %s %s\n", e.Method, e.Path, e.Request)
+				fmt.Printf("BEFORE  %s\n", e.Before)
+				fmt.Printf("AFTER   %s\n", e.After)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	auditCmd.Flags().StringVar(&auditRoute, keyRoute, "", "show only requests served by the route with this name")
+	auditCmd.Flags().UintVar(&auditAccountID, keyAccountID, 0, "show only requests that targeted the account with this ID")
+	auditCmd.Flags().UintVar(&auditBalanceID, keyBalanceID, 0, "show only requests that targeted the balance with this ID")
+	auditCmd.Flags().Var(auditFrom, keyFrom, "show only requests made on or after a date")
+	auditCmd.Flags().Var(auditTo, keyTo, "show only requests made on or before a date")
+	auditCmd.Flags().UintVarP(&auditLimit, keyLimit, "l", 0, "show only the latest requests, up to this number")
+	auditCmd.Flags().BoolVar(&auditDetails, keyDetails, false, "show the body of each request and its target before and after it")
+	rootCmd.AddCommand(auditCmd)
+}
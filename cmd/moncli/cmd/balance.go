@@ -1,16 +1,40 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"os"
 
+	"github.com/glynternet/mon/internal/client"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/table"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var balanceCmd = &cobra.Command{
-	Use:   "balance",
-	Short: "interact with a balance",
+	Use:   "balance [ID]",
+	Short: "show or interact with a balance",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return errors.Wrap(err, "parsing balance ID")
+		}
+		c := newClient()
+		b, err := c.SelectBalance(uint(id))
+		if err != nil {
+			return errors.Wrap(err, "selecting balance")
+		}
+		a, err := c.SelectAccount(b.AccountID)
+		if err != nil {
+			return errors.Wrapf(err, "selecting account with id %d", b.AccountID)
+		}
+		table.Accounts(storage.Accounts{*a}, os.Stdout)
+		table.Balances(storage.Balances{*b}, os.Stdout)
+		return nil
+	},
 }
 
 var balanceDeleteCmd = &cobra.Command{
@@ -26,6 +50,82 @@ var balanceDeleteCmd = &cobra.Command{
 	},
 }
 
+var balanceUndeleteCmd = &cobra.Command{
+	Use:   "undelete [ID]",
+	Short: "restore a deleted balance",
+	Long: `restore a deleted balance.
+A balance cannot be restored whilst the account that it belongs to is deleted.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return errors.Wrap(err, "parsing balance ID")
+		}
+		b, err := newClient().UndeleteBalance(uint(id))
+		if err != nil {
+			return errors.Wrap(err, "undeleting balance")
+		}
+		fmt.Println("Restored:")
+		table.Balances(storage.Balances{*b}, os.Stdout)
+		return nil
+	},
+}
+
+var balanceUpdateCmd = &cobra.Command{
+	Use:   "update [ID]",
+	Short: "update a balance",
+	Long: `update a balance with the given details.
+Only the details that are provided will be changed, any others will remain the 
+same as the original balance.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return errors.Wrap(err, "parsing balance ID")
+		}
+
+		c := newClient()
+		var original, u *storage.Balance
+		err = client.RetryStale(staleAttempts, func() error {
+			original, err = c.SelectBalance(uint(id))
+			if err != nil {
+				return errors.Wrap(err, "selecting balance to update")
+			}
+
+			updates := original.Balance
+			note := original.Note
+			if balanceDate.Time != nil {
+				updates.Date = *balanceDate.Time
+			}
+			if cmd.Flags().Changed(keyAmount) {
+				updates.Amount, err = cmd.Flags().GetInt(keyAmount)
+				if err != nil {
+					return errors.Wrap(err, "getting amount")
+				}
+			}
+			if cmd.Flags().Changed(keyNote) {
+				note, err = cmd.Flags().GetString(keyNote)
+				if err != nil {
+					return errors.Wrap(err, "getting note")
+				}
+			}
+
+			u, err = c.IfMatch(original.Version).UpdateBalance(original.AccountID, original.ID, updates, note)
+			return errors.Wrap(err, "updating balance")
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("ORIGINAL")
+		table.Balances(storage.Balances{*original}, os.Stdout)
+
+		fmt.Println("UPDATED")
+		table.Balances(storage.Balances{*u}, os.Stdout)
+		return nil
+	},
+}
+
 func init() {
 	err := viper.BindPFlags(balanceCmd.PersistentFlags())
 	if err != nil {
@@ -35,6 +135,7 @@ func init() {
 
 	for _, c := range []*cobra.Command{
 		balanceDeleteCmd,
+		balanceUndeleteCmd,
 	} {
 		err := viper.BindPFlags(c.Flags())
 		if err != nil {
@@ -42,4 +143,11 @@ func init() {
 		}
 		balanceCmd.AddCommand(c)
 	}
+
+	balanceUpdateCmd.Flags().VarP(balanceDate, keyDate, "d", "updated date of balance")
+	balanceUpdateCmd.Flags().IntP(keyAmount, "a", 0, "updated amount of balance")
+	balanceUpdateCmd.Flags().String(keyNote, "", "updated note of balance")
+	// The flags of balanceUpdateCmd are read directly from the command rather
+	// than through viper as they share keys with the flags of other commands.
+	balanceCmd.AddCommand(balanceUpdateCmd)
 }
@@ -5,6 +5,11 @@ import (
 	"github.com/spf13/viper"
 )
 
+// staleAttempts is the number of times that a command attempts to change an
+// item that is changed by someone else between the command selecting and
+// changing it.
+const staleAttempts = 3
+
 func newClient() client.Client {
-	return client.Client(viper.GetString(keyServerHost))
+	return client.New(viper.GetString(keyServerHost)).WithToken(viper.GetString(keyToken))
 }
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/glynternet/mon/internal/router"
+	"github.com/glynternet/mon/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const keyAfter = "after"
+
+// the flags of the events command are held in variables rather than retrieved
+// with viper, as some share their names with flags of other commands.
+var (
+	eventsAfter uint64
+	eventsLimit uint
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "list the events that changed accounts and balances",
+	Long: `list the events that record every change made to accounts and balances,
+in the order that they were made, along with the user that made each change.
+Events are only kept by servers that use eventsourced storage.
+
+Only tokens with the admin role can list the events.`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		es, err := newClient().SelectEvents(router.EventsQuery{
+			After: eventsAfter,
+			Limit: eventsLimit,
+		})
+		if err != nil {
+			return errors.Wrap(err, "selecting events")
+		}
+		table.Events(*es, os.Stdout)
+		return nil
+	},
+}
+
+func init() {
+	eventsCmd.Flags().Uint64Var(&eventsAfter, keyAfter, 0, "show only events after the event with this sequence number")
+	eventsCmd.Flags().UintVarP(&eventsLimit, keyLimit, "l", 0, "show only the earliest events, up to this number")
+	rootCmd.AddCommand(eventsCmd)
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/glynternet/mon/internal/client"
+	"github.com/glynternet/mon/internal/router"
+	"github.com/glynternet/mon/pkg/statement"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	keyFormat     = "format"
+	keyDryRun     = "dry-run"
+	keyHeader     = "header"
+	keyDelimiter  = "delimiter"
+	keyBalance    = "balance"
+	keyDateLayout = "date-layout"
+	keyDecimals   = "decimals"
+
+	formatCSV = "csv"
+	formatOFX = "ofx"
+	formatQIF = "qif"
+)
+
+// the flags of the import command are held in variables rather than retrieved
+// with viper, as some share their names with flags of other commands.
+var (
+	importFormat     string
+	importDryRun     bool
+	importHeader     bool
+	importDelimiter  string
+	importDate       string
+	importAmount     string
+	importBalance    string
+	importNotes      []string
+	importDateLayout string
+	importDecimals   int
+	importOpening    int
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [ACCOUNT ID] [FILE]",
+	Short: "insert the balances of a statement file into an account",
+	Long: `insert the balances of a statement file into an account.
+The file can be CSV, OFX or QIF, which is decided by the extension of the file
+unless --format is given.
+
+The columns of a CSV file are given by the name of the column in the header
+row, when --header is given, or by the 1-based index of the column. Each row
+holds either the amount of a transaction, given by --amount, or the balance of
+the account after it, given by --balance.
+
+OFX and QIF files hold transactions. The balance after each transaction is
+worked out from the ledger balance of an OFX file, when it has one, or else
+from the opening balance, which is the latest balance of the account before the
+first transaction unless --opening-balance is given.
+
+Each balance is checked against the time range of the account and all of them
+are shown before they are inserted. No balances are inserted if any are
+invalid, or if any fail to be inserted.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := parseID(args[0])
+		if err != nil {
+			return errors.Wrap(err, "parsing account id")
+		}
+		s, err := readStatement(args[1])
+		if err != nil {
+			return errors.Wrapf(err, "reading statement from %s", args[1])
+		}
+		if len(s.Lines) == 0 {
+			return fmt.Errorf("no balances found in %s", args[1])
+		}
+
+		c := newClient()
+		a, err := c.SelectAccount(uint(id))
+		if err != nil {
+			return errors.Wrap(err, "selecting account")
+		}
+
+		opening := importOpening
+		if s.Transactions && s.Closing == nil && !cmd.Flags().Changed(keyOpeningBalance) {
+			if opening, err = balanceBefore(c, a.ID, s.Entries(0)[0].Balance.Date); err != nil {
+				return errors.Wrap(err, "selecting opening balance")
+			}
+		}
+
+		es := s.Entries(opening)
+		errs := make(map[int]error)
+		for i, e := range es {
+			if err := a.Account.ValidateBalance(e.Balance); err != nil {
+				errs[i] = err
+			}
+		}
+
+		table.Accounts(storage.Accounts{*a}, os.Stdout)
+		table.StatementEntries(es, errs, os.Stdout)
+		if len(errs) > 0 {
+			return fmt.Errorf("%d of %d balances are invalid", len(errs), len(es))
+		}
+		if importDryRun {
+			return nil
+		}
+
+		bs := make([]router.BalanceInsertBody, len(es))
+		for i, e := range es {
+			bs[i] = router.BalanceInsertBody{Balance: e.Balance, Note: e.Note}
+		}
+		inserted, err := c.V2().InsertBalances(a.ID, bs)
+		if err != nil {
+			return errors.Wrap(err, "inserting balances")
+		}
+		fmt.Println("Inserted:")
+		table.Balances(*inserted, os.Stdout)
+		return nil
+	},
+}
+
+func readStatement(path string) (*statement.Statement, error) {
+	format := importFormat
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	var read func(io.Reader, statement.Options) (*statement.Statement, error)
+	switch format {
+	case formatCSV:
+		f, err := csvFormat()
+		if err != nil {
+			return nil, err
+		}
+		read = func(r io.Reader, o statement.Options) (*statement.Statement, error) {
+			return statement.ReadCSV(r, f, o)
+		}
+	case formatOFX:
+		read = statement.ReadOFX
+	case formatQIF:
+		read = statement.ReadQIF
+	default:
+		return nil, fmt.Errorf("unsupported format %q, --%s must be one of %s, %s or %s", format, keyFormat, formatCSV, formatOFX, formatQIF)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening file")
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, errors.Wrap(err, "closing file"))
+		}
+	}()
+	return read(file, statement.Options{
+		Decimals:   importDecimals,
+		DateLayout: importDateLayout,
+	})
+}
+
+func csvFormat() (statement.CSVFormat, error) {
+	f := statement.CSVFormat{
+		Header:  importHeader,
+		Date:    importDate,
+		Amount:  importAmount,
+		Balance: importBalance,
+		Note:    importNotes,
+	}
+	if importDelimiter != "" {
+		r, size := utf8.DecodeRuneInString(importDelimiter)
+		if size != len(importDelimiter) {
+			return f, fmt.Errorf("--%s must be a single character", keyDelimiter)
+		}
+		f.Comma = r
+	}
+	return f, nil
+}
+
+// balanceBefore returns the amount of the latest balance of the account with
+// the given ID that is before the given time, or 0 if there is none.
+func balanceBefore(c client.Client, accountID uint, t time.Time) (int, error) {
+	to := t.Add(-time.Nanosecond)
+	bs, _, err := c.V2().SelectAccountBalancesPage(accountID, router.BalancesQuery{
+		To:    &to,
+		Order: storage.OrderDescending,
+		Limit: 1,
+	})
+	if err != nil || len(*bs) == 0 {
+		return 0, err
+	}
+	return (*bs)[0].Amount, nil
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFormat, keyFormat, "", "format of the file, one of csv, ofx or qif")
+	importCmd.Flags().BoolVar(&importDryRun, keyDryRun, false, "show the balances that would be inserted without inserting them")
+	importCmd.Flags().BoolVar(&importHeader, keyHeader, false, "the first row of a CSV file is a header row")
+	importCmd.Flags().StringVar(&importDelimiter, keyDelimiter, "", "field delimiter of a CSV file (default \",\")")
+	importCmd.Flags().StringVar(&importDate, keyDate, "1", "CSV column of the date of each row")
+	importCmd.Flags().StringVar(&importAmount, keyAmount, "", "CSV column of the amount of the transaction of each row")
+	importCmd.Flags().StringVar(&importBalance, keyBalance, "", "CSV column of the balance of each row")
+	importCmd.Flags().StringSliceVar(&importNotes, keyNote, nil, "CSV columns that are joined to make the note of each row")
+	importCmd.Flags().StringVar(&importDateLayout, keyDateLayout, "", "layout of the dates of a CSV or QIF file, written as the date 2006-01-02 would be")
+	importCmd.Flags().IntVar(&importDecimals, keyDecimals, 2, "number of decimal places of the minor units of the currency of the account")
+	importCmd.Flags().IntVar(&importOpening, keyOpeningBalance, 0, "balance of the account before the first transaction of the file")
+	rootCmd.AddCommand(importCmd)
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/glynternet/mon/internal/router"
+	"github.com/glynternet/mon/pkg/storage"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,6 +17,7 @@ const (
 	appName = "moncli"
 
 	keyServerHost = "server-host"
+	keyToken      = "token"
 )
 
 var rootCmd = &cobra.Command{
@@ -24,14 +27,43 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Println(errorMessage(err))
 		os.Exit(1)
 	}
 }
 
+// errorMessage returns a message describing err that is suitable for a user of
+// moncli. Errors with a storage.ErrorKind are described by their kind and the
+// message of their cause, rather than by every error that wraps the cause.
+func errorMessage(err error) string {
+	msg := errors.Cause(err).Error()
+	if e, ok := errors.Cause(err).(*router.Error); ok {
+		msg = e.Message
+		if e.Code == router.CodeUnauthorized {
+			return fmt.Sprintf("Unauthorized: %s. Provide an API token with --%s or the TOKEN environment variable.", msg, keyToken)
+		}
+	}
+	switch storage.KindOf(err) {
+	case storage.KindNotFound:
+		return "Not found: " + msg
+	case storage.KindConflict:
+		return "Not possible: " + msg
+	case storage.KindInvalid:
+		return "Invalid: " + msg
+	case storage.KindStale:
+		return "Changed by someone else, please try again: " + msg
+	case storage.KindForbidden:
+		return "Not permitted: " + msg
+	case storage.KindUnavailable:
+		return "The mon server is unavailable, please try again later."
+	}
+	return err.Error()
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringP(keyServerHost, "H", "", "server host")
+	rootCmd.PersistentFlags().String(keyToken, "", "API token to authenticate with the server")
 	err := viper.BindPFlags(rootCmd.PersistentFlags())
 	if err != nil {
 		log.Fatal(errors.Wrap(err, "binding root command flags"))
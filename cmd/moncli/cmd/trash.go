@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/glynternet/mon/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "list deleted accounts and balances",
+	Long: `list every account and balance that has been deleted, with the time that
+each was deleted. Deleted items can be restored with the undelete subcommands of
+account and balance.`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		c := newClient()
+		as, err := c.SelectDeletedAccounts()
+		if err != nil {
+			return errors.Wrap(err, "selecting deleted accounts")
+		}
+		bs, err := c.SelectDeletedBalances()
+		if err != nil {
+			return errors.Wrap(err, "selecting deleted balances")
+		}
+
+		fmt.Println("ACCOUNTS")
+		table.DeletedAccounts(*as, os.Stdout)
+
+		fmt.Println("BALANCES")
+		table.DeletedBalances(*bs, os.Stdout)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+}
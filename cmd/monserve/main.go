@@ -6,13 +6,18 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/glynternet/mon/internal/router"
 	"github.com/glynternet/mon/internal/versioncmd"
 	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/eventsourced"
+	"github.com/glynternet/mon/pkg/storage/memory"
 	"github.com/glynternet/mon/pkg/storage/postgres"
+	"github.com/glynternet/mon/pkg/storage/sqlite"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -21,6 +26,7 @@ const (
 
 	// viper keys
 	keyPort           = "port"
+	keyStorage        = "storage"
 	keySSLCertificate = "ssl-certificate"
 	keySSLKey         = "ssl-key"
 	keyDBHost         = "db-host"
@@ -28,6 +34,10 @@ const (
 	keyDBPassword     = "db-password"
 	keyDBName         = "db-name"
 	keyDBSSLMode      = "db-sslmode"
+	keyDBPath         = "db-path"
+	keyPurgeOlderThan = "purge-older-than"
+	keyPurgeInterval  = "purge-interval"
+	keyAuth           = "auth"
 )
 
 // to be changed using ldflags with the go build command
@@ -39,17 +49,27 @@ func main() {
 	var cmdDBServe = &cobra.Command{
 		Use: appName,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			store, err := newStorage(
-				viper.GetString(keyDBHost),
-				viper.GetString(keyDBUser),
-				viper.GetString(keyDBPassword),
-				viper.GetString(keyDBName),
-				viper.GetString(keyDBSSLMode),
-			)
+			store, err := newStorageFromConfig()
 			if err != nil {
 				return errors.Wrap(err, "error creating storage")
 			}
-			r, err := router.New(store, logger)
+			if olderThan := viper.GetDuration(keyPurgeOlderThan); olderThan > 0 {
+				p, err := purger(store)
+				if err != nil {
+					return err
+				}
+				interval := viper.GetDuration(keyPurgeInterval)
+				if interval <= 0 {
+					return fmt.Errorf("%s must be positive but was %s", keyPurgeInterval, interval)
+				}
+				logger.Printf("Purging records deleted more than %s ago every %s", olderThan, interval)
+				go purgePeriodically(logger, p, olderThan, interval)
+			}
+			tokens, err := serverTokenStore(logger, store, cmd.Flags().Changed(keyAuth))
+			if err != nil {
+				return err
+			}
+			r, err := router.New(store, tokens, logger)
 			if err != nil {
 				return errors.Wrap(err, "error creating new server")
 			}
@@ -66,20 +86,33 @@ func main() {
 	}
 
 	cmdDBServe.AddCommand(versioncmd.New(version, os.Stdout))
+	cmdDBServe.AddCommand(newMigrateCmd(os.Stdout))
+	cmdDBServe.AddCommand(newPurgeCmd(os.Stdout))
+	cmdDBServe.AddCommand(newTokenCmd(os.Stdout))
 
 	cobra.OnInitialize(viperAutoEnvVar)
 	cmdDBServe.Flags().String(keyPort, "80", "server listening port")
 	cmdDBServe.Flags().String(keySSLCertificate, "", "path to SSL certificate, leave empty for http")
 	cmdDBServe.Flags().String(keySSLKey, "", "path to SSL key, leave empty for https")
-	cmdDBServe.Flags().String(keyDBHost, "", "host address of the DB backend")
-	cmdDBServe.Flags().String(keyDBName, "", "name of the DB set to use")
-	cmdDBServe.Flags().String(keyDBUser, "", "DB user to authenticate with")
-	cmdDBServe.Flags().String(keyDBPassword, "", "DB password to authenticate with")
-	cmdDBServe.Flags().String(keyDBSSLMode, "", "DB SSL mode to use")
-	err := viper.BindPFlags(cmdDBServe.Flags())
-	if err != nil {
-		logger.Printf("unable to BindPFlags: %v", err)
-		os.Exit(1)
+	cmdDBServe.Flags().Duration(keyPurgeOlderThan, 0, "retention period of deleted records, after which they are purged in the background. Zero disables purging")
+	cmdDBServe.Flags().Duration(keyPurgeInterval, 24*time.Hour, "interval between background purges of deleted records")
+	cmdDBServe.Flags().Bool(keyAuth, true, "require every request to be authenticated with an API token, created with the token command. Requests are not authenticated with memory storage, which cannot hold tokens, unless this is set explicitly, in which case the server will not start")
+	cmdDBServe.PersistentFlags().String(keyStorage, storagePostgres, fmt.Sprintf("storage backend to use, one of %s", strings.Join(storageTypes, ",")))
+	cmdDBServe.PersistentFlags().String(keyDBHost, "", "host address of the DB backend")
+	cmdDBServe.PersistentFlags().String(keyDBName, "", "name of the DB set to use")
+	cmdDBServe.PersistentFlags().String(keyDBUser, "", "DB user to authenticate with")
+	cmdDBServe.PersistentFlags().String(keyDBPassword, "", "DB password to authenticate with")
+	cmdDBServe.PersistentFlags().String(keyDBSSLMode, "", "DB SSL mode to use")
+	cmdDBServe.PersistentFlags().String(keyDBPath, "", "path to the DB file, used only by file based storage backends")
+	for _, fs := range []*pflag.FlagSet{
+		cmdDBServe.Flags(),
+		cmdDBServe.PersistentFlags(),
+	} {
+		err := viper.BindPFlags(fs)
+		if err != nil {
+			logger.Printf("unable to BindPFlags: %v", err)
+			os.Exit(1)
+		}
 	}
 
 	if err := cmdDBServe.Execute(); err != nil {
@@ -93,12 +126,75 @@ func viperAutoEnvVar() {
 	viper.AutomaticEnv() // read in environment variables that match
 }
 
-func newStorage(host, user, password, dbname, sslmode string) (storage.Storage, error) {
-	cs, err := postgres.NewConnectionString(host, user, password, dbname, sslmode)
-	if err != nil {
-		return nil, fmt.Errorf("unable to create connection string: %v", err)
+const (
+	storagePostgres = "postgres"
+	storageMemory   = "memory"
+	storageSQLite   = "sqlite"
+	// storageEventSourced stores an event log at the DB path
+	storageEventSourced = "eventsourced"
+)
+
+var storageTypes = []string{storagePostgres, storageMemory, storageSQLite, storageEventSourced}
+
+// newStorageFromConfig returns the storage described by the configured
+// storage flags.
+func newStorageFromConfig() (storage.Storage, error) {
+	return newStorage(
+		viper.GetString(keyStorage),
+		viper.GetString(keyDBHost),
+		viper.GetString(keyDBUser),
+		viper.GetString(keyDBPassword),
+		viper.GetString(keyDBName),
+		viper.GetString(keyDBSSLMode),
+		viper.GetString(keyDBPath),
+	)
+}
+
+func newStorage(storageType, host, user, password, dbname, sslmode, path string) (storage.Storage, error) {
+	switch storageType {
+	case storagePostgres:
+		cs, err := postgres.NewConnectionString(host, user, password, dbname, sslmode)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create connection string: %v", err)
+		}
+		return postgres.New(cs)
+	case storageMemory:
+		return memory.New(), nil
+	case storageSQLite:
+		if len(strings.TrimSpace(path)) == 0 {
+			return nil, errors.New("sqlite storage requires a DB path")
+		}
+		return sqlite.New(path)
+	case storageEventSourced:
+		if len(strings.TrimSpace(path)) == 0 {
+			return nil, errors.New("eventsourced storage requires a DB path for the event log")
+		}
+		l, err := eventsourced.OpenFileLog(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening event log")
+		}
+		return eventsourced.New(l)
+	}
+	return nil, fmt.Errorf("unsupported storage type %q, must be one of %s", storageType, strings.Join(storageTypes, ","))
+}
+
+// serverTokenStore returns the TokenStore that requests to the server should
+// be authenticated against, or nil if authentication has been disabled.
+// API tokens cannot be created for memory storage, so unless authentication
+// has been explicitly required, requests to a server with memory storage are
+// not authenticated, with a warning logged to say so.
+func serverTokenStore(logger *log.Logger, store storage.Storage, explicit bool) (storage.TokenStore, error) {
+	if !viper.GetBool(keyAuth) {
+		return nil, nil
+	}
+	if viper.GetString(keyStorage) == storageMemory {
+		if explicit {
+			return nil, fmt.Errorf("API tokens cannot be created for %s storage, authentication must be disabled with --%s=false", storageMemory, keyAuth)
+		}
+		logger.Printf("WARNING: requests are not authenticated, as API tokens cannot be created for %s storage. Anyone that can reach the server can read and change every account.", storageMemory)
+		return nil, nil
 	}
-	return postgres.New(cs)
+	return tokenStore(store)
 }
 
 // newServeFn returns a function that can be used to start a server.
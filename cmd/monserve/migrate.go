@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/glynternet/mon/pkg/storage/postgres"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	keyTarget = "target"
+	keySteps  = "steps"
+)
+
+// newMigrateCmd provides a command to manage the schema migrations of a
+// postgres storage backend.
+func newMigrateCmd(w io.Writer) *cobra.Command {
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "show the schema version of the postgres storage",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cs, err := postgresConnectionString()
+			if err != nil {
+				return err
+			}
+			v, err := postgres.SchemaVersion(cs)
+			if err != nil {
+				return errors.Wrap(err, "getting schema version")
+			}
+			_, err = fmt.Fprintf(w, "current: %d\nlatest: %d\n", v, postgres.LatestSchemaVersion())
+			return err
+		},
+	}
+
+	upCmd := &cobra.Command{
+		Use:   "up",
+		Short: "apply migrations up to the target version",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cs, err := postgresConnectionString()
+			if err != nil {
+				return err
+			}
+			target, err := cmd.Flags().GetUint(keyTarget)
+			if err != nil {
+				return errors.Wrap(err, "getting target version")
+			}
+			return errors.Wrapf(postgres.Migrate(cs, target), "migrating to version %d", target)
+		},
+	}
+	upCmd.Flags().Uint(keyTarget, postgres.LatestSchemaVersion(), "schema version to migrate to")
+
+	downCmd := &cobra.Command{
+		Use:   "down",
+		Short: "revert the most recently applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cs, err := postgresConnectionString()
+			if err != nil {
+				return err
+			}
+			steps, err := cmd.Flags().GetUint(keySteps)
+			if err != nil {
+				return errors.Wrap(err, "getting number of steps")
+			}
+			return errors.Wrapf(postgres.MigrateDown(cs, steps), "reverting %d migrations", steps)
+		},
+	}
+	downCmd.Flags().Uint(keySteps, 1, "number of migrations to revert")
+
+	migrateCmd.AddCommand(upCmd, downCmd)
+	return migrateCmd
+}
+
+func postgresConnectionString() (string, error) {
+	if st := viper.GetString(keyStorage); st != storagePostgres {
+		return "", fmt.Errorf("migrations are only supported for %s storage but storage is %q", storagePostgres, st)
+	}
+	cs, err := postgres.NewConnectionString(
+		viper.GetString(keyDBHost),
+		viper.GetString(keyDBUser),
+		viper.GetString(keyDBPassword),
+		viper.GetString(keyDBName),
+		viper.GetString(keyDBSSLMode),
+	)
+	return cs, errors.Wrap(err, "creating connection string")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const keyOlderThan = "older-than"
+
+// newPurgeCmd provides a command to permanently remove records that were
+// deleted longer ago than a given retention period.
+func newPurgeCmd(w io.Writer) *cobra.Command {
+	purgeCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "permanently remove records that were deleted before the retention period",
+		Long: `permanently remove the accounts and balances that were deleted longer ago
+than the retention period given by --older-than. Every balance of a removed
+account is also removed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			olderThan, err := cmd.Flags().GetDuration(keyOlderThan)
+			if err != nil {
+				return errors.Wrap(err, "getting retention period")
+			}
+			store, err := newStorageFromConfig()
+			if err != nil {
+				return errors.Wrap(err, "creating storage")
+			}
+			defer func() {
+				if err := store.Close(); err != nil {
+					log.Print(errors.Wrap(err, "closing storage"))
+				}
+			}()
+			p, err := purger(store)
+			if err != nil {
+				return err
+			}
+			purged, err := purge(p, olderThan)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "purged accounts: %d\npurged balances: %d\n", purged.Accounts, purged.Balances)
+			return err
+		},
+	}
+	purgeCmd.Flags().Duration(keyOlderThan, 30*24*time.Hour, "retention period of deleted records")
+	return purgeCmd
+}
+
+func purger(store storage.Storage) (storage.Purger, error) {
+	p, ok := store.(storage.Purger)
+	if !ok {
+		return nil, fmt.Errorf("%s storage does not support purging", viper.GetString(keyStorage))
+	}
+	return p, nil
+}
+
+// purge removes the records that were deleted longer ago than the given
+// retention period.
+func purge(p storage.Purger, olderThan time.Duration) (*storage.Purged, error) {
+	if olderThan < 0 {
+		return nil, fmt.Errorf("retention period must not be negative but was %s", olderThan)
+	}
+	before := time.Now().Add(-olderThan)
+	purged, err := p.Purge(before)
+	return purged, errors.Wrapf(err, "purging records deleted before %s", before)
+}
+
+// purgePeriodically purges, at every interval, the records that were deleted
+// longer ago than the given retention period. The count of removed records is
+// logged after each purge. purgePeriodically never returns.
+func purgePeriodically(logger *log.Logger, p storage.Purger, olderThan, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for range t.C {
+		purged, err := purge(p, olderThan)
+		if err != nil {
+			logger.Print(err)
+			continue
+		}
+		logger.Printf("Purged %d accounts and %d balances", purged.Accounts, purged.Balances)
+	}
+}
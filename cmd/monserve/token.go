@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/glynternet/mon/internal/auth"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/table"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newTokenCmd provides a command to manage the API tokens that are used to
+// authenticate requests to the server.
+func newTokenCmd(w io.Writer) *cobra.Command {
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "manage the API tokens that authenticate requests",
+		Long: `manage the API tokens that authenticate requests.
+
+The event log of eventsourced storage is locked by the server whilst it is
+running, so the tokens of eventsourced storage can only be managed whilst the
+server is stopped.`,
+	}
+
+	var user, role string
+	createCmd := &cobra.Command{
+		Use:   "create [NAME]",
+		Short: "create an API token, printing its secret",
+		Long: `create an API token with the given name, printing its secret. Only a hash
+of the secret is stored, so the secret cannot be shown again.
+
+Requests authenticated with the token are made as the given user, who can only
+access the accounts that they own or that have been shared with them. Accounts
+created before accounts had owners belong to the default user, whose name is
+empty.
+
+The role of the token determines which requests it can authenticate:
+  read-only  can only read accounts and balances
+  editor     can also add, change, delete and restore accounts and balances
+  admin      can also share accounts with other users and list the audit log`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := storage.ParseRole(role)
+			if err != nil {
+				return errors.Wrap(err, "parsing role")
+			}
+			secret, err := auth.NewToken()
+			if err != nil {
+				return errors.Wrap(err, "generating token")
+			}
+			return withTokenStore(func(ts storage.TokenStore) error {
+				t, err := ts.InsertToken(args[0], user, r, auth.HashToken(secret))
+				if err != nil {
+					return errors.Wrap(err, "inserting token")
+				}
+				_, err = fmt.Fprintf(w, "id: %d\ntoken: %s\n", t.ID, secret)
+				return err
+			})
+		},
+	}
+
+	createCmd.Flags().StringVar(&user, "user", storage.DefaultUser, "user that requests authenticated with the token are made as")
+	createCmd.Flags().StringVar(&role, "role", string(storage.RoleAdmin), "role of the token, which determines the requests that it can authenticate")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the API tokens, including those that have been revoked",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withTokenStore(func(ts storage.TokenStore) error {
+				tokens, err := ts.SelectTokens()
+				if err != nil {
+					return errors.Wrap(err, "selecting tokens")
+				}
+				rows := [][]string{{"ID", "Name", "User", "Role", "Created", "Revoked"}}
+				for _, t := range *tokens {
+					var revoked string
+					if t.Revoked != nil {
+						revoked = t.Revoked.Format(time.RFC3339)
+					}
+					rows = append(rows, []string{
+						strconv.FormatUint(uint64(t.ID), 10),
+						t.Name,
+						t.User,
+						string(t.Role),
+						t.Created.Format(time.RFC3339),
+						revoked,
+					})
+				}
+				return errors.Wrap(table.Basic(rows, w), "printing tokens table")
+			})
+		},
+	}
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke [ID]",
+		Short: "revoke an API token so that it can no longer authenticate requests",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return errors.Wrap(err, "parsing token id")
+			}
+			return withTokenStore(func(ts storage.TokenStore) error {
+				return errors.Wrapf(ts.RevokeToken(uint(id)), "revoking token %d", id)
+			})
+		},
+	}
+
+	tokenCmd.AddCommand(createCmd, listCmd, revokeCmd)
+	return tokenCmd
+}
+
+// withTokenStore runs fn with the TokenStore of the configured storage,
+// closing the storage once fn has returned.
+func withTokenStore(fn func(storage.TokenStore) error) error {
+	if viper.GetString(keyStorage) == storageMemory {
+		return fmt.Errorf("tokens of %s storage are lost as soon as the command exits", storageMemory)
+	}
+	store, err := newStorageFromConfig()
+	if err != nil {
+		return errors.Wrap(err, "creating storage")
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			log.Print(errors.Wrap(err, "closing storage"))
+		}
+	}()
+	ts, err := tokenStore(store)
+	if err != nil {
+		return err
+	}
+	return fn(ts)
+}
+
+func tokenStore(store storage.Storage) (storage.TokenStore, error) {
+	ts, ok := store.(storage.TokenStore)
+	if !ok {
+		return nil, fmt.Errorf("%s storage does not support API tokens", viper.GetString(keyStorage))
+	}
+	return ts, nil
+}
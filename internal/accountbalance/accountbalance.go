@@ -1,6 +1,8 @@
 package accountbalance
 
 import (
+	"encoding/json"
+
 	"github.com/glynternet/go-accounting/balance"
 	"github.com/glynternet/mon/pkg/storage"
 )
@@ -11,3 +13,28 @@ type AccountBalance struct {
 	storage.Account
 	balance.Balance
 }
+
+// jsonAccountBalance is the json representation of an AccountBalance. It is
+// needed because the json methods of storage.Account would otherwise be
+// promoted to AccountBalance, leaving out the balance.Balance.
+type jsonAccountBalance struct {
+	Account storage.Account
+	Balance balance.Balance
+}
+
+// MarshalJSON marshals an AccountBalance into a json blob holding both the
+// Account and the Balance.
+func (ab AccountBalance) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAccountBalance{Account: ab.Account, Balance: ab.Balance})
+}
+
+// UnmarshalJSON unmarshals a json blob produced by MarshalJSON into an
+// AccountBalance.
+func (ab *AccountBalance) UnmarshalJSON(data []byte) error {
+	var jab jsonAccountBalance
+	if err := json.Unmarshal(data, &jab); err != nil {
+		return err
+	}
+	*ab = AccountBalance{Account: jab.Account, Balance: jab.Balance}
+	return nil
+}
@@ -0,0 +1,34 @@
+// Package auth provides the API tokens that are used to authenticate requests
+// to a mon server.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+// tokenSize is the number of random bytes in the secret of a token.
+const tokenSize = 32
+
+// NewToken returns a new, randomly generated, token secret.
+func NewToken() (string, error) {
+	bs := make([]byte, tokenSize)
+	if _, err := rand.Read(bs); err != nil {
+		return "", errors.Wrap(err, "reading random bytes")
+	}
+	return base64.RawURLEncoding.EncodeToString(bs), nil
+}
+
+// HashToken returns the hash of the given token secret, which is what is held
+// by a storage.TokenStore in place of the secret itself. As token secrets are
+// long and random, a single round of SHA-256 is enough to make the secret
+// unrecoverable from its hash whilst still allowing a token to be looked up by
+// its hash.
+func HashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
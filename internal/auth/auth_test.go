@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewToken(t *testing.T) {
+	a, err := NewToken()
+	common.FatalIfError(t, err, "creating first token")
+	b, err := NewToken()
+	common.FatalIfError(t, err, "creating second token")
+	assert.NotEqual(t, a, b)
+	assert.Len(t, a, 43)
+}
+
+func TestHashToken(t *testing.T) {
+	h := HashToken("secret")
+	assert.Len(t, h, 64)
+	assert.Equal(t, h, HashToken("secret"))
+	assert.NotEqual(t, h, HashToken("secret2"))
+}
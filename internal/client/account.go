@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
 	"github.com/glynternet/mon/internal/router"
 	"github.com/glynternet/mon/pkg/storage"
 	"github.com/pkg/errors"
@@ -16,13 +19,60 @@ func (c Client) SelectAccounts() (*storage.Accounts, error) {
 	return c.getAccountsFromEndpoint(router.EndpointAccounts)
 }
 
+// SelectAccountsAsOf retrieves the accounts as they were stored at the given
+// time from the mon server
+func (c Client) SelectAccountsAsOf(t time.Time) (*storage.Accounts, error) {
+	return c.getAccountsFromEndpoint(router.EndpointAccounts + asOfQuery(t))
+}
+
+// SelectAccountsPage retrieves the accounts that match the given
+// router.AccountsQuery from the mon server, which filters and sorts them. If
+// the query has a Limit and there are more accounts to retrieve, the returned
+// cursor can be given as the Cursor of the query to retrieve the next page.
+func (c Client) SelectAccountsPage(q router.AccountsQuery) (*storage.Accounts, string, error) {
+	return c.selectAccountsPage(router.EndpointAccounts, q)
+}
+
+func (c Client) selectAccountsPage(e string, q router.AccountsQuery) (*storage.Accounts, string, error) {
+	bod, next, err := c.getPageFromEndpoint(e, q.Values())
+	if err != nil {
+		return nil, "", errors.Wrap(err, "getting page from endpoint")
+	}
+	as, err := unmarshalJSONToAccounts(bod)
+	return as, next, err
+}
+
+// SelectAccountsSummary retrieves from the mon server the balance of each of
+// the accounts that match the given router.AccountsQuery at the query's
+// AtDate, or now if it has none, along with the totals of each currency.
+func (c Client) SelectAccountsSummary(q router.AccountsQuery) (*router.AccountsSummary, error) {
+	return c.selectAccountsSummary(router.EndpointAccountsSummary, q)
+}
+
+func (c Client) selectAccountsSummary(e string, q router.AccountsQuery) (*router.AccountsSummary, error) {
+	bod, _, err := c.getPageFromEndpoint(e, q.Values())
+	if err != nil {
+		return nil, errors.Wrap(err, "getting body from endpoint")
+	}
+	s := &router.AccountsSummary{}
+	err = errors.Wrapf(json.Unmarshal(bod, s), "unmarshalling response body: %s", string(bod))
+	if err != nil {
+		s = nil
+	}
+	return s, err
+}
+
 func (c Client) getAccountsFromEndpoint(e string) (*storage.Accounts, error) {
 	bod, err := c.getBodyFromEndpoint(e)
 	if err != nil {
 		return nil, errors.Wrap(err, "getting body from endpoint")
 	}
+	return unmarshalJSONToAccounts(bod)
+}
+
+func unmarshalJSONToAccounts(bod []byte) (*storage.Accounts, error) {
 	as := &storage.Accounts{}
-	err = errors.Wrapf(json.Unmarshal(bod, as), "unmarshalling response body: %s", string(bod))
+	err := errors.Wrapf(json.Unmarshal(bod, as), "unmarshalling response body: %s", string(bod))
 	if err != nil {
 		as = nil
 	}
@@ -61,17 +111,61 @@ func (c Client) UpdateAccount(id uint, updates account.Account) (*storage.Accoun
 	return unmarshalJSONToAccount(bs)
 }
 
-// DeleteAccount will attempt to delete an account through the mon server by the given id
-func (c Client) DeleteAccount(id uint) error {
-	endpoint := fmt.Sprintf(router.EndpointFmtAccount, id)
+// DeleteAccount will attempt to delete an account through the mon server by
+// the given id, treating the live balances of the account according to the
+// given storage.DeletionPolicy
+func (c Client) DeleteAccount(id uint, policy storage.DeletionPolicy) error {
+	endpoint := fmt.Sprintf(router.EndpointFmtAccount, id) +
+		"?" + url.Values{router.QueryDeletionPolicy: {string(policy)}}.Encode()
 	r, err := c.deleteToEndpoint(endpoint)
 	if err != nil {
 		return errors.Wrapf(err, "deleting account to endpoint %s", endpoint)
 	}
-	if r.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code %d (%s)", r.StatusCode, http.StatusText(r.StatusCode))
+	_, err = processResponseForBody(r)
+	return errors.Wrap(err, "processing response for body")
+}
+
+// OpenAccount will open an account with an opening balance through the mon
+// server, returning the stored Account and Balance. Either both the account
+// and balance will be stored or neither will be.
+func (c Client) OpenAccount(a account.Account, amount int, note string) (*storage.Account, *storage.Balance, error) {
+	res, err := c.postAsJSONToEndpoint(router.EndpointAccountOpen, router.AccountOpenBody{
+		Account: a,
+		Amount:  amount,
+		Note:    note,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "posting AccountOpenBody to endpoint %s", router.EndpointAccountOpen)
+	}
+	return processResponseForAccountBalance(res)
+}
+
+// CloseAccount will close the account at the given id with a closing balance
+// through the mon server, returning the stored Account and Balance. Either
+// both the balance will be stored and the account closed or neither will.
+func (c Client) CloseAccount(id uint, b balance.Balance, note string) (*storage.Account, *storage.Balance, error) {
+	endpoint := fmt.Sprintf(router.EndpointFmtAccountClose, id)
+	res, err := c.postAsJSONToEndpoint(endpoint, router.BalanceInsertBody{
+		Balance: b,
+		Note:    note,
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "posting BalanceInsertBody to endpoint %s", endpoint)
+	}
+	return processResponseForAccountBalance(res)
+}
+
+func processResponseForAccountBalance(res *http.Response) (*storage.Account, *storage.Balance, error) {
+	bs, err := processResponseForBody(res)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "processing response for body")
+	}
+	var ab router.AccountBalance
+	err = json.Unmarshal(bs, &ab)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "json unmarshalling into account balance. bytes as string: %s", bs)
 	}
-	return nil
+	return &ab.Account, &ab.Balance, nil
 }
 
 func (c Client) postAccountToEndpoint(e string, a account.Account) ([]byte, error) {
@@ -13,7 +13,7 @@ import (
 
 func TestGetAccountsFromEndpoint(t *testing.T) {
 	t.Run("get body error", func(t *testing.T) {
-		c := Client("bloopybloop")
+		c := New("bloopybloop")
 		as, err := c.getAccountsFromEndpoint("")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "getting from endpoint")
@@ -26,7 +26,7 @@ func TestGetAccountsFromEndpoint(t *testing.T) {
 			http.StatusOK,
 		)
 		defer srv.Close()
-		c := Client(srv.URL)
+		c := New(srv.URL)
 		as, err := c.getAccountsFromEndpoint("")
 		if assert.Error(t, err) {
 			assert.IsType(t, &json.UnmarshalTypeError{}, errors.Cause(err))
@@ -37,7 +37,7 @@ func TestGetAccountsFromEndpoint(t *testing.T) {
 
 func TestGetAccountFromEndpoint(t *testing.T) {
 	t.Run("get body error", func(t *testing.T) {
-		c := Client("bloopybleep")
+		c := New("bloopybleep")
 		a, err := c.getAccountFromEndpoint("")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "getting from endpoint")
@@ -50,7 +50,7 @@ func TestGetAccountFromEndpoint(t *testing.T) {
 			http.StatusOK,
 		)
 		defer srv.Close()
-		c := Client(srv.URL)
+		c := New(srv.URL)
 		as, err := c.getAccountFromEndpoint("")
 		if assert.Error(t, err) {
 			assert.Contains(t, err.Error(), "json unmarshalling into account")
@@ -63,7 +63,7 @@ func TestPostAccountToEndpoint(t *testing.T) {
 	// TODO: this error can probably be caused by a timeout when timeouts are
 	// implemented in the repo
 	//t.Run("post as json error", func(t *testing.T) {
-	//	bod, err := Client("BLOOOOP").postAccountToEndpoint("", nil)
+	//	bod, err := New("BLOOOOP").postAccountToEndpoint("", nil)
 	//	if assert.Error(t, err) {
 	//		assert.Contains(t, err.Error(), "posting as JSON")
 	//	}
@@ -74,7 +74,7 @@ func TestPostAccountToEndpoint(t *testing.T) {
 		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
 		}))
-		bod, err := Client(srv.URL).postAccountToEndpoint("", account.Account{})
+		bod, err := New(srv.URL).postAccountToEndpoint("", account.Account{})
 		if assert.Error(t, err) {
 			assert.Contains(t, err.Error(), "server returned unexpected code ")
 		}
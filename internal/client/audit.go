@@ -0,0 +1,27 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/glynternet/mon/internal/router"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// SelectAuditEntriesPage retrieves from the mon server the record of the
+// requests made by the user of the Client's token that changed accounts or
+// balances and that match the given router.AuditQuery. If the query has a
+// Limit and there are more entries to retrieve, the returned cursor can be
+// given as the Cursor of the query to retrieve the next page.
+func (c Client) SelectAuditEntriesPage(q router.AuditQuery) (*storage.AuditEntries, string, error) {
+	bod, next, err := c.getPageFromEndpoint(router.EndpointAudit, q.Values())
+	if err != nil {
+		return nil, "", errors.Wrap(err, "getting page from endpoint")
+	}
+	es := &storage.AuditEntries{}
+	err = errors.Wrapf(json.Unmarshal(bod, es), "unmarshalling response body: %s", string(bod))
+	if err != nil {
+		return nil, "", err
+	}
+	return es, next, nil
+}
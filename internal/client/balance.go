@@ -3,7 +3,8 @@ package client
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/glynternet/go-accounting/balance"
 	"github.com/glynternet/mon/internal/router"
@@ -16,19 +17,61 @@ func (c Client) SelectAccountBalances(id uint) (*storage.Balances, error) {
 	return c.getBalancesFromEndpoint(fmt.Sprintf(router.EndpointFmtAccountBalances, id))
 }
 
+// SelectAccountBalancesAsOf will select the Balances of a given Account as
+// they were stored at the given time
+func (c Client) SelectAccountBalancesAsOf(id uint, t time.Time) (*storage.Balances, error) {
+	return c.getBalancesFromEndpoint(fmt.Sprintf(router.EndpointFmtAccountBalances, id) + asOfQuery(t))
+}
+
+// SelectAccountBalancesPage will select the Balances of a given Account that
+// match the given router.BalancesQuery, which are filtered and ordered by the
+// mon server. If the query has a Limit and there are more Balances to
+// retrieve, the returned cursor can be given as the Cursor of the query to
+// retrieve the next page.
+func (c Client) SelectAccountBalancesPage(id uint, q router.BalancesQuery) (*storage.Balances, string, error) {
+	return c.selectBalancesPage(fmt.Sprintf(router.EndpointFmtAccountBalances, id), q)
+}
+
+func (c Client) selectBalancesPage(e string, q router.BalancesQuery) (*storage.Balances, string, error) {
+	bod, next, err := c.getPageFromEndpoint(e, q.Values())
+	if err != nil {
+		return nil, "", errors.Wrap(err, "getting page from endpoint")
+	}
+	bs, err := unmarshalJSONToBalances(bod)
+	return bs, next, err
+}
+
+// asOfQuery returns the query string to request data as of the given time.
+func asOfQuery(t time.Time) string {
+	return "?" + url.Values{router.QueryAsOf: {t.Format(time.RFC3339Nano)}}.Encode()
+}
+
 func (c Client) getBalancesFromEndpoint(e string) (*storage.Balances, error) {
 	bod, err := c.getBodyFromEndpoint(e)
 	if err != nil {
 		return nil, errors.Wrap(err, "getting body from endpoint")
 	}
+	return unmarshalJSONToBalances(bod)
+}
+
+func unmarshalJSONToBalances(bod []byte) (*storage.Balances, error) {
 	bs := &storage.Balances{}
-	err = errors.Wrap(json.Unmarshal(bod, bs), "unmarshalling response")
+	err := errors.Wrap(json.Unmarshal(bod, bs), "unmarshalling response")
 	if err != nil {
 		bs = nil
 	}
 	return bs, err
 }
 
+// SelectBalance retrieves a balance from the mon server by a given ID
+func (c Client) SelectBalance(id uint) (*storage.Balance, error) {
+	bod, err := c.getBodyFromEndpoint(fmt.Sprintf(router.EndpointFmtBalance, id))
+	if err != nil {
+		return nil, errors.Wrap(err, "getting body from endpoint")
+	}
+	return unmarshalJSONToBalance(bod)
+}
+
 // InsertBalance will insert a balance for a given Account
 func (c Client) InsertBalance(accountID uint, b balance.Balance, note string) (*storage.Balance, error) {
 	endpoint := fmt.Sprintf(router.EndpointFmtAccountBalanceInsert, accountID)
@@ -47,6 +90,25 @@ func (c Client) InsertBalance(accountID uint, b balance.Balance, note string) (*
 	return unmarshalJSONToBalance(bs)
 }
 
+// UpdateBalance will update the balance with the given id, that belongs to the
+// Account with the given accountID
+func (c Client) UpdateBalance(accountID, id uint, b balance.Balance, note string) (*storage.Balance, error) {
+	endpoint := fmt.Sprintf(router.EndpointFmtAccountBalanceUpdate, accountID, id)
+
+	res, err := c.postAsJSONToEndpoint(endpoint, router.BalanceInsertBody{
+		Balance: b,
+		Note:    note,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "posting BalanceInsertBody to endpoint:%s", endpoint)
+	}
+	bs, err := processResponseForBody(res)
+	if err != nil {
+		return nil, errors.Wrap(err, "processing response for body")
+	}
+	return unmarshalJSONToBalance(bs)
+}
+
 // DeleteBalance deletes a balance at a given id
 func (c Client) DeleteBalance(id uint) error {
 	endpoint := fmt.Sprintf(router.EndpointFmtBalance, id)
@@ -54,10 +116,8 @@ func (c Client) DeleteBalance(id uint) error {
 	if err != nil {
 		return errors.Wrapf(err, "deleting balance to endpoint %s", endpoint)
 	}
-	if r.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code %d (%s)", r.StatusCode, http.StatusText(r.StatusCode))
-	}
-	return nil
+	_, err = processResponseForBody(r)
+	return errors.Wrap(err, "processing response for body")
 }
 
 func unmarshalJSONToBalance(data []byte) (*storage.Balance, error) {
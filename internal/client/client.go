@@ -2,19 +2,105 @@ package client
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/glynternet/mon/internal/router"
+	"github.com/glynternet/mon/pkg/storage"
 	"github.com/pkg/errors"
 )
 
 // Client is a client to retrieve accounting items over http using REST
-type Client string
+type Client struct {
+	host           string
+	token          string
+	ifMatch        uint
+	idempotencyKey string
+}
+
+const (
+	// postAttempts is the number of times that a POST request is made when
+	// the mon server cannot be reached. Every attempt is made with the same
+	// idempotency key, so a request that reached the server before its
+	// response was lost is not served again.
+	postAttempts = 3
+	// postRetryDelay is the delay before the second attempt of a POST
+	// request, which increases with each attempt.
+	postRetryDelay = 100 * time.Millisecond
+	// inProgressWait is how long a POST request is made again for while the
+	// mon server responds that an earlier attempt with the same idempotency
+	// key is still being served, and inProgressRetryDelay is the delay
+	// before each of those attempts. The wait is longer than the time after
+	// which the server serves a repeat of a request that has been abandoned.
+	inProgressWait       = time.Minute
+	inProgressRetryDelay = time.Second
+)
+
+// New returns a Client for the mon server at the given host.
+func New(host string) Client {
+	return Client{host: host}
+}
+
+// WithToken returns a copy of the Client that authenticates every request
+// that it makes with the given API token.
+func (c Client) WithToken(token string) Client {
+	c.token = token
+	return c
+}
+
+// IfMatch returns a copy of the Client that makes every request that updates
+// or deletes an Account or Balance conditional on the item being at the given
+// version, as given by the Version of a selected storage.Account or
+// storage.Balance. A request whose condition is not met fails with an error of
+// storage.KindStale. A version of zero makes requests unconditional.
+func (c Client) IfMatch(version uint) Client {
+	c.ifMatch = version
+	return c
+}
+
+// WithIdempotencyKey returns a copy of the Client that makes every POST
+// request with the given idempotency key, instead of a key that is generated
+// for each request. This allows a request to be repeated safely by something
+// other than the Client, such as a user that runs a command again after it
+// failed, as long as the same key is given. The server gives the response to
+// the first request with a key to every repeat of it.
+func (c Client) WithIdempotencyKey(key string) Client {
+	c.idempotencyKey = key
+	return c
+}
+
+// newIdempotencyKey returns a random key that is suitable for use as an
+// idempotency key.
+func newIdempotencyKey() (string, error) {
+	bs := make([]byte, 16)
+	if _, err := rand.Read(bs); err != nil {
+		return "", errors.Wrap(err, "reading random bytes")
+	}
+	return hex.EncodeToString(bs), nil
+}
+
+// RetryStale calls fn until it returns an error that is not of
+// storage.KindStale or it has been called the given number of times,
+// returning the last error. fn should select the items that it changes each
+// time that it is called, so that it changes the latest version of them.
+func RetryStale(attempts int, fn func() error) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		err = fn()
+		if storage.KindOf(err) != storage.KindStale {
+			return err
+		}
+	}
+	return err
+}
 
 // newClient provides the client that should be used to make any calls against
 // the mon server
@@ -22,20 +108,91 @@ func newClient() *http.Client {
 	return &http.Client{Timeout: 5 * time.Second}
 }
 
+// getFromEndpoint returns any error that occurs whilst making the request to
+// the mon server with storage.KindUnavailable, as do postToEndpoint and
+// deleteToEndpoint.
 func (c Client) getFromEndpoint(endpoint string) (*http.Response, error) {
-	return http.Get(string(c) + endpoint)
+	return c.requestToEndpoint(http.MethodGet, endpoint, "", nil)
 }
 
-func (c Client) postToEndpoint(endpoint string, contentType string, body io.Reader) (*http.Response, error) {
-	return http.Post(string(c)+endpoint, contentType, body)
+// postToEndpoint makes the request with an idempotency key, generating one if
+// the Client was not given one, and makes it again with the same key if the
+// mon server cannot be reached or responds that an earlier attempt is still
+// being served.
+func (c Client) postToEndpoint(endpoint string, contentType string, body []byte) (*http.Response, error) {
+	if c.idempotencyKey == "" {
+		key, err := newIdempotencyKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "generating idempotency key")
+		}
+		c.idempotencyKey = key
+	}
+	deadline := time.Now().Add(inProgressWait)
+	for attempt := 1; ; {
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(body)
+		}
+		res, err := c.requestToEndpoint(http.MethodPost, endpoint, contentType, r)
+		switch {
+		case storage.KindOf(err) == storage.KindUnavailable && attempt < postAttempts:
+			time.Sleep(time.Duration(attempt) * postRetryDelay)
+			attempt++
+		case err == nil && inProgress(res) && time.Now().Before(deadline):
+			time.Sleep(inProgressRetryDelay)
+		default:
+			return res, err
+		}
+	}
+}
+
+// inProgress returns true if the response is an Error of
+// router.CodeInProgress, in which case the body of the response is closed.
+// Otherwise, the body of the response is left to be read again.
+func inProgress(res *http.Response) bool {
+	if res.StatusCode != http.StatusConflict {
+		return false
+	}
+	bod, err := ioutil.ReadAll(res.Body)
+	if cErr := res.Body.Close(); cErr != nil {
+		log.Print(errors.Wrap(cErr, "closing response body"))
+	}
+	var e router.Error
+	if err == nil && json.Unmarshal(bod, &e) == nil && e.Code == router.CodeInProgress {
+		return true
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(bod))
+	return false
 }
 
 func (c Client) deleteToEndpoint(endpoint string) (*http.Response, error) {
-	r, err := http.NewRequest(http.MethodDelete, string(c)+endpoint, nil)
+	return c.requestToEndpoint(http.MethodDelete, endpoint, "", nil)
+}
+
+// requestToEndpoint makes a request to the mon server, authenticated with the
+// token of the Client if it has one. The contentType is only set if the
+// request has a body. Requests other than GET requests are made conditional
+// on the version given to IfMatch, if any, and POST requests are made with the
+// idempotency key of the Client, if it has one.
+func (c Client) requestToEndpoint(method, endpoint, contentType string, body io.Reader) (*http.Response, error) {
+	r, err := http.NewRequest(method, c.host+endpoint, body)
 	if err != nil {
 		return nil, errors.Wrap(err, "creating new request")
 	}
-	return newClient().Do(r)
+	if body != nil {
+		r.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		r.Header.Set(router.HeaderAuthorization, router.AuthSchemeBearer+" "+c.token)
+	}
+	if c.ifMatch != 0 && method != http.MethodGet {
+		r.Header.Set(router.HeaderIfMatch, router.EntityTag(c.ifMatch))
+	}
+	if c.idempotencyKey != "" && method == http.MethodPost {
+		r.Header.Set(router.HeaderIdempotencyKey, c.idempotencyKey)
+	}
+	res, err := newClient().Do(r)
+	return res, storage.Unavailable(err)
 }
 
 // Available reports whether the mon server is available using the Client
@@ -45,6 +202,14 @@ func (c Client) Available() bool {
 	return err == nil
 }
 
+// Atomic is not supported by the Client because there is no way to hold a
+// transaction open across multiple requests to the mon server. Operations
+// that must be atomic should be made through the composite endpoints, such as
+// OpenAccount and CloseAccount.
+func (c Client) Atomic(func(storage.Storage) error) error {
+	return errors.New("atomic operations are not supported by the client")
+}
+
 // Close is a noop closer as there is not behaviour required to close this client
 func (c Client) Close() error {
 	return nil
@@ -58,28 +223,63 @@ func (c Client) getBodyFromEndpoint(e string) ([]byte, error) {
 	return processResponseForBody(res)
 }
 
-func processResponseForBody(r *http.Response) ([]byte, error) {
-	if r.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server returned unexpected code %d (%s)", r.StatusCode, r.Status)
+// getPageFromEndpoint returns the body of the response to a request to the
+// given endpoint with the given query, along with the cursor of the next page
+// of the response, if there is one.
+func (c Client) getPageFromEndpoint(e string, query url.Values) ([]byte, string, error) {
+	if len(query) > 0 {
+		e += "?" + query.Encode()
 	}
-	bod, err := ioutil.ReadAll(r.Body)
+	res, err := c.getFromEndpoint(e)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "getting from endpoint")
+	}
+	next := res.Header.Get(router.HeaderNextCursor)
+	bod, err := processResponseForBody(res)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "processing response for body")
+	}
+	return bod, next, nil
+}
 
+// processResponseForBody returns the body of a response with a successful
+// status. For any other status, the returned error will be the *router.Error
+// that the server responded with, if there is one.
+func processResponseForBody(r *http.Response) ([]byte, error) {
 	defer func() {
 		// TODO: this handler only needs to take a []byte which would mean we can handle closing the body elsewhere
 		cErr := r.Body.Close()
 		if cErr != nil {
-			log.Print(errors.Wrap(err, "closing response body"))
+			log.Print(errors.Wrap(cErr, "closing response body"))
 		}
 	}()
 
+	if r.StatusCode < 200 || r.StatusCode > 299 {
+		return nil, responseError(r)
+	}
+	bod, err := ioutil.ReadAll(r.Body)
 	return bod, errors.Wrap(err, "reading response body")
 }
 
+// responseError returns the *router.Error held in the body of the given
+// response or, if the body does not hold one, an error describing the status
+// of the response.
+func responseError(r *http.Response) error {
+	bod, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		e := &router.Error{}
+		if json.Unmarshal(bod, e) == nil && e.Code != "" {
+			return e
+		}
+	}
+	return fmt.Errorf("server returned unexpected code %d (%s)", r.StatusCode, r.Status)
+}
+
 func (c Client) postAsJSONToEndpoint(e string, thing interface{}) (*http.Response, error) {
 	bs, err := json.Marshal(thing)
 	if err != nil {
 		return nil, errors.Wrap(err, "marshalling json")
 	}
-	res, err := c.postToEndpoint(e, `application/json; charset=UTF-8`, bytes.NewReader(bs))
+	res, err := c.postToEndpoint(e, `application/json; charset=UTF-8`, bs)
 	return res, errors.Wrap(err, "posting to endpoint")
 }
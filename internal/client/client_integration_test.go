@@ -11,10 +11,14 @@ import (
 	"github.com/glynternet/go-accounting/accountingtest"
 	"github.com/glynternet/go-accounting/balance"
 	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/internal/auth"
 	"github.com/glynternet/mon/internal/router"
 	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/eventsourced"
+	"github.com/glynternet/mon/pkg/storage/memory"
 	"github.com/glynternet/mon/pkg/storage/storagetest"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -63,6 +67,46 @@ func TestClient_SelectAccounts(t *testing.T) {
 	common.FatalIfError(t, <-errCh, "received error")
 }
 
+func TestClient_SelectAccountsPage(t *testing.T) {
+	opened := time.Now().UTC().Truncate(time.Nanosecond)
+	s := &storagetest.Storage{
+		Accounts: &storage.Accounts{
+			{ID: 3, Account: *accountingtest.NewAccount(t, "c", accountingtest.NewCurrencyCode(t, "GBP"), opened)},
+			{ID: 1, Account: *accountingtest.NewAccount(t, "a", accountingtest.NewCurrencyCode(t, "EUR"), opened)},
+			{ID: 2, Account: *accountingtest.NewAccount(t, "b", accountingtest.NewCurrencyCode(t, "GBP"), opened)},
+		},
+	}
+
+	r, listener, client := newTestComponents(t, s)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, r)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		q := router.AccountsQuery{Currencies: []string{"GBP"}, Limit: 1}
+		first, cursor, err := client.SelectAccountsPage(q)
+		assert.NoError(t, err)
+		assert.Equal(t, &storage.Accounts{(*s.Accounts)[2]}, first)
+		assert.NotEmpty(t, cursor)
+
+		q.Cursor = cursor
+		second, cursor, err := client.SelectAccountsPage(q)
+		assert.NoError(t, err)
+		assert.Equal(t, &storage.Accounts{(*s.Accounts)[0]}, second)
+		assert.Empty(t, cursor)
+
+		_, _, err = client.SelectAccountsPage(router.AccountsQuery{SortBy: "balance"})
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
 func TestClient_SelectAccount(t *testing.T) {
 	s := &storagetest.Storage{
 		Account: &storage.Account{
@@ -132,6 +176,93 @@ func TestClient_SelectAccountBalances(t *testing.T) {
 	common.FatalIfError(t, <-errCh, "received error")
 }
 
+func TestClient_SelectAccountBalancesPage(t *testing.T) {
+	opened := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := memory.New()
+	a, err := s.InsertAccount(*accountingtest.NewAccount(t, "test", accountingtest.NewCurrencyCode(t, "EUR"), opened))
+	common.FatalIfError(t, err, "inserting account")
+	var inserted storage.Balances
+	for i := 0; i < 3; i++ {
+		b, err := s.InsertBalance(a.ID, balance.Balance{Date: opened.AddDate(0, 0, i), Amount: i}, "")
+		common.FatalIfError(t, err, "inserting balance")
+		inserted = append(inserted, *b)
+	}
+
+	r, listener, client := newTestComponents(t, s)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, r)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		from := opened.AddDate(0, 0, 1)
+		q := router.BalancesQuery{From: &from, Order: storage.OrderDescending, Limit: 1}
+		first, cursor, err := client.SelectAccountBalancesPage(a.ID, q)
+		assert.NoError(t, err)
+		assert.Equal(t, &storage.Balances{inserted[2]}, first)
+		assert.NotEmpty(t, cursor)
+
+		q.Cursor = cursor
+		second, cursor, err := client.V2().SelectAccountBalancesPage(a.ID, q)
+		assert.NoError(t, err)
+		assert.Equal(t, &storage.Balances{inserted[1]}, second)
+		assert.Empty(t, cursor)
+
+		_, _, err = client.SelectAccountBalancesPage(a.ID, router.BalancesQuery{Order: "sideways"})
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestClient_SelectAccountsSummary(t *testing.T) {
+	opened := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := memory.New()
+	a, err := s.InsertAccount(*accountingtest.NewAccount(t, "test", accountingtest.NewCurrencyCode(t, "EUR"), opened))
+	common.FatalIfError(t, err, "inserting account")
+	for i := 1; i <= 3; i++ {
+		_, err := s.InsertBalance(a.ID, balance.Balance{Date: opened.AddDate(0, 0, i), Amount: i}, "")
+		common.FatalIfError(t, err, "inserting balance")
+	}
+
+	r, listener, client := newTestComponents(t, s)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, r)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		at := opened.AddDate(0, 0, 2)
+		summary, err := client.SelectAccountsSummary(router.AccountsQuery{AtDate: &at})
+		assert.NoError(t, err)
+		if assert.NotNil(t, summary) && assert.Len(t, summary.Accounts, 1) {
+			assert.Equal(t, a.ID, summary.Accounts[0].Account.ID)
+			assert.Equal(t, 3, summary.Accounts[0].Balance.Amount)
+			assert.True(t, at.Equal(summary.Accounts[0].Balance.Date))
+			assert.Equal(t, map[string]int{"EUR": 3}, summary.Totals)
+		}
+
+		summary, err = client.V2().SelectAccountsSummary(router.AccountsQuery{})
+		assert.NoError(t, err)
+		if assert.NotNil(t, summary) {
+			assert.Equal(t, map[string]int{"EUR": 6}, summary.Totals)
+		}
+
+		_, err = client.SelectAccountsSummary(router.AccountsQuery{Limit: 1})
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
 func TestClient_InsertAccount(t *testing.T) {
 	account := &storage.Account{
 		ID: 51,
@@ -200,6 +331,426 @@ func TestClient_InsertBalance(t *testing.T) {
 	common.FatalIfError(t, <-errCh, "received error")
 }
 
+func TestClient_OpenAndCloseAccount(t *testing.T) {
+	opened := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := accountingtest.NewAccount(t, "test", accountingtest.NewCurrencyCode(t, "EUR"), opened)
+
+	router, listener, client := newTestComponents(t, memory.New())
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, router)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		dba, dbb, err := client.OpenAccount(*a, 100, "opening")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, dba.Account.Equal(*a))
+		assert.Equal(t, 100, dbb.Amount)
+		assert.True(t, dbb.Date.Equal(opened))
+
+		_, _, err = client.CloseAccount(dba.ID, balance.Balance{Date: opened.Add(-time.Hour)}, "invalid")
+		assert.Error(t, err)
+
+		closed := opened.Add(time.Hour)
+		dba, dbb, err = client.CloseAccount(dba.ID, balance.Balance{Date: closed, Amount: 0}, "closing")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, dba.Account.Closed().EqualTime(closed))
+		assert.Equal(t, "closing", dbb.Note)
+
+		bs, err := client.SelectAccountBalances(dba.ID)
+		assert.NoError(t, err)
+		assert.Len(t, *bs, 2)
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestClient_StorageSuite(t *testing.T) {
+	router, listener, client := newTestComponents(t, memory.New())
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, router)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		storagetest.Test(t, client)
+		close(errCh)
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestV2_StorageSuite(t *testing.T) {
+	router, listener, client := newTestComponents(t, memory.New())
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, router)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		storagetest.Test(t, client.V2())
+		close(errCh)
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestClient_HistorySuite(t *testing.T) {
+	router, listener, client := newTestComponents(t, memory.New())
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, router)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		storagetest.TestHistory(t, client)
+		close(errCh)
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestClient_WithToken(t *testing.T) {
+	s := memory.New()
+	secret, err := auth.NewToken()
+	common.FatalIfError(t, err, "creating token")
+	_, err = s.InsertToken("test", storage.DefaultUser, storage.RoleAdmin, auth.HashToken(secret))
+	common.FatalIfError(t, err, "inserting token")
+
+	r, err := router.New(s, s, log.New(os.Stderr, "", log.LstdFlags))
+	common.FatalIfError(t, err, "creating new router")
+	listener := newTestNetListener(t)
+	client := newTestClient(listener)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, r)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		_, err := client.SelectAccounts()
+		if assert.IsType(t, &router.Error{}, errors.Cause(err)) {
+			assert.Equal(t, router.CodeUnauthorized, errors.Cause(err).(*router.Error).Code)
+		}
+
+		as, err := client.WithToken(secret).SelectAccounts()
+		assert.NoError(t, err)
+		assert.Equal(t, &storage.Accounts{}, as)
+
+		_, err = client.WithToken(secret).V2().InsertAccount(*accountingtest.NewAccount(t, "test", accountingtest.NewCurrencyCode(t, "EUR"), time.Now()))
+		assert.NoError(t, err)
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestV2_ShareAccount(t *testing.T) {
+	s := memory.New()
+	secrets := make(map[string]string)
+	for _, user := range []string{"owner", "other"} {
+		secret, err := auth.NewToken()
+		common.FatalIfError(t, err, "creating token")
+		_, err = s.InsertToken(user, user, storage.RoleAdmin, auth.HashToken(secret))
+		common.FatalIfError(t, err, "inserting token")
+		secrets[user] = secret
+	}
+
+	r, err := router.New(s, s, log.New(os.Stderr, "", log.LstdFlags))
+	common.FatalIfError(t, err, "creating new router")
+	listener := newTestNetListener(t)
+	client := newTestClient(listener)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, r)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		owner := client.WithToken(secrets["owner"]).V2()
+		other := client.WithToken(secrets["other"]).V2()
+		a, err := owner.InsertAccount(*accountingtest.NewAccount(t, "shared", accountingtest.NewCurrencyCode(t, "EUR"), time.Now()))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = other.SelectAccount(a.ID)
+		assert.Equal(t, storage.KindNotFound, storage.KindOf(err))
+
+		assert.NoError(t, owner.ShareAccount(a.ID, "other"))
+		users, err := owner.SelectAccountShares(a.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"other"}, users)
+
+		_, err = other.SelectAccount(a.ID)
+		assert.NoError(t, err)
+		_, err = other.InsertBalance(a.ID, balance.Balance{Date: time.Now()}, "")
+		assert.Equal(t, storage.KindForbidden, storage.KindOf(err))
+
+		assert.NoError(t, owner.UnshareAccount(a.ID, "other"))
+		_, err = other.SelectAccount(a.ID)
+		assert.Equal(t, storage.KindNotFound, storage.KindOf(err))
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestClient_SelectAuditEntriesPage(t *testing.T) {
+	s := memory.New()
+	secret, err := auth.NewToken()
+	common.FatalIfError(t, err, "creating token")
+	_, err = s.InsertToken("auditor", "auditor", storage.RoleAdmin, auth.HashToken(secret))
+	common.FatalIfError(t, err, "inserting token")
+
+	r, err := router.New(s, s, log.New(os.Stderr, "", log.LstdFlags))
+	common.FatalIfError(t, err, "creating new router")
+	listener := newTestNetListener(t)
+	client := newTestClient(listener).WithToken(secret)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, r)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		a, err := client.InsertAccount(*accountingtest.NewAccount(t, "audited", accountingtest.NewCurrencyCode(t, "EUR"), time.Now()))
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = client.InsertBalance(a.ID, balance.Balance{Date: time.Now(), Amount: 1}, "")
+		assert.NoError(t, err)
+
+		es, next, err := client.SelectAuditEntriesPage(router.AuditQuery{Limit: 1})
+		if !assert.NoError(t, err) || !assert.Len(t, *es, 1) {
+			return
+		}
+		assert.Equal(t, "AccountInsert", (*es)[0].Route)
+		assert.Equal(t, a.ID, (*es)[0].AccountID)
+		assert.NotEmpty(t, next)
+
+		es, next, err = client.SelectAuditEntriesPage(router.AuditQuery{Limit: 1, Cursor: next})
+		if !assert.NoError(t, err) || !assert.Len(t, *es, 1) {
+			return
+		}
+		assert.Equal(t, "BalanceInsert", (*es)[0].Route)
+		assert.Empty(t, next)
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestClient_SelectEvents(t *testing.T) {
+	s, err := eventsourced.New(eventsourced.NewMemoryLog())
+	common.FatalIfError(t, err, "creating storage")
+	secret, err := auth.NewToken()
+	common.FatalIfError(t, err, "creating token")
+	_, err = s.InsertToken("auditor", "auditor", storage.RoleAdmin, auth.HashToken(secret))
+	common.FatalIfError(t, err, "inserting token")
+
+	r, err := router.New(s, s, log.New(os.Stderr, "", log.LstdFlags))
+	common.FatalIfError(t, err, "creating new router")
+	listener := newTestNetListener(t)
+	client := newTestClient(listener).WithToken(secret)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, r)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		a, err := client.InsertAccount(*accountingtest.NewAccount(t, "evented", accountingtest.NewCurrencyCode(t, "EUR"), time.Now()))
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = client.InsertBalance(a.ID, balance.Balance{Date: time.Now(), Amount: 1}, "")
+		assert.NoError(t, err)
+
+		es, err := client.SelectEvents(router.EventsQuery{Limit: 1})
+		if !assert.NoError(t, err) || !assert.Len(t, *es, 1) {
+			return
+		}
+		assert.Equal(t, storage.AccountInserted, (*es)[0].Type)
+		assert.Equal(t, "auditor", (*es)[0].Actor)
+		assert.Equal(t, a.ID, (*es)[0].AccountID)
+
+		es, err = client.SelectEvents(router.EventsQuery{After: (*es)[0].Sequence})
+		if !assert.NoError(t, err) || !assert.Len(t, *es, 1) {
+			return
+		}
+		assert.Equal(t, storage.BalanceInserted, (*es)[0].Type)
+		assert.Equal(t, "auditor", (*es)[0].Actor)
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestClient_IfMatch(t *testing.T) {
+	router, listener, client := newTestComponents(t, memory.New())
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, router)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		storagetest.TestVersions(t, client.V2())
+
+		a, err := client.InsertAccount(*accountingtest.NewAccount(t, "if-match", accountingtest.NewCurrencyCode(t, "EUR"), time.Now()))
+		if !assert.NoError(t, err) {
+			return
+		}
+		b, err := client.InsertBalance(a.ID, balance.Balance{Date: time.Now(), Amount: 1}, "")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = client.IfMatch(a.Version+1).UpdateAccount(a.ID, a.Account)
+		assert.Equal(t, storage.KindStale, storage.KindOf(err))
+		_, err = client.IfMatch(b.Version+1).V2().UpdateBalance(a.ID, b.ID, b.Balance, "stale")
+		assert.Equal(t, storage.KindStale, storage.KindOf(err))
+		assert.Equal(t, storage.KindStale, storage.KindOf(client.IfMatch(b.Version+1).V2().DeleteBalance(b.ID)))
+
+		var attempts int
+		err = RetryStale(3, func() error {
+			attempts++
+			selected, err := client.SelectBalance(b.ID)
+			if err != nil {
+				return err
+			}
+			version := selected.Version
+			if attempts == 1 {
+				// the first attempt is made with a version that the balance
+				// is not at, as though it had been changed since it was
+				// selected
+				version++
+			}
+			_, err = client.IfMatch(version).UpdateBalance(a.ID, b.ID, b.Balance, "retried")
+			return err
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.NoError(t, client.IfMatch(b.Version+1).V2().DeleteBalance(b.ID))
+		assert.NoError(t, client.IfMatch(a.Version).V2().DeleteAccount(a.ID, storage.DeletionRefuse))
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestClient_WithIdempotencyKey(t *testing.T) {
+	s := memory.New()
+	router, listener, client := newTestComponents(t, s)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, router)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		a, err := client.InsertAccount(*accountingtest.NewAccount(t, "idempotent", accountingtest.NewCurrencyCode(t, "EUR"), time.Now()))
+		if !assert.NoError(t, err) {
+			return
+		}
+		b := balance.Balance{Date: time.Now(), Amount: 1}
+		keyed := client.WithIdempotencyKey("balance")
+		first, err := keyed.InsertBalance(a.ID, b, "first")
+		if !assert.NoError(t, err) {
+			return
+		}
+		repeat, err := keyed.InsertBalance(a.ID, b, "first")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, first.ID, repeat.ID, "repeated request should give the original balance")
+		_, err = keyed.InsertBalance(a.ID, b, "different")
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err), "key reused for different request")
+
+		_, err = client.InsertBalance(a.ID, b, "first")
+		assert.NoError(t, err)
+		bs, err := s.SelectAccountBalances(a.ID)
+		if assert.NoError(t, err) {
+			assert.Len(t, *bs, 2, "only requests without the key should insert another balance")
+		}
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
+func TestV2_InsertBalances(t *testing.T) {
+	s := memory.New()
+	r, listener, client := newTestComponents(t, s)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- http.Serve(listener, r)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+
+	go func() {
+		defer close(errCh)
+		opened := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		a, err := client.InsertAccount(*accountingtest.NewAccount(t, "bulk", accountingtest.NewCurrencyCode(t, "EUR"), opened))
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = client.V2().InsertBalances(a.ID, []router.BalanceInsertBody{
+			{Balance: balance.Balance{Date: opened, Amount: 1}},
+			{Balance: balance.Balance{Date: opened.AddDate(-1, 0, 0), Amount: 2}},
+		})
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err), "inserting balance before account opened")
+
+		inserted, err := client.V2().InsertBalances(a.ID, []router.BalanceInsertBody{
+			{Balance: balance.Balance{Date: opened, Amount: 1}, Note: "first"},
+			{Balance: balance.Balance{Date: opened.AddDate(0, 1, 0), Amount: 2}, Note: "second"},
+		})
+		if !assert.NoError(t, err) || !assert.Len(t, *inserted, 2) {
+			return
+		}
+		assert.Equal(t, "first", (*inserted)[0].Note)
+		assert.Equal(t, 2, (*inserted)[1].Amount)
+		bs, err := s.SelectAccountBalances(a.ID)
+		if assert.NoError(t, err) {
+			assert.Len(t, *bs, 2, "only the balances of the valid request should be inserted")
+		}
+	}()
+
+	common.FatalIfError(t, <-errCh, "received error")
+}
+
 func newTestComponents(t *testing.T, s storage.Storage) (*mux.Router, net.Listener, Client) {
 	r := newTestRouter(t, s)
 	l := newTestNetListener(t)
@@ -208,7 +759,7 @@ func newTestComponents(t *testing.T, s storage.Storage) (*mux.Router, net.Listen
 }
 
 func newTestRouter(t *testing.T, s storage.Storage) *mux.Router {
-	r, err := router.New(s, log.New(os.Stderr, "", log.LstdFlags))
+	r, err := router.New(s, nil, log.New(os.Stderr, "", log.LstdFlags))
 	common.FatalIfError(t, err, "creating new router")
 	if !assert.NotNil(t, r) {
 		t.Fatal("expected non-nil router")
@@ -226,5 +777,5 @@ func newTestNetListener(t *testing.T) net.Listener {
 }
 
 func newTestClient(l net.Listener) Client {
-	return Client("http://" + l.Addr().String())
+	return New("http://" + l.Addr().String())
 }
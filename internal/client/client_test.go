@@ -7,17 +7,25 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/internal/router"
 	"github.com/glynternet/mon/pkg/storage"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
 // ensure that a Client can be used as a storage.Storage
-var _ storage.Storage = Client("")
+var _ storage.Storage = Client{}
+
+// ensure that a Client can be used as a storage.History
+var _ storage.History = Client{}
+
+// ensure that a V2 can be used as a storage.Storage
+var _ storage.Storage = V2{}
 
 func Test_getBodyFromEndpoint(t *testing.T) {
 	t.Run("get error", func(t *testing.T) {
-		c := Client("bloopybloop")
+		c := New("bloopybloop")
 		bod, err := c.getBodyFromEndpoint("")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "getting from endpoint")
@@ -27,12 +35,54 @@ func Test_getBodyFromEndpoint(t *testing.T) {
 	t.Run("unexpected status", func(t *testing.T) {
 		srv := newJSONTestServer(nil, http.StatusTeapot)
 		defer srv.Close()
-		c := Client(srv.URL)
+		c := New(srv.URL)
 		as, err := c.getBodyFromEndpoint("")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "server returned unexpected code")
 		assert.Nil(t, as)
 	})
+
+	t.Run("error response", func(t *testing.T) {
+		expected := router.Error{
+			Code:    router.CodeNotFound,
+			Status:  http.StatusNotFound,
+			Message: "no account with id 1",
+		}
+		srv := newJSONTestServer(expected, http.StatusNotFound)
+		defer srv.Close()
+		c := New(srv.URL)
+		as, err := c.getBodyFromEndpoint("")
+		if assert.Error(t, err) {
+			e, ok := errors.Cause(err).(*router.Error)
+			if assert.True(t, ok, "cause of error should be a *router.Error") {
+				assert.Equal(t, expected, *e)
+			}
+		}
+		assert.Nil(t, as)
+	})
+}
+
+func TestRetryStale(t *testing.T) {
+	var calls int
+	stale := storage.Stalef("stale")
+	err := RetryStale(3, func() error {
+		calls++
+		return stale
+	})
+	assert.Equal(t, stale, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	other := errors.New("other")
+	err = RetryStale(3, func() error {
+		calls++
+		if calls == 1 {
+			return stale
+		}
+		return other
+	})
+	assert.Equal(t, other, err)
+	assert.Equal(t, 2, calls)
 }
 
 type stubMarshal struct {
@@ -45,7 +95,7 @@ func (m stubMarshal) MarshalJSON() ([]byte, error) {
 
 func Test_postAsJSONToEndpoint(t *testing.T) {
 	t.Run("marshal error", func(t *testing.T) {
-		c := Client("bloopybloop")
+		c := New("bloopybloop")
 		obj := stubMarshal{
 			err: errors.New("can't unmarshal me"),
 		}
@@ -57,7 +107,7 @@ func Test_postAsJSONToEndpoint(t *testing.T) {
 	})
 
 	t.Run("post to endpoint error", func(t *testing.T) {
-		c := Client("bloopybleep")
+		c := New("bloopybleep")
 		res, err := c.postAsJSONToEndpoint("", nil)
 		if assert.Error(t, err) {
 			assert.Contains(t, err.Error(), "posting to endpoint")
@@ -66,6 +116,74 @@ func Test_postAsJSONToEndpoint(t *testing.T) {
 	})
 }
 
+func Test_postToEndpoint(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(router.HeaderIdempotencyKey))
+		if len(keys) == 1 {
+			// the connection is closed without a response, as though the
+			// response was lost on the way to the client
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				panic(fmt.Sprintf("error hijacking connection: %v", err))
+			}
+			_ = conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	res, err := New(srv.URL).postToEndpoint("", "", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+	}
+	if assert.Len(t, keys, 2, "request should be made again") {
+		assert.NotEmpty(t, keys[0])
+		assert.Equal(t, keys[0], keys[1], "request should be made again with the same key")
+	}
+
+	keys = nil
+	_, err = New(srv.URL).postToEndpoint("", "", nil)
+	assert.NoError(t, err)
+	_, err = New(srv.URL).postToEndpoint("", "", nil)
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 3) {
+		assert.NotEqual(t, keys[1], keys[2], "each request should be made with a new key")
+	}
+
+	keys = []string{"skip the closed connection"}
+	_, err = New(srv.URL).WithIdempotencyKey("given").postToEndpoint("", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"skip the closed connection", "given"}, keys)
+}
+
+func Test_postToEndpoint_inProgress(t *testing.T) {
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(router.HeaderIdempotencyKey))
+		w.WriteHeader(http.StatusConflict)
+		code := router.CodeConflict
+		if len(keys) == 1 {
+			code = router.CodeInProgress
+		}
+		bs, err := json.Marshal(router.Error{Code: code, Status: http.StatusConflict})
+		if err != nil {
+			panic(fmt.Sprintf("error marshalling to json: %v", err))
+		}
+		_, _ = w.Write(bs)
+	}))
+	defer srv.Close()
+
+	res, err := New(srv.URL).postToEndpoint("", "", nil)
+	common.FatalIfError(t, err, "posting to endpoint")
+	if assert.Len(t, keys, 2, "request should be made again while it is in progress") {
+		assert.Equal(t, keys[0], keys[1], "request should be made again with the same key")
+	}
+	_, err = processResponseForBody(res)
+	assert.Equal(t, router.CodeConflict, errors.Cause(err).(*router.Error).Code, "other conflicts should be returned")
+}
+
 func newJSONTestServer(encode interface{}, code int) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		bs, err := json.Marshal(encode)
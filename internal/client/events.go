@@ -0,0 +1,26 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/glynternet/mon/internal/router"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// SelectEvents retrieves from the mon server the Events that record the
+// changes made to accounts and balances and that match the given
+// router.EventsQuery. The server must hold its data in a storage that keeps
+// an event log.
+func (c Client) SelectEvents(q router.EventsQuery) (*storage.Events, error) {
+	bod, _, err := c.getPageFromEndpoint(router.EndpointEvents, q.Values())
+	if err != nil {
+		return nil, errors.Wrap(err, "getting page from endpoint")
+	}
+	es := &storage.Events{}
+	err = errors.Wrapf(json.Unmarshal(bod, es), "unmarshalling response body: %s", string(bod))
+	if err != nil {
+		return nil, err
+	}
+	return es, nil
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/glynternet/mon/internal/router"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// SelectDeletedAccounts retrieves the accounts that have been deleted from the
+// mon server
+func (c Client) SelectDeletedAccounts() (*storage.Accounts, error) {
+	return c.getAccountsFromEndpoint(router.EndpointAccountsDeleted)
+}
+
+// UndeleteAccount will restore the deleted account with the given id through
+// the mon server
+func (c Client) UndeleteAccount(id uint) (*storage.Account, error) {
+	bs, err := c.postToUndeleteEndpoint(fmt.Sprintf(router.EndpointFmtAccountUndelete, id))
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalJSONToAccount(bs)
+}
+
+// SelectDeletedBalances retrieves the balances that have been deleted from the
+// mon server
+func (c Client) SelectDeletedBalances() (*storage.Balances, error) {
+	return c.getBalancesFromEndpoint(router.EndpointBalancesDeleted)
+}
+
+// UndeleteBalance will restore the deleted balance with the given id through
+// the mon server
+func (c Client) UndeleteBalance(id uint) (*storage.Balance, error) {
+	bs, err := c.postToUndeleteEndpoint(fmt.Sprintf(router.EndpointFmtBalanceUndelete, id))
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalJSONToBalance(bs)
+}
+
+func (c Client) postToUndeleteEndpoint(e string) ([]byte, error) {
+	res, err := c.postToEndpoint(e, "", nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "posting to endpoint %s", e)
+	}
+	bs, err := processResponseForBody(res)
+	return bs, errors.Wrap(err, "processing response for body")
+}
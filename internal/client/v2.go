@@ -0,0 +1,229 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/internal/router"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// V2 is a Client that uses the endpoints of the v2 API of the mon server for
+// the operations that the v2 API provides. All other operations are made
+// using the endpoints of the Client.
+type V2 struct {
+	Client
+}
+
+// V2 returns a V2 that makes requests to the same mon server as the Client.
+func (c Client) V2() V2 {
+	return V2{Client: c}
+}
+
+// SelectAccounts is used to retrieve accounts from the mon server
+func (c V2) SelectAccounts() (*storage.Accounts, error) {
+	return c.getAccountsFromEndpoint(router.EndpointV2Accounts)
+}
+
+// SelectAccountsPage retrieves the accounts that match the given
+// router.AccountsQuery from the mon server, along with the cursor of the next
+// page, if there is one.
+func (c V2) SelectAccountsPage(q router.AccountsQuery) (*storage.Accounts, string, error) {
+	return c.selectAccountsPage(router.EndpointV2Accounts, q)
+}
+
+// SelectAccountsSummary retrieves from the mon server the balance of each of
+// the accounts that match the given router.AccountsQuery, along with the
+// totals of each currency.
+func (c V2) SelectAccountsSummary(q router.AccountsQuery) (*router.AccountsSummary, error) {
+	return c.selectAccountsSummary(router.EndpointV2AccountsSummary, q)
+}
+
+// SelectAccount retrieves an account from the mon server by a given ID
+func (c V2) SelectAccount(id uint) (*storage.Account, error) {
+	return c.getAccountFromEndpoint(fmt.Sprintf(router.EndpointFmtV2Account, id))
+}
+
+// InsertAccount will attempt to insert an account by calling the mon server
+// and return the stored Account
+func (c V2) InsertAccount(a account.Account) (*storage.Account, error) {
+	bs, err := c.postAccountToEndpoint(router.EndpointV2Accounts, a)
+	if err != nil {
+		return nil, errors.Wrapf(err, "posting account to endpoint %s", router.EndpointV2Accounts)
+	}
+	return unmarshalJSONToAccount(bs)
+}
+
+// UpdateAccount will attempt to update the account with the given id to hold
+// the values of the given account.Account
+func (c V2) UpdateAccount(id uint, updates account.Account) (*storage.Account, error) {
+	endpoint := fmt.Sprintf(router.EndpointFmtV2Account, id)
+	res, err := c.requestAsJSONToEndpoint(http.MethodPut, endpoint, updates)
+	if err != nil {
+		return nil, errors.Wrapf(err, "putting account to endpoint %s", endpoint)
+	}
+	bs, err := processResponseForBody(res)
+	if err != nil {
+		return nil, errors.Wrap(err, "processing response for body")
+	}
+	return unmarshalJSONToAccount(bs)
+}
+
+// PatchAccount will attempt to change the details of the account with the
+// given id that are given in the router.AccountPatchBody, leaving all other
+// details unchanged. PatchAccount is only available through the v2 API.
+func (c V2) PatchAccount(id uint, p router.AccountPatchBody) (*storage.Account, error) {
+	endpoint := fmt.Sprintf(router.EndpointFmtV2Account, id)
+	res, err := c.requestAsJSONToEndpoint(http.MethodPatch, endpoint, p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "patching account to endpoint %s", endpoint)
+	}
+	bs, err := processResponseForBody(res)
+	if err != nil {
+		return nil, errors.Wrap(err, "processing response for body")
+	}
+	return unmarshalJSONToAccount(bs)
+}
+
+// DeleteAccount will attempt to delete an account through the mon server by
+// the given id, treating the live balances of the account according to the
+// given storage.DeletionPolicy
+func (c V2) DeleteAccount(id uint, policy storage.DeletionPolicy) error {
+	endpoint := fmt.Sprintf(router.EndpointFmtV2Account, id) +
+		"?" + url.Values{router.QueryDeletionPolicy: {string(policy)}}.Encode()
+	return c.deleteFromEndpoint(endpoint)
+}
+
+// SelectAccountShares retrieves the users that the account with the given id
+// has been shared with. Sharing is only available through the v2 API.
+func (c V2) SelectAccountShares(id uint) ([]string, error) {
+	endpoint := fmt.Sprintf(router.EndpointFmtV2AccountShares, id)
+	bod, err := c.getBodyFromEndpoint(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting body from endpoint")
+	}
+	var users []string
+	err = json.Unmarshal(bod, &users)
+	return users, errors.Wrapf(err, "unmarshalling response body: %s", string(bod))
+}
+
+// ShareAccount shares the account with the given id with the given user, who
+// will be able to read but not change the account and its balances.
+func (c V2) ShareAccount(id uint, user string) error {
+	endpoint := fmt.Sprintf(router.EndpointFmtV2AccountShares, id)
+	res, err := c.postAsJSONToEndpoint(endpoint, router.AccountShareBody{User: user})
+	if err != nil {
+		return errors.Wrapf(err, "posting AccountShareBody to endpoint:%s", endpoint)
+	}
+	_, err = processResponseForBody(res)
+	return errors.Wrap(err, "processing response for body")
+}
+
+// UnshareAccount stops sharing the account with the given id with the given
+// user.
+func (c V2) UnshareAccount(id uint, user string) error {
+	return c.deleteFromEndpoint(fmt.Sprintf(router.EndpointFmtV2AccountShare, id, url.PathEscape(user)))
+}
+
+// SelectAccountBalances will select the Balances that are stored for a given
+// Account
+func (c V2) SelectAccountBalances(id uint) (*storage.Balances, error) {
+	return c.getBalancesFromEndpoint(fmt.Sprintf(router.EndpointFmtV2AccountBalances, id))
+}
+
+// SelectAccountBalancesPage will select the Balances of a given Account that
+// match the given router.BalancesQuery, along with the cursor of the next
+// page, if there is one.
+func (c V2) SelectAccountBalancesPage(id uint, q router.BalancesQuery) (*storage.Balances, string, error) {
+	return c.selectBalancesPage(fmt.Sprintf(router.EndpointFmtV2AccountBalances, id), q)
+}
+
+// SelectBalance retrieves a balance from the mon server by a given ID
+func (c V2) SelectBalance(id uint) (*storage.Balance, error) {
+	bod, err := c.getBodyFromEndpoint(fmt.Sprintf(router.EndpointFmtV2Balance, id))
+	if err != nil {
+		return nil, errors.Wrap(err, "getting body from endpoint")
+	}
+	return unmarshalJSONToBalance(bod)
+}
+
+// InsertBalance will insert a Balance for the Account with the given ID
+func (c V2) InsertBalance(accountID uint, b balance.Balance, note string) (*storage.Balance, error) {
+	endpoint := fmt.Sprintf(router.EndpointFmtV2AccountBalances, accountID)
+	res, err := c.postAsJSONToEndpoint(endpoint, router.BalanceInsertBody{
+		Balance: b,
+		Note:    note,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "posting BalanceInsertBody to endpoint:%s", endpoint)
+	}
+	bs, err := processResponseForBody(res)
+	if err != nil {
+		return nil, errors.Wrap(err, "processing response for body")
+	}
+	return unmarshalJSONToBalance(bs)
+}
+
+// InsertBalances will insert all of the given balances for the Account with
+// the given ID, or none of them if any of them cannot be inserted.
+func (c V2) InsertBalances(accountID uint, bs []router.BalanceInsertBody) (*storage.Balances, error) {
+	endpoint := fmt.Sprintf(router.EndpointFmtV2AccountBalancesBulk, accountID)
+	res, err := c.postAsJSONToEndpoint(endpoint, router.BalancesInsertBody{Balances: bs})
+	if err != nil {
+		return nil, errors.Wrapf(err, "posting BalancesInsertBody to endpoint:%s", endpoint)
+	}
+	bod, err := processResponseForBody(res)
+	if err != nil {
+		return nil, errors.Wrap(err, "processing response for body")
+	}
+	return unmarshalJSONToBalances(bod)
+}
+
+// UpdateBalance will update the balance with the given id, that belongs to the
+// Account with the given accountID
+func (c V2) UpdateBalance(accountID, id uint, b balance.Balance, note string) (*storage.Balance, error) {
+	endpoint := fmt.Sprintf(router.EndpointFmtV2Balance, id)
+	res, err := c.requestAsJSONToEndpoint(http.MethodPut, endpoint, router.BalanceUpdateBody{
+		BalanceInsertBody: router.BalanceInsertBody{
+			Balance: b,
+			Note:    note,
+		},
+		AccountID: accountID,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "putting BalanceUpdateBody to endpoint:%s", endpoint)
+	}
+	bs, err := processResponseForBody(res)
+	if err != nil {
+		return nil, errors.Wrap(err, "processing response for body")
+	}
+	return unmarshalJSONToBalance(bs)
+}
+
+// DeleteBalance deletes a balance at a given id
+func (c V2) DeleteBalance(id uint) error {
+	return c.deleteFromEndpoint(fmt.Sprintf(router.EndpointFmtV2Balance, id))
+}
+
+func (c Client) deleteFromEndpoint(endpoint string) error {
+	r, err := c.deleteToEndpoint(endpoint)
+	if err != nil {
+		return errors.Wrapf(err, "deleting to endpoint %s", endpoint)
+	}
+	_, err = processResponseForBody(r)
+	return errors.Wrap(err, "processing response for body")
+}
+
+func (c Client) requestAsJSONToEndpoint(method, e string, thing interface{}) (*http.Response, error) {
+	bs, err := json.Marshal(thing)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling json")
+	}
+	return c.requestToEndpoint(method, e, `application/json; charset=UTF-8`, bytes.NewReader(bs))
+}
@@ -2,8 +2,11 @@ package model
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/currency"
 	"github.com/glynternet/mon/pkg/storage"
 	"github.com/pkg/errors"
 )
@@ -11,28 +14,158 @@ import (
 // UpdateAccount updates a stored account to reflect the details of some other
 // account data. The updates will be verified to ensure that any data to be
 // used will be logically sound with the balances and other account details.
+// If the Version of the given storage.Account is not zero, the account is only
+// updated if it is still at that Version, otherwise the error is of
+// storage.KindStale. The verification and the update happen atomically.
 func UpdateAccount(s storage.Storage, a storage.Account, updates account.Account) (*storage.Account, error) {
-	bs, err := s.SelectAccountBalances(a.ID)
+	var dba *storage.Account
+	err := s.Atomic(func(s storage.Storage) error {
+		if err := checkAccountVersion(s, a.ID, a.Version); err != nil {
+			return err
+		}
+		bs, err := s.SelectAccountBalances(a.ID)
+		if err != nil {
+			return errors.Wrap(err, "selecting Account Balances for update validation")
+		}
+		if bs != nil {
+			for _, b := range *bs {
+				err := updates.ValidateBalance(b.Balance)
+				if err != nil {
+					return storage.Invalidf("update would make balance invalid: %v", err)
+				}
+			}
+		}
+		dba, err = s.UpdateAccount(a.ID, updates)
+		return errors.Wrap(err, "updating account")
+	})
+	return dba, err
+}
+
+// checkAccountVersion returns an error of storage.KindStale if the given
+// version is not zero and the account with the given id is not at that
+// version.
+func checkAccountVersion(s storage.Storage, id, version uint) error {
+	if version == 0 {
+		return nil
+	}
+	a, err := s.SelectAccount(id)
 	if err != nil {
-		return nil, errors.Wrap(err, "selecting Account Balances for update validation")
+		return errors.Wrap(err, "selecting account to check version")
 	}
-	if bs != nil {
-		for _, b := range *bs {
-			err := updates.ValidateBalance(b.Balance)
-			if err != nil {
-				return nil, fmt.Errorf("update would make balance invalid: %v", err)
-			}
+	return storage.CheckVersion(fmt.Sprintf("account %d", id), version, a.Version)
+}
+
+// AccountPatch holds changes to some of the details of an account. Only the
+// details that are not nil are changed. A Closed time that is the zero time
+// will reopen the account.
+type AccountPatch struct {
+	Name     *string
+	Opened   *time.Time
+	Closed   *time.Time
+	Currency currency.Code
+}
+
+// PatchAccount applies the changes of the given AccountPatch to the details of
+// a stored account. The resulting account is verified in the same way as the
+// updates given to UpdateAccount.
+func PatchAccount(s storage.Storage, a storage.Account, p AccountPatch) (*storage.Account, error) {
+	name := a.Account.Name()
+	if p.Name != nil {
+		name = *p.Name
+	}
+	code := a.Account.CurrencyCode()
+	if p.Currency != nil {
+		code = p.Currency
+	}
+	opened := a.Account.Opened()
+	if p.Opened != nil {
+		opened = *p.Opened
+	}
+	closed := a.Account.Closed()
+	if p.Closed != nil {
+		closed.Time, closed.Valid = *p.Closed, !p.Closed.IsZero()
+	}
+	var ops []account.Option
+	if closed.Valid {
+		ops = append(ops, account.CloseTime(closed.Time))
+	}
+	updates, err := account.New(name, code, opened, ops...)
+	if err != nil {
+		return nil, errors.Wrap(storage.Invalid(err), "applying patch to account")
+	}
+	return UpdateAccount(s, a, *updates)
+}
+
+// DeleteAccount deletes an account with the given id, treating the live
+// balances of the account according to the given storage.DeletionPolicy.
+// If the given version is not zero, the account is only deleted if it is
+// still at that version, otherwise the error is of storage.KindStale.
+func DeleteAccount(s storage.Storage, id, version uint, policy storage.DeletionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return errors.Wrap(err, "validating deletion policy")
+	}
+	return s.Atomic(func(s storage.Storage) error {
+		if _, err := s.SelectAccount(id); err != nil {
+			return errors.Wrap(err, "selecting account to delete")
+		}
+		if err := checkAccountVersion(s, id, version); err != nil {
+			return err
 		}
+		return errors.Wrap(s.DeleteAccount(id, policy), "deleting account")
+	})
+}
+
+// OpenAccount inserts an account along with an opening balance, dated at the
+// time that the account was opened. Either both the account and the balance
+// will be stored or, if an error occurs, neither will be.
+func OpenAccount(s storage.Storage, a account.Account, amount int, note string) (*storage.Account, *storage.Balance, error) {
+	var dba *storage.Account
+	var dbb *storage.Balance
+	err := s.Atomic(func(s storage.Storage) error {
+		var err error
+		dba, err = s.InsertAccount(a)
+		if err != nil {
+			return errors.Wrap(err, "inserting account")
+		}
+		dbb, err = InsertBalance(s, *dba, balance.Balance{Date: dba.Account.Opened(), Amount: amount}, note)
+		return errors.Wrap(err, "inserting opening balance")
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	dba, err := s.UpdateAccount(a.ID, updates)
-	return dba, errors.Wrap(err, "updating account")
+	return dba, dbb, nil
 }
 
-// DeleteAccount deletes an account with the given id
-func DeleteAccount(s storage.Storage, id uint) error {
-	_, err := s.SelectAccount(id)
+// CloseAccount inserts a closing balance for the account with the given id
+// and closes the account at the time of the balance. Either both the balance
+// will be inserted and the account closed or, if an error occurs, neither will
+// happen.
+func CloseAccount(s storage.Storage, id uint, b balance.Balance, note string) (*storage.Account, *storage.Balance, error) {
+	var dba *storage.Account
+	var dbb *storage.Balance
+	err := s.Atomic(func(s storage.Storage) error {
+		a, err := s.SelectAccount(id)
+		if err != nil {
+			return errors.Wrap(err, "selecting account to close")
+		}
+		dbb, err = InsertBalance(s, *a, b, note)
+		if err != nil {
+			return errors.Wrap(err, "inserting closing balance")
+		}
+		updates, err := account.New(
+			a.Account.Name(),
+			a.Account.CurrencyCode(),
+			a.Account.Opened(),
+			account.CloseTime(b.Date),
+		)
+		if err != nil {
+			return errors.Wrap(err, "creating closed account")
+		}
+		dba, err = UpdateAccount(s, *a, *updates)
+		return errors.Wrap(err, "closing account")
+	})
 	if err != nil {
-		return errors.Wrap(err, "selecting account to delete")
+		return nil, nil, err
 	}
-	return errors.Wrap(s.DeleteAccount(id), "deleting account")
+	return dba, dbb, nil
 }
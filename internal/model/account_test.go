@@ -7,8 +7,10 @@ import (
 	"github.com/glynternet/go-accounting/account"
 	"github.com/glynternet/go-accounting/accountingtest"
 	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
 	"github.com/glynternet/mon/internal/model"
 	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
 	"github.com/glynternet/mon/pkg/storage/storagetest"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -70,6 +72,76 @@ func TestUpdateAccount(t *testing.T) {
 		assert.Equal(t, initial.ID, s.LastAccountID)
 		assert.Equal(t, s.AccountErr, errors.Cause(err))
 	})
+
+	t.Run("stale version", func(t *testing.T) {
+		s := memory.New()
+		inserted, err := s.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+		updated, err := model.UpdateAccount(s, *inserted, *accountingtest.NewAccount(t, "B", accountingtest.NewCurrencyCode(t, "YEN"), now))
+		common.FatalIfError(t, err, "updating account at current version")
+		assert.Equal(t, uint(2), updated.Version)
+
+		_, err = model.UpdateAccount(s, *inserted, *accountingtest.NewAccount(t, "C", accountingtest.NewCurrencyCode(t, "YEN"), now))
+		assert.Equal(t, storage.KindStale, storage.KindOf(err))
+		selected, err := s.SelectAccount(inserted.ID)
+		common.FatalIfError(t, err, "selecting account")
+		assert.Equal(t, "B", selected.Account.Name())
+	})
+}
+
+func TestPatchAccount(t *testing.T) {
+	opened := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	closed := opened.Add(48 * time.Hour)
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), opened, account.CloseTime(closed))
+
+	insert := func(t *testing.T) (storage.Storage, *storage.Account) {
+		s := memory.New()
+		inserted, err := s.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+		_, err = s.InsertBalance(inserted.ID, balance.Balance{Date: opened.Add(24 * time.Hour)}, "")
+		common.FatalIfError(t, err, "inserting balance")
+		return s, inserted
+	}
+
+	t.Run("empty patch changes nothing", func(t *testing.T) {
+		s, inserted := insert(t)
+		patched, err := model.PatchAccount(s, *inserted, model.AccountPatch{})
+		common.FatalIfError(t, err, "patching account")
+		assert.True(t, patched.Account.Equal(*a))
+	})
+
+	t.Run("only given fields change", func(t *testing.T) {
+		s, inserted := insert(t)
+		name := "B"
+		code := accountingtest.NewCurrencyCode(t, "EUR")
+		patched, err := model.PatchAccount(s, *inserted, model.AccountPatch{Name: &name, Currency: code})
+		common.FatalIfError(t, err, "patching account")
+		assert.Equal(t, "B", patched.Account.Name())
+		assert.Equal(t, code, patched.Account.CurrencyCode())
+		assert.True(t, patched.Account.Opened().Equal(opened))
+		assert.True(t, patched.Account.Closed().EqualTime(closed))
+	})
+
+	t.Run("zero closed time reopens", func(t *testing.T) {
+		s, inserted := insert(t)
+		patched, err := model.PatchAccount(s, *inserted, model.AccountPatch{Closed: &time.Time{}})
+		common.FatalIfError(t, err, "patching account")
+		assert.False(t, patched.Account.Closed().Valid)
+	})
+
+	t.Run("merged result is validated against balances", func(t *testing.T) {
+		s, inserted := insert(t)
+		later := opened.Add(36 * time.Hour)
+		_, err := model.PatchAccount(s, *inserted, model.AccountPatch{Opened: &later})
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+	})
+
+	t.Run("invalid merged account", func(t *testing.T) {
+		s, inserted := insert(t)
+		empty := ""
+		_, err := model.PatchAccount(s, *inserted, model.AccountPatch{Name: &empty})
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+	})
 }
 
 func TestDeleteAccount(t *testing.T) {
@@ -77,14 +149,114 @@ func TestDeleteAccount(t *testing.T) {
 		s := &storagetest.Storage{
 			AccountErr: errors.New("account error"),
 		}
-		err := model.DeleteAccount(s, 0)
+		err := model.DeleteAccount(s, 0, 0, storage.DeletionRefuse)
 		assert.Equal(t, s.AccountErr, errors.Cause(err))
 		assert.Contains(t, err.Error(), "selecting account to delete")
 	})
 
-	t.Run("check id is passed", func(t *testing.T) {
+	t.Run("invalid policy", func(t *testing.T) {
 		s := &storagetest.Storage{}
-		_ = model.DeleteAccount(s, 999)
+		err := model.DeleteAccount(s, 999, 0, storage.DeletionPolicy("shred"))
+		assert.Error(t, err)
+		assert.Zero(t, s.LastAccountID)
+	})
+
+	t.Run("check id and policy are passed", func(t *testing.T) {
+		s := &storagetest.Storage{}
+		_ = model.DeleteAccount(s, 999, 0, storage.DeletionCascade)
 		assert.Equal(t, uint(999), s.LastAccountID)
+		assert.Equal(t, storage.DeletionCascade, s.LastDeletionPolicy)
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		s := memory.New()
+		inserted, err := s.InsertAccount(*accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now()))
+		common.FatalIfError(t, err, "inserting account")
+		err = model.DeleteAccount(s, inserted.ID, inserted.Version+1, storage.DeletionRefuse)
+		assert.Equal(t, storage.KindStale, storage.KindOf(err))
+		_, err = s.SelectAccount(inserted.ID)
+		common.FatalIfError(t, err, "selecting account that should not have been deleted")
+		common.FatalIfError(t, model.DeleteAccount(s, inserted.ID, inserted.Version, storage.DeletionRefuse), "deleting account at current version")
+	})
+}
+
+func TestOpenAccount(t *testing.T) {
+	now := time.Now()
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "YEN"), now)
+
+	t.Run("opened with balance", func(t *testing.T) {
+		s := memory.New()
+		dba, dbb, err := model.OpenAccount(s, *a, 123, "opening")
+		common.FatalIfError(t, err, "opening account")
+		assert.True(t, dba.Account.Equal(*a))
+		assert.Equal(t, 123, dbb.Amount)
+		assert.Equal(t, "opening", dbb.Note)
+		assert.True(t, dbb.Date.Equal(now))
+
+		bs, err := s.SelectAccountBalances(dba.ID)
+		common.FatalIfError(t, err, "selecting balances")
+		assert.Len(t, *bs, 1)
+	})
+
+	t.Run("balance error discards account", func(t *testing.T) {
+		expected := errors.New("balance error")
+		s := &storagetest.Storage{
+			Account:    &storage.Account{ID: 1, Account: *a},
+			BalanceErr: expected,
+		}
+		dba, dbb, err := model.OpenAccount(s, *a, 123, "opening")
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Nil(t, dba)
+		assert.Nil(t, dbb)
+	})
+}
+
+func TestCloseAccount(t *testing.T) {
+	now := time.Now()
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "YEN"), now)
+
+	t.Run("closed with balance", func(t *testing.T) {
+		s := memory.New()
+		inserted, err := s.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+
+		closed := now.Add(time.Hour)
+		dba, dbb, err := model.CloseAccount(s, inserted.ID, balance.Balance{Date: closed, Amount: 10}, "closing")
+		common.FatalIfError(t, err, "closing account")
+		assert.True(t, dba.Account.Closed().EqualTime(closed))
+		assert.Equal(t, 10, dbb.Amount)
+	})
+
+	t.Run("invalid balance leaves account open", func(t *testing.T) {
+		s := memory.New()
+		inserted, err := s.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+
+		_, _, err = model.CloseAccount(s, inserted.ID, balance.Balance{Date: now.Add(-time.Hour)}, "closing")
+		assert.Error(t, err)
+
+		selected, err := s.SelectAccount(inserted.ID)
+		common.FatalIfError(t, err, "selecting account")
+		assert.False(t, selected.Account.Closed().Valid)
+		bs, err := s.SelectAccountBalances(inserted.ID)
+		common.FatalIfError(t, err, "selecting balances")
+		assert.Len(t, *bs, 0)
+	})
+
+	t.Run("balance before later balances is discarded with update", func(t *testing.T) {
+		s := memory.New()
+		inserted, err := s.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+		_, err = s.InsertBalance(inserted.ID, balance.Balance{Date: now.Add(2 * time.Hour)}, "later")
+		common.FatalIfError(t, err, "inserting balance")
+
+		_, _, err = model.CloseAccount(s, inserted.ID, balance.Balance{Date: now.Add(time.Hour)}, "closing")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "update would make balance invalid")
+		}
+
+		bs, err := s.SelectAccountBalances(inserted.ID)
+		common.FatalIfError(t, err, "selecting balances")
+		assert.Len(t, *bs, 1, "closing balance should not have been stored")
 	})
 }
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+
 	"github.com/glynternet/go-accounting/balance"
 	"github.com/glynternet/mon/pkg/storage"
 	"github.com/pkg/errors"
@@ -19,8 +21,93 @@ func SelectAccountBalances(s storage.Storage, a storage.Account) (*storage.Balan
 func InsertBalance(s storage.Storage, a storage.Account, b balance.Balance, note string) (*storage.Balance, error) {
 	err := a.Account.ValidateBalance(b)
 	if err != nil {
-		return nil, errors.Wrap(err, "validating balance")
+		return nil, errors.Wrap(storage.Invalid(err), "validating balance")
 	}
 	dbb, err := s.InsertBalance(a.ID, b, note)
 	return dbb, errors.Wrap(err, "inserting balance")
 }
+
+// BalanceInsert holds a balance.Balance and the note to insert along with it.
+type BalanceInsert struct {
+	Balance balance.Balance
+	Note    string
+}
+
+// InsertBalances inserts each of the given BalanceInserts for the Account with
+// the given accountID, performing the same logic checks as InsertBalance for
+// each of them. Either every Balance is inserted or, if any of them cannot be,
+// none of them are. The inserted Balances are returned in the order that they
+// were given.
+func InsertBalances(s storage.Storage, accountID uint, bs []BalanceInsert) (*storage.Balances, error) {
+	if len(bs) == 0 {
+		return nil, storage.Invalidf("no balances to insert")
+	}
+	inserted := make(storage.Balances, 0, len(bs))
+	err := s.Atomic(func(s storage.Storage) error {
+		a, err := s.SelectAccount(accountID)
+		if err != nil {
+			return errors.Wrap(err, "selecting account")
+		}
+		for i, b := range bs {
+			dbb, err := InsertBalance(s, *a, b.Balance, b.Note)
+			if err != nil {
+				return errors.Wrapf(err, "balance %d", i+1)
+			}
+			inserted = append(inserted, *dbb)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &inserted, nil
+}
+
+// UpdateBalance will update the Balance with the given id, belonging to the
+// given storage.Account, to hold the values of the given balance.Balance and
+// note. UpdateBalance will perform the same logic checks as InsertBalance
+// before attempting to update the balance in the given Storage. If the given
+// version is not zero, the balance is only updated if it is still at that
+// version, otherwise the error is of storage.KindStale.
+func UpdateBalance(s storage.Storage, a storage.Account, id, version uint, b balance.Balance, note string) (*storage.Balance, error) {
+	err := a.Account.ValidateBalance(b)
+	if err != nil {
+		return nil, errors.Wrap(storage.Invalid(err), "validating balance")
+	}
+	var dbb *storage.Balance
+	err = s.Atomic(func(s storage.Storage) error {
+		if err := checkBalanceVersion(s, id, version); err != nil {
+			return err
+		}
+		var err error
+		dbb, err = s.UpdateBalance(a.ID, id, b, note)
+		return errors.Wrap(err, "updating balance")
+	})
+	return dbb, err
+}
+
+// DeleteBalance deletes the balance with the given id. If the given version
+// is not zero, the balance is only deleted if it is still at that version,
+// otherwise the error is of storage.KindStale.
+func DeleteBalance(s storage.Storage, id, version uint) error {
+	return s.Atomic(func(s storage.Storage) error {
+		if err := checkBalanceVersion(s, id, version); err != nil {
+			return err
+		}
+		return errors.Wrap(s.DeleteBalance(id), "deleting balance")
+	})
+}
+
+// checkBalanceVersion returns an error of storage.KindStale if the given
+// version is not zero and the balance with the given id is not at that
+// version.
+func checkBalanceVersion(s storage.Storage, id, version uint) error {
+	if version == 0 {
+		return nil
+	}
+	b, err := s.SelectBalance(id)
+	if err != nil {
+		return errors.Wrap(err, "selecting balance to check version")
+	}
+	return storage.CheckVersion(fmt.Sprintf("balance %d", id), version, b.Version)
+}
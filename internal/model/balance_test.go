@@ -6,8 +6,10 @@ import (
 
 	"github.com/glynternet/go-accounting/accountingtest"
 	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
 	"github.com/glynternet/mon/internal/model"
 	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
 	"github.com/glynternet/mon/pkg/storage/storagetest"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -43,3 +45,104 @@ func TestInsertBalance(t *testing.T) {
 		assert.Equal(t, "test note", s.LastBalanceNote)
 	})
 }
+
+func TestInsertBalances(t *testing.T) {
+	s := memory.New()
+	a, _ := insertAccountAndBalance(t, s)
+	opened := a.Account.Opened()
+
+	_, err := model.InsertBalances(s, a.ID, nil)
+	assert.Equal(t, storage.KindInvalid, storage.KindOf(err), "no balances")
+
+	_, err = model.InsertBalances(s, a.ID, []model.BalanceInsert{
+		{Balance: balance.Balance{Date: opened, Amount: 2}},
+		{Balance: balance.Balance{Date: opened.Add(-time.Hour), Amount: 3}},
+	})
+	if assert.Equal(t, storage.KindInvalid, storage.KindOf(err)) {
+		assert.Contains(t, err.Error(), "balance 2")
+	}
+	bs, err := s.SelectAccountBalances(a.ID)
+	common.FatalIfError(t, err, "selecting balances")
+	assert.Len(t, *bs, 1, "no balances should be inserted when any are invalid")
+
+	inserted, err := model.InsertBalances(s, a.ID, []model.BalanceInsert{
+		{Balance: balance.Balance{Date: opened.Add(time.Hour), Amount: 4}, Note: "first"},
+		{Balance: balance.Balance{Date: opened, Amount: 5}, Note: "second"},
+	})
+	common.FatalIfError(t, err, "inserting balances")
+	if assert.Len(t, *inserted, 2) {
+		assert.Equal(t, 4, (*inserted)[0].Amount)
+		assert.Equal(t, "first", (*inserted)[0].Note)
+		assert.Equal(t, 5, (*inserted)[1].Amount)
+		assert.Equal(t, "second", (*inserted)[1].Note)
+	}
+
+	_, err = model.InsertBalances(s, a.ID+1, []model.BalanceInsert{{Balance: balance.Balance{Date: opened}}})
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err))
+}
+
+func TestUpdateBalance(t *testing.T) {
+	t.Run("validation error", func(t *testing.T) {
+		b, err := model.UpdateBalance(nil, storage.Account{}, 1, 0, balance.Balance{}, "test note")
+		assert.Nil(t, b)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "validating balance")
+	})
+
+	t.Run("ids and note are passed", func(t *testing.T) {
+		s := &storagetest.Storage{
+			Balance:    &storage.Balance{},
+			BalanceErr: errors.New("update balance error"),
+		}
+		now := time.Now()
+		b, err := model.UpdateBalance(s,
+			storage.Account{
+				ID: 9183,
+				Account: *accountingtest.NewAccount(t,
+					"test account",
+					accountingtest.NewCurrencyCode(t, "ABC"),
+					now),
+			},
+			3819,
+			0,
+			balance.Balance{Date: now},
+			"test note")
+		assert.Equal(t, s.BalanceErr, errors.Cause(err))
+		assert.Equal(t, s.Balance, b)
+		assert.Equal(t, uint(9183), s.LastAccountID)
+		assert.Equal(t, uint(3819), s.LastBalanceID)
+		assert.Equal(t, "test note", s.LastBalanceNote)
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		s := memory.New()
+		a, b := insertAccountAndBalance(t, s)
+		_, err := model.UpdateBalance(s, *a, b.ID, b.Version+1, b.Balance, "stale")
+		assert.Equal(t, storage.KindStale, storage.KindOf(err))
+		updated, err := model.UpdateBalance(s, *a, b.ID, b.Version, b.Balance, "current")
+		common.FatalIfError(t, err, "updating balance at current version")
+		assert.Equal(t, "current", updated.Note)
+		assert.Equal(t, b.Version+1, updated.Version)
+	})
+}
+
+func TestDeleteBalance(t *testing.T) {
+	s := memory.New()
+	_, b := insertAccountAndBalance(t, s)
+	err := model.DeleteBalance(s, b.ID, b.Version+1)
+	assert.Equal(t, storage.KindStale, storage.KindOf(err))
+	_, err = s.SelectBalance(b.ID)
+	common.FatalIfError(t, err, "selecting balance that should not have been deleted")
+	common.FatalIfError(t, model.DeleteBalance(s, b.ID, b.Version), "deleting balance at current version")
+	_, err = s.SelectBalance(b.ID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err))
+}
+
+func insertAccountAndBalance(t *testing.T, s storage.Storage) (*storage.Account, *storage.Balance) {
+	now := time.Now()
+	a, err := s.InsertAccount(*accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), now))
+	common.FatalIfError(t, err, "inserting account")
+	b, err := s.InsertBalance(a.ID, balance.Balance{Date: now, Amount: 1}, "")
+	common.FatalIfError(t, err, "inserting balance")
+	return a, b
+}
@@ -1,12 +1,16 @@
 package router
 
 import (
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
 	"github.com/glynternet/mon/internal/model"
 	"github.com/glynternet/mon/pkg/storage"
 	"github.com/gorilla/mux"
@@ -15,12 +19,42 @@ import (
 
 // TODO: redesign these so that they don't need to take a request? There could
 // TODO: be multiple handler types either take a request or don't take a request
-func (env *environment) handlerSelectAccounts(_ *http.Request) (int, interface{}, error) {
+func (env *environment) handlerSelectAccounts(r *http.Request) (int, interface{}, error) {
+	aq, err := extractAccountsQuery(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "extracting accounts query")
+	}
+	q, err := aq.storageQuery(time.Now())
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "preparing accounts query")
+	}
+	if aq.AsOf != nil {
+		return env.selectAccountsAsOf(*aq.AsOf, q)
+	}
+	return env.selectAccounts(q)
+}
+
+func (env *environment) selectAccounts(q storage.AccountQuery) (int, interface{}, error) {
+	as, err := env.queryAccounts(withNextPage(q))
+	if err != nil {
+		return errorStatus(err, http.StatusServiceUnavailable), nil, errors.Wrap(err, "selecting Accounts from client")
+	}
+	return http.StatusOK, paginate(as, q), nil
+}
+
+// queryAccounts selects the Accounts that match the given
+// storage.AccountQuery, letting the storage select them if it provides a
+// storage.AccountQuerier.
+func (env *environment) queryAccounts(q storage.AccountQuery) (*storage.Accounts, error) {
+	if aq, ok := storage.AsAccountQuerier(env.storage); ok {
+		return aq.QueryAccounts(q)
+	}
 	as, err := env.storage.SelectAccounts()
 	if err != nil {
-		return http.StatusServiceUnavailable, nil, errors.Wrap(err, "selecting Accounts from client")
+		return nil, err
 	}
-	return http.StatusOK, as, nil
+	matched := queryAccounts(*as, q)
+	return &matched, nil
 }
 
 func (env *environment) muxAccountIDHandlerFunc(r *http.Request) (int, interface{}, error) {
@@ -34,7 +68,7 @@ func (env *environment) muxAccountIDHandlerFunc(r *http.Request) (int, interface
 func (env *environment) handlerSelectAccount(id uint) (int, interface{}, error) {
 	a, err := env.storage.SelectAccount(id)
 	if err != nil {
-		return http.StatusNotFound, nil, errors.Wrapf(err, "selecting Account with id:%d from storage", id)
+		return errorStatus(err, http.StatusInternalServerError), nil, errors.Wrapf(err, "selecting Account with id:%d from storage", id)
 	}
 	return http.StatusOK, a, nil
 }
@@ -50,7 +84,7 @@ func (env *environment) muxAccountInsertHandlerFunc(r *http.Request) (int, inter
 		// TODO: can handle closing the body elsewhere
 		cErr := r.Body.Close()
 		if cErr != nil {
-			log.Print(errors.Wrap(err, "closing request body"))
+			log.Print(errors.Wrap(cErr, "closing request body"))
 		}
 	}()
 
@@ -74,6 +108,10 @@ func (env *environment) muxAccountUpdateHandlerFunc(r *http.Request) (int, inter
 	if err != nil {
 		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
 	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
 
 	o, err := env.storage.SelectAccount(id)
 	if err != nil {
@@ -89,7 +127,7 @@ func (env *environment) muxAccountUpdateHandlerFunc(r *http.Request) (int, inter
 		// TODO: this handler only needs to take a []byte which would mean we can handle closing the body elsewhere
 		cErr := r.Body.Close()
 		if cErr != nil {
-			log.Print(errors.Wrap(err, "closing request body"))
+			log.Print(errors.Wrap(cErr, "closing request body"))
 		}
 	}()
 
@@ -98,6 +136,8 @@ func (env *environment) muxAccountUpdateHandlerFunc(r *http.Request) (int, inter
 		return http.StatusBadRequest, nil, errors.Wrapf(err, "unmarshalling request body")
 	}
 
+	// the update is only conditional on the version given by the request
+	o.Version = version
 	return env.handlerUpdateAccount(*o, *updates)
 }
 
@@ -109,16 +149,113 @@ func (env *environment) handlerUpdateAccount(a storage.Account, updates account.
 	return http.StatusOK, updated, nil
 }
 
+// AccountOpenBody is a struct that should be marshalled to json and used as
+// the body of an account open request. The opening Balance will be dated at
+// the time that the Account was opened.
+type AccountOpenBody struct {
+	Account account.Account
+	Amount  int
+	Note    string
+}
+
+// AccountBalance is the response body of requests that store an Account and
+// a Balance together.
+type AccountBalance struct {
+	Account storage.Account
+	Balance storage.Balance
+}
+
+func (env *environment) muxAccountOpenHandlerFunc(r *http.Request) (int, interface{}, error) {
+	bod, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "reading request body")
+	}
+
+	defer func() {
+		cErr := r.Body.Close()
+		if cErr != nil {
+			log.Print(errors.Wrap(cErr, "closing request body"))
+		}
+	}()
+
+	var aob AccountOpenBody
+	err = json.Unmarshal(bod, &aob)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "unmarshalling request body")
+	}
+	return env.handlerOpenAccount(aob)
+}
+
+func (env *environment) handlerOpenAccount(aob AccountOpenBody) (int, interface{}, error) {
+	a, b, err := model.OpenAccount(env.storage, aob.Account, aob.Amount, aob.Note)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "opening account")
+	}
+	return http.StatusOK, AccountBalance{Account: *a, Balance: *b}, nil
+}
+
+func (env *environment) muxAccountCloseHandlerFunc(r *http.Request) (int, interface{}, error) {
+	id, err := extractID(mux.Vars(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
+	}
+
+	bod, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "reading request body")
+	}
+
+	defer func() {
+		cErr := r.Body.Close()
+		if cErr != nil {
+			log.Print(errors.Wrap(cErr, "closing request body"))
+		}
+	}()
+
+	var bib BalanceInsertBody
+	err = json.Unmarshal(bod, &bib)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "unmarshalling request body")
+	}
+	return env.handlerCloseAccount(id, bib.Balance, bib.Note)
+}
+
+func (env *environment) handlerCloseAccount(id uint, b balance.Balance, note string) (int, interface{}, error) {
+	a, dbb, err := model.CloseAccount(env.storage, id, b, note)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "closing account with id:%d", id)
+	}
+	return http.StatusOK, AccountBalance{Account: *a, Balance: *dbb}, nil
+}
+
 func (env *environment) muxAccountDeleteHandlerFunc(r *http.Request) (int, interface{}, error) {
 	id, err := extractID(mux.Vars(r))
 	if err != nil {
 		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
 	}
-	return env.handlerDeleteAccount(id)
+	policy, err := extractDeletionPolicy(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting deletion policy")
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	return env.handlerDeleteAccount(id, version, policy)
 }
 
-func (env *environment) handlerDeleteAccount(id uint) (int, interface{}, error) {
-	err := model.DeleteAccount(env.storage, id)
+// extractDeletionPolicy returns the storage.DeletionPolicy given by the
+// QueryDeletionPolicy parameter of the request, or storage.DeletionRefuse if
+// no policy was given.
+func extractDeletionPolicy(r *http.Request) (storage.DeletionPolicy, error) {
+	if r == nil || r.URL == nil {
+		return storage.DeletionRefuse, nil
+	}
+	return storage.ParseDeletionPolicy(r.URL.Query().Get(QueryDeletionPolicy))
+}
+
+func (env *environment) handlerDeleteAccount(id, version uint, policy storage.DeletionPolicy) (int, interface{}, error) {
+	err := model.DeleteAccount(env.storage, id, version, policy)
 	if err != nil {
 		return http.StatusBadRequest, nil, errors.Wrapf(err, "deleting Account with id:%d from storage", id)
 	}
@@ -126,12 +263,16 @@ func (env *environment) handlerDeleteAccount(id uint) (int, interface{}, error)
 }
 
 func extractID(vars map[string]string) (uint, error) {
+	return extractUint(vars, "id")
+}
+
+func extractUint(vars map[string]string, key string) (uint, error) {
 	if vars == nil {
 		return 0, errors.New("nil vars map")
 	}
-	idString, ok := vars["id"]
+	idString, ok := vars[key]
 	if !ok {
-		return 0, errors.New("no account id context variable")
+		return 0, fmt.Errorf("no %s context variable", key)
 	}
 	id, err := strconv.ParseUint(idString, 10, 64)
 	return uint(id), errors.Wrapf(err, "parsing %s to uint", idString)
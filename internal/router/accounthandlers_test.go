@@ -2,12 +2,16 @@ package router
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/glynternet/go-accounting/account"
 	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
 	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
 	"github.com/glynternet/mon/pkg/storage/storagetest"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -49,6 +53,17 @@ func Test_handlerSelectAccount(t *testing.T) {
 			storage: &storagetest.Storage{AccountErr: expected},
 		}
 		code, a, err := server.handlerSelectAccount(1)
+		assert.Equal(t, http.StatusInternalServerError, code)
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Nil(t, a)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		expected := storage.NotFoundf("no account with id 1")
+		server := &environment{
+			storage: &storagetest.Storage{AccountErr: expected},
+		}
+		code, a, err := server.handlerSelectAccount(1)
 		assert.Equal(t, http.StatusNotFound, code)
 		assert.Equal(t, expected, errors.Cause(err))
 		assert.Nil(t, a)
@@ -145,19 +160,106 @@ func Test_handlerDeleteAccount(t *testing.T) {
 		server := &environment{
 			storage: &storagetest.Storage{AccountErr: expected},
 		}
-		code, body, err := server.handlerDeleteAccount(1)
+		code, body, err := server.handlerDeleteAccount(1, 0, storage.DeletionRefuse)
 		assert.Equal(t, http.StatusBadRequest, code)
 		assert.Equal(t, expected, errors.Cause(err))
 		assert.Nil(t, body)
 	})
 
 	t.Run("success", func(t *testing.T) {
+		store := &storagetest.Storage{}
 		server := &environment{
-			storage: &storagetest.Storage{},
+			storage: store,
 		}
-		code, body, err := server.handlerDeleteAccount(1)
+		code, body, err := server.handlerDeleteAccount(1, 0, storage.DeletionArchive)
 		assert.Equal(t, http.StatusOK, code)
 		assert.NoError(t, err)
 		assert.Nil(t, body)
+		assert.Equal(t, storage.DeletionArchive, store.LastDeletionPolicy)
+	})
+}
+
+func Test_extractDeletionPolicy(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		query    string
+		expected storage.DeletionPolicy
+		err      bool
+	}{
+		{name: "no policy", expected: storage.DeletionRefuse},
+		{name: "refuse", query: "?policy=refuse", expected: storage.DeletionRefuse},
+		{name: "cascade", query: "?policy=cascade", expected: storage.DeletionCascade},
+		{name: "archive", query: "?policy=archive", expected: storage.DeletionArchive},
+		{name: "unknown", query: "?policy=shred", err: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "/account/1"+test.query, nil)
+			p, err := extractDeletionPolicy(r)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, p)
+		})
+	}
+}
+
+func Test_handlerOpenAccount(t *testing.T) {
+	a := accountingtest.NewAccount(t,
+		"open account",
+		accountingtest.NewCurrencyCode(t, "GBP"),
+		time.Date(1000, 1, 0, 0, 0, 0, 0, time.UTC))
+
+	t.Run("error", func(t *testing.T) {
+		expected := errors.New("open account test error")
+		server := &environment{
+			storage: &storagetest.Storage{AccountErr: expected},
+		}
+		code, body, err := server.handlerOpenAccount(AccountOpenBody{Account: *a})
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Nil(t, body)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		server := &environment{storage: memory.New()}
+		code, body, err := server.handlerOpenAccount(AccountOpenBody{Account: *a, Amount: 99, Note: "opening"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		ab := body.(AccountBalance)
+		assert.True(t, ab.Account.Account.Equal(*a))
+		assert.Equal(t, 99, ab.Balance.Amount)
+		assert.Equal(t, "opening", ab.Balance.Note)
+	})
+}
+
+func Test_handlerCloseAccount(t *testing.T) {
+	opened := time.Date(1000, 1, 0, 0, 0, 0, 0, time.UTC)
+	a := accountingtest.NewAccount(t, "close account", accountingtest.NewCurrencyCode(t, "GBP"), opened)
+
+	t.Run("invalid balance", func(t *testing.T) {
+		store := memory.New()
+		inserted, err := store.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+		server := &environment{storage: store}
+		code, body, err := server.handlerCloseAccount(inserted.ID, balance.Balance{Date: opened.Add(-time.Hour)}, "")
+		assert.Error(t, err)
+		assert.Nil(t, body)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		store := memory.New()
+		inserted, err := store.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+		server := &environment{storage: store}
+		closed := opened.Add(time.Hour)
+		code, body, err := server.handlerCloseAccount(inserted.ID, balance.Balance{Date: closed, Amount: 5}, "closing")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		ab := body.(AccountBalance)
+		assert.True(t, ab.Account.Account.Closed().EqualTime(closed))
+		assert.Equal(t, 5, ab.Balance.Amount)
 	})
 }
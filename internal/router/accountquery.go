@@ -0,0 +1,282 @@
+package router
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glynternet/go-money/currency"
+	"github.com/glynternet/mon/internal/sort"
+	"github.com/glynternet/mon/pkg/filter"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// AccountsQuery holds the query parameters that can be given to the Accounts
+// endpoints. The zero AccountsQuery selects every Account.
+type AccountsQuery struct {
+	// AsOf, if not nil, selects the Accounts as they were stored at the
+	// given time.
+	AsOf *time.Time
+	// Open selects only the Accounts that are open at AtDate.
+	Open bool
+	// AtDate, if not nil, selects only the Accounts that were opened at or
+	// before the given time. Open Accounts are selected at the current time
+	// when AtDate is nil.
+	AtDate     *time.Time
+	IDs        []uint
+	ExcludeIDs []uint
+	Currencies []string
+	SortBy     string
+	Limit      uint
+	Cursor     string
+}
+
+// Values returns the url.Values that represent the AccountsQuery.
+func (q AccountsQuery) Values() url.Values {
+	vs := url.Values{}
+	if q.AsOf != nil {
+		vs.Set(QueryAsOf, q.AsOf.Format(time.RFC3339Nano))
+	}
+	if q.Open {
+		vs.Set(QueryOpen, strconv.FormatBool(q.Open))
+	}
+	if q.AtDate != nil {
+		vs.Set(QueryAtDate, q.AtDate.Format(time.RFC3339Nano))
+	}
+	if len(q.IDs) > 0 {
+		vs.Set(QueryIDs, joinUints(q.IDs))
+	}
+	if len(q.ExcludeIDs) > 0 {
+		vs.Set(QueryExcludeIDs, joinUints(q.ExcludeIDs))
+	}
+	if len(q.Currencies) > 0 {
+		vs.Set(QueryCurrencies, strings.Join(q.Currencies, ","))
+	}
+	if q.SortBy != "" {
+		vs.Set(QuerySortBy, q.SortBy)
+	}
+	if q.Limit > 0 {
+		vs.Set(QueryLimit, strconv.FormatUint(uint64(q.Limit), 10))
+	}
+	if q.Cursor != "" {
+		vs.Set(QueryCursor, q.Cursor)
+	}
+	return vs
+}
+
+// extractAccountsQuery returns the AccountsQuery given by the query parameters
+// of the request. Any error returned is of storage.KindInvalid.
+func extractAccountsQuery(r *http.Request) (AccountsQuery, error) {
+	var q AccountsQuery
+	if r == nil || r.URL == nil {
+		return q, nil
+	}
+	vs := r.URL.Query()
+	var err error
+	if q.AsOf, err = extractTime(vs, QueryAsOf); err != nil {
+		return q, storage.Invalid(err)
+	}
+	if v := vs.Get(QueryOpen); v != "" {
+		if q.Open, err = strconv.ParseBool(v); err != nil {
+			return q, storage.Invalid(errors.Wrapf(err, "parsing %s", QueryOpen))
+		}
+	}
+	if q.AtDate, err = extractTime(vs, QueryAtDate); err != nil {
+		return q, storage.Invalid(err)
+	}
+	if q.IDs, err = splitUints(vs[QueryIDs]); err != nil {
+		return q, storage.Invalid(errors.Wrapf(err, "parsing %s", QueryIDs))
+	}
+	if q.ExcludeIDs, err = splitUints(vs[QueryExcludeIDs]); err != nil {
+		return q, storage.Invalid(errors.Wrapf(err, "parsing %s", QueryExcludeIDs))
+	}
+	q.Currencies = splitValues(vs[QueryCurrencies])
+	q.SortBy = vs.Get(QuerySortBy)
+	if q.Limit, err = extractLimit(vs); err != nil {
+		return q, storage.Invalid(err)
+	}
+	q.Cursor = vs.Get(QueryCursor)
+	return q, nil
+}
+
+// storageQuery returns the storage.AccountQuery that selects the Accounts of
+// the AccountsQuery, evaluated at the given time. Any error returned is of
+// storage.KindInvalid.
+func (q AccountsQuery) storageQuery(now time.Time) (storage.AccountQuery, error) {
+	sq := storage.AccountQuery{
+		ExistedAt:  q.AtDate,
+		IDs:        q.IDs,
+		ExcludeIDs: q.ExcludeIDs,
+		SortBy:     q.SortBy,
+		Limit:      q.Limit,
+	}
+	if q.Open {
+		at := now
+		if q.AtDate != nil {
+			at = *q.AtDate
+		}
+		sq.OpenAt = &at
+	}
+	for _, c := range q.Currencies {
+		code, err := currency.NewCode(c)
+		if err != nil {
+			return sq, storage.Invalid(errors.Wrapf(err, "parsing %s", QueryCurrencies))
+		}
+		sq.Currencies = append(sq.Currencies, *code)
+	}
+	if _, ok := sort.AccountSorts()[q.SortBy]; q.SortBy != "" && !ok {
+		return sq, storage.Invalidf("unsupported %s key: %q", QuerySortBy, q.SortBy)
+	}
+	offset, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return sq, storage.Invalid(err)
+	}
+	sq.Offset = offset
+	return sq, nil
+}
+
+// queryAccounts returns the Accounts of the given storage.Accounts that match
+// the storage.AccountQuery, for use with a Storage that is not a
+// storage.AccountQuerier.
+func queryAccounts(as storage.Accounts, q storage.AccountQuery) storage.Accounts {
+	c := accountCondition(q)
+	matched := storage.Accounts{}
+	for _, a := range as {
+		if c(a) {
+			matched = append(matched, a)
+		}
+	}
+	sort.AccountID(matched)
+	if s, ok := sort.AccountSorts()[q.SortBy]; ok {
+		s(matched)
+	}
+	if q.Offset >= uint(len(matched)) {
+		return storage.Accounts{}
+	}
+	matched = matched[q.Offset:]
+	if q.Limit > 0 && q.Limit < uint(len(matched)) {
+		matched = matched[:q.Limit]
+	}
+	return matched
+}
+
+// accountCondition returns the filter.AccountCondition that identifies the
+// Accounts that match the given storage.AccountQuery.
+func accountCondition(q storage.AccountQuery) filter.AccountCondition {
+	var cs filter.AccountConditions
+	if q.ExistedAt != nil {
+		cs = append(cs, filter.Existed(*q.ExistedAt))
+	}
+	if q.OpenAt != nil {
+		cs = append(cs, filter.OpenAt(*q.OpenAt))
+	}
+	if len(q.IDs) > 0 {
+		cs = append(cs, idsCondition(q.IDs))
+	}
+	if len(q.ExcludeIDs) > 0 {
+		cs = append(cs, filter.AccountNot(idsCondition(q.ExcludeIDs)))
+	}
+	if len(q.Currencies) > 0 {
+		var ccs filter.AccountConditions
+		for _, c := range q.Currencies {
+			ccs = append(ccs, filter.Currency(c))
+		}
+		cs = append(cs, ccs.Or)
+	}
+	return cs.And
+}
+
+func idsCondition(ids []uint) filter.AccountCondition {
+	var cs filter.AccountConditions
+	for _, id := range ids {
+		cs = append(cs, filter.ID(id))
+	}
+	return cs.Or
+}
+
+// withNextPage returns the given storage.AccountQuery with its Limit, if it
+// has one, increased by one, so that the Accounts that are selected show
+// whether there is a page after the one that was queried.
+func withNextPage(q storage.AccountQuery) storage.AccountQuery {
+	if q.Limit > 0 {
+		q.Limit++
+	}
+	return q
+}
+
+// paginate returns the body of the response for the given Accounts, selected
+// using withNextPage(q). If there is a page after the one of the given
+// storage.AccountQuery, the body is a page that holds the Accounts without
+// those of the next page, along with the cursor of the next page.
+func paginate(as *storage.Accounts, q storage.AccountQuery) interface{} {
+	if q.Limit == 0 || uint(len(*as)) <= q.Limit {
+		return as
+	}
+	limited := (*as)[:q.Limit]
+	return page{next: encodeCursor(q.Offset + q.Limit), body: &limited}
+}
+
+// page is a response body that holds one page of a paginated response, which
+// is written along with the cursor of the next page, if there is one.
+type page struct {
+	next string
+	body interface{}
+}
+
+// encodeCursor returns the opaque cursor of the page that starts at the given
+// offset.
+func encodeCursor(offset uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(offset), 10)))
+}
+
+// decodeCursor returns the offset of the page of the given cursor. The empty
+// cursor refers to the first page.
+func decodeCursor(c string) (uint, error) {
+	if c == "" {
+		return 0, nil
+	}
+	bs, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return 0, errors.Wrapf(err, "decoding %s", QueryCursor)
+	}
+	offset, err := strconv.ParseUint(string(bs), 10, 32)
+	return uint(offset), errors.Wrapf(err, "decoding %s", QueryCursor)
+}
+
+// splitValues returns the values of a query parameter that may be given
+// multiple times and as comma separated lists.
+func splitValues(vs []string) []string {
+	var split []string
+	for _, v := range vs {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				split = append(split, s)
+			}
+		}
+	}
+	return split
+}
+
+func splitUints(vs []string) ([]uint, error) {
+	var us []uint
+	for _, s := range splitValues(vs) {
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s to uint", s)
+		}
+		us = append(us, uint(u))
+	}
+	return us, nil
+}
+
+func joinUints(us []uint) string {
+	ss := make([]string, len(us))
+	for i, u := range us {
+		ss[i] = strconv.FormatUint(uint64(u), 10)
+	}
+	return strings.Join(ss, ",")
+}
@@ -0,0 +1,160 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/go-money/currency"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountsQuery_Values(t *testing.T) {
+	at := time.Date(2018, 2, 3, 4, 5, 6, 0, time.UTC)
+	q := AccountsQuery{
+		AsOf:       &at,
+		Open:       true,
+		AtDate:     &at,
+		IDs:        []uint{1, 2},
+		ExcludeIDs: []uint{3},
+		Currencies: []string{"GBP", "EUR"},
+		SortBy:     "name",
+		Limit:      10,
+		Cursor:     encodeCursor(20),
+	}
+	r := httptest.NewRequest(http.MethodGet, EndpointAccounts+"?"+q.Values().Encode(), nil)
+	extracted, err := extractAccountsQuery(r)
+	common.FatalIfError(t, err, "extracting accounts query")
+	assert.Equal(t, q, extracted)
+
+	assert.Empty(t, AccountsQuery{}.Values())
+}
+
+func Test_extractAccountsQuery(t *testing.T) {
+	t.Run("nil request", func(t *testing.T) {
+		q, err := extractAccountsQuery(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, AccountsQuery{}, q)
+	})
+
+	t.Run("repeated and comma separated values", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, EndpointAccounts+"?ids=1,2&ids=3&currencies=GBP,%20EUR", nil)
+		q, err := extractAccountsQuery(r)
+		common.FatalIfError(t, err, "extracting accounts query")
+		assert.Equal(t, []uint{1, 2, 3}, q.IDs)
+		assert.Equal(t, []string{"GBP", "EUR"}, q.Currencies)
+	})
+
+	for _, query := range []string{
+		"open=maybe",
+		"at-date=yesterday",
+		"ids=one",
+		"exclude-ids=-1",
+		"limit=lots",
+		"as-of=yesterday",
+	} {
+		t.Run(query, func(t *testing.T) {
+			_, err := extractAccountsQuery(httptest.NewRequest(http.MethodGet, EndpointAccounts+"?"+query, nil))
+			assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+		})
+	}
+}
+
+func TestAccountsQuery_storageQuery(t *testing.T) {
+	now := time.Date(2018, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	t.Run("open at current time", func(t *testing.T) {
+		q, err := AccountsQuery{Open: true}.storageQuery(now)
+		common.FatalIfError(t, err, "preparing query")
+		assert.Nil(t, q.ExistedAt)
+		assert.Equal(t, &now, q.OpenAt)
+	})
+
+	t.Run("open at date", func(t *testing.T) {
+		at := now.Add(-time.Hour)
+		q, err := AccountsQuery{Open: true, AtDate: &at}.storageQuery(now)
+		common.FatalIfError(t, err, "preparing query")
+		assert.Equal(t, &at, q.ExistedAt)
+		assert.Equal(t, &at, q.OpenAt)
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		q, err := AccountsQuery{Cursor: encodeCursor(7)}.storageQuery(now)
+		common.FatalIfError(t, err, "preparing query")
+		assert.Equal(t, uint(7), q.Offset)
+	})
+
+	for name, aq := range map[string]AccountsQuery{
+		"invalid currency": {Currencies: []string{"pounds"}},
+		"invalid sort key": {SortBy: "balance"},
+		"invalid cursor":   {Cursor: "!"},
+		"malformed cursor": {Cursor: "bm90IGFuIG9mZnNldA"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := aq.storageQuery(now)
+			assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+		})
+	}
+}
+
+func Test_queryAccounts(t *testing.T) {
+	now := time.Date(2018, 2, 3, 4, 5, 6, 0, time.UTC)
+	newAccount := func(id uint, name, code string, opened time.Time, os ...account.Option) storage.Account {
+		return storage.Account{
+			ID:      id,
+			Account: *accountingtest.NewAccount(t, name, accountingtest.NewCurrencyCode(t, code), opened, os...),
+		}
+	}
+	as := storage.Accounts{
+		newAccount(4, "d", "GBP", now.Add(time.Hour)),
+		newAccount(2, "b", "EUR", now.Add(-time.Hour)),
+		newAccount(3, "a", "GBP", now.Add(-time.Hour), account.CloseTime(now.Add(-time.Minute))),
+		newAccount(1, "c", "GBP", now.Add(-time.Hour)),
+	}
+	ids := func(as storage.Accounts) []uint {
+		var ids []uint
+		for _, a := range as {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+	gbp := accountingtest.NewCurrencyCode(t, "GBP")
+
+	for _, test := range []struct {
+		name string
+		storage.AccountQuery
+		ids []uint
+	}{
+		{name: "zero query", ids: []uint{1, 2, 3, 4}},
+		{name: "existed", AccountQuery: storage.AccountQuery{ExistedAt: &now}, ids: []uint{1, 2, 3}},
+		{name: "open", AccountQuery: storage.AccountQuery{OpenAt: &now}, ids: []uint{1, 2}},
+		{name: "ids", AccountQuery: storage.AccountQuery{IDs: []uint{4, 2, 9}}, ids: []uint{2, 4}},
+		{name: "exclude ids", AccountQuery: storage.AccountQuery{ExcludeIDs: []uint{1, 4}}, ids: []uint{2, 3}},
+		{name: "currencies", AccountQuery: storage.AccountQuery{Currencies: []currency.Code{gbp}}, ids: []uint{1, 3, 4}},
+		{name: "sort by name", AccountQuery: storage.AccountQuery{SortBy: storage.AccountSortName}, ids: []uint{3, 2, 1, 4}},
+		{name: "limit", AccountQuery: storage.AccountQuery{Limit: 2}, ids: []uint{1, 2}},
+		{name: "offset", AccountQuery: storage.AccountQuery{Offset: 1, Limit: 2}, ids: []uint{2, 3}},
+		{name: "offset past end", AccountQuery: storage.AccountQuery{Offset: 4}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.ids, ids(queryAccounts(as, test.AccountQuery)))
+		})
+	}
+}
+
+func Test_paginate(t *testing.T) {
+	as := &storage.Accounts{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	assert.Equal(t, as, paginate(as, storage.AccountQuery{}))
+	assert.Equal(t, as, paginate(as, storage.AccountQuery{Limit: 3}))
+	assert.Equal(t, page{
+		next: encodeCursor(12),
+		body: &storage.Accounts{{ID: 1}, {ID: 2}},
+	}, paginate(as, storage.AccountQuery{Offset: 10, Limit: 2}))
+	assert.Len(t, *as, 3)
+}
@@ -2,7 +2,6 @@ package router
 
 import (
 	"encoding/json"
-	"io"
 	"log"
 	"net/http"
 
@@ -19,40 +18,100 @@ func (ah appJSONHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// handle errors
 	if err != nil {
+		// only the method and path of the request are logged, as its headers
+		// hold the secret of the API token that it was authenticated with
 		log.Printf(
-			"error serving on appJSONHandler %v. Error: %v - Status: %d (%s) - Request: %+v",
-			ah, err, status, http.StatusText(status), r,
+			"error serving %s %s. Error: %v - Status: %d (%s)",
+			r.Method, r.URL.Path, err, status, http.StatusText(status),
 		)
-		switch status {
-		case http.StatusServiceUnavailable:
-			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
-			// We can have cases as granular as we like, if we wanted to
-			// return custom errors for specific status codes.
-			// TODO: if http.StatusInternalServerError is received, we should return bad request and log the error maybe?
-		case http.StatusInternalServerError:
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		default:
-			// Catch any other errors we haven't explicitly handled
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		writeError(w, newError(status, err))
+		return
+	}
+
+	res, ok := bod.(response)
+	if status == http.StatusNoContent {
+		if res.replayed {
+			w.Header().Set(HeaderIdempotentReplayed, "true")
 		}
+		w.WriteHeader(status)
 		return
 	}
+	if !ok {
+		res, err = render(bod)
+		if err != nil {
+			log.Print(errors.Wrap(err, "marshalling json reponse"))
+			writeError(w, newError(http.StatusInternalServerError, err))
+			return
+		}
+	}
+	res.write(w, status)
+}
+
+// response is the body of a response as JSON, along with the headers that
+// describe it, each of which is empty if the response does not have it.
+type response struct {
+	location string
+	next     string
+	etag     string
+	body     []byte
+	// replayed is true if the response is the stored response to an
+	// earlier request that was made with the same idempotency key.
+	replayed bool
+}
+
+// render returns the response for the body that an appJSONHandler returned.
+func render(bod interface{}) (response, error) {
+	var res response
+	if l, ok := bod.(located); ok {
+		res.location = l.location
+		bod = l.body
+	}
+	if p, ok := bod.(page); ok {
+		res.next = p.next
+		bod = p.body
+	}
+	if v, ok := version(bod); ok {
+		res.etag = EntityTag(v)
+	}
 
 	// here, I don't want to write to the writer immediately using a json
 	// encoder, in case there is an error in json encoding
-	bs, err := json.Marshal(bod)
+	var err error
+	res.body, err = json.Marshal(bod)
+	return res, err
+}
+
+func (res response) write(w http.ResponseWriter, status int) {
+	if res.location != "" {
+		w.Header().Set("Location", res.location)
+	}
+	if res.next != "" {
+		w.Header().Set(HeaderNextCursor, res.next)
+	}
+	if res.etag != "" {
+		w.Header().Set(HeaderETag, res.etag)
+	}
+	if res.replayed {
+		w.Header().Set(HeaderIdempotentReplayed, "true")
+	}
+	writeJSON(w, status, res.body)
+}
+
+// writeError writes the given Error to the ResponseWriter as JSON, with the
+// status code of the Error.
+func writeError(w http.ResponseWriter, e *Error) {
+	bs, err := json.Marshal(e)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, wErr := io.WriteString(w, http.StatusText(http.StatusInternalServerError))
-		if wErr != nil {
-			log.Print(errors.Wrap(wErr, "writing status text to ResponseWriter"))
-		}
-		log.Print(errors.Wrap(err, "marshalling json reponse"))
+		log.Print(errors.Wrap(err, "marshalling json error"))
+		http.Error(w, http.StatusText(e.Status), e.Status)
 		return
 	}
+	writeJSON(w, e.Status, bs)
+}
 
-	w.WriteHeader(status)
+func writeJSON(w http.ResponseWriter, status int, bs []byte) {
 	w.Header().Set(`Content-Type`, `application/json; charset=UTF-8`)
+	w.WriteHeader(status)
 	_, wErr := w.Write(bs)
 	if wErr != nil {
 		log.Print(errors.Wrap(wErr, "writing body to ResponseWriter"))
@@ -0,0 +1,156 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppJSONHandler_ServeHTTP_logsNoSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	h := appJSONHandler(func(*http.Request) (int, interface{}, error) {
+		return http.StatusBadRequest, nil, errors.New("bad request")
+	})
+	req := httptest.NewRequest(http.MethodPost, "/account/1", nil)
+	req.Header.Set(HeaderAuthorization, AuthSchemeBearer+" secret-token")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, buf.String(), "POST /account/1")
+	assert.Contains(t, buf.String(), "400")
+	assert.NotContains(t, buf.String(), "secret-token")
+}
+
+func TestAppJSONHandler_ServeHTTP(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		h := appJSONHandler(func(*http.Request) (int, interface{}, error) {
+			return http.StatusOK, []int{1, 2}, nil
+		})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `application/json; charset=UTF-8`, rec.Header().Get(`Content-Type`))
+		assert.Equal(t, `[1,2]`, rec.Body.String())
+		assert.Empty(t, rec.Header().Get(HeaderETag))
+	})
+
+	t.Run("versioned body", func(t *testing.T) {
+		h := appJSONHandler(func(*http.Request) (int, interface{}, error) {
+			return http.StatusOK, &storage.Balance{ID: 1, Version: 4}, nil
+		})
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, `"4"`, rec.Header().Get(HeaderETag))
+	})
+
+	for _, test := range []struct {
+		name           string
+		status         int
+		err            error
+		expectedStatus int
+		expectedCode   ErrorCode
+		expectedMsg    string
+	}{
+		{
+			name:           "bad request",
+			status:         http.StatusBadRequest,
+			err:            errors.New("balance outside account time range"),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeBadRequest,
+			expectedMsg:    "balance outside account time range",
+		},
+		{
+			name:           "not found",
+			status:         http.StatusNotFound,
+			err:            errors.New("no account with id 1"),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeNotFound,
+			expectedMsg:    "no account with id 1",
+		},
+		{
+			name:           "unavailable",
+			status:         http.StatusServiceUnavailable,
+			err:            errors.New("storage unavailable"),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedCode:   CodeUnavailable,
+			expectedMsg:    http.StatusText(http.StatusServiceUnavailable),
+		},
+		{
+			name:           "internal error hides details",
+			status:         http.StatusInternalServerError,
+			err:            errors.New("connection refused to 10.0.0.1"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
+			expectedMsg:    http.StatusText(http.StatusInternalServerError),
+		},
+		{
+			name:           "error with success status",
+			status:         http.StatusOK,
+			err:            errors.New("oops"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
+			expectedMsg:    http.StatusText(http.StatusInternalServerError),
+		},
+		{
+			name:           "storage conflict",
+			status:         http.StatusBadRequest,
+			err:            errors.Wrap(storage.Conflictf("account 1 has live balances"), "deleting account"),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodeConflict,
+			expectedMsg:    "deleting account: account 1 has live balances",
+		},
+		{
+			name:           "storage stale",
+			status:         http.StatusBadRequest,
+			err:            errors.Wrap(storage.Stalef("account 1 has been changed"), "updating account"),
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   CodeStale,
+			expectedMsg:    "updating account: account 1 has been changed",
+		},
+		{
+			name:           "storage invalid",
+			status:         http.StatusInternalServerError,
+			err:            storage.Invalidf("balance outside account time range"),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalid,
+			expectedMsg:    "balance outside account time range",
+		},
+		{
+			name:           "typed error",
+			status:         http.StatusBadRequest,
+			err:            &Error{Code: CodeConflict, Status: http.StatusConflict, Message: "conflict"},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodeConflict,
+			expectedMsg:    "conflict",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h := appJSONHandler(func(*http.Request) (int, interface{}, error) {
+				return test.status, nil, test.err
+			})
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			assert.Equal(t, `application/json; charset=UTF-8`, rec.Header().Get(`Content-Type`))
+
+			var e Error
+			common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &e), "unmarshalling error")
+			assert.Equal(t, Error{
+				Code:    test.expectedCode,
+				Status:  test.expectedStatus,
+				Message: test.expectedMsg,
+			}, e)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// audited returns true if requests to a route that requires the given
+// Permission should be recorded in the audit log, which is the case for every
+// route that changes the data of the storage.
+func (p Permission) audited() bool {
+	switch p {
+	case PermissionWrite, PermissionDelete, PermissionShare:
+		return true
+	}
+	return false
+}
+
+// auditRoutes returns the given routes with the handler of each that requires
+// an audited Permission wrapped by auditHandler.
+func auditRoutes(rs []route) []route {
+	for i, r := range rs {
+		if r.permission.audited() {
+			rs[i].handler = auditHandler(r)
+		}
+	}
+	return rs
+}
+
+// auditHandler returns an envHandler that serves a request with the handler
+// of the given route and records the request, along with the Account or
+// Balance that it targeted as it was before and after the request was served,
+// in the audit log of the environment. A request is served even if it cannot
+// be recorded.
+func auditHandler(rt route) envHandler {
+	return func(env *environment, r *http.Request) (int, interface{}, error) {
+		e := storage.AuditEntry{
+			User:    requestUser(r),
+			TokenID: requestTokenID(r),
+			Route:   rt.name,
+			Method:  r.Method,
+			Path:    r.URL.RequestURI(),
+		}
+		var err error
+		if e.Request, err = requestPayload(r); err != nil {
+			return http.StatusBadRequest, nil, errors.Wrap(err, "reading request body")
+		}
+		e.AccountID, e.BalanceID = env.auditTarget(rt.pattern, mux.Vars(r))
+		e.Before = env.snapshot(e.AccountID, e.BalanceID)
+
+		status, bod, err := rt.handler(env, r)
+		if err != nil {
+			serr := newError(status, err)
+			e.Status, e.Error = serr.Status, serr.Message
+		} else {
+			e.Status = status
+			e.After = env.afterSnapshot(&e, bod)
+		}
+
+		if _, aErr := env.audit.InsertAuditEntry(e); aErr != nil {
+			log.Print(errors.Wrapf(aErr, "inserting audit entry for %s request to %s", e.Method, e.Path))
+		}
+		return status, bod, err
+	}
+}
+
+// requestPayload returns the body of the request as JSON, replacing the body
+// so that it can be read again by the handler. A body that is not valid JSON
+// is returned as a JSON string.
+func requestPayload(r *http.Request) (json.RawMessage, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	bs, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Body.Close(); err != nil {
+		log.Print(errors.Wrap(err, "closing request body"))
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(bs))
+	if len(bytes.TrimSpace(bs)) == 0 {
+		return nil, nil
+	}
+	var compact bytes.Buffer
+	if json.Compact(&compact, bs) == nil {
+		return compact.Bytes(), nil
+	}
+	return json.Marshal(string(bs))
+}
+
+// auditTarget returns the IDs of the Account and the Balance that a request to
+// a route with the given pattern targets, according to the variables of the
+// request. The ID of the Account that a targeted Balance belongs to is
+// selected from the storage if it is not given by the request.
+func (env *environment) auditTarget(pattern string, vars map[string]string) (accountID, balanceID uint) {
+	if id, err := extractUint(vars, "balanceID"); err == nil {
+		balanceID = id
+	}
+	if id, err := extractID(vars); err == nil {
+		if strings.HasPrefix(pattern, EndpointBalance+"/") || strings.HasPrefix(pattern, EndpointV2Balances+"/") {
+			balanceID = id
+		} else {
+			accountID = id
+		}
+	}
+	if accountID == 0 && balanceID != 0 {
+		if b, err := env.storage.SelectBalance(balanceID); err == nil {
+			accountID = b.AccountID
+		}
+	}
+	return accountID, balanceID
+}
+
+// snapshot returns the JSON of the Balance with the given balanceID or, if
+// balanceID is zero, the Account with the given accountID. The returned JSON
+// is empty if there is no such item.
+func (env *environment) snapshot(accountID, balanceID uint) json.RawMessage {
+	var item interface{}
+	var err error
+	switch {
+	case balanceID != 0:
+		item, err = env.storage.SelectBalance(balanceID)
+	case accountID != 0:
+		item, err = env.storage.SelectAccount(accountID)
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	return marshalSnapshot(item)
+}
+
+// afterSnapshot returns the JSON of the target of a request that was served
+// successfully with the given response body. If the body is the stored item
+// that the request changed, the body is used as the snapshot and the IDs of
+// the item are recorded as the target of the AuditEntry where it did not
+// already have them. Otherwise, the target is selected again from the storage.
+func (env *environment) afterSnapshot(e *storage.AuditEntry, bod interface{}) json.RawMessage {
+	if l, ok := bod.(located); ok {
+		bod = l.body
+	}
+	var accountID, balanceID uint
+	switch i := bod.(type) {
+	case *storage.Account:
+		accountID = i.ID
+	case *storage.Balance:
+		accountID, balanceID = i.AccountID, i.ID
+	case AccountBalance:
+		accountID, balanceID = i.Account.ID, i.Balance.ID
+	default:
+		return env.snapshot(e.AccountID, e.BalanceID)
+	}
+	if e.AccountID == 0 {
+		e.AccountID = accountID
+	}
+	if e.BalanceID == 0 {
+		e.BalanceID = balanceID
+	}
+	return marshalSnapshot(bod)
+}
+
+func marshalSnapshot(item interface{}) json.RawMessage {
+	bs, err := json.Marshal(item)
+	if err != nil {
+		log.Print(errors.Wrap(err, "marshalling audit snapshot"))
+		return nil
+	}
+	return bs
+}
@@ -0,0 +1,189 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/internal/auth"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_audit(t *testing.T) {
+	store := memory.New()
+	secrets := make(map[string]string)
+	tokenIDs := make(map[string]uint)
+	for _, user := range []string{"auditor", "other"} {
+		secret, err := auth.NewToken()
+		common.FatalIfError(t, err, "creating token")
+		token, err := store.InsertToken(user, user, storage.RoleAdmin, auth.HashToken(secret))
+		common.FatalIfError(t, err, "inserting token")
+		secrets[user] = secret
+		tokenIDs[user] = token.ID
+	}
+
+	r, err := New(store, store, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	serve := func(user, method, endpoint string, body interface{}) *httptest.ResponseRecorder {
+		var bs []byte
+		if body != nil {
+			var err error
+			bs, err = json.Marshal(body)
+			common.FatalIfError(t, err, "marshalling body")
+		}
+		req := httptest.NewRequest(method, endpoint, bytes.NewReader(bs))
+		req.Header.Set(HeaderAuthorization, AuthSchemeBearer+" "+secrets[user])
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	audit := func(user string, q AuditQuery) storage.AuditEntries {
+		rec := serve(user, http.MethodGet, EndpointAudit+"?"+q.Values().Encode(), nil)
+		if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+			t.FailNow()
+		}
+		var es storage.AuditEntries
+		common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &es), "unmarshalling audit entries")
+		return es
+	}
+
+	gbp := accountingtest.NewCurrencyCode(t, "GBP")
+	rec := serve("auditor", http.MethodPost, EndpointV2Accounts, accountingtest.NewAccount(t, "before", gbp, time.Now()))
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	var a storage.Account
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &a), "unmarshalling account")
+	accountEndpoint := fmt.Sprintf(EndpointFmtV2Account, a.ID)
+
+	name := "after"
+	rec = serve("auditor", http.MethodPatch, accountEndpoint, AccountPatchBody{Name: &name})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = serve("auditor", http.MethodPost, fmt.Sprintf(EndpointFmtV2AccountBalances, a.ID), BalanceInsertBody{
+		Balance: balance.Balance{Date: time.Now(), Amount: 10},
+	})
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	var b storage.Balance
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &b), "unmarshalling balance")
+
+	rec = serve("auditor", http.MethodDelete, fmt.Sprintf(EndpointFmtV2Balance, b.ID), nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+
+	rec = serve("other", http.MethodDelete, accountEndpoint, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+
+	assert.Equal(t, http.StatusOK, serve("auditor", http.MethodGet, accountEndpoint, nil).Code)
+
+	t.Run("mutating requests are recorded", func(t *testing.T) {
+		es := audit("auditor", AuditQuery{})
+		if !assert.Len(t, es, 4) {
+			return
+		}
+		for _, e := range es {
+			assert.Equal(t, "auditor", e.User)
+			assert.Equal(t, tokenIDs["auditor"], e.TokenID)
+			assert.Equal(t, a.ID, e.AccountID)
+		}
+
+		insert := es[0]
+		assert.Equal(t, "V2AccountInsert", insert.Route)
+		assert.Equal(t, http.MethodPost, insert.Method)
+		assert.Equal(t, EndpointV2Accounts, insert.Path)
+		assert.Equal(t, http.StatusCreated, insert.Status)
+		assert.Nil(t, insert.Before)
+		assert.Contains(t, string(insert.Request), `"before"`)
+		assert.Contains(t, string(insert.After), `"before"`)
+
+		patch := es[1]
+		assert.Equal(t, "V2AccountPatch", patch.Route)
+		assert.JSONEq(t, `{"Name":"after"}`, string(patch.Request))
+		assert.Contains(t, string(patch.Before), `"before"`)
+		assert.Contains(t, string(patch.After), `"after"`)
+
+		insertBalance := es[2]
+		assert.Equal(t, "V2BalanceInsert", insertBalance.Route)
+		assert.Equal(t, b.ID, insertBalance.BalanceID)
+
+		deleteBalance := es[3]
+		assert.Equal(t, "V2BalanceDelete", deleteBalance.Route)
+		assert.Equal(t, b.ID, deleteBalance.BalanceID)
+		assert.Equal(t, http.StatusNoContent, deleteBalance.Status)
+		assert.NotNil(t, deleteBalance.Before)
+		assert.Nil(t, deleteBalance.After)
+	})
+
+	t.Run("failed requests are recorded for their user only", func(t *testing.T) {
+		es := audit("other", AuditQuery{})
+		if !assert.Len(t, es, 1) {
+			return
+		}
+		assert.Equal(t, "V2AccountDelete", es[0].Route)
+		assert.Equal(t, http.StatusNotFound, es[0].Status)
+		assert.NotEmpty(t, es[0].Error)
+		assert.Nil(t, es[0].Before, "the account of another user should not be recorded")
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		es := audit("auditor", AuditQuery{BalanceID: b.ID, Order: storage.OrderDescending})
+		if assert.Len(t, es, 2) {
+			assert.Equal(t, "V2BalanceDelete", es[0].Route)
+			assert.Equal(t, "V2BalanceInsert", es[1].Route)
+		}
+		es = audit("auditor", AuditQuery{Route: "V2AccountPatch"})
+		assert.Len(t, es, 1)
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		rec := serve("auditor", http.MethodGet, EndpointAudit+"?"+AuditQuery{Limit: 3}.Values().Encode(), nil)
+		next := rec.Header().Get(HeaderNextCursor)
+		if !assert.NotEmpty(t, next) {
+			return
+		}
+		es := audit("auditor", AuditQuery{Limit: 3, Cursor: next})
+		if assert.Len(t, es, 1) {
+			assert.Equal(t, "V2BalanceDelete", es[0].Route)
+		}
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		vs := url.Values{QueryAccountID: {"one"}}
+		rec := serve("auditor", http.MethodGet, EndpointAudit+"?"+vs.Encode(), nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestRequestPayload(t *testing.T) {
+	for _, test := range []struct {
+		name, body, expected string
+	}{
+		{name: "empty"},
+		{name: "json", body: "{\n\"Name\": \"a\"\n}", expected: `{"Name":"a"}`},
+		{name: "not json", body: "name=a", expected: `"name=a"`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(test.body)))
+			payload, err := requestPayload(req)
+			common.FatalIfError(t, err, "reading payload")
+			assert.Equal(t, test.expected, string(payload))
+			bs, err := ioutil.ReadAll(req.Body)
+			common.FatalIfError(t, err, "reading body again")
+			assert.Equal(t, test.body, string(bs))
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// handlerSelectAuditEntries responds with the AuditEntries of the user that
+// made the request that match the AuditQuery given by the query parameters of
+// the request.
+func (env *environment) handlerSelectAuditEntries(r *http.Request) (int, interface{}, error) {
+	if env.audit == nil {
+		return http.StatusNotImplemented, nil, errors.New("storage does not keep an audit log")
+	}
+	q, err := extractAuditQuery(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "extracting audit query")
+	}
+	sq, err := q.storageQuery(requestUser(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "building audit query")
+	}
+	es, err := env.audit.SelectAuditEntries(withNextAuditPage(sq))
+	if err != nil {
+		return errorStatus(err, http.StatusServiceUnavailable), nil, errors.Wrap(err, "selecting audit entries")
+	}
+	return http.StatusOK, paginateAuditEntries(es, sq), nil
+}
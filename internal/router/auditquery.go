@@ -0,0 +1,143 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// AuditQuery holds the query parameters that can be given to the
+// EndpointAudit endpoint. The zero AuditQuery selects every AuditEntry of the
+// user that made the request, in the order in which they were recorded.
+type AuditQuery struct {
+	Route     string
+	AccountID uint
+	BalanceID uint
+	From      *time.Time
+	To        *time.Time
+	Order     string
+	Limit     uint
+	Cursor    string
+}
+
+// Values returns the url.Values that represent the AuditQuery.
+func (q AuditQuery) Values() url.Values {
+	vs := url.Values{}
+	if q.Route != "" {
+		vs.Set(QueryRoute, q.Route)
+	}
+	if q.AccountID > 0 {
+		vs.Set(QueryAccountID, strconv.FormatUint(uint64(q.AccountID), 10))
+	}
+	if q.BalanceID > 0 {
+		vs.Set(QueryBalanceID, strconv.FormatUint(uint64(q.BalanceID), 10))
+	}
+	if q.From != nil {
+		vs.Set(QueryFrom, q.From.Format(time.RFC3339Nano))
+	}
+	if q.To != nil {
+		vs.Set(QueryTo, q.To.Format(time.RFC3339Nano))
+	}
+	if q.Order != "" {
+		vs.Set(QueryOrder, q.Order)
+	}
+	if q.Limit > 0 {
+		vs.Set(QueryLimit, strconv.FormatUint(uint64(q.Limit), 10))
+	}
+	if q.Cursor != "" {
+		vs.Set(QueryCursor, q.Cursor)
+	}
+	return vs
+}
+
+// extractAuditQuery returns the AuditQuery given by the query parameters of
+// the request. Any error returned is of storage.KindInvalid.
+func extractAuditQuery(r *http.Request) (AuditQuery, error) {
+	var q AuditQuery
+	if r == nil || r.URL == nil {
+		return q, nil
+	}
+	vs := r.URL.Query()
+	var err error
+	q.Route = vs.Get(QueryRoute)
+	if q.AccountID, err = extractQueryUint(vs, QueryAccountID); err != nil {
+		return q, storage.Invalid(err)
+	}
+	if q.BalanceID, err = extractQueryUint(vs, QueryBalanceID); err != nil {
+		return q, storage.Invalid(err)
+	}
+	if q.From, err = extractTime(vs, QueryFrom); err != nil {
+		return q, storage.Invalid(err)
+	}
+	if q.To, err = extractTime(vs, QueryTo); err != nil {
+		return q, storage.Invalid(err)
+	}
+	q.Order = vs.Get(QueryOrder)
+	if q.Limit, err = extractLimit(vs); err != nil {
+		return q, storage.Invalid(err)
+	}
+	q.Cursor = vs.Get(QueryCursor)
+	return q, nil
+}
+
+// storageQuery returns the storage.AuditQuery that selects the AuditEntries
+// of the AuditQuery that were recorded for the given user. Any error
+// returned is of storage.KindInvalid.
+func (q AuditQuery) storageQuery(user string) (storage.AuditQuery, error) {
+	sq := storage.AuditQuery{
+		User:      &user,
+		Route:     q.Route,
+		AccountID: q.AccountID,
+		BalanceID: q.BalanceID,
+		From:      q.From,
+		To:        q.To,
+		Order:     q.Order,
+		Limit:     q.Limit,
+	}
+	switch q.Order {
+	case "", storage.OrderAscending, storage.OrderDescending:
+	default:
+		return sq, storage.Invalidf("unsupported %s: %q", QueryOrder, q.Order)
+	}
+	offset, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return sq, storage.Invalid(err)
+	}
+	sq.Offset = offset
+	return sq, nil
+}
+
+// withNextAuditPage returns the given storage.AuditQuery with its Limit, if
+// it has one, increased by one, in the same way as withNextPage.
+func withNextAuditPage(q storage.AuditQuery) storage.AuditQuery {
+	if q.Limit > 0 {
+		q.Limit++
+	}
+	return q
+}
+
+// paginateAuditEntries returns the body of the response for the given
+// AuditEntries, selected using withNextAuditPage(q), in the same way as
+// paginate.
+func paginateAuditEntries(es *storage.AuditEntries, q storage.AuditQuery) interface{} {
+	if q.Limit == 0 || uint(len(*es)) <= q.Limit {
+		return es
+	}
+	limited := (*es)[:q.Limit]
+	return page{next: encodeCursor(q.Offset + q.Limit), body: &limited}
+}
+
+// extractQueryUint returns the unsigned integer value of the given query
+// parameter, or zero if no value was given.
+func extractQueryUint(vs url.Values, key string) (uint, error) {
+	v := vs.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	u, err := strconv.ParseUint(v, 10, 32)
+	return uint(u), errors.Wrapf(err, "parsing %s", key)
+}
@@ -0,0 +1,96 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/glynternet/mon/internal/auth"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// authenticate returns a http.Handler that serves a request with the inner
+// handler only if the HeaderAuthorization header of the request holds the
+// secret of a Token that is held by the TokenStore and has not been revoked.
+// Any other request is responded to with an Error of CodeUnauthorized. The
+// Token is held in the context of the request that is served by the inner
+// handler.
+func authenticate(tokens storage.TokenStore, inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := bearerToken(r)
+		if !ok {
+			unauthorized(w, errors.New("request has no API token"))
+			return
+		}
+		t, err := tokens.SelectTokenByHash(auth.HashToken(secret))
+		if storage.KindOf(err) == storage.KindNotFound {
+			unauthorized(w, errors.New("API token is unknown or has been revoked"))
+			return
+		}
+		if err != nil {
+			writeError(w, newError(http.StatusServiceUnavailable, err))
+			return
+		}
+		inner.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, t)))
+	})
+}
+
+// tokenKey is the key of the Token that a request was authenticated with in
+// the context of the request.
+type tokenKey struct{}
+
+// requestToken returns the Token that the request was authenticated with, if
+// it was authenticated.
+func requestToken(r *http.Request) (*storage.Token, bool) {
+	t, ok := r.Context().Value(tokenKey{}).(*storage.Token)
+	return t, ok
+}
+
+// requestRole returns the Role of the Token that the request was authenticated
+// with, or an empty Role if the request was not authenticated.
+func requestRole(r *http.Request) storage.Role {
+	t, ok := requestToken(r)
+	if !ok {
+		return ""
+	}
+	return t.Role
+}
+
+// requestUser returns the user that made the request, which is the User of the
+// Token that the request was authenticated with or storage.DefaultUser if the
+// request was not authenticated.
+func requestUser(r *http.Request) string {
+	t, ok := requestToken(r)
+	if !ok {
+		return storage.DefaultUser
+	}
+	return t.User
+}
+
+// requestTokenID returns the ID of the Token that the request was
+// authenticated with, or zero if the request was not authenticated.
+func requestTokenID(r *http.Request) uint {
+	t, ok := requestToken(r)
+	if !ok {
+		return 0
+	}
+	return t.ID
+}
+
+// bearerToken returns the secret held by the HeaderAuthorization header of
+// the request, if the header uses the AuthSchemeBearer scheme.
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get(HeaderAuthorization)
+	prefix := AuthSchemeBearer + " "
+	if len(h) <= len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(h[len(prefix):]), true
+}
+
+// unauthorized responds to a request that could not be authenticated.
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", AuthSchemeBearer)
+	writeError(w, newError(http.StatusUnauthorized, err))
+}
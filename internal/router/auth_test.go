@@ -0,0 +1,71 @@
+package router
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/internal/auth"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_authentication(t *testing.T) {
+	store := memory.New()
+	secret, err := auth.NewToken()
+	common.FatalIfError(t, err, "creating token")
+	token, err := store.InsertToken("test", storage.DefaultUser, storage.RoleAdmin, auth.HashToken(secret))
+	common.FatalIfError(t, err, "inserting token")
+	revoked, err := auth.NewToken()
+	common.FatalIfError(t, err, "creating revoked token")
+	revokedToken, err := store.InsertToken("revoked", storage.DefaultUser, storage.RoleAdmin, auth.HashToken(revoked))
+	common.FatalIfError(t, err, "inserting revoked token")
+	common.FatalIfError(t, store.RevokeToken(revokedToken.ID), "revoking token")
+
+	r, err := New(store, store, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, EndpointAccounts, nil)
+		if authorization != "" {
+			req.Header.Set(HeaderAuthorization, authorization)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, test := range []struct {
+		name, authorization string
+	}{
+		{name: "no token"},
+		{name: "other scheme", authorization: "Basic " + secret},
+		{name: "empty token", authorization: AuthSchemeBearer + " "},
+		{name: "unknown token", authorization: AuthSchemeBearer + " unknown"},
+		{name: "revoked token", authorization: AuthSchemeBearer + " " + revoked},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(test.authorization)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Equal(t, AuthSchemeBearer, rec.Header().Get("WWW-Authenticate"))
+			var e Error
+			common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &e), "unmarshalling error")
+			assert.Equal(t, CodeUnauthorized, e.Code)
+		})
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		rec := serve(AuthSchemeBearer + " " + secret)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	})
+
+	t.Run("revoked after use", func(t *testing.T) {
+		common.FatalIfError(t, store.RevokeToken(token.ID), "revoking token")
+		assert.Equal(t, http.StatusUnauthorized, serve(AuthSchemeBearer+" "+secret).Code)
+	})
+}
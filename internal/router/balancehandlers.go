@@ -13,17 +13,27 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (env *environment) balances(accountID uint) (int, interface{}, error) {
+// balances selects the Balances of the account with the given id that match
+// the given storage.BalanceQuery.
+func (env *environment) balances(accountID uint, q storage.BalanceQuery) (int, interface{}, error) {
 	a, err := env.storage.SelectAccount(accountID)
 	if err != nil {
 		return http.StatusBadRequest, nil, errors.Wrapf(err, "selecting account with id %d", accountID)
 	}
 	var bs *storage.Balances
-	bs, err = model.SelectAccountBalances(env.storage, *a)
+	if bq, ok := storage.AsBalanceQuerier(env.storage); ok {
+		bs, err = bq.QueryAccountBalances(a.ID, withNextBalancesPage(q))
+	} else {
+		bs, err = model.SelectAccountBalances(env.storage, *a)
+		if err == nil {
+			matched := queryBalances(*bs, withNextBalancesPage(q))
+			bs = &matched
+		}
+	}
 	if err != nil {
 		return http.StatusBadRequest, nil, errors.Wrapf(err, "selecting balances for account %+v", *a)
 	}
-	return http.StatusOK, bs, nil
+	return http.StatusOK, paginateBalances(bs, q), nil
 }
 
 func (env *environment) muxAccountBalancesHandlerFunc(r *http.Request) (int, interface{}, error) {
@@ -31,7 +41,34 @@ func (env *environment) muxAccountBalancesHandlerFunc(r *http.Request) (int, int
 	if err != nil {
 		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
 	}
-	return env.balances(id)
+	bq, err := extractBalancesQuery(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "extracting balances query")
+	}
+	q, err := bq.storageQuery()
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "preparing balances query")
+	}
+	if bq.AsOf != nil {
+		return env.balancesAsOf(id, *bq.AsOf, q)
+	}
+	return env.balances(id, q)
+}
+
+func (env *environment) muxBalanceIDHandlerFunc(r *http.Request) (int, interface{}, error) {
+	id, err := extractID(mux.Vars(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting balance ID")
+	}
+	return env.selectBalance(id)
+}
+
+func (env *environment) selectBalance(id uint) (int, interface{}, error) {
+	b, err := env.storage.SelectBalance(id)
+	if err != nil {
+		return errorStatus(err, http.StatusInternalServerError), nil, errors.Wrapf(err, "selecting Balance with id:%d from storage", id)
+	}
+	return http.StatusOK, b, nil
 }
 
 func (env *environment) insertBalance(accountID uint, b balance.Balance, note string) (int, interface{}, error) {
@@ -46,16 +83,32 @@ func (env *environment) insertBalance(accountID uint, b balance.Balance, note st
 	return http.StatusOK, inserted, nil
 }
 
+func (env *environment) updateBalance(accountID, id, version uint, b balance.Balance, note string) (int, interface{}, error) {
+	a, err := env.storage.SelectAccount(accountID)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "selecting account")
+	}
+	updated, err := model.UpdateBalance(env.storage, *a, id, version, b, note)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "updating balance")
+	}
+	return http.StatusOK, updated, nil
+}
+
 func (env *environment) muxBalanceDeleteHandlerFunc(r *http.Request) (int, interface{}, error) {
 	id, err := extractID(mux.Vars(r))
 	if err != nil {
 		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting ID")
 	}
-	return env.deleteBalance(id)
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	return env.deleteBalance(id, version)
 }
 
-func (env *environment) deleteBalance(id uint) (int, interface{}, error) {
-	err := env.storage.DeleteBalance(id)
+func (env *environment) deleteBalance(id, version uint) (int, interface{}, error) {
+	err := model.DeleteBalance(env.storage, id, version)
 	if err != nil {
 		return http.StatusBadRequest, "", errors.Wrap(err, "deleting balance")
 	}
@@ -85,7 +138,7 @@ func (env *environment) muxAccountBalanceInsertHandlerFunc(r *http.Request) (int
 		// TODO: this handler only needs to take a []byte or io.Reader, so we could handle closing the body elsewhere
 		cErr := r.Body.Close()
 		if cErr != nil {
-			log.Print(errors.Wrap(err, "closing request body"))
+			log.Print(errors.Wrap(cErr, "closing request body"))
 		}
 	}()
 
@@ -96,3 +149,38 @@ func (env *environment) muxAccountBalanceInsertHandlerFunc(r *http.Request) (int
 	}
 	return env.insertBalance(id, bib.Balance, bib.Note)
 }
+
+func (env *environment) muxAccountBalanceUpdateHandlerFunc(r *http.Request) (int, interface{}, error) {
+	vars := mux.Vars(r)
+	accountID, err := extractID(vars)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
+	}
+	id, err := extractUint(vars, "balanceID")
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting balance ID")
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+
+	bod, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "reading request body")
+	}
+
+	defer func() {
+		cErr := r.Body.Close()
+		if cErr != nil {
+			log.Print(errors.Wrap(cErr, "closing request body"))
+		}
+	}()
+
+	var bib BalanceInsertBody
+	err = json.Unmarshal(bod, &bib)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "unmarshalling request body")
+	}
+	return env.updateBalance(accountID, id, version, bib.Balance, bib.Note)
+}
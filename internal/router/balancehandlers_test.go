@@ -21,7 +21,7 @@ func Test_balances(t *testing.T) {
 				AccountErr: expected,
 			},
 		}
-		code, bs, err := srv.balances(1) // any ID can be used because of the stub
+		code, bs, err := srv.balances(1, storage.BalanceQuery{}) // any ID can be used because of the stub
 		assert.Equal(t, http.StatusBadRequest, code)
 		assert.Equal(t, expected, errors.Cause(err))
 		assert.Nil(t, bs)
@@ -36,7 +36,7 @@ func Test_balances(t *testing.T) {
 				BalancesErr: expected,
 			},
 		}
-		code, bs, err := srv.balances(1) // any ID can be used because of the stub
+		code, bs, err := srv.balances(1, storage.BalanceQuery{}) // any ID can be used because of the stub
 		assert.Equal(t, http.StatusBadRequest, code)
 		assert.Equal(t, expected, errors.Cause(err))
 		assert.Nil(t, bs)
@@ -50,7 +50,7 @@ func Test_balances(t *testing.T) {
 				Balances: expected,
 			},
 		}
-		code, bs, err := srv.balances(1) // any ID can be used because of the stub
+		code, bs, err := srv.balances(1, storage.BalanceQuery{}) // any ID can be used because of the stub
 		assert.Equal(t, http.StatusOK, code)
 		assert.NoError(t, err)
 		assert.IsType(t, &storage.Balances{}, bs)
@@ -58,10 +58,47 @@ func Test_balances(t *testing.T) {
 	})
 }
 
+func TestServer_SelectBalance(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		expected := errors.New("SelectBalance error")
+		srv := environment{storage: &storagetest.Storage{
+			BalanceErr: expected,
+		}}
+		code, b, err := srv.selectBalance(1)
+		assert.Equal(t, http.StatusInternalServerError, code)
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Nil(t, b)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		expected := storage.NotFoundf("no balance with id 1")
+		srv := environment{storage: &storagetest.Storage{
+			BalanceErr: expected,
+		}}
+		code, b, err := srv.selectBalance(1)
+		assert.Equal(t, http.StatusNotFound, code)
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Nil(t, b)
+	})
+
+	t.Run("all ok", func(t *testing.T) {
+		expected := &storage.Balance{ID: 1, AccountID: 2}
+		mockStore := storagetest.Storage{
+			Balance: expected,
+		}
+		srv := environment{storage: &mockStore}
+		code, b, err := srv.selectBalance(1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, expected, b)
+		assert.Equal(t, uint(1), mockStore.LastBalanceID)
+	})
+}
+
 func TestServer_InsertBalance(t *testing.T) {
 	t.Run("SelectAccount error", func(t *testing.T) {
 		expected := errors.New("SelectAccount error")
-		srv := environment{&storagetest.Storage{
+		srv := environment{storage: &storagetest.Storage{
 			AccountErr: expected,
 		}}
 		code, b, err := srv.insertBalance(0, balance.Balance{}, "")
@@ -79,7 +116,7 @@ func TestServer_InsertBalance(t *testing.T) {
 
 	t.Run("InsertBalance error", func(t *testing.T) {
 		expected := errors.New("InsertBalance error")
-		srv := environment{&storagetest.Storage{
+		srv := environment{storage: &storagetest.Storage{
 			Account:    account,
 			BalanceErr: expected,
 		}}
@@ -96,7 +133,7 @@ func TestServer_InsertBalance(t *testing.T) {
 			Account: account,
 			Balance: expected,
 		}
-		srv := environment{&mockStore}
+		srv := environment{storage: &mockStore}
 		code, b, err := srv.insertBalance(0, balance, "test note")
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, code)
@@ -105,6 +142,51 @@ func TestServer_InsertBalance(t *testing.T) {
 	})
 }
 
+func TestServer_UpdateBalance(t *testing.T) {
+	t.Run("SelectAccount error", func(t *testing.T) {
+		expected := errors.New("SelectAccount error")
+		srv := environment{storage: &storagetest.Storage{
+			AccountErr: expected,
+		}}
+		code, b, err := srv.updateBalance(0, 0, 0, balance.Balance{}, "")
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Contains(t, err.Error(), "selecting account")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Nil(t, b)
+	})
+
+	now := time.Now()
+	account := &storage.Account{
+		Account: *accountingtest.NewAccount(t, "test account", accountingtest.NewCurrencyCode(t, "ABC"), now),
+	}
+
+	t.Run("invalid balance", func(t *testing.T) {
+		srv := environment{storage: &storagetest.Storage{
+			Account: account,
+		}}
+		code, b, err := srv.updateBalance(0, 0, 0, balance.Balance{Date: now.Add(-time.Hour)}, "")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "validating balance")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Nil(t, b)
+	})
+
+	t.Run("all ok", func(t *testing.T) {
+		expected := &storage.Balance{}
+		mockStore := storagetest.Storage{
+			Account: account,
+			Balance: expected,
+		}
+		srv := environment{storage: &mockStore}
+		code, b, err := srv.updateBalance(1, 2, 0, balance.Balance{Date: now}, "test note")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, expected, b)
+		assert.Equal(t, uint(2), mockStore.LastBalanceID)
+		assert.Equal(t, "test note", mockStore.LastBalanceNote)
+	})
+}
+
 func TestServer_DeleteBalance(t *testing.T) {
 	t.Run("DeleteBalance error", func(t *testing.T) {
 		expected := errors.New("DeleteBalance error")
@@ -112,7 +194,7 @@ func TestServer_DeleteBalance(t *testing.T) {
 			Err: expected,
 		}}
 
-		code, body, err := srv.deleteBalance(0)
+		code, body, err := srv.deleteBalance(0, 0)
 		assert.Equal(t, http.StatusBadRequest, code)
 		assert.Equal(t, expected, errors.Cause(err))
 		assert.Equal(t, "", body)
@@ -120,7 +202,7 @@ func TestServer_DeleteBalance(t *testing.T) {
 
 	t.Run("all ok", func(t *testing.T) {
 		srv := environment{storage: &storagetest.Storage{}}
-		code, body, err := srv.deleteBalance(0)
+		code, body, err := srv.deleteBalance(0, 0)
 		assert.Nil(t, err)
 		assert.Equal(t, "", body)
 		assert.Equal(t, http.StatusOK, code)
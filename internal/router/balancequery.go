@@ -0,0 +1,178 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// BalancesQuery holds the query parameters that can be given to the account
+// Balances endpoints. The zero BalancesQuery selects every Balance of an
+// Account in chronological order.
+type BalancesQuery struct {
+	// AsOf, if not nil, selects the Balances as they were stored at the
+	// given time.
+	AsOf   *time.Time
+	From   *time.Time
+	To     *time.Time
+	Order  string
+	Limit  uint
+	Cursor string
+}
+
+// Values returns the url.Values that represent the BalancesQuery.
+func (q BalancesQuery) Values() url.Values {
+	vs := url.Values{}
+	if q.AsOf != nil {
+		vs.Set(QueryAsOf, q.AsOf.Format(time.RFC3339Nano))
+	}
+	if q.From != nil {
+		vs.Set(QueryFrom, q.From.Format(time.RFC3339Nano))
+	}
+	if q.To != nil {
+		vs.Set(QueryTo, q.To.Format(time.RFC3339Nano))
+	}
+	if q.Order != "" {
+		vs.Set(QueryOrder, q.Order)
+	}
+	if q.Limit > 0 {
+		vs.Set(QueryLimit, strconv.FormatUint(uint64(q.Limit), 10))
+	}
+	if q.Cursor != "" {
+		vs.Set(QueryCursor, q.Cursor)
+	}
+	return vs
+}
+
+// extractBalancesQuery returns the BalancesQuery given by the query
+// parameters of the request. Any error returned is of storage.KindInvalid.
+func extractBalancesQuery(r *http.Request) (BalancesQuery, error) {
+	var q BalancesQuery
+	if r == nil || r.URL == nil {
+		return q, nil
+	}
+	vs := r.URL.Query()
+	var err error
+	if q.AsOf, err = extractTime(vs, QueryAsOf); err != nil {
+		return q, storage.Invalid(err)
+	}
+	if q.From, err = extractTime(vs, QueryFrom); err != nil {
+		return q, storage.Invalid(err)
+	}
+	if q.To, err = extractTime(vs, QueryTo); err != nil {
+		return q, storage.Invalid(err)
+	}
+	q.Order = vs.Get(QueryOrder)
+	if q.Limit, err = extractLimit(vs); err != nil {
+		return q, storage.Invalid(err)
+	}
+	q.Cursor = vs.Get(QueryCursor)
+	return q, nil
+}
+
+// storageQuery returns the storage.BalanceQuery that selects the Balances of
+// the BalancesQuery. Any error returned is of storage.KindInvalid.
+func (q BalancesQuery) storageQuery() (storage.BalanceQuery, error) {
+	sq := storage.BalanceQuery{
+		From:  q.From,
+		To:    q.To,
+		Order: q.Order,
+		Limit: q.Limit,
+	}
+	switch q.Order {
+	case "", storage.OrderAscending, storage.OrderDescending:
+	default:
+		return sq, storage.Invalidf("unsupported %s: %q", QueryOrder, q.Order)
+	}
+	offset, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return sq, storage.Invalid(err)
+	}
+	sq.Offset = offset
+	return sq, nil
+}
+
+// queryBalances returns the Balances of the given storage.Balances that match
+// the storage.BalanceQuery, for use with a Storage that is not a
+// storage.BalanceQuerier.
+func queryBalances(bs storage.Balances, q storage.BalanceQuery) storage.Balances {
+	matched := storage.Balances{}
+	for _, b := range bs {
+		if q.From != nil && b.Date.Before(*q.From) {
+			continue
+		}
+		if q.To != nil && b.Date.After(*q.To) {
+			continue
+		}
+		matched = append(matched, b)
+	}
+	before := func(a, b storage.Balance) bool {
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.ID < b.ID
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if q.Order == storage.OrderDescending {
+			return before(matched[j], matched[i])
+		}
+		return before(matched[i], matched[j])
+	})
+	if q.Offset >= uint(len(matched)) {
+		return storage.Balances{}
+	}
+	matched = matched[q.Offset:]
+	if q.Limit > 0 && q.Limit < uint(len(matched)) {
+		matched = matched[:q.Limit]
+	}
+	return matched
+}
+
+// withNextBalancesPage returns the given storage.BalanceQuery with its Limit,
+// if it has one, increased by one, in the same way as withNextPage.
+func withNextBalancesPage(q storage.BalanceQuery) storage.BalanceQuery {
+	if q.Limit > 0 {
+		q.Limit++
+	}
+	return q
+}
+
+// paginateBalances returns the body of the response for the given Balances,
+// selected using withNextBalancesPage(q), in the same way as paginate.
+func paginateBalances(bs *storage.Balances, q storage.BalanceQuery) interface{} {
+	if q.Limit == 0 || uint(len(*bs)) <= q.Limit {
+		return bs
+	}
+	limited := (*bs)[:q.Limit]
+	return page{next: encodeCursor(q.Offset + q.Limit), body: &limited}
+}
+
+// extractTime returns the RFC3339 formatted time of the given query
+// parameter, or nil if no time was given.
+func extractTime(vs url.Values, key string) (*time.Time, error) {
+	v := vs.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s time", key)
+	}
+	return &t, nil
+}
+
+// extractLimit returns the value of the QueryLimit parameter, or zero if no
+// limit was given.
+func extractLimit(vs url.Values) (uint, error) {
+	v := vs.Get(QueryLimit)
+	if v == "" {
+		return 0, nil
+	}
+	l, err := strconv.ParseUint(v, 10, 32)
+	return uint(l), errors.Wrapf(err, "parsing %s", QueryLimit)
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancesQuery_Values(t *testing.T) {
+	at := time.Date(2018, 2, 3, 4, 5, 6, 0, time.UTC)
+	to := at.Add(time.Hour)
+	q := BalancesQuery{
+		AsOf:   &at,
+		From:   &at,
+		To:     &to,
+		Order:  storage.OrderDescending,
+		Limit:  10,
+		Cursor: encodeCursor(20),
+	}
+	r := httptest.NewRequest(http.MethodGet, "/?"+q.Values().Encode(), nil)
+	extracted, err := extractBalancesQuery(r)
+	common.FatalIfError(t, err, "extracting balances query")
+	assert.Equal(t, q, extracted)
+
+	assert.Empty(t, BalancesQuery{}.Values())
+}
+
+func Test_extractBalancesQuery(t *testing.T) {
+	t.Run("nil request", func(t *testing.T) {
+		q, err := extractBalancesQuery(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, BalancesQuery{}, q)
+	})
+
+	for _, query := range []string{
+		"as-of=yesterday",
+		"from=yesterday",
+		"to=tomorrow",
+		"limit=-1",
+	} {
+		t.Run(query, func(t *testing.T) {
+			_, err := extractBalancesQuery(httptest.NewRequest(http.MethodGet, "/?"+query, nil))
+			assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+		})
+	}
+}
+
+func TestBalancesQuery_storageQuery(t *testing.T) {
+	q, err := BalancesQuery{Order: storage.OrderAscending, Limit: 3, Cursor: encodeCursor(6)}.storageQuery()
+	common.FatalIfError(t, err, "preparing query")
+	assert.Equal(t, storage.BalanceQuery{Order: storage.OrderAscending, Limit: 3, Offset: 6}, q)
+
+	_, err = BalancesQuery{Order: "sideways"}.storageQuery()
+	assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+
+	_, err = BalancesQuery{Cursor: "!"}.storageQuery()
+	assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+}
+
+func Test_queryBalances(t *testing.T) {
+	at := time.Date(2018, 2, 3, 4, 5, 6, 0, time.UTC)
+	bs := storage.Balances{
+		{ID: 4, Balance: balance.Balance{Date: at.Add(2 * time.Hour)}},
+		{ID: 3, Balance: balance.Balance{Date: at}},
+		{ID: 1, Balance: balance.Balance{Date: at.Add(time.Hour)}},
+		{ID: 2, Balance: balance.Balance{Date: at}},
+	}
+	ids := func(bs storage.Balances) []uint {
+		var ids []uint
+		for _, b := range bs {
+			ids = append(ids, b.ID)
+		}
+		return ids
+	}
+	later := at.Add(time.Hour)
+
+	for _, test := range []struct {
+		name string
+		storage.BalanceQuery
+		ids []uint
+	}{
+		{name: "zero query", ids: []uint{2, 3, 1, 4}},
+		{name: "descending", BalanceQuery: storage.BalanceQuery{Order: storage.OrderDescending}, ids: []uint{4, 1, 3, 2}},
+		{name: "from", BalanceQuery: storage.BalanceQuery{From: &later}, ids: []uint{1, 4}},
+		{name: "to", BalanceQuery: storage.BalanceQuery{To: &later}, ids: []uint{2, 3, 1}},
+		{name: "from and to", BalanceQuery: storage.BalanceQuery{From: &later, To: &later}, ids: []uint{1}},
+		{name: "limit", BalanceQuery: storage.BalanceQuery{Limit: 2}, ids: []uint{2, 3}},
+		{name: "offset", BalanceQuery: storage.BalanceQuery{Offset: 1, Limit: 2}, ids: []uint{3, 1}},
+		{name: "latest", BalanceQuery: storage.BalanceQuery{Order: storage.OrderDescending, Limit: 1}, ids: []uint{4}},
+		{name: "offset past end", BalanceQuery: storage.BalanceQuery{Offset: 4}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.ids, ids(queryBalances(bs, test.BalanceQuery)))
+		})
+	}
+}
+
+func Test_paginateBalances(t *testing.T) {
+	bs := &storage.Balances{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	assert.Equal(t, bs, paginateBalances(bs, storage.BalanceQuery{}))
+	assert.Equal(t, bs, paginateBalances(bs, storage.BalanceQuery{Limit: 3}))
+	assert.Equal(t, page{
+		next: encodeCursor(2),
+		body: &storage.Balances{{ID: 1}, {ID: 2}},
+	}, paginateBalances(bs, storage.BalanceQuery{Limit: 2}))
+}
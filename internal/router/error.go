@@ -0,0 +1,146 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// ErrorCode is a machine-readable identifier for the kind of error that
+// caused a request to fail.
+type ErrorCode string
+
+// The ErrorCodes that can be returned by the router.
+const (
+	CodeBadRequest     ErrorCode = "bad_request"
+	CodeUnauthorized   ErrorCode = "unauthorized"
+	CodeForbidden      ErrorCode = "forbidden"
+	CodeInvalid        ErrorCode = "invalid"
+	CodeNotFound       ErrorCode = "not_found"
+	CodeConflict       ErrorCode = "conflict"
+	CodeStale          ErrorCode = "stale"
+	CodeNotImplemented ErrorCode = "not_implemented"
+	CodeUnavailable    ErrorCode = "unavailable"
+	CodeInternal       ErrorCode = "internal"
+
+	// CodeInProgress is the code of an Error of storage.KindConflict that is
+	// returned to a repeat of a request that is still being served, which
+	// can be made again after a short wait.
+	CodeInProgress ErrorCode = "in_progress"
+)
+
+// Error is the JSON body that is returned when a request could not be served.
+// Status is the HTTP status code of the response. Permission is the Permission
+// that the request required if the request was refused because the API token
+// that it was authenticated with is not granted the Permission.
+type Error struct {
+	Code       ErrorCode  `json:"code"`
+	Status     int        `json:"status"`
+	Message    string     `json:"message"`
+	Permission Permission `json:"permission,omitempty"`
+}
+
+// Error returns a human readable description of the Error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d %s): %s", e.Code, e.Status, http.StatusText(e.Status), e.Message)
+}
+
+// Kind returns the storage.ErrorKind that corresponds to the Code of the
+// Error, so that an Error can be inspected in the same way as an error
+// returned by a storage.Storage.
+func (e *Error) Kind() storage.ErrorKind {
+	if e.Code == CodeInProgress {
+		return storage.KindConflict
+	}
+	for k, c := range kindCodes {
+		if c == e.Code {
+			return k
+		}
+	}
+	return ""
+}
+
+// kindCodes holds the ErrorCode for each storage.ErrorKind.
+var kindCodes = map[storage.ErrorKind]ErrorCode{
+	storage.KindNotFound:    CodeNotFound,
+	storage.KindConflict:    CodeConflict,
+	storage.KindStale:       CodeStale,
+	storage.KindInvalid:     CodeInvalid,
+	storage.KindForbidden:   CodeForbidden,
+	storage.KindUnavailable: CodeUnavailable,
+}
+
+// kindStatuses holds the HTTP status code for each storage.ErrorKind.
+var kindStatuses = map[storage.ErrorKind]int{
+	storage.KindNotFound:    http.StatusNotFound,
+	storage.KindConflict:    http.StatusConflict,
+	storage.KindStale:       http.StatusPreconditionFailed,
+	storage.KindInvalid:     http.StatusBadRequest,
+	storage.KindForbidden:   http.StatusForbidden,
+	storage.KindUnavailable: http.StatusServiceUnavailable,
+}
+
+// errorStatus returns the HTTP status code for the storage.ErrorKind of err,
+// or the given fallback if err has no storage.ErrorKind.
+func errorStatus(err error, fallback int) int {
+	if status, ok := kindStatuses[storage.KindOf(err)]; ok {
+		return status
+	}
+	return fallback
+}
+
+// newError returns the Error that should be returned to a client after a
+// handler has returned the given status and error. If the cause of err is
+// already an *Error, it is returned as is. If err has a storage.ErrorKind,
+// the status and code of the Error are those of the kind. The details of
+// internal errors and unavailable backends are not returned to the client, as
+// they may describe the internals of the server.
+func newError(status int, err error) *Error {
+	if e, ok := errors.Cause(err).(*Error); ok {
+		return e
+	}
+	status = errorStatus(err, status)
+	if status < http.StatusBadRequest {
+		status = http.StatusInternalServerError
+	}
+	code, ok := kindCodes[storage.KindOf(err)]
+	if !ok {
+		code = codeForStatus(status)
+	}
+	e := &Error{
+		Code:    code,
+		Status:  status,
+		Message: err.Error(),
+	}
+	if e.Code == CodeInternal || e.Code == CodeUnavailable {
+		e.Message = http.StatusText(status)
+	}
+	return e
+}
+
+// codeForStatus returns the ErrorCode that best describes the given HTTP
+// status code.
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusPreconditionFailed:
+		return CodeStale
+	case http.StatusNotImplemented:
+		return CodeNotImplemented
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
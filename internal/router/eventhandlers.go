@@ -0,0 +1,70 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// EventsQuery holds the query parameters that can be given to the
+// EndpointEvents endpoint. The zero EventsQuery selects every Event, in the
+// order in which they were recorded.
+type EventsQuery struct {
+	After uint64
+	Limit uint
+}
+
+// Values returns the url.Values that represent the EventsQuery.
+func (q EventsQuery) Values() url.Values {
+	vs := url.Values{}
+	if q.After > 0 {
+		vs.Set(QueryAfter, strconv.FormatUint(q.After, 10))
+	}
+	if q.Limit > 0 {
+		vs.Set(QueryLimit, strconv.FormatUint(uint64(q.Limit), 10))
+	}
+	return vs
+}
+
+// extractEventsQuery returns the EventsQuery given by the query parameters of
+// the request. Any error returned is of storage.KindInvalid.
+func extractEventsQuery(r *http.Request) (EventsQuery, error) {
+	var q EventsQuery
+	if r == nil || r.URL == nil {
+		return q, nil
+	}
+	vs := r.URL.Query()
+	if v := vs.Get(QueryAfter); v != "" {
+		after, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return q, storage.Invalid(errors.Wrapf(err, "parsing %s", QueryAfter))
+		}
+		q.After = after
+	}
+	var err error
+	if q.Limit, err = extractLimit(vs); err != nil {
+		return q, storage.Invalid(err)
+	}
+	return q, nil
+}
+
+// handlerSelectEvents responds with the Events of the storage that match the
+// EventsQuery given by the query parameters of the request.
+func (env *environment) handlerSelectEvents(r *http.Request) (int, interface{}, error) {
+	el, ok := storage.AsEventLog(env.storage)
+	if !ok {
+		return http.StatusNotImplemented, nil, errors.New("storage does not keep an event log")
+	}
+	q, err := extractEventsQuery(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "extracting events query")
+	}
+	es, err := el.SelectEvents(q.After, q.Limit)
+	if err != nil {
+		return errorStatus(err, http.StatusServiceUnavailable), nil, errors.Wrap(err, "selecting events")
+	}
+	return http.StatusOK, es, nil
+}
@@ -0,0 +1,108 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/internal/auth"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/eventsourced"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_events(t *testing.T) {
+	store, err := eventsourced.New(eventsourced.NewMemoryLog())
+	common.FatalIfError(t, err, "creating storage")
+	secrets := make(map[string]string)
+	for _, user := range []string{"alice", "bob"} {
+		secret, err := auth.NewToken()
+		common.FatalIfError(t, err, "creating token")
+		_, err = store.InsertToken(user, user, storage.RoleAdmin, auth.HashToken(secret))
+		common.FatalIfError(t, err, "inserting token")
+		secrets[user] = secret
+	}
+
+	r, err := New(store, store, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	serve := func(user, method, endpoint string, body interface{}) *httptest.ResponseRecorder {
+		var bs []byte
+		if body != nil {
+			var err error
+			bs, err = json.Marshal(body)
+			common.FatalIfError(t, err, "marshalling body")
+		}
+		req := httptest.NewRequest(method, endpoint, bytes.NewReader(bs))
+		req.Header.Set(HeaderAuthorization, AuthSchemeBearer+" "+secrets[user])
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	rec := serve("alice", http.MethodPost, EndpointV2Accounts, a)
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	var inserted struct{ ID uint }
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &inserted), "unmarshalling account")
+	rec = serve("alice", http.MethodPost, fmt.Sprintf(EndpointFmtV2AccountBalances, inserted.ID), BalanceInsertBody{
+		Balance: balance.Balance{Date: a.Opened(), Amount: 1},
+	})
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	rec = serve("bob", http.MethodPost, EndpointV2Accounts, a)
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+
+	rec = serve("alice", http.MethodGet, EndpointEvents, nil)
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	var es storage.Events
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &es), "unmarshalling events")
+	if !assert.Len(t, es, 2) {
+		t.FailNow()
+	}
+	assert.Equal(t, storage.AccountInserted, es[0].Type)
+	assert.Equal(t, "alice", es[0].Actor)
+	assert.Equal(t, storage.BalanceInserted, es[1].Type)
+	assert.Equal(t, "alice", es[1].Actor)
+	assert.NotContains(t, rec.Body.String(), "Token")
+
+	rec = serve("alice", http.MethodGet, EndpointEvents+"?"+EventsQuery{After: es[0].Sequence}.Values().Encode(), nil)
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &es), "unmarshalling events")
+	if assert.Len(t, es, 1) {
+		assert.Equal(t, storage.BalanceInserted, es[0].Type)
+	}
+
+	rec = serve("bob", http.MethodGet, EndpointEvents, nil)
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &es), "unmarshalling events")
+	if assert.Len(t, es, 1) {
+		assert.Equal(t, storage.AccountInserted, es[0].Type)
+		assert.Equal(t, "bob", es[0].Actor)
+	}
+
+	rec = serve("alice", http.MethodGet, EndpointEvents+"?"+QueryAfter+"=first", nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandlerSelectEvents_noEventLog(t *testing.T) {
+	env := &environment{storage: memory.New()}
+	code, _, err := env.handlerSelectEvents(httptest.NewRequest(http.MethodGet, EndpointEvents, nil))
+	assert.Equal(t, http.StatusNotImplemented, code)
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// extractAsOf returns the time given by the QueryAsOf parameter of the
+// request, or nil if no time was given.
+func extractAsOf(r *http.Request) (*time.Time, error) {
+	if r == nil || r.URL == nil {
+		return nil, nil
+	}
+	return extractTime(r.URL.Query(), QueryAsOf)
+}
+
+func (env *environment) history() (storage.History, error) {
+	h, ok := storage.AsHistory(env.storage)
+	if !ok {
+		return nil, errors.New("storage does not retain history")
+	}
+	return h, nil
+}
+
+// selectAccountsAsOf selects the Accounts that match the given
+// storage.AccountQuery as they were stored at the given time.
+func (env *environment) selectAccountsAsOf(t time.Time, q storage.AccountQuery) (int, interface{}, error) {
+	h, err := env.history()
+	if err != nil {
+		return http.StatusNotImplemented, nil, err
+	}
+	as, err := h.SelectAccountsAsOf(t)
+	if err != nil {
+		return http.StatusServiceUnavailable, nil, errors.Wrapf(err, "selecting Accounts as of %s", t)
+	}
+	matched := queryAccounts(*as, withNextPage(q))
+	return http.StatusOK, paginate(&matched, q), nil
+}
+
+// balancesAsOf selects the Balances of the account with the given id that
+// match the given storage.BalanceQuery, as they were stored at the given time.
+func (env *environment) balancesAsOf(accountID uint, t time.Time, q storage.BalanceQuery) (int, interface{}, error) {
+	h, err := env.history()
+	if err != nil {
+		return http.StatusNotImplemented, nil, err
+	}
+	bs, err := h.SelectAccountBalancesAsOf(accountID, t)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "selecting balances for account %d as of %s", accountID, t)
+	}
+	matched := queryBalances(*bs, withNextBalancesPage(q))
+	return http.StatusOK, paginateBalances(&matched, q), nil
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/glynternet/mon/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_extractAsOf(t *testing.T) {
+	t.Run("nil request", func(t *testing.T) {
+		asOf, err := extractAsOf(nil)
+		assert.NoError(t, err)
+		assert.Nil(t, asOf)
+	})
+
+	t.Run("no as-of", func(t *testing.T) {
+		asOf, err := extractAsOf(httptest.NewRequest(http.MethodGet, EndpointAccounts, nil))
+		assert.NoError(t, err)
+		assert.Nil(t, asOf)
+	})
+
+	t.Run("invalid as-of", func(t *testing.T) {
+		asOf, err := extractAsOf(httptest.NewRequest(http.MethodGet, EndpointAccounts+"?as-of=yesterday", nil))
+		assert.Error(t, err)
+		assert.Nil(t, asOf)
+	})
+
+	t.Run("valid as-of", func(t *testing.T) {
+		asOf, err := extractAsOf(httptest.NewRequest(http.MethodGet, EndpointAccounts+"?as-of=2018-02-03T04:05:06Z", nil))
+		assert.NoError(t, err)
+		if assert.NotNil(t, asOf) {
+			assert.True(t, time.Date(2018, 2, 3, 4, 5, 6, 0, time.UTC).Equal(*asOf))
+		}
+	})
+}
+
+func Test_selectAccountsAsOf(t *testing.T) {
+	t.Run("storage without history", func(t *testing.T) {
+		srv := environment{storage: &storagetest.Storage{}}
+		code, as, err := srv.selectAccountsAsOf(time.Now(), storage.AccountQuery{})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusNotImplemented, code)
+		assert.Nil(t, as)
+	})
+
+	t.Run("storage with history", func(t *testing.T) {
+		srv := environment{storage: memory.New()}
+		code, as, err := srv.selectAccountsAsOf(time.Now(), storage.AccountQuery{})
+		common.FatalIfError(t, err, "selecting accounts as of now")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, &storage.Accounts{}, as)
+	})
+}
+
+func Test_balancesAsOf(t *testing.T) {
+	t.Run("storage without history", func(t *testing.T) {
+		srv := environment{storage: &storagetest.Storage{}}
+		code, bs, err := srv.balancesAsOf(1, time.Now(), storage.BalanceQuery{})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusNotImplemented, code)
+		assert.Nil(t, bs)
+	})
+
+	t.Run("storage with history", func(t *testing.T) {
+		srv := environment{storage: memory.New()}
+		code, bs, err := srv.balancesAsOf(1, time.Now(), storage.BalanceQuery{})
+		common.FatalIfError(t, err, "selecting balances as of now")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, &storage.Balances{}, bs)
+	})
+}
@@ -0,0 +1,140 @@
+package router
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	// idempotencyWindow is how long the response to a request that was made
+	// with a HeaderIdempotencyKey is given again to repeats of the request.
+	idempotencyWindow = 24 * time.Hour
+
+	// idempotencyReservationTimeout is how long a request that was made with
+	// a HeaderIdempotencyKey can be served for before it is taken to have
+	// been abandoned, after which a repeat of the request is served in its
+	// place. The response to a request that is taken over is not stored, so
+	// that it does not replace the response to the repeat.
+	idempotencyReservationTimeout = 30 * time.Second
+
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotentRoutes returns the given routes with the appHandler of each POST
+// route wrapped by idempotentHandler.
+func idempotentRoutes(store storage.IdempotencyStore, rs []route) []route {
+	for i, r := range rs {
+		if r.method == http.MethodPost {
+			rs[i].appHandler = idempotentHandler(store, r.appHandler)
+		}
+	}
+	return rs
+}
+
+// idempotentHandler returns an appJSONHandler that serves a request that was
+// made with a HeaderIdempotencyKey with the given appJSONHandler only once,
+// storing its response in the IdempotencyStore and giving the stored response
+// to any repeat of the request. A request that fails is not stored, so that
+// it can be made again with the same key.
+func idempotentHandler(store storage.IdempotencyStore, h appJSONHandler) appJSONHandler {
+	return func(r *http.Request) (int, interface{}, error) {
+		key := r.Header.Get(HeaderIdempotencyKey)
+		if key == "" {
+			return h(r)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return http.StatusBadRequest, nil, storage.Invalidf("%s header is longer than %d characters", HeaderIdempotencyKey, maxIdempotencyKeyLength)
+		}
+		fp, err := fingerprint(r)
+		if err != nil {
+			return http.StatusBadRequest, nil, errors.Wrap(err, "reading request body")
+		}
+		reservation, err := newReservation()
+		if err != nil {
+			return http.StatusInternalServerError, nil, errors.Wrap(err, "creating reservation")
+		}
+		ir := storage.IdempotentRequest{User: requestUser(r), Key: key, Fingerprint: fp, Reservation: reservation}
+		now := time.Now()
+		existing, reserved, err := store.ReserveIdempotentRequest(ir, now.Add(-idempotencyWindow), now.Add(-idempotencyReservationTimeout))
+		if err != nil {
+			return http.StatusServiceUnavailable, nil, errors.Wrapf(err, "reserving idempotency key %q", key)
+		}
+		if !reserved {
+			return replay(*existing, fp)
+		}
+
+		status, bod, err := h(r)
+		var res response
+		if err == nil && status != http.StatusNoContent {
+			if res, err = render(bod); err != nil {
+				status = http.StatusInternalServerError
+			}
+		}
+		if err != nil {
+			if rErr := store.ReleaseIdempotentRequest(ir.User, key, reservation); rErr != nil {
+				log.Print(errors.Wrapf(rErr, "releasing idempotency key %q", key))
+			}
+			return status, nil, err
+		}
+
+		ir.Status, ir.Location, ir.ETag, ir.Body = status, res.location, res.etag, res.body
+		if cErr := store.CompleteIdempotentRequest(ir); cErr != nil {
+			log.Print(errors.Wrapf(cErr, "storing response for idempotency key %q", key))
+		}
+		return status, res, nil
+	}
+}
+
+// replay returns the stored response of the given IdempotentRequest, for a
+// repeat of the request that has the given fingerprint.
+func replay(ir storage.IdempotentRequest, fingerprint string) (int, interface{}, error) {
+	if ir.Fingerprint != fingerprint {
+		return http.StatusBadRequest, nil, storage.Invalidf("idempotency key %q has already been used for a different request", ir.Key)
+	}
+	if ir.Status == 0 {
+		return http.StatusConflict, nil, &Error{
+			Code:    CodeInProgress,
+			Status:  http.StatusConflict,
+			Message: fmt.Sprintf("request with idempotency key %q is still being served", ir.Key),
+		}
+	}
+	return ir.Status, response{
+		location: ir.Location,
+		etag:     ir.ETag,
+		body:     ir.Body,
+		replayed: true,
+	}, nil
+}
+
+// newReservation returns a random value that identifies a single reservation
+// of an idempotency key.
+func newReservation() (string, error) {
+	bs := make([]byte, 16)
+	if _, err := rand.Read(bs); err != nil {
+		return "", errors.Wrap(err, "reading random bytes")
+	}
+	return hex.EncodeToString(bs), nil
+}
+
+// fingerprint returns a digest of the method, path and body of the request,
+// replacing the body so that it can be read again by the handler.
+func fingerprint(r *http.Request) (string, error) {
+	bod, err := requestPayload(r)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(r.Method), []byte(r.URL.RequestURI()), bod} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
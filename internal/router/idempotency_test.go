@@ -0,0 +1,125 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_idempotency(t *testing.T) {
+	store := memory.New()
+	r, err := New(store, nil, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	serve := func(endpoint, key string, body interface{}) *httptest.ResponseRecorder {
+		bs, err := json.Marshal(body)
+		common.FatalIfError(t, err, "marshalling body")
+		req := httptest.NewRequest(http.MethodPost, endpoint, bytes.NewReader(bs))
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	countAccounts := func(t *testing.T) int {
+		as, err := store.SelectAccounts()
+		common.FatalIfError(t, err, "selecting accounts")
+		return len(*as)
+	}
+
+	a := accountingtest.NewAccount(t, "idempotent", accountingtest.NewCurrencyCode(t, "GBP"), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	t.Run("repeated request", func(t *testing.T) {
+		first := serve(EndpointV2Accounts, "account", a)
+		if !assert.Equal(t, http.StatusCreated, first.Code, first.Body.String()) {
+			t.FailNow()
+		}
+		assert.Empty(t, first.Header().Get(HeaderIdempotentReplayed))
+		count := countAccounts(t)
+
+		repeat := serve(EndpointV2Accounts, "account", a)
+		assert.Equal(t, http.StatusCreated, repeat.Code, repeat.Body.String())
+		assert.Equal(t, "true", repeat.Header().Get(HeaderIdempotentReplayed))
+		assert.Equal(t, first.Header().Get("Location"), repeat.Header().Get("Location"))
+		assert.Equal(t, first.Header().Get(HeaderETag), repeat.Header().Get(HeaderETag))
+		assert.JSONEq(t, first.Body.String(), repeat.Body.String())
+		assert.Equal(t, count, countAccounts(t), "repeated request should not insert another account")
+
+		var inserted storage.Account
+		common.FatalIfError(t, json.Unmarshal(first.Body.Bytes(), &inserted), "unmarshalling account")
+		endpoint := fmt.Sprintf(EndpointFmtAccountBalanceInsert, inserted.ID)
+		b := BalanceInsertBody{Balance: balance.Balance{Date: a.Opened(), Amount: 10}}
+		rec := serve(endpoint, "balance", b)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		rec = serve(endpoint, "balance", b)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, "true", rec.Header().Get(HeaderIdempotentReplayed))
+		bs, err := store.SelectAccountBalances(inserted.ID)
+		common.FatalIfError(t, err, "selecting balances")
+		assert.Len(t, *bs, 1)
+	})
+
+	t.Run("key reused for different request", func(t *testing.T) {
+		other := accountingtest.NewAccount(t, "other", accountingtest.NewCurrencyCode(t, "GBP"), a.Opened())
+		rec := serve(EndpointV2Accounts, "account", other)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		var e Error
+		common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &e), "unmarshalling error")
+		assert.Equal(t, CodeInvalid, e.Code)
+	})
+
+	t.Run("request without key", func(t *testing.T) {
+		count := countAccounts(t)
+		for i := 0; i < 2; i++ {
+			rec := serve(EndpointV2Accounts, "", a)
+			assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		assert.Equal(t, count+2, countAccounts(t))
+	})
+
+	t.Run("failed request", func(t *testing.T) {
+		rec := serve(EndpointV2Accounts, "failed", "not an account")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		rec = serve(EndpointV2Accounts, "failed", "not an account")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+		assert.Empty(t, rec.Header().Get(HeaderIdempotentReplayed), "failed request should be served again")
+	})
+
+	t.Run("key too long", func(t *testing.T) {
+		rec := serve(EndpointV2Accounts, strings.Repeat("k", maxIdempotencyKeyLength+1), a)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	})
+}
+
+func TestReplay(t *testing.T) {
+	ir := storage.IdempotentRequest{Key: "key", Fingerprint: "fingerprint"}
+
+	_, _, err := replay(ir, "other")
+	assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+
+	status, _, err := replay(ir, ir.Fingerprint)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, storage.KindConflict, storage.KindOf(err), "request without response")
+	assert.Equal(t, CodeInProgress, newError(status, err).Code)
+
+	ir.Status, ir.Location, ir.ETag, ir.Body = http.StatusCreated, "/account/1", `"1"`, json.RawMessage(`{"ID":1}`)
+	status, bod, err := replay(ir, ir.Fingerprint)
+	common.FatalIfError(t, err, "replaying request")
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, response{location: ir.Location, etag: ir.ETag, body: ir.Body, replayed: true}, bod)
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// Permission is what a request must be permitted to do for it to be served.
+// Each route declares the Permission that it requires and each storage.Role
+// is granted a set of Permissions.
+type Permission string
+
+// The Permissions that a route can require.
+const (
+	// PermissionRead permits reading Accounts and Balances.
+	PermissionRead Permission = "read"
+	// PermissionWrite permits inserting, changing and restoring Accounts and
+	// Balances.
+	PermissionWrite Permission = "write"
+	// PermissionDelete permits deleting Accounts and Balances.
+	PermissionDelete Permission = "delete"
+	// PermissionShare permits sharing Accounts with other users.
+	PermissionShare Permission = "share"
+	// PermissionAudit permits reading the record of the requests and the
+	// Events that changed Accounts and Balances.
+	PermissionAudit Permission = "audit"
+)
+
+// rolePermissions holds the Permissions that are granted to each
+// storage.Role.
+var rolePermissions = map[storage.Role][]Permission{
+	storage.RoleReadOnly: {PermissionRead},
+	storage.RoleEditor:   {PermissionRead, PermissionWrite, PermissionDelete},
+	storage.RoleAdmin:    {PermissionRead, PermissionWrite, PermissionDelete, PermissionShare, PermissionAudit},
+}
+
+// granted returns true if the given storage.Role is granted the Permission.
+func (p Permission) granted(role storage.Role) bool {
+	for _, rp := range rolePermissions[role] {
+		if rp == p {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize returns a http.Handler that serves a request with the inner
+// handler only if the Role of the Token that the request was authenticated
+// with is granted the given Permission. Any other request is responded to
+// with an Error of CodeForbidden that holds the Permission. authorize must
+// only be used to wrap handlers that are themselves wrapped by authenticate.
+func authorize(p Permission, inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := requestRole(r)
+		if !p.granted(role) {
+			writeError(w, &Error{
+				Code:       CodeForbidden,
+				Status:     http.StatusForbidden,
+				Message:    fmt.Sprintf("API token with role %q is not granted the %s permission", role, p),
+				Permission: p,
+			})
+			return
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
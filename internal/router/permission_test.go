@@ -0,0 +1,89 @@
+package router
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/internal/auth"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_authorization(t *testing.T) {
+	store := memory.New()
+	secrets := make(map[storage.Role]string)
+	for _, role := range storage.Roles {
+		secret, err := auth.NewToken()
+		common.FatalIfError(t, err, "creating token")
+		_, err = store.InsertToken(string(role), storage.DefaultUser, role, auth.HashToken(secret))
+		common.FatalIfError(t, err, "inserting token")
+		secrets[role] = secret
+	}
+
+	r, err := New(store, store, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	for _, test := range []struct {
+		method, endpoint string
+		permission       Permission
+	}{
+		{method: http.MethodGet, endpoint: EndpointAccounts, permission: PermissionRead},
+		{method: http.MethodGet, endpoint: "/v2/accounts/1/shares", permission: PermissionRead},
+		{method: http.MethodPost, endpoint: EndpointAccountInsert, permission: PermissionWrite},
+		{method: http.MethodPatch, endpoint: "/v2/accounts/1", permission: PermissionWrite},
+		{method: http.MethodPost, endpoint: "/account/1/undelete", permission: PermissionWrite},
+		{method: http.MethodDelete, endpoint: "/account/1", permission: PermissionDelete},
+		{method: http.MethodDelete, endpoint: "/v2/balances/1", permission: PermissionDelete},
+		{method: http.MethodPost, endpoint: "/v2/accounts/1/shares", permission: PermissionShare},
+		{method: http.MethodDelete, endpoint: "/v2/accounts/1/shares/other", permission: PermissionShare},
+		{method: http.MethodGet, endpoint: EndpointAudit, permission: PermissionAudit},
+	} {
+		for _, role := range storage.Roles {
+			t.Run(strings.Join([]string{test.method, test.endpoint, string(role)}, " "), func(t *testing.T) {
+				req := httptest.NewRequest(test.method, test.endpoint, strings.NewReader("{}"))
+				req.Header.Set(HeaderAuthorization, AuthSchemeBearer+" "+secrets[role])
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+
+				if test.permission.granted(role) {
+					assert.NotEqual(t, http.StatusForbidden, rec.Code, rec.Body.String())
+					return
+				}
+				assert.Equal(t, http.StatusForbidden, rec.Code)
+				var e Error
+				common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &e), "unmarshalling error")
+				assert.Equal(t, CodeForbidden, e.Code)
+				assert.Equal(t, test.permission, e.Permission)
+			})
+		}
+	}
+}
+
+func TestPermission_granted(t *testing.T) {
+	assert.True(t, PermissionRead.granted(storage.RoleReadOnly))
+	assert.False(t, PermissionWrite.granted(storage.RoleReadOnly))
+	assert.False(t, PermissionDelete.granted(storage.RoleReadOnly))
+	assert.True(t, PermissionDelete.granted(storage.RoleEditor))
+	assert.False(t, PermissionShare.granted(storage.RoleEditor))
+	assert.True(t, PermissionShare.granted(storage.RoleAdmin))
+	assert.False(t, PermissionAudit.granted(storage.RoleEditor))
+	assert.True(t, PermissionAudit.granted(storage.RoleAdmin))
+	assert.False(t, PermissionRead.granted(""), "unknown role")
+}
+
+func Test_newRouter_undeclaredPermission(t *testing.T) {
+	_, err := newRouter([]route{{
+		name:       "NoPermission",
+		method:     http.MethodGet,
+		pattern:    "/",
+		appHandler: func(*http.Request) (int, interface{}, error) { return http.StatusOK, nil, nil },
+	}}, nil, log.New(ioutil.Discard, "", 0))
+	assert.Error(t, err)
+}
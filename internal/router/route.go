@@ -1,8 +1,19 @@
 package router
 
+import "net/http"
+
 type route struct {
 	name       string
 	method     string
 	pattern    string
+	permission Permission
+	// handler serves requests to the route with the environment of each
+	// request. It is bound to the environment by userRoutes, to give the
+	// appHandler that the route is served with.
+	handler    envHandler
 	appHandler appJSONHandler
 }
+
+// envHandler serves a request with the given environment, in the same way as
+// an appJSONHandler.
+type envHandler func(env *environment, r *http.Request) (int, interface{}, error)
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 
@@ -10,10 +11,121 @@ import (
 )
 
 const (
+	// QueryAsOf is the query parameter that can be given, as an RFC3339
+	// formatted time, to the Accounts and account Balances endpoints to
+	// retrieve data as it was stored at that time.
+	QueryAsOf = "as-of"
+
+	// QueryDeletionPolicy is the query parameter that can be given to the
+	// Account delete endpoint to choose the storage.DeletionPolicy used to
+	// treat the live Balances of the Account. The policy defaults to
+	// storage.DeletionRefuse.
+	QueryDeletionPolicy = "policy"
+
+	// The query parameters that can be given to the Accounts endpoints to
+	// select a subset of the Accounts. They mirror the flags of the accounts
+	// command of moncli. ids, exclude-ids and currencies take comma
+	// separated lists and at-date takes an RFC3339 formatted time.
+	QueryOpen       = "open"
+	QueryAtDate     = "at-date"
+	QueryIDs        = "ids"
+	QueryExcludeIDs = "exclude-ids"
+	QueryCurrencies = "currencies"
+	QuerySortBy     = "sort-by"
+
+	// The query parameters that can be given to the account Balances
+	// endpoints to select a subset of the Balances. from and to take RFC3339
+	// formatted times and order takes either storage.OrderAscending or
+	// storage.OrderDescending.
+	QueryFrom  = "from"
+	QueryTo    = "to"
+	QueryOrder = "order"
+
+	// The query parameters that can be given to the EndpointAudit endpoint,
+	// along with QueryFrom, QueryTo and QueryOrder, to select a subset of the
+	// recorded requests. route takes the name of a route and account-id and
+	// balance-id take the ID of the targeted Account or Balance.
+	QueryRoute     = "route"
+	QueryAccountID = "account-id"
+	QueryBalanceID = "balance-id"
+
+	// QueryAfter is the query parameter that can be given, along with
+	// QueryLimit, to the EndpointEvents endpoint to select only the Events
+	// with a greater Sequence.
+	QueryAfter = "after"
+
+	// QueryLimit is the query parameter that can be given to the Accounts and
+	// account Balances endpoints to limit the number of items that are
+	// returned. When more items are available, the response has a
+	// HeaderNextCursor header.
+	QueryLimit = "limit"
+
+	// QueryCursor is the query parameter that can be given, as the value of
+	// a HeaderNextCursor header, to retrieve the next page of items.
+	QueryCursor = "cursor"
+
+	// HeaderNextCursor is the response header that holds the cursor of the
+	// next page of a paginated response.
+	HeaderNextCursor = "Next-Cursor"
+
+	// HeaderAuthorization is the request header that must hold the secret of
+	// an API token, in the form "Bearer <token>", when the router
+	// authenticates requests.
+	HeaderAuthorization = "Authorization"
+
+	// AuthSchemeBearer is the scheme of the HeaderAuthorization header.
+	AuthSchemeBearer = "Bearer"
+
+	// HeaderETag is the response header that holds the version of the
+	// Account or Balance in the body of the response, as an entity tag.
+	HeaderETag = "ETag"
+
+	// HeaderIfMatch is the request header that can hold the entity tag of a
+	// HeaderETag header, to make a request that updates or deletes an Account
+	// or Balance conditional on it not having been changed since. A request
+	// whose condition is not met fails with an Error of CodeStale.
+	HeaderIfMatch = "If-Match"
+
+	// HeaderIdempotencyKey is the request header that can hold a unique key
+	// for a POST request, so that the request can be retried safely. The
+	// response to a request with a key is stored and given again to any
+	// repeat of the request that is made with the same key by the same user
+	// within a day, without the request being served again. A key that is
+	// reused for a different request fails with an Error of CodeInvalid and
+	// a repeat made while the request is still being served fails with an
+	// Error of CodeInProgress. A request that is still being served after 30
+	// seconds is taken to have been abandoned, so a repeat is served in its
+	// place.
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	// HeaderIdempotentReplayed is the response header that is set to true
+	// when the response is the stored response to an earlier request that
+	// was made with the same HeaderIdempotencyKey.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// EndpointAudit is the endpoint for the record of the requests that
+	// changed Accounts and Balances, which holds only the requests made by
+	// the user that requests it.
+	EndpointAudit = "/audit"
+
+	// EndpointEvents is the endpoint for the Events that record every change
+	// made to the Accounts and Balances of a storage that keeps an event log.
+	EndpointEvents = "/events"
+
 	// EndpointAccounts is the endpoint for Accounts
 	EndpointAccounts = "/accounts"
 	patternAccounts  = EndpointAccounts
 
+	// EndpointAccountsDeleted is the endpoint for Accounts that have been
+	// deleted
+	EndpointAccountsDeleted = EndpointAccounts + "/deleted"
+
+	// EndpointAccountsSummary is the endpoint for the balance of each Account
+	// at the time given by the QueryAtDate parameter, which defaults to the
+	// current time. It accepts the same query parameters as EndpointAccounts,
+	// except for QueryLimit and QueryCursor.
+	EndpointAccountsSummary = EndpointAccounts + "/summary"
+
 	// EndpointAccount is the base endpoint for single account requests
 	EndpointAccount = "/account"
 
@@ -33,6 +145,20 @@ const (
 	EndpointFmtAccountUpdate = EndpointFmtAccount + "/update"
 	patternAccountUpdate     = patternAccount + "/update"
 
+	// EndpointFmtAccountUndelete is the format string for generating the
+	// endpoint to use when restoring a specific deleted Account
+	EndpointFmtAccountUndelete = EndpointFmtAccount + "/undelete"
+	patternAccountUndelete     = patternAccount + "/undelete"
+
+	// EndpointAccountOpen is the endpoint for opening an Account with an
+	// opening Balance
+	EndpointAccountOpen = EndpointAccount + "/open"
+
+	// EndpointFmtAccountClose is the format string for generating the
+	// endpoint to use when closing a specific Account with a closing Balance
+	EndpointFmtAccountClose = EndpointFmtAccount + "/close"
+	patternAccountClose     = patternAccount + "/close"
+
 	// EndpointBalance is the base endpoint for single balance requests
 	EndpointBalance = "/balance"
 
@@ -41,6 +167,15 @@ const (
 	EndpointFmtBalance = EndpointBalance + "/%d"
 	patternBalance     = EndpointBalance + "/{id}"
 
+	// EndpointFmtBalanceUndelete is the format string for generating the
+	// endpoint to use when restoring a specific deleted Balance
+	EndpointFmtBalanceUndelete = EndpointFmtBalance + "/undelete"
+	patternBalanceUndelete     = patternBalance + "/undelete"
+
+	// EndpointBalancesDeleted is the endpoint for Balances that have been
+	// deleted
+	EndpointBalancesDeleted = "/balances/deleted"
+
 	// EndpointFmtAccountBalances is the format string for use when generating
 	// the endpoint to get the balances for a specific Account
 	EndpointFmtAccountBalances = EndpointAccount + "/%d/balances"
@@ -50,22 +185,57 @@ const (
 	// the endpoint insert a Balance for a specific Account
 	EndpointFmtAccountBalanceInsert = EndpointAccount + "/%d/balance/insert"
 	patternAccountBalanceInsert     = EndpointAccount + "/{id}/balance/insert"
+
+	// EndpointFmtAccountBalanceUpdate is the format string for use when
+	// generating the endpoint to update a specific Balance of a specific
+	// Account
+	EndpointFmtAccountBalanceUpdate = EndpointAccount + "/%d/balance/%d/update"
+	patternAccountBalanceUpdate     = EndpointAccount + "/{id}/balance/{balanceID}/update"
 )
 
-// New creates a new mux.Router and initialises it with generateRoutes for the store
-func New(store storage.Storage, log *log.Logger) (*mux.Router, error) {
+// New creates a new mux.Router and initialises it with generateRoutes for the
+// store. Every request is authenticated against the given tokens, unless
+// tokens is nil, in which case requests are not authenticated at all.
+// If the store is a storage.TenantStorage, each request is served using only
+// the Accounts of the user that made it, as given by storage.ForUser.
+// Otherwise, only requests made by the storage.DefaultUser are served.
+// If the store is a storage.Attributor, the changes made by each request are
+// attributed to the user that made it.
+// If the store is a storage.AuditLog, every request that changes its data is
+// recorded in it.
+// If the store is a storage.IdempotencyStore, POST requests that are made with
+// a HeaderIdempotencyKey are served only once, with the response given again
+// to any repeat of the request.
+func New(store storage.Storage, tokens storage.TokenStore, log *log.Logger) (*mux.Router, error) {
 	if store == nil {
 		return nil, errors.New("nil store")
 	}
-	rs := generateRoutes(environment{storage: store})
-	return newRouter(rs, log)
+	e := environment{storage: store}
+	rs := append(generateRoutes(), generateV2Routes()...)
+	if al, ok := store.(storage.AuditLog); ok {
+		e.audit = al
+		rs = auditRoutes(rs)
+	}
+	rs = userRoutes(e, rs, userStorage(store))
+	if is, ok := store.(storage.IdempotencyStore); ok {
+		rs = idempotentRoutes(is, rs)
+	}
+	return newRouter(rs, tokens, log)
 }
 
 // New creates a new Router and initialises it will all of the global generateRoutes
-func newRouter(rs []route, log *log.Logger) (*mux.Router, error) {
+// Each request to a route must be authorized with the Permission of the route.
+func newRouter(rs []route, tokens storage.TokenStore, log *log.Logger) (*mux.Router, error) {
 	router := mux.NewRouter().StrictSlash(true)
 	for _, route := range rs {
-		handler := logger(log, route.appHandler, route.name)
+		if route.permission == "" {
+			return nil, fmt.Errorf("route %s does not declare a permission", route.name)
+		}
+		var handler http.Handler = route.appHandler
+		if tokens != nil {
+			handler = authenticate(tokens, authorize(route.permission, handler))
+		}
+		handler = logger(log, handler, route.name)
 		router.
 			Methods(route.method).
 			Path(route.pattern).
@@ -78,57 +248,145 @@ func newRouter(rs []route, log *log.Logger) (*mux.Router, error) {
 
 type environment struct {
 	storage storage.Storage
+	// audit, if not nil, records every request that changes the data of
+	// the storage.
+	audit storage.AuditLog
 }
 
-func generateRoutes(e environment) []route {
+func generateRoutes() []route {
 	return []route{
 		{
 			name:       "Accounts",
 			pattern:    patternAccounts,
-			appHandler: e.handlerSelectAccounts,
+			handler:    (*environment).handlerSelectAccounts,
 			method:     http.MethodGet,
+			permission: PermissionRead,
 		},
 		{
 			name:       "Account",
 			pattern:    patternAccount,
-			appHandler: e.muxAccountIDHandlerFunc,
+			handler:    (*environment).muxAccountIDHandlerFunc,
 			method:     http.MethodGet,
+			permission: PermissionRead,
 		},
 		{
 			name:       "AccountInsert",
 			pattern:    EndpointAccountInsert,
-			appHandler: e.muxAccountInsertHandlerFunc,
+			handler:    (*environment).muxAccountInsertHandlerFunc,
 			method:     http.MethodPost,
+			permission: PermissionWrite,
 		},
 		{
 			name:       "AccountUpdate",
 			pattern:    patternAccountUpdate,
-			appHandler: e.muxAccountUpdateHandlerFunc,
+			handler:    (*environment).muxAccountUpdateHandlerFunc,
 			method:     http.MethodPost,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "AccountOpen",
+			pattern:    EndpointAccountOpen,
+			handler:    (*environment).muxAccountOpenHandlerFunc,
+			method:     http.MethodPost,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "AccountClose",
+			pattern:    patternAccountClose,
+			handler:    (*environment).muxAccountCloseHandlerFunc,
+			method:     http.MethodPost,
+			permission: PermissionWrite,
 		},
 		{
 			name:       "AccountDelete",
 			pattern:    patternAccount,
-			appHandler: e.muxAccountDeleteHandlerFunc,
+			handler:    (*environment).muxAccountDeleteHandlerFunc,
 			method:     http.MethodDelete,
+			permission: PermissionDelete,
 		},
 		{
 			name:       "Balances",
 			pattern:    patternAccountBalances,
-			appHandler: e.muxAccountBalancesHandlerFunc,
+			handler:    (*environment).muxAccountBalancesHandlerFunc,
 			method:     http.MethodGet,
+			permission: PermissionRead,
 		},
 		{
 			name:       "BalanceInsert",
 			pattern:    patternAccountBalanceInsert,
-			appHandler: e.muxAccountBalanceInsertHandlerFunc,
+			handler:    (*environment).muxAccountBalanceInsertHandlerFunc,
 			method:     http.MethodPost,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "Balance",
+			pattern:    patternBalance,
+			handler:    (*environment).muxBalanceIDHandlerFunc,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "BalanceUpdate",
+			pattern:    patternAccountBalanceUpdate,
+			handler:    (*environment).muxAccountBalanceUpdateHandlerFunc,
+			method:     http.MethodPost,
+			permission: PermissionWrite,
 		},
 		{
 			name:       "BalanceDelete",
 			pattern:    patternBalance,
-			appHandler: e.muxBalanceDeleteHandlerFunc,
+			handler:    (*environment).muxBalanceDeleteHandlerFunc,
 			method:     http.MethodDelete,
+			permission: PermissionDelete,
+		},
+		{
+			name:       "AccountsSummary",
+			pattern:    EndpointAccountsSummary,
+			handler:    (*environment).handlerAccountsSummary,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "AccountsDeleted",
+			pattern:    EndpointAccountsDeleted,
+			handler:    (*environment).handlerSelectDeletedAccounts,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "AccountUndelete",
+			pattern:    patternAccountUndelete,
+			handler:    (*environment).muxAccountUndeleteHandlerFunc,
+			method:     http.MethodPost,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "BalancesDeleted",
+			pattern:    EndpointBalancesDeleted,
+			handler:    (*environment).handlerSelectDeletedBalances,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "BalanceUndelete",
+			pattern:    patternBalanceUndelete,
+			handler:    (*environment).muxBalanceUndeleteHandlerFunc,
+			method:     http.MethodPost,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "Audit",
+			pattern:    EndpointAudit,
+			handler:    (*environment).handlerSelectAuditEntries,
+			method:     http.MethodGet,
+			permission: PermissionAudit,
+		},
+		{
+			name:       "Events",
+			pattern:    EndpointEvents,
+			handler:    (*environment).handlerSelectEvents,
+			method:     http.MethodGet,
+			permission: PermissionAudit,
 		},
 	}
 }
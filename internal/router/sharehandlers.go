@@ -0,0 +1,115 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+func (env *environment) sharer() (storage.AccountSharer, error) {
+	s, ok := env.storage.(storage.AccountSharer)
+	if !ok {
+		return nil, errors.New("storage does not support sharing accounts")
+	}
+	return s, nil
+}
+
+func (env *environment) muxAccountSharesHandlerFunc(r *http.Request) (int, interface{}, error) {
+	id, err := extractID(mux.Vars(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
+	}
+	return env.handlerSelectAccountShares(id)
+}
+
+func (env *environment) handlerSelectAccountShares(id uint) (int, interface{}, error) {
+	s, err := env.sharer()
+	if err != nil {
+		return http.StatusNotImplemented, nil, err
+	}
+	users, err := s.SelectAccountShares(id)
+	if err != nil {
+		return http.StatusServiceUnavailable, nil, errors.Wrapf(err, "selecting shares of account with id:%d", id)
+	}
+	return http.StatusOK, users, nil
+}
+
+func (env *environment) muxAccountShareHandlerFunc(r *http.Request) (int, interface{}, error) {
+	id, err := extractID(mux.Vars(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
+	}
+
+	bod, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "reading request body")
+	}
+
+	defer func() {
+		cErr := r.Body.Close()
+		if cErr != nil {
+			log.Print(errors.Wrap(cErr, "closing request body"))
+		}
+	}()
+
+	var asb AccountShareBody
+	err = json.Unmarshal(bod, &asb)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "unmarshalling request body")
+	}
+	return env.handlerShareAccount(id, asb.User)
+}
+
+// handlerShareAccount shares the Account with the given id with the given
+// user, responding with the users that the Account has been shared with.
+func (env *environment) handlerShareAccount(id uint, user string) (int, interface{}, error) {
+	if user == "" {
+		return http.StatusBadRequest, nil, storage.Invalidf("user to share account with must not be empty")
+	}
+	s, err := env.sharer()
+	if err != nil {
+		return http.StatusNotImplemented, nil, err
+	}
+	if err := s.ShareAccount(id, user); err != nil {
+		return http.StatusServiceUnavailable, nil, errors.Wrapf(err, "sharing account with id:%d", id)
+	}
+	status, users, err := env.handlerSelectAccountShares(id)
+	if err != nil {
+		return status, nil, err
+	}
+	return http.StatusCreated, located{
+		location: fmt.Sprintf(EndpointFmtV2AccountShare, id, url.PathEscape(user)),
+		body:     users,
+	}, nil
+}
+
+func (env *environment) muxAccountUnshareHandlerFunc(r *http.Request) (int, interface{}, error) {
+	vars := mux.Vars(r)
+	id, err := extractID(vars)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
+	}
+	user, ok := vars["user"]
+	if !ok {
+		return http.StatusBadRequest, nil, errors.New("no user context variable")
+	}
+	return env.handlerUnshareAccount(id, user)
+}
+
+func (env *environment) handlerUnshareAccount(id uint, user string) (int, interface{}, error) {
+	s, err := env.sharer()
+	if err != nil {
+		return http.StatusNotImplemented, nil, err
+	}
+	if err := s.UnshareAccount(id, user); err != nil {
+		return http.StatusServiceUnavailable, nil, errors.Wrapf(err, "unsharing account with id:%d", id)
+	}
+	return http.StatusOK, nil, nil
+}
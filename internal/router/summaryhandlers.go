@@ -0,0 +1,134 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/internal/accountbalance"
+	"github.com/glynternet/mon/internal/sort"
+	"github.com/glynternet/mon/pkg/filter"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// AccountsSummary is the response body of the accounts summary endpoints. The
+// Balance of each AccountBalance holds the sum of the amounts of the Balances
+// of the Account at or before At, and the date of the latest of them. An
+// Account without any Balances at or before At has a zero Balance. Totals
+// holds the sum of the amounts of the Accounts of each currency, keyed by the
+// currency code.
+type AccountsSummary struct {
+	At       time.Time
+	Accounts []accountbalance.AccountBalance
+	Totals   map[string]int
+}
+
+func (env *environment) handlerAccountsSummary(r *http.Request) (int, interface{}, error) {
+	aq, err := extractAccountsQuery(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "extracting accounts query")
+	}
+	return env.accountsSummary(aq, time.Now())
+}
+
+// accountsSummary summarises the Accounts of the given AccountsQuery at its
+// AtDate, or at the given current time if it has no AtDate. The summary can
+// be sorted by any of the keys of sort.AccountSorts or
+// sort.AccountbalanceSorts.
+func (env *environment) accountsSummary(aq AccountsQuery, now time.Time) (int, interface{}, error) {
+	if aq.Limit > 0 || aq.Cursor != "" {
+		return http.StatusBadRequest, nil, storage.Invalidf("the accounts summary cannot be paginated")
+	}
+	if aq.AtDate == nil {
+		aq.AtDate = &now
+	}
+	at := *aq.AtDate
+	balanceSort, sortByBalance := sort.AccountbalanceSorts()[aq.SortBy]
+	if sortByBalance {
+		aq.SortBy = ""
+	}
+	q, err := aq.storageQuery(now)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrap(err, "preparing accounts query")
+	}
+
+	var as *storage.Accounts
+	var sums map[uint]balance.Balance
+	if aq.AsOf != nil {
+		h, err := env.history()
+		if err != nil {
+			return http.StatusNotImplemented, nil, err
+		}
+		as, err = h.SelectAccountsAsOf(*aq.AsOf)
+		if err != nil {
+			return errorStatus(err, http.StatusServiceUnavailable), nil, errors.Wrapf(err, "selecting Accounts as of %s", *aq.AsOf)
+		}
+		matched := queryAccounts(*as, q)
+		as = &matched
+		sums, err = sumAccountBalances(*as, at, func(id uint) (*storage.Balances, error) {
+			return h.SelectAccountBalancesAsOf(id, *aq.AsOf)
+		})
+		if err != nil {
+			return errorStatus(err, http.StatusServiceUnavailable), nil, errors.Wrapf(err, "summing balances as of %s", *aq.AsOf)
+		}
+	} else {
+		as, err = env.queryAccounts(q)
+		if err != nil {
+			return errorStatus(err, http.StatusServiceUnavailable), nil, errors.Wrap(err, "selecting Accounts")
+		}
+		sums, err = env.sumAccountBalances(*as, at)
+		if err != nil {
+			return errorStatus(err, http.StatusServiceUnavailable), nil, errors.Wrap(err, "summing balances")
+		}
+	}
+
+	s := AccountsSummary{
+		At:       at,
+		Accounts: make([]accountbalance.AccountBalance, len(*as)),
+		Totals:   make(map[string]int),
+	}
+	for i, a := range *as {
+		s.Accounts[i] = accountbalance.AccountBalance{Account: a, Balance: sums[a.ID]}
+		s.Totals[a.Account.CurrencyCode().String()] += sums[a.ID].Amount
+	}
+	if sortByBalance {
+		balanceSort(s.Accounts)
+	}
+	return http.StatusOK, s, nil
+}
+
+// sumAccountBalances totals the Balances of the given Accounts at the given
+// time, letting the storage total them if it provides a storage.BalanceSummer.
+func (env *environment) sumAccountBalances(as storage.Accounts, at time.Time) (map[uint]balance.Balance, error) {
+	if bs, ok := storage.AsBalanceSummer(env.storage); ok {
+		ids := make([]uint, len(as))
+		for i, a := range as {
+			ids[i] = a.ID
+		}
+		return bs.SumAccountBalances(ids, at)
+	}
+	return sumAccountBalances(as, at, env.storage.SelectAccountBalances)
+}
+
+// sumAccountBalances totals the Balances of each of the given Accounts at the
+// given time, in the same way as storage.BalanceSummer, using selectBalances
+// to select the Balances of each Account.
+func sumAccountBalances(as storage.Accounts, at time.Time, selectBalances func(uint) (*storage.Balances, error)) (map[uint]balance.Balance, error) {
+	sums := make(map[uint]balance.Balance)
+	notAfter := filter.BalanceNot(filter.BalanceAfter(at))
+	for _, a := range as {
+		bs, err := selectBalances(a.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "selecting balances of account %d", a.ID)
+		}
+		ibs := notAfter.Filter(*bs).InnerBalances()
+		latest, err := ibs.Latest()
+		if err != nil {
+			// the account has no balances at or before the time
+			continue
+		}
+		sums[a.ID] = balance.Balance{Date: latest.Date, Amount: ibs.Sum()}
+	}
+	return sums, nil
+}
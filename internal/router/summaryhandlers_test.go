@@ -0,0 +1,109 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/glynternet/mon/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_accountsSummary(t *testing.T) {
+	opened := time.Date(2018, 2, 3, 4, 5, 6, 0, time.UTC)
+	store := memory.New()
+	var as storage.Accounts
+	for _, c := range []string{"GBP", "GBP", "EUR"} {
+		a, err := store.InsertAccount(*accountingtest.NewAccount(t, "summary "+c, accountingtest.NewCurrencyCode(t, c), opened))
+		common.FatalIfError(t, err, "inserting account")
+		as = append(as, *a)
+	}
+	for _, b := range []struct {
+		accountID uint
+		balance.Balance
+	}{
+		{accountID: as[0].ID, Balance: balance.Balance{Date: opened.Add(time.Hour), Amount: 10}},
+		{accountID: as[0].ID, Balance: balance.Balance{Date: opened.Add(3 * time.Hour), Amount: 5}},
+		{accountID: as[1].ID, Balance: balance.Balance{Date: opened.Add(2 * time.Hour), Amount: -20}},
+	} {
+		_, err := store.InsertBalance(b.accountID, b.Balance, "")
+		common.FatalIfError(t, err, "inserting balance")
+	}
+	env := environment{storage: store}
+	at := opened.Add(2 * time.Hour)
+
+	amounts := func(s AccountsSummary) map[uint]int {
+		amounts := make(map[uint]int)
+		for _, ab := range s.Accounts {
+			amounts[ab.Account.ID] = ab.Balance.Amount
+		}
+		return amounts
+	}
+
+	t.Run("at date", func(t *testing.T) {
+		code, body, err := env.accountsSummary(AccountsQuery{AtDate: &at}, time.Now())
+		common.FatalIfError(t, err, "summarising accounts")
+		assert.Equal(t, http.StatusOK, code)
+		s := body.(AccountsSummary)
+		assert.True(t, at.Equal(s.At))
+		assert.Equal(t, map[uint]int{as[0].ID: 10, as[1].ID: -20, as[2].ID: 0}, amounts(s))
+		assert.True(t, opened.Add(time.Hour).Equal(s.Accounts[0].Balance.Date))
+		assert.Equal(t, map[string]int{"GBP": -10, "EUR": 0}, s.Totals)
+	})
+
+	t.Run("now", func(t *testing.T) {
+		_, body, err := env.accountsSummary(AccountsQuery{}, opened.Add(24*time.Hour))
+		common.FatalIfError(t, err, "summarising accounts")
+		assert.Equal(t, map[string]int{"GBP": -5, "EUR": 0}, body.(AccountsSummary).Totals)
+	})
+
+	t.Run("filtered and sorted by balance", func(t *testing.T) {
+		_, body, err := env.accountsSummary(AccountsQuery{
+			AtDate:     &at,
+			Currencies: []string{"GBP"},
+			SortBy:     "balance",
+		}, time.Now())
+		common.FatalIfError(t, err, "summarising accounts")
+		s := body.(AccountsSummary)
+		if assert.Len(t, s.Accounts, 2) {
+			assert.Equal(t, as[1].ID, s.Accounts[0].Account.ID)
+			assert.Equal(t, as[0].ID, s.Accounts[1].Account.ID)
+		}
+		assert.Equal(t, map[string]int{"GBP": -10}, s.Totals)
+	})
+
+	t.Run("before accounts existed", func(t *testing.T) {
+		before := opened.Add(-time.Hour)
+		_, body, err := env.accountsSummary(AccountsQuery{AtDate: &before}, time.Now())
+		common.FatalIfError(t, err, "summarising accounts")
+		assert.Empty(t, body.(AccountsSummary).Accounts)
+	})
+
+	t.Run("as of", func(t *testing.T) {
+		asOf := time.Now()
+		_, err := store.InsertBalance(as[2].ID, balance.Balance{Date: opened, Amount: 7}, "")
+		common.FatalIfError(t, err, "inserting balance")
+		_, body, err := env.accountsSummary(AccountsQuery{AsOf: &asOf, AtDate: &at}, time.Now())
+		common.FatalIfError(t, err, "summarising accounts")
+		assert.Equal(t, map[string]int{"GBP": -10, "EUR": 0}, body.(AccountsSummary).Totals)
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		code, _, err := env.accountsSummary(AccountsQuery{Limit: 1}, time.Now())
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("as of without history", func(t *testing.T) {
+		asOf := time.Now()
+		env := environment{storage: &storagetest.Storage{}}
+		code, _, err := env.accountsSummary(AccountsQuery{AsOf: &asOf}, time.Now())
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusNotImplemented, code)
+	})
+}
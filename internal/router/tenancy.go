@@ -0,0 +1,51 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// userStorage returns a function that gives the storage that the requests of
+// a user are served with. If the store is a storage.Attributor, changes are
+// attributed to the user that made them, and if it is a storage.TenantStorage,
+// only the Accounts of the user are held. A store that is not a
+// storage.TenantStorage cannot keep the Accounts of users apart, so the
+// requests of any user other than the storage.DefaultUser are forbidden.
+func userStorage(store storage.Storage) func(user string) (storage.Storage, error) {
+	a, attributes := store.(storage.Attributor)
+	_, tenanted := store.(storage.TenantStorage)
+	return func(user string) (storage.Storage, error) {
+		if !tenanted && user != storage.DefaultUser {
+			return nil, storage.Forbiddenf("storage cannot hold the accounts of user %q", user)
+		}
+		s := store
+		if attributes {
+			s = a.As(user)
+		}
+		if ts, ok := s.(storage.TenantStorage); ok && tenanted {
+			s = storage.ForUser(ts, user)
+		}
+		return s, nil
+	}
+}
+
+// userRoutes returns the given routes with the appHandler of each set to
+// serve a request with its handler and the given environment, with the
+// storage of the environment replaced by the one given by forUser for the
+// user that made the request.
+func userRoutes(e environment, rs []route, forUser func(user string) (storage.Storage, error)) []route {
+	for i, r := range rs {
+		h := r.handler
+		rs[i].appHandler = func(req *http.Request) (int, interface{}, error) {
+			s, err := forUser(requestUser(req))
+			if err != nil {
+				return http.StatusForbidden, nil, err
+			}
+			env := e
+			env.storage = s
+			return h(&env, req)
+		}
+	}
+	return rs
+}
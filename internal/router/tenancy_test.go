@@ -0,0 +1,131 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/internal/auth"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_tenancy(t *testing.T) {
+	store := memory.New()
+	secrets := make(map[string]string)
+	for _, user := range []string{"owner", "other"} {
+		secret, err := auth.NewToken()
+		common.FatalIfError(t, err, "creating token")
+		_, err = store.InsertToken(user, user, storage.RoleAdmin, auth.HashToken(secret))
+		common.FatalIfError(t, err, "inserting token")
+		secrets[user] = secret
+	}
+
+	r, err := New(store, store, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	serve := func(user, method, endpoint string, body interface{}) *httptest.ResponseRecorder {
+		var bs []byte
+		if body != nil {
+			var err error
+			bs, err = json.Marshal(body)
+			common.FatalIfError(t, err, "marshalling body")
+		}
+		req := httptest.NewRequest(method, endpoint, bytes.NewReader(bs))
+		req.Header.Set(HeaderAuthorization, AuthSchemeBearer+" "+secrets[user])
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	errorCode := func(rec *httptest.ResponseRecorder) ErrorCode {
+		var e Error
+		common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &e), "unmarshalling error")
+		return e.Code
+	}
+
+	rec := serve("owner", http.MethodPost, EndpointV2Accounts,
+		accountingtest.NewAccount(t, "owned", accountingtest.NewCurrencyCode(t, "GBP"), time.Now()))
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	var a struct{ ID uint }
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &a), "unmarshalling account")
+	account := fmt.Sprintf(EndpointFmtV2Account, a.ID)
+	shares := fmt.Sprintf(EndpointFmtV2AccountShares, a.ID)
+
+	t.Run("not shared", func(t *testing.T) {
+		rec := serve("other", http.MethodGet, account, nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = serve("other", http.MethodGet, EndpointV2Accounts, nil)
+		assert.Equal(t, "[]", rec.Body.String())
+		rec = serve("owner", http.MethodGet, shares, nil)
+		assert.Equal(t, "[]", rec.Body.String())
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		rec := serve("owner", http.MethodPost, shares, AccountShareBody{User: "other"})
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		assert.Equal(t, fmt.Sprintf(EndpointFmtV2AccountShare, a.ID, "other"), rec.Header().Get("Location"))
+		assert.Equal(t, `["other"]`, rec.Body.String())
+
+		assert.Equal(t, http.StatusOK, serve("other", http.MethodGet, account, nil).Code)
+		rec = serve("other", http.MethodPost, fmt.Sprintf(EndpointFmtV2AccountBalances, a.ID), BalanceInsertBody{
+			Balance: balance.Balance{Date: time.Now(), Amount: 1},
+		})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, CodeForbidden, errorCode(rec))
+		rec = serve("other", http.MethodGet, shares, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("share with nobody", func(t *testing.T) {
+		rec := serve("owner", http.MethodPost, shares, AccountShareBody{})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, CodeInvalid, errorCode(rec))
+	})
+
+	t.Run("unshared", func(t *testing.T) {
+		share := fmt.Sprintf(EndpointFmtV2AccountShare, a.ID, "other")
+		assert.Equal(t, http.StatusNoContent, serve("owner", http.MethodDelete, share, nil).Code)
+		assert.Equal(t, http.StatusNotFound, serve("owner", http.MethodDelete, share, nil).Code)
+		assert.Equal(t, http.StatusNotFound, serve("other", http.MethodGet, account, nil).Code)
+	})
+}
+
+func TestNew_untenanted(t *testing.T) {
+	tokens := memory.New()
+	secrets := make(map[string]string)
+	for _, user := range []string{storage.DefaultUser, "other"} {
+		secret, err := auth.NewToken()
+		common.FatalIfError(t, err, "creating token")
+		_, err = tokens.InsertToken(user, user, storage.RoleAdmin, auth.HashToken(secret))
+		common.FatalIfError(t, err, "inserting token")
+		secrets[user] = secret
+	}
+
+	// the embedding hides the storage.Tenancy of the memory store
+	store := struct{ storage.Storage }{memory.New()}
+	r, err := New(store, tokens, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	for user, status := range map[string]int{
+		storage.DefaultUser: http.StatusOK,
+		"other":             http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, EndpointV2Accounts, nil)
+		req.Header.Set(HeaderAuthorization, AuthSchemeBearer+" "+secrets[user])
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		assert.Equal(t, status, rec.Code, "user %q", user)
+	}
+}
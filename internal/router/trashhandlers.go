@@ -0,0 +1,56 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+func (env *environment) handlerSelectDeletedAccounts(_ *http.Request) (int, interface{}, error) {
+	as, err := env.storage.SelectDeletedAccounts()
+	if err != nil {
+		return http.StatusServiceUnavailable, nil, errors.Wrap(err, "selecting deleted Accounts from storage")
+	}
+	return http.StatusOK, as, nil
+}
+
+func (env *environment) muxAccountUndeleteHandlerFunc(r *http.Request) (int, interface{}, error) {
+	id, err := extractID(mux.Vars(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
+	}
+	return env.handlerUndeleteAccount(id)
+}
+
+func (env *environment) handlerUndeleteAccount(id uint) (int, interface{}, error) {
+	a, err := env.storage.UndeleteAccount(id)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "undeleting Account with id:%d in storage", id)
+	}
+	return http.StatusOK, a, nil
+}
+
+func (env *environment) handlerSelectDeletedBalances(_ *http.Request) (int, interface{}, error) {
+	bs, err := env.storage.SelectDeletedBalances()
+	if err != nil {
+		return http.StatusServiceUnavailable, nil, errors.Wrap(err, "selecting deleted Balances from storage")
+	}
+	return http.StatusOK, bs, nil
+}
+
+func (env *environment) muxBalanceUndeleteHandlerFunc(r *http.Request) (int, interface{}, error) {
+	id, err := extractID(mux.Vars(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting balance ID")
+	}
+	return env.handlerUndeleteBalance(id)
+}
+
+func (env *environment) handlerUndeleteBalance(id uint) (int, interface{}, error) {
+	b, err := env.storage.UndeleteBalance(id)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "undeleting Balance with id:%d in storage", id)
+	}
+	return http.StatusOK, b, nil
+}
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/storagetest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_SelectDeletedAccounts(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		expected := errors.New("SelectDeletedAccounts error")
+		srv := environment{storage: &storagetest.Storage{Err: expected}}
+		code, as, err := srv.handlerSelectDeletedAccounts(nil)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Nil(t, as)
+	})
+
+	t.Run("all ok", func(t *testing.T) {
+		expected := &storage.Accounts{{ID: 1}}
+		srv := environment{storage: &storagetest.Storage{Accounts: expected}}
+		code, as, err := srv.handlerSelectDeletedAccounts(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, expected, as)
+	})
+}
+
+func TestServer_UndeleteAccount(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		expected := errors.New("UndeleteAccount error")
+		srv := environment{storage: &storagetest.Storage{AccountErr: expected}}
+		code, a, err := srv.handlerUndeleteAccount(1)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Nil(t, a)
+	})
+
+	t.Run("all ok", func(t *testing.T) {
+		expected := &storage.Account{ID: 1}
+		mockStore := storagetest.Storage{Account: expected}
+		srv := environment{storage: &mockStore}
+		code, a, err := srv.handlerUndeleteAccount(1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, expected, a)
+		assert.Equal(t, uint(1), mockStore.LastAccountID)
+	})
+}
+
+func TestServer_SelectDeletedBalances(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		expected := errors.New("SelectDeletedBalances error")
+		srv := environment{storage: &storagetest.Storage{BalancesErr: expected}}
+		code, bs, err := srv.handlerSelectDeletedBalances(nil)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Nil(t, bs)
+	})
+
+	t.Run("all ok", func(t *testing.T) {
+		expected := &storage.Balances{{ID: 1, AccountID: 2}}
+		srv := environment{storage: &storagetest.Storage{Balances: expected}}
+		code, bs, err := srv.handlerSelectDeletedBalances(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, expected, bs)
+	})
+}
+
+func TestServer_UndeleteBalance(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		expected := errors.New("UndeleteBalance error")
+		srv := environment{storage: &storagetest.Storage{BalanceErr: expected}}
+		code, b, err := srv.handlerUndeleteBalance(1)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, expected, errors.Cause(err))
+		assert.Nil(t, b)
+	})
+
+	t.Run("all ok", func(t *testing.T) {
+		expected := &storage.Balance{ID: 1, AccountID: 2}
+		mockStore := storagetest.Storage{Balance: expected}
+		srv := environment{storage: &mockStore}
+		code, b, err := srv.handlerUndeleteBalance(1)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, expected, b)
+		assert.Equal(t, uint(1), mockStore.LastBalanceID)
+	})
+}
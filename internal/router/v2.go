@@ -0,0 +1,399 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/glynternet/go-money/currency"
+	"github.com/glynternet/mon/internal/model"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// The endpoints of the v2 API use resource paths and HTTP methods to describe
+// a request, rather than describing the action in the path of the request.
+const (
+	// EndpointV2 is the prefix of every endpoint of the v2 API
+	EndpointV2 = "/v2"
+
+	// EndpointV2Accounts is the v2 endpoint for selecting Accounts with GET
+	// and inserting an Account with POST
+	EndpointV2Accounts = EndpointV2 + "/accounts"
+
+	// EndpointV2AccountsSummary is the v2 endpoint for the balance of each
+	// Account at a time, in the same way as EndpointAccountsSummary
+	EndpointV2AccountsSummary = EndpointV2Accounts + "/summary"
+
+	// EndpointFmtV2Account is the format string for generating the v2
+	// endpoint of a specific Account, which can be used to select the Account
+	// with GET, update it with PUT, update some of its details with PATCH and
+	// delete it with DELETE
+	EndpointFmtV2Account = EndpointV2Accounts + "/%d"
+	patternV2Account     = EndpointV2Accounts + "/{id}"
+
+	// EndpointFmtV2AccountBalances is the format string for generating the
+	// v2 endpoint for selecting the Balances of a specific Account with GET
+	// and inserting a Balance for the Account with POST
+	EndpointFmtV2AccountBalances = EndpointFmtV2Account + "/balances"
+	patternV2AccountBalances     = patternV2Account + "/balances"
+
+	// EndpointFmtV2AccountBalancesBulk is the format string for generating
+	// the v2 endpoint for inserting many Balances for a specific Account at
+	// once with POST. Either every Balance is inserted or none of them are.
+	EndpointFmtV2AccountBalancesBulk = EndpointFmtV2AccountBalances + "/bulk"
+	patternV2AccountBalancesBulk     = patternV2AccountBalances + "/bulk"
+
+	// EndpointFmtV2AccountShares is the format string for generating the v2
+	// endpoint for selecting the users that a specific Account has been
+	// shared with using GET and sharing the Account with a user using POST
+	EndpointFmtV2AccountShares = EndpointFmtV2Account + "/shares"
+	patternV2AccountShares     = patternV2Account + "/shares"
+
+	// EndpointFmtV2AccountShare is the format string for generating the v2
+	// endpoint for the sharing of a specific Account with a specific user,
+	// which can be used to stop sharing the Account with the user with DELETE
+	EndpointFmtV2AccountShare = EndpointFmtV2AccountShares + "/%s"
+	patternV2AccountShare     = patternV2AccountShares + "/{user}"
+
+	// EndpointV2Balances is the base v2 endpoint for Balances
+	EndpointV2Balances = EndpointV2 + "/balances"
+
+	// EndpointFmtV2Balance is the format string for generating the v2
+	// endpoint of a specific Balance, which can be used to select the Balance
+	// with GET, update it with PUT and delete it with DELETE
+	EndpointFmtV2Balance = EndpointV2Balances + "/%d"
+	patternV2Balance     = EndpointV2Balances + "/{id}"
+)
+
+// BalanceUpdateBody is a struct that should be marshalled to json and used as
+// the body of a v2 balance update request. If AccountID is not zero, the
+// Balance will only be updated if it belongs to the Account with that ID.
+type BalanceUpdateBody struct {
+	BalanceInsertBody
+	AccountID uint `json:",omitempty"`
+}
+
+// BalancesInsertBody is a struct that should be marshalled to json and used as
+// the body of a v2 bulk balance insert request.
+type BalancesInsertBody struct {
+	Balances []BalanceInsertBody
+}
+
+// AccountShareBody is a struct that should be marshalled to json and used as
+// the body of a v2 account share request.
+type AccountShareBody struct {
+	User string
+}
+
+// AccountPatchBody is a struct that should be marshalled to json and used as
+// the body of a v2 account patch request. Only the details of the Account that
+// are given will be changed. A Closed time that is the zero time will reopen
+// the Account.
+type AccountPatchBody struct {
+	Name     *string    `json:",omitempty"`
+	Opened   *time.Time `json:",omitempty"`
+	Closed   *time.Time `json:",omitempty"`
+	Currency *string    `json:",omitempty"`
+}
+
+func generateV2Routes() []route {
+	return []route{
+		{
+			name:       "V2Accounts",
+			pattern:    EndpointV2Accounts,
+			handler:    (*environment).handlerSelectAccounts,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "V2AccountInsert",
+			pattern:    EndpointV2Accounts,
+			handler:    created((*environment).muxAccountInsertHandlerFunc),
+			method:     http.MethodPost,
+			permission: PermissionWrite,
+		},
+		{
+			// registered before the routes of a specific Account, so that the
+			// summary is not taken to be an Account ID
+			name:       "V2AccountsSummary",
+			pattern:    EndpointV2AccountsSummary,
+			handler:    (*environment).handlerAccountsSummary,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "V2Account",
+			pattern:    patternV2Account,
+			handler:    (*environment).muxAccountIDHandlerFunc,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "V2AccountUpdate",
+			pattern:    patternV2Account,
+			handler:    (*environment).muxAccountUpdateHandlerFunc,
+			method:     http.MethodPut,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "V2AccountPatch",
+			pattern:    patternV2Account,
+			handler:    (*environment).muxAccountPatchHandlerFunc,
+			method:     http.MethodPatch,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "V2AccountDelete",
+			pattern:    patternV2Account,
+			handler:    noContent((*environment).muxAccountDeleteHandlerFunc),
+			method:     http.MethodDelete,
+			permission: PermissionDelete,
+		},
+		{
+			name:       "V2AccountShares",
+			pattern:    patternV2AccountShares,
+			handler:    (*environment).muxAccountSharesHandlerFunc,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "V2AccountShare",
+			pattern:    patternV2AccountShares,
+			handler:    (*environment).muxAccountShareHandlerFunc,
+			method:     http.MethodPost,
+			permission: PermissionShare,
+		},
+		{
+			name:       "V2AccountUnshare",
+			pattern:    patternV2AccountShare,
+			handler:    noContent((*environment).muxAccountUnshareHandlerFunc),
+			method:     http.MethodDelete,
+			permission: PermissionShare,
+		},
+		{
+			name:       "V2Balances",
+			pattern:    patternV2AccountBalances,
+			handler:    (*environment).muxAccountBalancesHandlerFunc,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "V2BalanceInsert",
+			pattern:    patternV2AccountBalances,
+			handler:    created((*environment).muxAccountBalanceInsertHandlerFunc),
+			method:     http.MethodPost,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "V2BalancesInsert",
+			pattern:    patternV2AccountBalancesBulk,
+			handler:    created((*environment).muxV2AccountBalancesInsertHandlerFunc),
+			method:     http.MethodPost,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "V2Balance",
+			pattern:    patternV2Balance,
+			handler:    (*environment).muxBalanceIDHandlerFunc,
+			method:     http.MethodGet,
+			permission: PermissionRead,
+		},
+		{
+			name:       "V2BalanceUpdate",
+			pattern:    patternV2Balance,
+			handler:    (*environment).muxV2BalanceUpdateHandlerFunc,
+			method:     http.MethodPut,
+			permission: PermissionWrite,
+		},
+		{
+			name:       "V2BalanceDelete",
+			pattern:    patternV2Balance,
+			handler:    noContent((*environment).muxBalanceDeleteHandlerFunc),
+			method:     http.MethodDelete,
+			permission: PermissionDelete,
+		},
+	}
+}
+
+func (env *environment) muxAccountPatchHandlerFunc(r *http.Request) (int, interface{}, error) {
+	id, err := extractID(mux.Vars(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+
+	bod, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "reading request body")
+	}
+
+	defer func() {
+		cErr := r.Body.Close()
+		if cErr != nil {
+			log.Print(errors.Wrap(cErr, "closing request body"))
+		}
+	}()
+
+	var apb AccountPatchBody
+	err = json.Unmarshal(bod, &apb)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "unmarshalling request body")
+	}
+	return env.handlerPatchAccount(id, version, apb)
+}
+
+func (env *environment) handlerPatchAccount(id, version uint, apb AccountPatchBody) (int, interface{}, error) {
+	p := model.AccountPatch{
+		Name:   apb.Name,
+		Opened: apb.Opened,
+		Closed: apb.Closed,
+	}
+	if apb.Currency != nil {
+		c, err := currency.NewCode(*apb.Currency)
+		if err != nil {
+			return http.StatusBadRequest, nil, errors.Wrap(storage.Invalid(err), "creating currency code")
+		}
+		p.Currency = *c
+	}
+	// the patch is applied to the account as it is selected, so the update is
+	// conditional on the version that was selected when no version is given
+	var patched *storage.Account
+	err := env.storage.Atomic(func(s storage.Storage) error {
+		a, err := s.SelectAccount(id)
+		if err != nil {
+			return errors.Wrapf(err, "selecting account with id:%d", id)
+		}
+		if version != 0 {
+			a.Version = version
+		}
+		patched, err = model.PatchAccount(s, *a, p)
+		return errors.Wrapf(err, "patching account with id:%d", id)
+	})
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	return http.StatusOK, patched, nil
+}
+
+func (env *environment) muxV2BalanceUpdateHandlerFunc(r *http.Request) (int, interface{}, error) {
+	id, err := extractID(mux.Vars(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting balance ID")
+	}
+	version, err := ifMatchVersion(r)
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+
+	bod, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "reading request body")
+	}
+
+	defer func() {
+		cErr := r.Body.Close()
+		if cErr != nil {
+			log.Print(errors.Wrap(cErr, "closing request body"))
+		}
+	}()
+
+	var bub BalanceUpdateBody
+	err = json.Unmarshal(bod, &bub)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "unmarshalling request body")
+	}
+
+	accountID := bub.AccountID
+	if accountID == 0 {
+		b, err := env.storage.SelectBalance(id)
+		if err != nil {
+			return http.StatusBadRequest, nil, errors.Wrapf(err, "selecting balance with id:%d", id)
+		}
+		accountID = b.AccountID
+	}
+	return env.updateBalance(accountID, id, version, bub.Balance, bub.Note)
+}
+
+func (env *environment) muxV2AccountBalancesInsertHandlerFunc(r *http.Request) (int, interface{}, error) {
+	id, err := extractID(mux.Vars(r))
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "extracting account ID")
+	}
+
+	bod, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "reading request body")
+	}
+
+	defer func() {
+		cErr := r.Body.Close()
+		if cErr != nil {
+			log.Print(errors.Wrap(cErr, "closing request body"))
+		}
+	}()
+
+	var bib BalancesInsertBody
+	err = json.Unmarshal(bod, &bib)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "unmarshalling request body")
+	}
+	bs := make([]model.BalanceInsert, len(bib.Balances))
+	for i, b := range bib.Balances {
+		bs[i] = model.BalanceInsert{Balance: b.Balance, Note: b.Note}
+	}
+	inserted, err := model.InsertBalances(env.storage, id, bs)
+	if err != nil {
+		return http.StatusBadRequest, nil, errors.Wrapf(err, "inserting balances for account with id:%d", id)
+	}
+	return http.StatusOK, inserted, nil
+}
+
+// created wraps an envHandler that stores an item, so that a successful
+// response has the status http.StatusCreated and the Location of the item.
+func created(h envHandler) envHandler {
+	return func(env *environment, r *http.Request) (int, interface{}, error) {
+		status, bod, err := h(env, r)
+		if err != nil || status != http.StatusOK {
+			return status, bod, err
+		}
+		return http.StatusCreated, located{location: v2Location(bod), body: bod}, nil
+	}
+}
+
+// noContent wraps an envHandler so that a successful response has the status
+// http.StatusNoContent and no body.
+func noContent(h envHandler) envHandler {
+	return func(env *environment, r *http.Request) (int, interface{}, error) {
+		status, bod, err := h(env, r)
+		if err != nil || status != http.StatusOK {
+			return status, bod, err
+		}
+		return http.StatusNoContent, nil, nil
+	}
+}
+
+// located is a response body that is written along with a Location header.
+type located struct {
+	location string
+	body     interface{}
+}
+
+// v2Location returns the v2 endpoint of the given stored item, or an empty
+// string if the item has no v2 endpoint.
+func v2Location(item interface{}) string {
+	switch i := item.(type) {
+	case *storage.Account:
+		return fmt.Sprintf(EndpointFmtV2Account, i.ID)
+	case *storage.Balance:
+		return fmt.Sprintf(EndpointFmtV2Balance, i.ID)
+	}
+	return ""
+}
@@ -0,0 +1,218 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestV2Routes(t *testing.T) {
+	r, err := New(memory.New(), nil, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	serve := func(method, endpoint string, body interface{}) *httptest.ResponseRecorder {
+		var bs []byte
+		if body != nil {
+			bs, err = json.Marshal(body)
+			common.FatalIfError(t, err, "marshalling body")
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, endpoint, bytes.NewReader(bs)))
+		return rec
+	}
+
+	a := accountingtest.NewAccount(t, "V2", accountingtest.NewCurrencyCode(t, "GBP"), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	rec := serve(http.MethodPost, EndpointV2Accounts, a)
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	var inserted storage.Account
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &inserted), "unmarshalling account")
+	assert.Equal(t, fmt.Sprintf(EndpointFmtV2Account, inserted.ID), rec.Header().Get("Location"))
+
+	rec = serve(http.MethodGet, rec.Header().Get("Location"), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	updates := accountingtest.NewAccount(t, "V2 UPDATED", accountingtest.NewCurrencyCode(t, "GBP"), a.Opened())
+	rec = serve(http.MethodPut, fmt.Sprintf(EndpointFmtV2Account, inserted.ID), updates)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	name := "V2 PATCHED"
+	rec = serve(http.MethodPatch, fmt.Sprintf(EndpointFmtV2Account, inserted.ID), AccountPatchBody{Name: &name})
+	if assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		var patched storage.Account
+		common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &patched), "unmarshalling account")
+		assert.Equal(t, name, patched.Account.Name())
+		assert.Equal(t, updates.CurrencyCode(), patched.Account.CurrencyCode())
+	}
+
+	invalid := "INVALID"
+	rec = serve(http.MethodPatch, fmt.Sprintf(EndpointFmtV2Account, inserted.ID), AccountPatchBody{Currency: &invalid})
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodPost, fmt.Sprintf(EndpointFmtV2AccountBalances, inserted.ID), BalanceInsertBody{
+		Balance: balance.Balance{Date: a.Opened(), Amount: 10},
+	})
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	var b storage.Balance
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &b), "unmarshalling balance")
+	assert.Equal(t, fmt.Sprintf(EndpointFmtV2Balance, b.ID), rec.Header().Get("Location"))
+
+	rec = serve(http.MethodPut, fmt.Sprintf(EndpointFmtV2Balance, b.ID), BalanceUpdateBody{
+		BalanceInsertBody: BalanceInsertBody{Balance: balance.Balance{Date: a.Opened(), Amount: 20}},
+	})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = serve(http.MethodPut, fmt.Sprintf(EndpointFmtV2Balance, b.ID), BalanceUpdateBody{
+		BalanceInsertBody: BalanceInsertBody{Balance: balance.Balance{Date: a.Opened(), Amount: 20}},
+		AccountID:         inserted.ID + 1,
+	})
+	assert.NotEqual(t, http.StatusOK, rec.Code, "updating balance of another account")
+
+	rec = serve(http.MethodGet, fmt.Sprintf(EndpointFmtV2AccountBalances, inserted.ID), nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(http.MethodDelete, fmt.Sprintf(EndpointFmtV2Balance, b.ID), nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = serve(http.MethodDelete, fmt.Sprintf(EndpointFmtV2Account, inserted.ID), nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serve(http.MethodGet, fmt.Sprintf(EndpointFmtV2Account, inserted.ID), nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(http.MethodGet, EndpointAccounts, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, "v1 routes should remain mounted")
+}
+
+func TestV2Routes_balancesInsert(t *testing.T) {
+	r, err := New(memory.New(), nil, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	serve := func(method, endpoint string, body interface{}) *httptest.ResponseRecorder {
+		bs, err := json.Marshal(body)
+		common.FatalIfError(t, err, "marshalling body")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, endpoint, bytes.NewReader(bs)))
+		return rec
+	}
+	selectBalances := func(t *testing.T, accountID uint) storage.Balances {
+		rec := serve(http.MethodGet, fmt.Sprintf(EndpointFmtV2AccountBalances, accountID), nil)
+		var bs storage.Balances
+		common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &bs), "unmarshalling balances")
+		return bs
+	}
+
+	a := accountingtest.NewAccount(t, "bulk", accountingtest.NewCurrencyCode(t, "GBP"), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	rec := serve(http.MethodPost, EndpointV2Accounts, a)
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	var inserted storage.Account
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &inserted), "unmarshalling account")
+	endpoint := fmt.Sprintf(EndpointFmtV2AccountBalancesBulk, inserted.ID)
+
+	rec = serve(http.MethodPost, endpoint, BalancesInsertBody{Balances: []BalanceInsertBody{
+		{Balance: balance.Balance{Date: a.Opened(), Amount: 10}, Note: "first"},
+		{Balance: balance.Balance{Date: a.Opened().Add(-time.Hour), Amount: 20}},
+	}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	assert.Empty(t, selectBalances(t, inserted.ID), "no balances should be inserted when any are invalid")
+
+	rec = serve(http.MethodPost, endpoint, BalancesInsertBody{})
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "inserting no balances")
+
+	rec = serve(http.MethodPost, endpoint, BalancesInsertBody{Balances: []BalanceInsertBody{
+		{Balance: balance.Balance{Date: a.Opened(), Amount: 10}, Note: "first"},
+		{Balance: balance.Balance{Date: a.Opened().Add(time.Hour), Amount: 20}},
+	}})
+	if assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		var bs storage.Balances
+		common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &bs), "unmarshalling balances")
+		if assert.Len(t, bs, 2) {
+			assert.Equal(t, "first", bs[0].Note)
+			assert.Equal(t, 20, bs[1].Amount)
+		}
+	}
+	assert.Len(t, selectBalances(t, inserted.ID), 2)
+
+	rec = serve(http.MethodPost, fmt.Sprintf(EndpointFmtV2AccountBalancesBulk, inserted.ID+1), BalancesInsertBody{
+		Balances: []BalanceInsertBody{{Balance: balance.Balance{Date: a.Opened()}}},
+	})
+	assert.Equal(t, http.StatusNotFound, rec.Code, rec.Body.String())
+}
+
+// interleavedUpdate is a storage.Storage that updates an account, as a
+// concurrent request would, as soon as the account has first been selected.
+type interleavedUpdate struct {
+	storage.Storage
+	updated bool
+}
+
+func (s *interleavedUpdate) SelectAccount(id uint) (*storage.Account, error) {
+	a, err := s.Storage.SelectAccount(id)
+	if err == nil && !s.updated {
+		s.updated = true
+		_, err = s.Storage.UpdateAccount(id, a.Account)
+	}
+	return a, err
+}
+
+func (s *interleavedUpdate) Atomic(fn func(storage.Storage) error) error {
+	return fn(s)
+}
+
+func TestHandlerPatchAccount_interleavedUpdate(t *testing.T) {
+	store := memory.New()
+	a, err := store.InsertAccount(*accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now()))
+	common.FatalIfError(t, err, "inserting account")
+
+	env := &environment{storage: &interleavedUpdate{Storage: store}}
+	name := "B"
+	code, _, err := env.handlerPatchAccount(a.ID, 0, AccountPatchBody{Name: &name})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, storage.KindStale, storage.KindOf(err))
+
+	selected, err := store.SelectAccount(a.ID)
+	common.FatalIfError(t, err, "selecting account")
+	assert.Equal(t, "A", selected.Account.Name())
+}
+
+func TestCreated(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		expected := errors.New("insert error")
+		code, bod, err := created(func(*environment, *http.Request) (int, interface{}, error) {
+			return http.StatusBadRequest, nil, expected
+		})(nil, nil)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Nil(t, bod)
+		assert.Equal(t, expected, err)
+	})
+
+	t.Run("all ok", func(t *testing.T) {
+		b := &storage.Balance{ID: 3}
+		code, bod, err := created(func(*environment, *http.Request) (int, interface{}, error) {
+			return http.StatusOK, b, nil
+		})(nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, code)
+		assert.Equal(t, located{location: "/v2/balances/3", body: b}, bod)
+	})
+}
@@ -0,0 +1,44 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// version returns the Version of the given response body, if the body is an
+// Account or Balance whose Version is known.
+func version(bod interface{}) (uint, bool) {
+	var v uint
+	switch b := bod.(type) {
+	case *storage.Account:
+		v = b.Version
+	case *storage.Balance:
+		v = b.Version
+	}
+	return v, v != 0
+}
+
+// EntityTag returns the entity tag of the given version, as held by a
+// HeaderETag header.
+func EntityTag(version uint) string {
+	return strconv.Quote(strconv.FormatUint(uint64(version), 10))
+}
+
+// ifMatchVersion returns the version held by the HeaderIfMatch header of the
+// request, or zero if the request is unconditional because it has no such
+// header or the header is "*". The error is of storage.KindInvalid if the
+// header does not hold the entity tag of a version.
+func ifMatchVersion(r *http.Request) (uint, error) {
+	h := strings.TrimSpace(r.Header.Get(HeaderIfMatch))
+	if h == "" || h == "*" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(h, "W/"), `"`), 10, 64)
+	if err != nil || v == 0 {
+		return 0, storage.Invalidf("%s header %q does not hold the entity tag of a version", HeaderIfMatch, h)
+	}
+	return uint(v), nil
+}
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	for _, test := range []struct {
+		header  string
+		version uint
+		invalid bool
+	}{
+		{header: "", version: 0},
+		{header: "*", version: 0},
+		{header: `"3"`, version: 3},
+		{header: `W/"3"`, version: 3},
+		{header: "3", version: 3},
+		{header: `"0"`, invalid: true},
+		{header: `"three"`, invalid: true},
+		{header: `"3", "4"`, invalid: true},
+	} {
+		t.Run(test.header, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/", nil)
+			r.Header.Set(HeaderIfMatch, test.header)
+			v, err := ifMatchVersion(r)
+			if test.invalid {
+				assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+				return
+			}
+			common.FatalIfError(t, err, "getting version")
+			assert.Equal(t, test.version, v)
+		})
+	}
+}
+
+func TestNew_versions(t *testing.T) {
+	r, err := New(memory.New(), nil, log.New(ioutil.Discard, "", 0))
+	common.FatalIfError(t, err, "creating router")
+
+	serve := func(method, endpoint, ifMatch string, body interface{}) *httptest.ResponseRecorder {
+		var bs []byte
+		if body != nil {
+			bs, err = json.Marshal(body)
+			common.FatalIfError(t, err, "marshalling body")
+		}
+		req := httptest.NewRequest(method, endpoint, bytes.NewReader(bs))
+		if ifMatch != "" {
+			req.Header.Set(HeaderIfMatch, ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	assertStale := func(t *testing.T, rec *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code, rec.Body.String())
+		var e Error
+		common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &e), "unmarshalling error")
+		assert.Equal(t, CodeStale, e.Code)
+	}
+
+	a := accountingtest.NewAccount(t, "versions", accountingtest.NewCurrencyCode(t, "GBP"), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	rec := serve(http.MethodPost, EndpointV2Accounts, "", a)
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	assert.Equal(t, `"1"`, rec.Header().Get(HeaderETag))
+	var inserted storage.Account
+	common.FatalIfError(t, json.Unmarshal(rec.Body.Bytes(), &inserted), "unmarshalling account")
+	endpoint := fmt.Sprintf(EndpointFmtV2Account, inserted.ID)
+
+	rec = serve(http.MethodGet, endpoint, "", nil)
+	assert.Equal(t, `"1"`, rec.Header().Get(HeaderETag))
+
+	t.Run("account update", func(t *testing.T) {
+		updates := accountingtest.NewAccount(t, "versions updated", accountingtest.NewCurrencyCode(t, "GBP"), a.Opened())
+		rec := serve(http.MethodPut, endpoint, `"1"`, updates)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, `"2"`, rec.Header().Get(HeaderETag))
+
+		assertStale(t, serve(http.MethodPut, endpoint, `"1"`, updates))
+		name := "versions patched"
+		assertStale(t, serve(http.MethodPatch, endpoint, `"1"`, AccountPatchBody{Name: &name}))
+		rec = serve(http.MethodPatch, endpoint, `"2"`, AccountPatchBody{Name: &name})
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, `"3"`, rec.Header().Get(HeaderETag))
+
+		rec = serve(http.MethodPut, endpoint, "invalid", updates)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+	})
+
+	t.Run("balance update and delete", func(t *testing.T) {
+		rec := serve(http.MethodPost, fmt.Sprintf(EndpointFmtV2AccountBalances, inserted.ID), "", BalanceInsertBody{
+			Balance: balance.Balance{Date: a.Opened(), Amount: 10},
+		})
+		if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+			t.FailNow()
+		}
+		balanceEndpoint := rec.Header().Get("Location")
+		update := BalanceUpdateBody{
+			BalanceInsertBody: BalanceInsertBody{Balance: balance.Balance{Date: a.Opened(), Amount: 20}},
+		}
+		rec = serve(http.MethodPut, balanceEndpoint, `"1"`, update)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, `"2"`, rec.Header().Get(HeaderETag))
+
+		assertStale(t, serve(http.MethodPut, balanceEndpoint, `"1"`, update))
+		assertStale(t, serve(http.MethodDelete, balanceEndpoint, `"1"`, nil))
+		rec = serve(http.MethodDelete, balanceEndpoint, `"2"`, nil)
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	})
+
+	t.Run("account delete", func(t *testing.T) {
+		assertStale(t, serve(http.MethodDelete, endpoint, `"1"`, nil))
+		rec := serve(http.MethodDelete, endpoint, "*", nil)
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	})
+}
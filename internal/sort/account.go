@@ -7,9 +7,10 @@ import (
 )
 
 // sortAccounts sorts a slice of storage.Accounts into the order determined by
-// the given accountComparison, c.
+// the given accountComparison, c. Accounts that are neither before nor after
+// one another keep their original order.
 func sortAccounts(as storage.Accounts, c accountComparison) {
-	sort.Slice(as, func(i, j int) bool {
+	sort.SliceStable(as, func(i, j int) bool {
 		return c(as[i], as[j])
 	})
 }
@@ -30,8 +31,7 @@ func accountID(a, b storage.Account) bool {
 }
 
 // AccountName sorts a storage.Accounts by AccountName in ascending order.
-// AccountName cannot guarantee any specific order within a subsection of
-// storage.Accounts when multiple accounts have the same name.
+// Accounts with the same name keep the order that they were given in.
 func AccountName(as storage.Accounts) {
 	sortAccounts(as, accountName)
 }
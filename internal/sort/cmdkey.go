@@ -6,8 +6,8 @@ import (
 )
 
 const (
-	sortKeyID               = "id"
-	sortKeyName             = "name"
+	sortKeyID               = storage.AccountSortID
+	sortKeyName             = storage.AccountSortName
 	sortKeyBalance          = "balance"
 	sortKeyBalanceMagnitude = "balance-magnitude"
 )
@@ -0,0 +1,154 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const defaultCSVDateLayout = "2006-01-02"
+
+// CSVFormat describes the columns of a CSV statement. Each column is given
+// either as the name of the column in the header row or as the 1-based
+// index of the column.
+type CSVFormat struct {
+	// Comma is the field delimiter, which is ',' when zero.
+	Comma rune
+	// Header is true when the first row of the file is a header row.
+	Header bool
+	Date   string
+	// Exactly one of Amount and Balance must be given. When Amount is given,
+	// the Statement holds transactions.
+	Amount  string
+	Balance string
+	// Note is the columns that are joined together to make the note of each
+	// Line.
+	Note []string
+}
+
+// ReadCSV reads a Statement from CSV of the given CSVFormat.
+func ReadCSV(r io.Reader, f CSVFormat, o Options) (*Statement, error) {
+	if f.Date == "" {
+		return nil, errors.New("no date column given")
+	}
+	if (f.Amount == "") == (f.Balance == "") {
+		return nil, errors.New("exactly one of amount and balance columns must be given")
+	}
+
+	cr := csv.NewReader(r)
+	if f.Comma != 0 {
+		cr.Comma = f.Comma
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading csv")
+	}
+
+	var header []string
+	if f.Header {
+		if len(rows) == 0 {
+			return nil, errors.New("no header row")
+		}
+		header, rows = rows[0], rows[1:]
+	}
+	date, err := columnIndex(f.Date, header)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding date column")
+	}
+	amountColumn := f.Balance
+	if f.Amount != "" {
+		amountColumn = f.Amount
+	}
+	amount, err := columnIndex(amountColumn, header)
+	if err != nil {
+		return nil, errors.Wrap(err, "finding amount column")
+	}
+	notes := make([]int, len(f.Note))
+	for i, n := range f.Note {
+		if notes[i], err = columnIndex(n, header); err != nil {
+			return nil, errors.Wrap(err, "finding note column")
+		}
+	}
+
+	s := &Statement{Transactions: f.Amount != ""}
+	for i, row := range rows {
+		rowNum := i + 1
+		if f.Header {
+			rowNum++
+		}
+		if isBlank(row) {
+			continue
+		}
+		l, err := csvLine(row, date, amount, notes, o)
+		if err != nil {
+			return nil, errors.Wrapf(err, "row %d", rowNum)
+		}
+		s.Lines = append(s.Lines, l)
+	}
+	return s, nil
+}
+
+func csvLine(row []string, date, amount int, notes []int, o Options) (Line, error) {
+	field := func(i int) (string, error) {
+		if i >= len(row) {
+			return "", errors.Errorf("no column %d", i+1)
+		}
+		return row[i], nil
+	}
+	var l Line
+	d, err := field(date)
+	if err != nil {
+		return l, err
+	}
+	if l.Date, err = o.parseDate(d, defaultCSVDateLayout); err != nil {
+		return l, err
+	}
+	a, err := field(amount)
+	if err != nil {
+		return l, err
+	}
+	if l.Amount, err = parseAmount(a, o.Decimals); err != nil {
+		return l, err
+	}
+	var ns []string
+	for _, i := range notes {
+		n, err := field(i)
+		if err != nil {
+			return l, err
+		}
+		if n = strings.TrimSpace(n); n != "" {
+			ns = append(ns, n)
+		}
+	}
+	l.Note = strings.Join(ns, " - ")
+	return l, nil
+}
+
+// columnIndex returns the 0-based index of the given column, which is either
+// the name of a column in the given header or a 1-based index.
+func columnIndex(column string, header []string) (int, error) {
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), column) {
+			return i, nil
+		}
+	}
+	i, err := strconv.Atoi(column)
+	if err != nil || i < 1 {
+		return 0, errors.Errorf("no column %q", column)
+	}
+	return i - 1, nil
+}
+
+func isBlank(row []string) bool {
+	for _, f := range row {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
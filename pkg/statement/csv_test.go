@@ -0,0 +1,69 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCSV(t *testing.T) {
+	t.Run("header names", func(t *testing.T) {
+		in := "Date,Description,Reference,Amount\n" +
+			"2000-01-02,Coffee,ref1,-2.50\n" +
+			"\n" +
+			"2000-01-01,\"Salary, January\",,\"1,000.00\"\n"
+		s, err := ReadCSV(strings.NewReader(in), CSVFormat{
+			Header: true,
+			Date:   "date",
+			Amount: "Amount",
+			Note:   []string{"Description", "Reference"},
+		}, Options{Decimals: 2})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, &Statement{
+			Transactions: true,
+			Lines: []Line{
+				{Date: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), Amount: -250, Note: "Coffee - ref1"},
+				{Date: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), Amount: 100000, Note: "Salary, January"},
+			},
+		}, s)
+	})
+
+	t.Run("column indexes", func(t *testing.T) {
+		in := "02/01/2000;balance;12\n"
+		s, err := ReadCSV(strings.NewReader(in), CSVFormat{
+			Comma:   ';',
+			Date:    "1",
+			Balance: "3",
+			Note:    []string{"2"},
+		}, Options{Decimals: 1, DateLayout: "02/01/2006"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, &Statement{
+			Lines: []Line{{Date: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), Amount: 120, Note: "balance"}},
+		}, s)
+	})
+
+	for _, test := range []struct {
+		name string
+		in   string
+		CSVFormat
+	}{
+		{name: "no date column", in: "1", CSVFormat: CSVFormat{Amount: "1"}},
+		{name: "no amount or balance column", in: "1", CSVFormat: CSVFormat{Date: "1"}},
+		{name: "amount and balance columns", in: "1", CSVFormat: CSVFormat{Date: "1", Amount: "2", Balance: "3"}},
+		{name: "unknown column", in: "Date,Amount\n", CSVFormat: CSVFormat{Header: true, Date: "Date", Amount: "Value"}},
+		{name: "missing field", in: "2000-01-01\n", CSVFormat: CSVFormat{Date: "1", Amount: "2"}},
+		{name: "invalid date", in: "01/01/2000,1\n", CSVFormat: CSVFormat{Date: "1", Amount: "2"}},
+		{name: "invalid amount", in: "2000-01-01,one\n", CSVFormat: CSVFormat{Date: "1", Amount: "2"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadCSV(strings.NewReader(test.in), test.CSVFormat, Options{Decimals: 2})
+			assert.Error(t, err)
+		})
+	}
+}
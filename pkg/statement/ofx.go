@@ -0,0 +1,130 @@
+package statement
+
+import (
+	"html"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ReadOFX reads a Statement of transactions from an OFX file, which may be
+// either of the SGML or the XML versions of OFX. The Closing balance of the
+// Statement is the ledger balance of the file, if it has one. The DateLayout
+// of the Options is not used, as OFX has its own date format.
+func ReadOFX(r io.Reader, o Options) (*Statement, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading ofx")
+	}
+	tokens := strings.Split(string(bs), "<")
+	if len(tokens) < 2 {
+		return nil, errors.New("no ofx elements found")
+	}
+
+	s := &Statement{Transactions: true}
+	var txn ofxTransaction
+	var inLedger bool
+	for _, token := range tokens[1:] {
+		end := strings.Index(token, ">")
+		if end < 0 {
+			return nil, errors.Errorf("unterminated element %q", token)
+		}
+		tag := strings.ToUpper(strings.TrimSpace(token[:end]))
+		value := html.UnescapeString(strings.TrimSpace(token[end+1:]))
+		switch tag {
+		case "STMTTRN":
+			txn = ofxTransaction{}
+		case "/STMTTRN":
+			if txn == nil {
+				return nil, errors.New("transaction closed without being opened")
+			}
+			l, err := txn.line(o)
+			if err != nil {
+				return nil, errors.Wrapf(err, "transaction %d", len(s.Lines)+1)
+			}
+			s.Lines = append(s.Lines, l)
+			txn = nil
+		case "LEDGERBAL":
+			inLedger = true
+		case "/LEDGERBAL":
+			inLedger = false
+		case "BALAMT":
+			if !inLedger {
+				continue
+			}
+			closing, err := parseAmount(value, o.Decimals)
+			if err != nil {
+				return nil, errors.Wrap(err, "parsing ledger balance")
+			}
+			s.Closing = &closing
+		case "DTPOSTED", "TRNAMT", "NAME", "MEMO":
+			if txn != nil {
+				txn[tag] = value
+			}
+		}
+	}
+	if txn != nil {
+		return nil, errors.New("transaction was not closed")
+	}
+	return s, nil
+}
+
+// ofxTransaction holds the values of the fields of a transaction of an OFX
+// file, by the name of each field.
+type ofxTransaction map[string]string
+
+func (t ofxTransaction) line(o Options) (Line, error) {
+	var l Line
+	var err error
+	if l.Date, err = parseOFXDate(t["DTPOSTED"]); err != nil {
+		return l, errors.Wrap(err, "parsing DTPOSTED")
+	}
+	if l.Amount, err = parseAmount(t["TRNAMT"], o.Decimals); err != nil {
+		return l, errors.Wrap(err, "parsing TRNAMT")
+	}
+	var ns []string
+	for _, n := range []string{t["NAME"], t["MEMO"]} {
+		if n != "" {
+			ns = append(ns, n)
+		}
+	}
+	l.Note = strings.Join(ns, " - ")
+	return l, nil
+}
+
+// parseOFXDate parses a date of the form YYYYMMDD[HHMM[SS[.XXX]]], which
+// may be followed by a timezone of the form [offset:name], such as [-5:EST].
+// A date without a timezone is in UTC.
+func parseOFXDate(value string) (time.Time, error) {
+	loc := time.UTC
+	if i := strings.Index(value, "["); i >= 0 {
+		tz := strings.TrimSuffix(value[i+1:], "]")
+		value = value[:i]
+		if j := strings.Index(tz, ":"); j >= 0 {
+			tz = tz[:j]
+		}
+		hours, err := strconv.ParseFloat(tz, 64)
+		if err != nil {
+			return time.Time{}, errors.Errorf("invalid timezone offset %q", tz)
+		}
+		loc = time.FixedZone("", int(hours*60*60))
+	}
+	if i := strings.Index(value, "."); i >= 0 {
+		value = value[:i]
+	}
+	layouts := map[int]string{
+		8:  "20060102",
+		12: "200601021504",
+		14: "20060102150405",
+	}
+	layout, ok := layouts[len(value)]
+	if !ok {
+		return time.Time{}, errors.Errorf("invalid date %q", value)
+	}
+	t, err := time.ParseInLocation(layout, value, loc)
+	return t, errors.Wrapf(err, "parsing date %q", value)
+}
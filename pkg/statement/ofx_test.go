@@ -0,0 +1,87 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOFX(t *testing.T) {
+	sgml := `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>GBP
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20000102120000.000[-5:EST]
+<TRNAMT>-2.50
+<NAME>Coffee &amp; cake
+<MEMO>Card payment
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20000101
+<TRNAMT>1000
+<NAME>Salary
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1234.56<DTASOF>20000103</LEDGERBAL>
+<AVAILBAL><BALAMT>99.99<DTASOF>20000103</AVAILBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>
+<STMTTRN><DTPOSTED>20000102120000[-5:EST]</DTPOSTED><TRNAMT>-2.50</TRNAMT><NAME>Coffee &amp; cake</NAME><MEMO>Card payment</MEMO></STMTTRN>
+<STMTTRN><DTPOSTED>20000101</DTPOSTED><TRNAMT>1000</TRNAMT><NAME>Salary</NAME></STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1234.56</BALAMT><DTASOF>20000103</DTASOF></LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>
+`
+	closing := 123456
+	expected := &Statement{
+		Transactions: true,
+		Closing:      &closing,
+		Lines: []Line{
+			{Date: time.Date(2000, 1, 2, 17, 0, 0, 0, time.UTC), Amount: -250, Note: "Coffee & cake - Card payment"},
+			{Date: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), Amount: 100000, Note: "Salary"},
+		},
+	}
+
+	for name, in := range map[string]string{"sgml": sgml, "xml": xml} {
+		t.Run(name, func(t *testing.T) {
+			s, err := ReadOFX(strings.NewReader(in), Options{Decimals: 2})
+			if !assert.NoError(t, err) || !assert.Len(t, s.Lines, 2) {
+				return
+			}
+			assert.Equal(t, expected.Transactions, s.Transactions)
+			assert.Equal(t, expected.Closing, s.Closing)
+			for i, l := range s.Lines {
+				assert.True(t, expected.Lines[i].Date.Equal(l.Date), "line %d date: %s", i, l.Date)
+				assert.Equal(t, expected.Lines[i].Amount, l.Amount)
+				assert.Equal(t, expected.Lines[i].Note, l.Note)
+			}
+		})
+	}
+
+	for name, in := range map[string]string{
+		"not ofx":          "date,amount",
+		"unclosed":         "<OFX><STMTTRN><DTPOSTED>20000101<TRNAMT>1",
+		"invalid date":     "<STMTTRN><DTPOSTED>2000-01-01<TRNAMT>1</STMTTRN>",
+		"invalid amount":   "<STMTTRN><DTPOSTED>20000101<TRNAMT>one</STMTTRN>",
+		"missing amount":   "<STMTTRN><DTPOSTED>20000101</STMTTRN>",
+		"unterminated tag": "<OFX",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadOFX(strings.NewReader(in), Options{Decimals: 2})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package statement
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// defaultQIFDateLayouts are the layouts that QIF dates are parsed with when
+// no DateLayout is given. An apostrophe before the year, as written by some
+// programs, is then read as a slash, and spaces are ignored.
+var defaultQIFDateLayouts = []string{"1/2/2006", "1/2/06"}
+
+// ReadQIF reads a Statement of transactions from a QIF file. Each record of
+// the file gives a Line from its date (D), amount (T or U), payee (P) and
+// memo (M) fields, and is ended by a line holding only ^.
+func ReadQIF(r io.Reader, o Options) (*Statement, error) {
+	s := &Statement{Transactions: true}
+	fields := map[byte]string{}
+	scanner := bufio.NewScanner(r)
+	var lineNum int
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '!' {
+			continue
+		}
+		if line[0] != '^' {
+			code := line[0]
+			if _, ok := fields[code]; !ok {
+				fields[code] = line[1:]
+			}
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		l, err := qifLine(fields, o)
+		if err != nil {
+			return nil, errors.Wrapf(err, "record ending on line %d", lineNum)
+		}
+		s.Lines = append(s.Lines, l)
+		fields = map[byte]string{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "reading qif")
+	}
+	if len(fields) > 0 {
+		return nil, errors.New("last record was not ended with ^")
+	}
+	return s, nil
+}
+
+func qifLine(fields map[byte]string, o Options) (Line, error) {
+	var l Line
+	var err error
+	date := fields['D']
+	if o.DateLayout == "" {
+		date = strings.NewReplacer("'", "/", " ", "").Replace(date)
+	}
+	if l.Date, err = o.parseDate(date, defaultQIFDateLayouts...); err != nil {
+		return l, err
+	}
+	amount, ok := fields['T']
+	if !ok {
+		amount = fields['U']
+	}
+	if l.Amount, err = parseAmount(amount, o.Decimals); err != nil {
+		return l, err
+	}
+	var ns []string
+	for _, n := range []string{fields['P'], fields['M']} {
+		if n = strings.TrimSpace(n); n != "" {
+			ns = append(ns, n)
+		}
+	}
+	l.Note = strings.Join(ns, " - ")
+	return l, nil
+}
@@ -0,0 +1,50 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadQIF(t *testing.T) {
+	in := `!Type:Bank
+D1/ 2'00
+T-2.50
+PCoffee
+MCard payment
+^
+D01/01/2000
+U1,000.00
+PSalary
+^
+`
+	s, err := ReadQIF(strings.NewReader(in), Options{Decimals: 2})
+	if assert.NoError(t, err) {
+		assert.Equal(t, &Statement{
+			Transactions: true,
+			Lines: []Line{
+				{Date: time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), Amount: -250, Note: "Coffee - Card payment"},
+				{Date: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), Amount: 100000, Note: "Salary"},
+			},
+		}, s)
+	}
+
+	s, err = ReadQIF(strings.NewReader("D02.01.2000\nT1\n^\n"), Options{Decimals: 2, DateLayout: "02.01.2006"})
+	if assert.NoError(t, err) && assert.Len(t, s.Lines, 1) {
+		assert.Equal(t, time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), s.Lines[0].Date)
+	}
+
+	for name, in := range map[string]string{
+		"unended record": "D1/1/2000\nT1\n",
+		"invalid date":   "D2000-01-01\nT1\n^\n",
+		"invalid amount": "D1/1/2000\nTone\n^\n",
+		"missing amount": "D1/1/2000\n^\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadQIF(strings.NewReader(in), Options{Decimals: 2})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Package statement reads the lines of bank statements from CSV, OFX and QIF
+// files, so that they can be stored as the balances of an account.
+package statement
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/pkg/errors"
+)
+
+// Line is a single line of a Statement. Amount is in the minor units of the
+// currency of the statement and is either the amount of a transaction or the
+// balance after it, depending on the Statement that holds the Line.
+type Line struct {
+	Date   time.Time
+	Amount int
+	Note   string
+}
+
+// Statement is a set of Lines that have been read from a file.
+type Statement struct {
+	Lines []Line
+	// Transactions is true when the Amount of each Line is the amount of a
+	// transaction, rather than the balance after it.
+	Transactions bool
+	// Closing is the balance at the end of the Statement, when the file gave
+	// one.
+	Closing *int
+}
+
+// Entry is a Balance to be inserted for a single Line of a Statement,
+// along with the note of the Line.
+type Entry struct {
+	Balance balance.Balance
+	Note    string
+}
+
+// Entries returns an Entry for each Line of the Statement, in date order.
+// When the Statement holds transactions, the Balance of each Entry is the
+// balance after the transaction, starting from the given opening balance, or
+// from the balance that agrees with the Closing balance if there is one.
+func (s Statement) Entries(opening int) []Entry {
+	ls := make([]Line, len(s.Lines))
+	copy(ls, s.Lines)
+	sort.SliceStable(ls, func(i, j int) bool {
+		return ls[i].Date.Before(ls[j].Date)
+	})
+
+	if s.Transactions && s.Closing != nil {
+		opening = *s.Closing
+		for _, l := range ls {
+			opening -= l.Amount
+		}
+	}
+
+	es := make([]Entry, len(ls))
+	running := opening
+	for i, l := range ls {
+		amount := l.Amount
+		if s.Transactions {
+			running += l.Amount
+			amount = running
+		}
+		es[i] = Entry{
+			Balance: balance.Balance{Date: l.Date, Amount: amount},
+			Note:    l.Note,
+		}
+	}
+	return es
+}
+
+// Options are the options that are used to read the values of a file.
+type Options struct {
+	// Decimals is the number of decimal places of the minor units of the
+	// currency of the amounts.
+	Decimals int
+	// DateLayout is the layout that dates are parsed with, as used by
+	// time.Parse. Each format has its own default.
+	DateLayout string
+}
+
+func (o Options) parseDate(value string, defaultLayouts ...string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	layouts := defaultLayouts
+	if o.DateLayout != "" {
+		layouts = []string{o.DateLayout}
+	}
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Wrapf(err, "parsing date %q", value)
+}
+
+// parseAmount parses a decimal amount into an amount in minor units, for a
+// currency with the given number of decimal places. The amount may have a
+// sign or be in parentheses to be negative, and may have commas separating
+// thousands.
+func parseAmount(value string, decimals int) (int, error) {
+	s := strings.Replace(strings.TrimSpace(value), ",", "", -1)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative, s = true, s[1:len(s)-1]
+	}
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative, s = !negative, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, fraction := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		whole, fraction = s[:i], s[i+1:]
+	}
+	if len(fraction) > decimals {
+		return 0, errors.Errorf("amount %q has more than %d decimal places", value, decimals)
+	}
+	digits := whole + fraction
+	if digits == "" || strings.ContainsAny(digits, "+-") {
+		return 0, errors.Errorf("invalid amount %q", value)
+	}
+	amount, err := strconv.Atoi(digits + strings.Repeat("0", decimals-len(fraction)))
+	if err != nil {
+		return 0, errors.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
@@ -0,0 +1,85 @@
+package statement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatement_Entries(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2000, 1, d, 0, 0, 0, 0, time.UTC) }
+	lines := []Line{
+		{Date: day(3), Amount: -5, Note: "third"},
+		{Date: day(1), Amount: 10, Note: "first"},
+		{Date: day(2), Amount: 20, Note: "second"},
+	}
+	closing := 100
+
+	for _, test := range []struct {
+		name string
+		Statement
+		opening  int
+		expected []int
+	}{
+		{
+			name:      "balances",
+			Statement: Statement{Lines: lines},
+			opening:   1000,
+			expected:  []int{10, 20, -5},
+		},
+		{
+			name:      "transactions",
+			Statement: Statement{Lines: lines, Transactions: true},
+			opening:   1000,
+			expected:  []int{1010, 1030, 1025},
+		},
+		{
+			name:      "transactions with closing balance",
+			Statement: Statement{Lines: lines, Transactions: true, Closing: &closing},
+			opening:   1000,
+			expected:  []int{85, 105, 100},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			es := test.Entries(test.opening)
+			if !assert.Len(t, es, len(test.expected)) {
+				return
+			}
+			for i, e := range es {
+				assert.Equal(t, balance.Balance{Date: day(i + 1), Amount: test.expected[i]}, e.Balance)
+			}
+			assert.Equal(t, []string{"first", "second", "third"}, []string{es[0].Note, es[1].Note, es[2].Note})
+		})
+	}
+	assert.Equal(t, "third", lines[0].Note, "lines of statement should not be reordered")
+}
+
+func TestParseAmount(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		decimals int
+		expected int
+	}{
+		{value: "0", decimals: 2, expected: 0},
+		{value: "12", decimals: 2, expected: 1200},
+		{value: "12.3", decimals: 2, expected: 1230},
+		{value: "-12.34", decimals: 2, expected: -1234},
+		{value: "+12.34", decimals: 2, expected: 1234},
+		{value: "(12.34)", decimals: 2, expected: -1234},
+		{value: " 1,234.56 ", decimals: 2, expected: 123456},
+		{value: ".5", decimals: 2, expected: 50},
+		{value: "1234", decimals: 0, expected: 1234},
+		{value: "1.234", decimals: 3, expected: 1234},
+	} {
+		actual, err := parseAmount(test.value, test.decimals)
+		assert.NoError(t, err, test.value)
+		assert.Equal(t, test.expected, actual, test.value)
+	}
+
+	for _, value := range []string{"", ".", "-", "abc", "1.234", "1.2.3", "--1", "1-"} {
+		_, err := parseAmount(value, 2)
+		assert.Error(t, err, value)
+	}
+}
@@ -12,8 +12,12 @@ import (
 )
 
 // Account holds logic for an Account item that is held within a Storage.
+// The Version of an Account is increased each time that it is changed, so a
+// change can be made conditional on the Account not having been changed since
+// it was selected. A Version of zero is not known to the Storage.
 type Account struct {
 	ID        uint
+	Version   uint
 	Account   account.Account
 	deletedAt gtime.NullTime
 }
@@ -26,6 +30,12 @@ func DeletedAt(t time.Time) func(*Account) error {
 	}
 }
 
+// Deleted returns the time that the Account was deleted at, if it has been
+// deleted.
+func (a Account) Deleted() gtime.NullTime {
+	return a.deletedAt
+}
+
 // Accounts holds multiple Account items.
 type Accounts []Account
 
@@ -65,6 +75,7 @@ func (a Account) MarshalJSON() ([]byte, error) {
 func (a *Account) UnmarshalJSON(data []byte) (err error) {
 	aux := &struct {
 		ID      uint
+		Version uint
 		Account struct {
 			Name     string
 			Opened   time.Time
@@ -81,6 +92,7 @@ func (a *Account) UnmarshalJSON(data []byte) (err error) {
 		return errors.New("unmarshalling into auxiliary caused nil value")
 	}
 	a.ID = aux.ID
+	a.Version = aux.Version
 	a.deletedAt = aux.DeletedAt
 	c, err := currency.NewCode(aux.Account.Currency)
 	if err != nil {
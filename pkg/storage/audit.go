@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry is the record of a single request that changed, or attempted to
+// change, the data of a Storage.
+type AuditEntry struct {
+	ID   uint
+	Time time.Time
+	// User is the user that made the request and TokenID is the ID of the
+	// Token that the request was authenticated with, which is zero if the
+	// request was not authenticated.
+	User    string
+	TokenID uint
+	// Route is the name of the route that served the request.
+	Route  string
+	Method string
+	Path   string
+	// AccountID and BalanceID are the IDs of the Account and Balance that
+	// the request targeted, each of which is zero if there was none.
+	AccountID uint
+	BalanceID uint
+	// Request holds the body of the request. Before and After hold the
+	// target of the request as it was before and after the request was
+	// served, each of which is empty if the target did not exist. After is
+	// also empty if the request failed.
+	Request json.RawMessage `json:",omitempty"`
+	Before  json.RawMessage `json:",omitempty"`
+	After   json.RawMessage `json:",omitempty"`
+	// Status is the status code of the response and Error holds the message
+	// of the error that the request failed with, if any.
+	Status int
+	Error  string
+}
+
+// AuditEntries holds multiple AuditEntry items.
+type AuditEntries []AuditEntry
+
+// AuditQuery describes a subset of the AuditEntries of a Storage. The zero
+// AuditQuery describes every AuditEntry, ordered by ID.
+type AuditQuery struct {
+	// User, if not nil, matches only the AuditEntries of requests made by
+	// the given user.
+	User *string
+	// Route, if not empty, matches only the AuditEntries of requests that
+	// were served by the route with the given name.
+	Route string
+	// AccountID and BalanceID, if not zero, match only the AuditEntries of
+	// requests that targeted the Account or Balance with the given ID.
+	AccountID uint
+	BalanceID uint
+	// From, if not nil, matches only the AuditEntries at or after the given
+	// time.
+	From *time.Time
+	// To, if not nil, matches only the AuditEntries at or before the given
+	// time.
+	To *time.Time
+	// Order is the order that the matching AuditEntries are returned in,
+	// by ID. It should be empty or one of OrderAscending or OrderDescending.
+	Order string
+	// Offset is the number of matching AuditEntries to skip.
+	Offset uint
+	// Limit, if not zero, is the maximum number of AuditEntries to return.
+	Limit uint
+}
+
+// AuditLog is implemented by a Storage that is able to hold a record of the
+// requests that changed its data.
+type AuditLog interface {
+	// InsertAuditEntry stores the given AuditEntry, returning it with its ID
+	// and Time set.
+	InsertAuditEntry(e AuditEntry) (*AuditEntry, error)
+	// SelectAuditEntries returns the AuditEntries that match the given
+	// AuditQuery. The error is of KindInvalid if the AuditQuery has an
+	// unsupported Order.
+	SelectAuditEntries(q AuditQuery) (*AuditEntries, error)
+}
@@ -1,14 +1,37 @@
 package storage
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/glynternet/go-accounting/balance"
+	gtime "github.com/glynternet/go-time"
 )
 
 // Balance holds logic for an Account item that is held within a go-money database.
+// The Version of a Balance is increased each time that it is changed, in the
+// same way as the Version of an Account.
 type Balance struct {
 	balance.Balance
-	ID   uint
-	Note string
+	ID        uint
+	Version   uint
+	AccountID uint
+	Note      string
+	deletedAt gtime.NullTime
+}
+
+// BalanceDeletedAt will set a balance to deleted at a given time.
+func BalanceDeletedAt(t time.Time) func(*Balance) error {
+	return func(b *Balance) error {
+		b.deletedAt = gtime.NullTime{Valid: true, Time: t}
+		return nil
+	}
+}
+
+// Deleted returns the time that the Balance was deleted at, if it has been
+// deleted.
+func (b Balance) Deleted() gtime.NullTime {
+	return b.deletedAt
 }
 
 // Equal returns true if two Balance items are logically identical
@@ -16,6 +39,36 @@ func (b Balance) Equal(ob Balance) bool {
 	return b.ID == ob.ID && b.Note == ob.Note && b.Balance.Equal(ob.Balance)
 }
 
+// MarshalJSON marshals a Balance into a json blob, returning the blob with any
+// errors that occur during the marshalling.
+func (b Balance) MarshalJSON() ([]byte, error) {
+	type Alias Balance
+	return json.Marshal(&struct {
+		*Alias
+		DeletedAt gtime.NullTime
+	}{
+		Alias:     (*Alias)(&b),
+		DeletedAt: b.deletedAt,
+	})
+}
+
+// UnmarshalJSON attempts to unmarshal a json blob into a Balance object,
+// returning any errors that occur during the unmarshalling.
+func (b *Balance) UnmarshalJSON(data []byte) error {
+	type Alias Balance
+	aux := &struct {
+		*Alias
+		DeletedAt gtime.NullTime
+	}{
+		Alias: (*Alias)(b),
+	}
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	b.deletedAt = aux.DeletedAt
+	return nil
+}
+
 // Balances holds multiple Balance items
 type Balances []Balance
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/glynternet/go-accounting/balance"
 	"github.com/glynternet/go-money/common"
+	gtime "github.com/glynternet/go-time"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -48,10 +49,25 @@ func TestBalance_Equal(t *testing.T) {
 	}
 }
 
+func TestBalanceDeletedAt(t *testing.T) {
+	var b Balance
+	assert.Equal(t, gtime.NullTime{}, b.Deleted())
+
+	time := time.Date(1000, 0, 0, 0, 0, 0, 0, time.UTC)
+	err := BalanceDeletedAt(time)(&b)
+	assert.Nil(t, err)
+	assert.Equal(t, gtime.NullTime{Valid: true, Time: time}, b.Deleted())
+}
+
 func TestBalance_JSONLoop(t *testing.T) {
 	b := Balance{
-		ID:   47,
-		Note: "test note",
+		ID:        47,
+		AccountID: 12,
+		Note:      "test note",
+		deletedAt: gtime.NullTime{
+			Valid: true,
+			Time:  time.Date(1001, 0, 0, 0, 0, 0, 0, time.UTC),
+		},
 		Balance: balance.Balance{
 			Date:   time.Date(1000, 0, 0, 0, 0, 0, 0, time.UTC),
 			Amount: -34567,
@@ -0,0 +1,44 @@
+package storage
+
+// DeletionPolicy describes what happens to the live Balances of an Account
+// when the Account is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionRefuse refuses to delete an Account that has any live Balances.
+	DeletionRefuse DeletionPolicy = "refuse"
+
+	// DeletionCascade deletes every live Balance of an Account along with the
+	// Account. The Balances must be restored individually once the Account
+	// has been restored.
+	DeletionCascade DeletionPolicy = "cascade"
+
+	// DeletionArchive deletes an Account but leaves its Balances untouched.
+	// The Balances cannot be reached whilst the Account is deleted and become
+	// reachable again when the Account is restored.
+	DeletionArchive DeletionPolicy = "archive"
+)
+
+// DeletionPolicies holds every valid DeletionPolicy.
+var DeletionPolicies = []DeletionPolicy{DeletionRefuse, DeletionCascade, DeletionArchive}
+
+// ParseDeletionPolicy returns the DeletionPolicy with the given name. An empty
+// name is parsed as DeletionRefuse.
+func ParseDeletionPolicy(name string) (DeletionPolicy, error) {
+	if name == "" {
+		return DeletionRefuse, nil
+	}
+	p := DeletionPolicy(name)
+	return p, p.Validate()
+}
+
+// Validate returns an error if the DeletionPolicy is not one of the
+// DeletionPolicies.
+func (p DeletionPolicy) Validate() error {
+	for _, vp := range DeletionPolicies {
+		if p == vp {
+			return nil
+		}
+	}
+	return Invalidf("unknown deletion policy %q, must be one of %v", p, DeletionPolicies)
+}
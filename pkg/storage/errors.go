@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorKind classifies an error returned by a Storage so that callers can
+// respond to it without inspecting its message.
+type ErrorKind string
+
+// The ErrorKinds of error that can be returned by a Storage.
+const (
+	// KindNotFound is used when the item that an operation refers to does
+	// not exist.
+	KindNotFound ErrorKind = "not_found"
+	// KindConflict is used when an operation cannot be made because of the
+	// current state of the Storage.
+	KindConflict ErrorKind = "conflict"
+	// KindInvalid is used when the values given to an operation are invalid.
+	KindInvalid ErrorKind = "invalid"
+	// KindStale is used when an operation is conditional on the version of
+	// an item that is no longer the current version, because the item has
+	// been changed since that version was selected.
+	KindStale ErrorKind = "stale"
+	// KindForbidden is used when an operation is not permitted for the user
+	// that made it, such as changing an Account that has only been shared
+	// with them.
+	KindForbidden ErrorKind = "forbidden"
+	// KindUnavailable is used when the backend of a Storage cannot be reached.
+	KindUnavailable ErrorKind = "unavailable"
+)
+
+// Error is an error with an ErrorKind.
+type Error struct {
+	kind ErrorKind
+	err  error
+}
+
+// Kind returns the ErrorKind of the Error.
+func (e *Error) Kind() ErrorKind {
+	return e.kind
+}
+
+// Error returns the message of the Error.
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error that the Error was created with.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// KindOf returns the ErrorKind of the cause of err, or an empty ErrorKind if
+// the cause of err has no Kind method.
+func KindOf(err error) ErrorKind {
+	k, ok := errors.Cause(err).(interface {
+		Kind() ErrorKind
+	})
+	if !ok {
+		return ""
+	}
+	return k.Kind()
+}
+
+// NotFoundf returns an Error of KindNotFound with the formatted message.
+func NotFoundf(format string, args ...interface{}) error {
+	return &Error{kind: KindNotFound, err: fmt.Errorf(format, args...)}
+}
+
+// Conflictf returns an Error of KindConflict with the formatted message.
+func Conflictf(format string, args ...interface{}) error {
+	return &Error{kind: KindConflict, err: fmt.Errorf(format, args...)}
+}
+
+// Stalef returns an Error of KindStale with the formatted message.
+func Stalef(format string, args ...interface{}) error {
+	return &Error{kind: KindStale, err: fmt.Errorf(format, args...)}
+}
+
+// CheckVersion returns an Error of KindStale if the given version is not zero
+// and is not the current version of an item. The name describes the item in
+// the message of the Error.
+func CheckVersion(name string, version, current uint) error {
+	if version == 0 || version == current {
+		return nil
+	}
+	return Stalef("%s has been changed since version %d was selected and is now at version %d", name, version, current)
+}
+
+// Forbiddenf returns an Error of KindForbidden with the formatted message.
+func Forbiddenf(format string, args ...interface{}) error {
+	return &Error{kind: KindForbidden, err: fmt.Errorf(format, args...)}
+}
+
+// Invalidf returns an Error of KindInvalid with the formatted message.
+func Invalidf(format string, args ...interface{}) error {
+	return &Error{kind: KindInvalid, err: fmt.Errorf(format, args...)}
+}
+
+// Invalid returns an Error of KindInvalid that holds err, or nil if err is
+// nil.
+func Invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{kind: KindInvalid, err: err}
+}
+
+// Unavailable returns an Error of KindUnavailable that holds err, or nil if
+// err is nil.
+func Unavailable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{kind: KindUnavailable, err: err}
+}
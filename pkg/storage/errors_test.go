@@ -0,0 +1,51 @@
+package storage
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKindOf(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		err  error
+		kind ErrorKind
+	}{
+		{name: "nil"},
+		{name: "untyped", err: stderrors.New("untyped")},
+		{name: "not found", err: NotFoundf("no account with id %d", 1), kind: KindNotFound},
+		{name: "conflict", err: Conflictf("conflict"), kind: KindConflict},
+		{name: "invalid", err: Invalidf("invalid"), kind: KindInvalid},
+		{name: "forbidden", err: Forbiddenf("forbidden"), kind: KindForbidden},
+		{name: "stale", err: Stalef("stale"), kind: KindStale},
+		{name: "invalid error", err: Invalid(stderrors.New("invalid")), kind: KindInvalid},
+		{name: "unavailable", err: Unavailable(stderrors.New("unavailable")), kind: KindUnavailable},
+		{name: "wrapped", err: errors.Wrap(NotFoundf("missing"), "selecting"), kind: KindNotFound},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.kind, KindOf(test.err))
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	assert.Nil(t, Invalid(nil))
+	assert.Nil(t, Unavailable(nil))
+
+	inner := stderrors.New("connection refused")
+	err := Unavailable(inner)
+	assert.Equal(t, "connection refused", err.Error())
+	assert.True(t, stderrors.Is(err, inner))
+	assert.Equal(t, "no account with id 2", NotFoundf("no account with id %d", 2).Error())
+}
+
+func TestCheckVersion(t *testing.T) {
+	assert.NoError(t, CheckVersion("account 1", 0, 3), "zero version")
+	assert.NoError(t, CheckVersion("account 1", 3, 3), "current version")
+	err := CheckVersion("account 1", 2, 3)
+	assert.Equal(t, KindStale, KindOf(err))
+	assert.Equal(t, "account 1 has been changed since version 2 was selected and is now at version 3", err.Error())
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+)
+
+// EventType describes the change to the Accounts or Balances of a Storage
+// that an Event records.
+type EventType string
+
+// The types of Event that can be recorded by an EventLog.
+const (
+	AccountInserted  EventType = "AccountInserted"
+	AccountUpdated   EventType = "AccountUpdated"
+	AccountDeleted   EventType = "AccountDeleted"
+	AccountUndeleted EventType = "AccountUndeleted"
+	BalanceInserted  EventType = "BalanceInserted"
+	BalanceUpdated   EventType = "BalanceUpdated"
+	BalanceDeleted   EventType = "BalanceDeleted"
+	BalanceUndeleted EventType = "BalanceUndeleted"
+)
+
+// Event is an immutable record of a single change made to the Accounts or
+// Balances of a Storage. Actor is the user that made the change, which is
+// empty if the change was not attributed to a user. Only the fields relevant
+// to the Type of the Event will be populated.
+type Event struct {
+	Sequence  uint64
+	Type      EventType
+	Time      time.Time
+	Actor     string           `json:",omitempty"`
+	AccountID uint             `json:",omitempty"`
+	BalanceID uint             `json:",omitempty"`
+	Account   *account.Account `json:",omitempty"`
+	Balance   *balance.Balance `json:",omitempty"`
+	Note      string           `json:",omitempty"`
+}
+
+// Events holds multiple Event items.
+type Events []Event
+
+// EventLog is implemented by a Storage that records every change made to its
+// Accounts and Balances as an Event.
+type EventLog interface {
+	// SelectEvents returns the Events with a Sequence greater than after, in
+	// the order in which they were recorded. If limit is not zero, no more
+	// than limit Events are returned.
+	SelectEvents(after uint64, limit uint) (*Events, error)
+}
+
+// Attributor is implemented by a Storage that records the user that made
+// each change to it.
+type Attributor interface {
+	// As returns a Storage that holds the same data as the Attributor and
+	// records the given user as the maker of every change made through it.
+	// The returned Storage implements the same optional interfaces as the
+	// Attributor.
+	As(user string) Storage
+}
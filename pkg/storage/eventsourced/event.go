@@ -0,0 +1,77 @@
+package eventsourced
+
+import (
+	"time"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// EventType describes the change to the storage that an Event records.
+type EventType = storage.EventType
+
+// The types of Event that can be recorded in a Log. Events of TokenInserted
+// and TokenRevoked change the Tokens of the storage, and Events of
+// AccountOwnerSet, AccountShared and AccountUnshared change the users that can
+// access an Account, so none of them are given by SelectEvents.
+const (
+	AccountInserted  = storage.AccountInserted
+	AccountUpdated   = storage.AccountUpdated
+	AccountDeleted   = storage.AccountDeleted
+	AccountUndeleted = storage.AccountUndeleted
+	BalanceInserted  = storage.BalanceInserted
+	BalanceUpdated   = storage.BalanceUpdated
+	BalanceDeleted   = storage.BalanceDeleted
+	BalanceUndeleted = storage.BalanceUndeleted
+
+	TokenInserted EventType = "TokenInserted"
+	TokenRevoked  EventType = "TokenRevoked"
+
+	AccountOwnerSet EventType = "AccountOwnerSet"
+	AccountShared   EventType = "AccountShared"
+	AccountUnshared EventType = "AccountUnshared"
+)
+
+// Event is an immutable record of a single change made to the storage.
+// Only the fields relevant to the Type of the Event will be populated.
+type Event struct {
+	Sequence  uint64
+	Type      EventType
+	Time      time.Time
+	Actor     string           `json:",omitempty"`
+	AccountID uint             `json:",omitempty"`
+	BalanceID uint             `json:",omitempty"`
+	Account   *account.Account `json:",omitempty"`
+	Balance   *balance.Balance `json:",omitempty"`
+	Note      string           `json:",omitempty"`
+	TokenID   uint             `json:",omitempty"`
+	Token     *storage.Token   `json:",omitempty"`
+	TokenHash string           `json:",omitempty"`
+	User      string           `json:",omitempty"`
+}
+
+// changesAccess returns true if the Event changes the Tokens of the storage
+// or the users that can access one of its Accounts.
+func (e Event) changesAccess() bool {
+	switch e.Type {
+	case TokenInserted, TokenRevoked, AccountOwnerSet, AccountShared, AccountUnshared:
+		return true
+	}
+	return false
+}
+
+// storageEvent returns the Event as a storage.Event.
+func (e Event) storageEvent() storage.Event {
+	return storage.Event{
+		Sequence:  e.Sequence,
+		Type:      e.Type,
+		Time:      e.Time,
+		Actor:     e.Actor,
+		AccountID: e.AccountID,
+		BalanceID: e.BalanceID,
+		Account:   e.Account,
+		Balance:   e.Balance,
+		Note:      e.Note,
+	}
+}
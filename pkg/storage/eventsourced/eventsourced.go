@@ -0,0 +1,242 @@
+// Package eventsourced provides a storage.Storage implementation that records
+// every change as an immutable Event in an append-only Log. The current state
+// of the storage is derived by projecting the Events of the Log in order, so
+// the full history of every change is retained and can be audited.
+package eventsourced
+
+import (
+	"io"
+	"sync"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// New returns a Storage that records changes to the given Log. The current
+// state of the Storage is projected from any Events that the Log already
+// holds.
+func New(l Log) (*eventsourced, error) {
+	if l == nil {
+		return nil, errors.New("nil log")
+	}
+	es, err := l.Events()
+	if err != nil {
+		return nil, errors.Wrap(err, "reading events from log")
+	}
+	p, err := project(es)
+	if err != nil {
+		return nil, errors.Wrap(err, "projecting events")
+	}
+	return &eventsourced{store: &store{log: l, state: p}}, nil
+}
+
+// eventsourced is safe for concurrent use.
+type eventsourced struct {
+	*store
+	actor string
+}
+
+// store is shared between every eventsourced that is created from the same
+// call to New.
+type store struct {
+	sync.RWMutex
+	log   Log
+	state *projection
+}
+
+// As returns a view of the Storage that records the given actor against every
+// Event that is recorded through it.
+func (es *eventsourced) As(actor string) storage.Storage {
+	return &eventsourced{store: es.store, actor: actor}
+}
+
+// Events returns every Event that has been recorded, in the order that they
+// were recorded.
+func (es *eventsourced) Events() ([]Event, error) {
+	es.RLock()
+	defer es.RUnlock()
+	return es.log.Events()
+}
+
+// SelectEvents returns the Events that changed Accounts or Balances and have a
+// Sequence greater than after, in the order that they were recorded. If limit
+// is not zero, no more than limit Events are returned.
+func (es *eventsourced) SelectEvents(after uint64, limit uint) (*storage.Events, error) {
+	all, err := es.Events()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting events")
+	}
+	selected := storage.Events{}
+	for _, e := range all {
+		if limit > 0 && uint(len(selected)) == limit {
+			break
+		}
+		if e.Sequence > after && !e.changesAccess() {
+			selected = append(selected, e.storageEvent())
+		}
+	}
+	return &selected, nil
+}
+
+// Available returns true if the Storage is available.
+func (es *eventsourced) Available() bool {
+	return true
+}
+
+// Close closes the Log of the Storage, if the Log is able to be closed.
+func (es *eventsourced) Close() error {
+	if c, ok := es.log.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// InsertAccount inserts an account.Account in the Storage and returns it.
+func (es *eventsourced) InsertAccount(a account.Account) (*storage.Account, error) {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().InsertAccount(a)
+}
+
+// SelectAccount returns an Account with the given id.
+func (es *eventsourced) SelectAccount(id uint) (*storage.Account, error) {
+	es.RLock()
+	defer es.RUnlock()
+	return es.session().SelectAccount(id)
+}
+
+// SelectAccounts returns all of the Accounts that have not been deleted,
+// ordered by their ID.
+func (es *eventsourced) SelectAccounts() (*storage.Accounts, error) {
+	es.RLock()
+	defer es.RUnlock()
+	return es.session().SelectAccounts()
+}
+
+// UpdateAccount updates the account at a given id with the values from the
+// given account.Account
+func (es *eventsourced) UpdateAccount(id uint, updates account.Account) (*storage.Account, error) {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().UpdateAccount(id, updates)
+}
+
+// DeleteAccount deletes an account with the given id, treating the live
+// balances of the account according to the given storage.DeletionPolicy.
+// Any cascaded deletions are recorded together with the deletion of the
+// account.
+func (es *eventsourced) DeleteAccount(id uint, policy storage.DeletionPolicy) error {
+	return es.Atomic(func(s storage.Storage) error {
+		return s.DeleteAccount(id, policy)
+	})
+}
+
+// SelectBalance returns the Balance with the given id.
+func (es *eventsourced) SelectBalance(id uint) (*storage.Balance, error) {
+	es.RLock()
+	defer es.RUnlock()
+	return es.session().SelectBalance(id)
+}
+
+// InsertBalance inserts a Balance for the account with the given ID.
+func (es *eventsourced) InsertBalance(accountID uint, b balance.Balance, note string) (*storage.Balance, error) {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().InsertBalance(accountID, b, note)
+}
+
+// SelectAccountBalances returns all Balances for a given account ID. The
+// Balances are sorted by chronological order then by the id of the Balance.
+func (es *eventsourced) SelectAccountBalances(id uint) (*storage.Balances, error) {
+	es.RLock()
+	defer es.RUnlock()
+	return es.session().SelectAccountBalances(id)
+}
+
+// UpdateBalance updates the balance with the given id, that belongs to the
+// account with the given accountID, to hold the given balance.Balance and note.
+func (es *eventsourced) UpdateBalance(accountID, id uint, b balance.Balance, note string) (*storage.Balance, error) {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().UpdateBalance(accountID, id, b, note)
+}
+
+// DeleteBalance deletes a balance with the given id
+func (es *eventsourced) DeleteBalance(id uint) error {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().DeleteBalance(id)
+}
+
+// SelectDeletedAccounts returns all of the Accounts that have been deleted,
+// ordered by their ID.
+func (es *eventsourced) SelectDeletedAccounts() (*storage.Accounts, error) {
+	es.RLock()
+	defer es.RUnlock()
+	return es.session().SelectDeletedAccounts()
+}
+
+// UndeleteAccount restores the deleted account with the given id.
+func (es *eventsourced) UndeleteAccount(id uint) (*storage.Account, error) {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().UndeleteAccount(id)
+}
+
+// SelectDeletedBalances returns all of the Balances that have been deleted,
+// ordered by their ID.
+func (es *eventsourced) SelectDeletedBalances() (*storage.Balances, error) {
+	es.RLock()
+	defer es.RUnlock()
+	return es.session().SelectDeletedBalances()
+}
+
+// UndeleteBalance restores the deleted balance with the given id. A balance
+// cannot be restored whilst its account is deleted.
+func (es *eventsourced) UndeleteBalance(id uint) (*storage.Balance, error) {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().UndeleteBalance(id)
+}
+
+// Atomic runs fn against a copy of the projected state of the Storage. The
+// Events recorded by fn are appended to the Log together, and the copy
+// replaces the projected state, only if fn returns a nil error. All other
+// operations on the Storage are blocked until fn has returned.
+func (es *eventsourced) Atomic(fn func(storage.Storage) error) error {
+	es.Lock()
+	defer es.Unlock()
+	p := es.state.copy()
+	var pending []Event
+	err := fn(&session{
+		projection: &p,
+		actor:      es.actor,
+		commit: func(e Event) error {
+			pending = append(pending, e)
+			return nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		if err := es.log.Append(pending...); err != nil {
+			return errors.Wrap(err, "appending events to log")
+		}
+	}
+	*es.state = p
+	return nil
+}
+
+// session returns a session that appends each Event directly to the Log.
+func (es *eventsourced) session() *session {
+	return &session{
+		projection: es.state,
+		actor:      es.actor,
+		commit: func(e Event) error {
+			return es.log.Append(e)
+		},
+	}
+}
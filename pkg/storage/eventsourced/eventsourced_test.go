@@ -0,0 +1,225 @@
+package eventsourced_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/eventsourced"
+	"github.com/glynternet/mon/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// This line ensures that an eventsourced Storage can be assigned to a
+// storage.Storage variable and, therefore, ensures that it satisfies the
+// storage.Storage interface
+var _ storage.Storage = func() storage.Storage {
+	store, _ := eventsourced.New(eventsourced.NewMemoryLog())
+	return store
+}()
+
+func newStorage(t *testing.T) storage.Storage {
+	store, err := eventsourced.New(eventsourced.NewMemoryLog())
+	common.FatalIfError(t, err, "creating storage")
+	return store
+}
+
+func TestSuite(t *testing.T) {
+	storagetest.Test(t, newStorage(t))
+}
+
+func TestAtomic(t *testing.T) {
+	storagetest.TestAtomic(t, newStorage(t))
+}
+
+func TestVersions(t *testing.T) {
+	storagetest.TestVersions(t, newStorage(t))
+}
+
+func TestHistory(t *testing.T) {
+	store, err := eventsourced.New(eventsourced.NewMemoryLog())
+	common.FatalIfError(t, err, "creating storage")
+	storagetest.TestHistory(t, store)
+}
+
+func TestTokens(t *testing.T) {
+	store, err := eventsourced.New(eventsourced.NewMemoryLog())
+	common.FatalIfError(t, err, "creating storage")
+	storagetest.TestTokens(t, store)
+}
+
+func TestTenancy(t *testing.T) {
+	store, err := eventsourced.New(eventsourced.NewMemoryLog())
+	common.FatalIfError(t, err, "creating storage")
+	storagetest.TestTenancy(t, store)
+}
+
+func TestNew(t *testing.T) {
+	t.Run("nil log", func(t *testing.T) {
+		store, err := eventsourced.New(nil)
+		assert.Error(t, err)
+		assert.Nil(t, store)
+	})
+
+	t.Run("out of sequence events", func(t *testing.T) {
+		l := eventsourced.NewMemoryLog()
+		common.FatalIfError(t, l.Append(eventsourced.Event{Sequence: 2, Type: eventsourced.AccountDeleted}), "appending event")
+		store, err := eventsourced.New(l)
+		assert.Error(t, err)
+		assert.Nil(t, store)
+	})
+}
+
+func TestEventsourced_Events(t *testing.T) {
+	store, err := eventsourced.New(eventsourced.NewMemoryLog())
+	common.FatalIfError(t, err, "creating storage")
+
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	inserted, err := store.As("alice").InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+	b, err := store.As("bob").InsertBalance(inserted.ID, balance.Balance{Date: a.Opened(), Amount: 10}, "note")
+	common.FatalIfError(t, err, "inserting balance")
+	common.FatalIfError(t, store.As("carol").DeleteBalance(b.ID), "deleting balance")
+
+	_, err = store.UpdateBalance(inserted.ID, b.ID, balance.Balance{Date: a.Opened()}, "")
+	assert.Error(t, err, "updating deleted balance")
+
+	es, err := store.Events()
+	common.FatalIfError(t, err, "getting events")
+	if !assert.Len(t, es, 3) {
+		t.FailNow()
+	}
+	for i, expected := range []struct {
+		eventType eventsourced.EventType
+		actor     string
+	}{
+		{eventType: eventsourced.AccountInserted, actor: "alice"},
+		{eventType: eventsourced.BalanceInserted, actor: "bob"},
+		{eventType: eventsourced.BalanceDeleted, actor: "carol"},
+	} {
+		assert.Equal(t, uint64(i+1), es[i].Sequence)
+		assert.Equal(t, expected.eventType, es[i].Type)
+		assert.Equal(t, expected.actor, es[i].Actor)
+		assert.Equal(t, inserted.ID, es[i].AccountID)
+	}
+	assert.Equal(t, b.ID, es[2].BalanceID)
+}
+
+func TestEventsourced_SelectEvents(t *testing.T) {
+	store, err := eventsourced.New(eventsourced.NewMemoryLog())
+	common.FatalIfError(t, err, "creating storage")
+
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	inserted, err := store.As("alice").InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+	_, err = store.InsertToken("token", "alice", storage.RoleAdmin, "hash")
+	common.FatalIfError(t, err, "inserting token")
+	b, err := store.As("bob").InsertBalance(inserted.ID, balance.Balance{Date: a.Opened(), Amount: 10}, "note")
+	common.FatalIfError(t, err, "inserting balance")
+
+	es, err := store.SelectEvents(0, 0)
+	common.FatalIfError(t, err, "selecting events")
+	if !assert.Len(t, *es, 2, "events that change tokens should not be selected") {
+		t.FailNow()
+	}
+	assert.Equal(t, storage.Event{
+		Sequence:  1,
+		Type:      storage.AccountInserted,
+		Time:      (*es)[0].Time,
+		Actor:     "alice",
+		AccountID: inserted.ID,
+		Account:   a,
+	}, (*es)[0])
+	assert.Equal(t, uint64(3), (*es)[1].Sequence)
+	assert.Equal(t, "bob", (*es)[1].Actor)
+	assert.Equal(t, b.ID, (*es)[1].BalanceID)
+
+	es, err = store.SelectEvents(1, 0)
+	common.FatalIfError(t, err, "selecting events after first")
+	if assert.Len(t, *es, 1) {
+		assert.Equal(t, uint64(3), (*es)[0].Sequence)
+	}
+
+	es, err = store.SelectEvents(0, 1)
+	common.FatalIfError(t, err, "selecting limited events")
+	if assert.Len(t, *es, 1) {
+		assert.Equal(t, uint64(1), (*es)[0].Sequence)
+	}
+}
+
+func TestEventsourced_AtomicRollbackRecordsNoEvents(t *testing.T) {
+	store, err := eventsourced.New(eventsourced.NewMemoryLog())
+	common.FatalIfError(t, err, "creating storage")
+
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	err = store.Atomic(func(s storage.Storage) error {
+		_, err := s.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+
+	es, err := store.Events()
+	common.FatalIfError(t, err, "getting events")
+	assert.Len(t, es, 0)
+}
+
+func TestOpenFileLog_Replay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mon-eventsourced")
+	common.FatalIfError(t, err, "creating temp dir")
+	defer func() {
+		common.FatalIfError(t, os.RemoveAll(dir), "removing temp dir")
+	}()
+	path := filepath.Join(dir, "events.jsonl")
+
+	l, err := eventsourced.OpenFileLog(path)
+	common.FatalIfError(t, err, "opening log")
+	store, err := eventsourced.New(l)
+	common.FatalIfError(t, err, "creating storage")
+
+	_, err = eventsourced.OpenFileLog(path)
+	assert.Error(t, err, "opening log that is already open")
+
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	inserted, err := store.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+	updates := accountingtest.NewAccount(t, "B", accountingtest.NewCurrencyCode(t, "EUR"), a.Opened())
+	updated, err := store.UpdateAccount(inserted.ID, *updates)
+	common.FatalIfError(t, err, "updating account")
+	b, err := store.InsertBalance(inserted.ID, balance.Balance{Date: a.Opened(), Amount: 123}, "note")
+	common.FatalIfError(t, err, "inserting balance")
+	common.FatalIfError(t, store.Close(), "closing storage")
+
+	l, err = eventsourced.OpenFileLog(path)
+	common.FatalIfError(t, err, "reopening log")
+	reopened, err := eventsourced.New(l)
+	common.FatalIfError(t, err, "recreating storage")
+	defer func() {
+		common.FatalIfError(t, reopened.Close(), "closing storage")
+	}()
+
+	selected, err := reopened.SelectAccount(inserted.ID)
+	common.FatalIfError(t, err, "selecting account")
+	equal, err := updated.Equal(*selected)
+	common.FatalIfError(t, err, "equaling updated and selected")
+	assert.True(t, equal)
+
+	selectedB, err := reopened.SelectBalance(b.ID)
+	common.FatalIfError(t, err, "selecting balance")
+	assert.True(t, b.Equal(*selectedB))
+
+	es, err := reopened.Events()
+	common.FatalIfError(t, err, "getting events")
+	assert.Len(t, es, 3)
+
+	another, err := reopened.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account after replay")
+	assert.Equal(t, inserted.ID+1, another.ID)
+}
@@ -0,0 +1,44 @@
+package eventsourced
+
+import (
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// SelectAccountsAsOf returns the Accounts that were stored and not deleted at
+// the given time, as they were at that time, ordered by their ID.
+func (es *eventsourced) SelectAccountsAsOf(t time.Time) (*storage.Accounts, error) {
+	p, err := es.projectAsOf(t)
+	if err != nil {
+		return nil, err
+	}
+	return p.selectAccounts(), nil
+}
+
+// SelectAccountBalancesAsOf returns the Balances of the account with the given
+// id that were stored and not deleted at the given time, as they were at that
+// time. The Balances are sorted by chronological order then by their id.
+func (es *eventsourced) SelectAccountBalancesAsOf(accountID uint, t time.Time) (*storage.Balances, error) {
+	p, err := es.projectAsOf(t)
+	if err != nil {
+		return nil, err
+	}
+	return p.selectAccountBalances(accountID), nil
+}
+
+// projectAsOf returns the projection of every Event that was recorded at or
+// before the given time.
+func (es *eventsourced) projectAsOf(t time.Time) (*projection, error) {
+	all, err := es.Events()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting events")
+	}
+	var n int
+	for n < len(all) && !all[n].Time.After(t) {
+		n++
+	}
+	p, err := project(all[:n])
+	return p, errors.Wrap(err, "projecting events")
+}
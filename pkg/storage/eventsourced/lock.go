@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package eventsourced
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on the given file without waiting for it,
+// returning an error if the file is already locked by another process. The
+// lock is released when the file is closed.
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
@@ -0,0 +1,9 @@
+package eventsourced
+
+import "os"
+
+// lockFile is a noop on windows, so a file must not be opened as a Log by
+// more than one process at once.
+func lockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,111 @@
+package eventsourced
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Log is an append-only sequence of Events.
+type Log interface {
+	// Append adds the given Events to the end of the Log. Either all of the
+	// Events are appended or none of them are.
+	Append(es ...Event) error
+	// Events returns every Event in the Log, in the order that they were
+	// appended.
+	Events() ([]Event, error)
+}
+
+// NewMemoryLog returns a new, empty Log that holds its Events in memory.
+func NewMemoryLog() *memoryLog {
+	return &memoryLog{}
+}
+
+type memoryLog struct {
+	sync.RWMutex
+	events []Event
+}
+
+// Append adds the given Events to the end of the Log.
+func (l *memoryLog) Append(es ...Event) error {
+	l.Lock()
+	defer l.Unlock()
+	l.events = append(l.events, es...)
+	return nil
+}
+
+// Events returns a copy of every Event in the Log.
+func (l *memoryLog) Events() ([]Event, error) {
+	l.RLock()
+	defer l.RUnlock()
+	return append([]Event(nil), l.events...), nil
+}
+
+// OpenFileLog opens the Log held in the file at the given path, creating the
+// file if it does not exist. Events are stored in the file as JSON, one Event
+// per line. The file is locked until the Log is closed, as the Events that
+// are appended to it are numbered from those that it held when it was opened,
+// so OpenFileLog fails if the file is already held by another Log.
+func OpenFileLog(path string) (*fileLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening file at %s", path)
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, errors.Wrapf(err, "locking file at %s, which may be in use by another process", path)
+	}
+	return &fileLog{file: f}, nil
+}
+
+type fileLog struct {
+	sync.Mutex
+	file *os.File
+}
+
+// Append writes the given Events to the end of the file in a single write.
+func (l *fileLog) Append(es ...Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range es {
+		if err := enc.Encode(e); err != nil {
+			return errors.Wrapf(err, "encoding event %d", e.Sequence)
+		}
+	}
+	l.Lock()
+	defer l.Unlock()
+	if _, err := l.file.Write(buf.Bytes()); err != nil {
+		return errors.Wrap(err, "writing events")
+	}
+	return errors.Wrap(l.file.Sync(), "syncing file")
+}
+
+// Events reads every Event from the file.
+func (l *fileLog) Events() ([]Event, error) {
+	l.Lock()
+	defer l.Unlock()
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "seeking to start of file")
+	}
+	var es []Event
+	s := bufio.NewScanner(l.file)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e Event
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, errors.Wrapf(err, "decoding event %d", len(es)+1)
+		}
+		es = append(es, e)
+	}
+	return es, errors.Wrap(s.Err(), "reading events")
+}
+
+// Close closes the underlying file of the Log.
+func (l *fileLog) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,353 @@
+package eventsourced
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// projection is the current state of the storage, derived by applying every
+// Event of a Log in order. The methods of projection are not safe for
+// concurrent use.
+type projection struct {
+	accounts      []projectedAccount
+	balances      []projectedBalance
+	tokens        []projectedToken
+	lastSequence  uint64
+	lastAccountID uint
+	lastBalanceID uint
+	lastTokenID   uint
+}
+
+// The version of a projected account or balance is the number of Events that
+// have inserted or updated it. The shares of a projected account are sorted
+// and are replaced, rather than changed in place, so that they are not shared
+// with copies of the projection.
+type projectedAccount struct {
+	id      uint
+	version uint
+	account account.Account
+	deleted *time.Time
+	owner   string
+	shares  []string
+}
+
+type projectedBalance struct {
+	id        uint
+	version   uint
+	accountID uint
+	balance   balance.Balance
+	note      string
+	deleted   *time.Time
+}
+
+type projectedToken struct {
+	token storage.Token
+	hash  string
+}
+
+// project returns the projection that results from applying the given Events
+// in order.
+func project(es []Event) (*projection, error) {
+	p := &projection{}
+	for _, e := range es {
+		if err := p.apply(e); err != nil {
+			return nil, errors.Wrapf(err, "applying event %d", e.Sequence)
+		}
+	}
+	return p, nil
+}
+
+// apply updates the projection with the change recorded by the given Event.
+func (p *projection) apply(e Event) error {
+	if e.Sequence != p.lastSequence+1 {
+		return fmt.Errorf("expected event with sequence %d but got %d", p.lastSequence+1, e.Sequence)
+	}
+	switch e.Type {
+	case AccountInserted:
+		if e.Account == nil {
+			return errors.New("no account in event")
+		}
+		p.accounts = append(p.accounts, projectedAccount{id: e.AccountID, version: 1, account: *e.Account})
+		p.lastAccountID = e.AccountID
+	case AccountUpdated:
+		pa := p.findAccount(e.AccountID)
+		if pa == nil || e.Account == nil {
+			return fmt.Errorf("no account with id %d to update", e.AccountID)
+		}
+		pa.version++
+		pa.account = *e.Account
+	case AccountDeleted:
+		pa := p.findAccount(e.AccountID)
+		if pa == nil {
+			return fmt.Errorf("no account with id %d to delete", e.AccountID)
+		}
+		t := e.Time
+		pa.deleted = &t
+	case AccountUndeleted:
+		pa := p.findDeletedAccount(e.AccountID)
+		if pa == nil {
+			return fmt.Errorf("no deleted account with id %d to undelete", e.AccountID)
+		}
+		pa.deleted = nil
+	case BalanceInserted:
+		if e.Balance == nil {
+			return errors.New("no balance in event")
+		}
+		p.balances = append(p.balances, projectedBalance{
+			id:        e.BalanceID,
+			version:   1,
+			accountID: e.AccountID,
+			balance:   *e.Balance,
+			note:      e.Note,
+		})
+		p.lastBalanceID = e.BalanceID
+	case BalanceUpdated:
+		pb := p.findBalance(e.BalanceID)
+		if pb == nil || e.Balance == nil {
+			return fmt.Errorf("no balance with id %d to update", e.BalanceID)
+		}
+		pb.version++
+		pb.balance = *e.Balance
+		pb.note = e.Note
+	case BalanceDeleted:
+		pb := p.findBalance(e.BalanceID)
+		if pb == nil {
+			return fmt.Errorf("no balance with id %d to delete", e.BalanceID)
+		}
+		t := e.Time
+		pb.deleted = &t
+	case BalanceUndeleted:
+		pb := p.findDeletedBalance(e.BalanceID)
+		if pb == nil {
+			return fmt.Errorf("no deleted balance with id %d to undelete", e.BalanceID)
+		}
+		pb.deleted = nil
+	case TokenInserted:
+		if e.Token == nil {
+			return errors.New("no token in event")
+		}
+		p.tokens = append(p.tokens, projectedToken{token: *e.Token, hash: e.TokenHash})
+		p.lastTokenID = e.Token.ID
+	case TokenRevoked:
+		pt := p.findToken(e.TokenID)
+		if pt == nil {
+			return fmt.Errorf("no unrevoked token with id %d to revoke", e.TokenID)
+		}
+		t := e.Time
+		pt.token.Revoked = &t
+	case AccountOwnerSet:
+		pa := p.findAnyAccount(e.AccountID)
+		if pa == nil {
+			return fmt.Errorf("no account with id %d to set the owner of", e.AccountID)
+		}
+		pa.owner = e.User
+	case AccountShared:
+		pa := p.findAnyAccount(e.AccountID)
+		if pa == nil {
+			return fmt.Errorf("no account with id %d to share", e.AccountID)
+		}
+		shares := append(append([]string(nil), pa.shares...), e.User)
+		sort.Strings(shares)
+		pa.shares = shares
+	case AccountUnshared:
+		pa := p.findAnyAccount(e.AccountID)
+		if pa == nil || !pa.sharedWith(e.User) {
+			return fmt.Errorf("no account with id %d shared with user %q to unshare", e.AccountID, e.User)
+		}
+		var shares []string
+		for _, u := range pa.shares {
+			if u != e.User {
+				shares = append(shares, u)
+			}
+		}
+		pa.shares = shares
+	default:
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+	p.lastSequence = e.Sequence
+	return nil
+}
+
+// copy returns a copy of the projection that can have Events applied to it
+// without affecting the original.
+func (p projection) copy() projection {
+	c := p
+	c.accounts = append([]projectedAccount(nil), p.accounts...)
+	c.balances = append([]projectedBalance(nil), p.balances...)
+	c.tokens = append([]projectedToken(nil), p.tokens...)
+	return c
+}
+
+// findAccount returns a pointer to the projected account with the given id,
+// or nil if no account exists or the account has been deleted.
+func (p *projection) findAccount(id uint) *projectedAccount {
+	for i := range p.accounts {
+		if p.accounts[i].id == id && p.accounts[i].deleted == nil {
+			return &p.accounts[i]
+		}
+	}
+	return nil
+}
+
+// findAnyAccount returns a pointer to the projected account with the given
+// id, whether or not it has been deleted, or nil if no account exists.
+func (p *projection) findAnyAccount(id uint) *projectedAccount {
+	for i := range p.accounts {
+		if p.accounts[i].id == id {
+			return &p.accounts[i]
+		}
+	}
+	return nil
+}
+
+// findBalance returns a pointer to the projected balance with the given id,
+// or nil if no balance exists or the balance has been deleted.
+func (p *projection) findBalance(id uint) *projectedBalance {
+	for i := range p.balances {
+		if p.balances[i].id == id && p.balances[i].deleted == nil {
+			return &p.balances[i]
+		}
+	}
+	return nil
+}
+
+// findToken returns a pointer to the projected token with the given id, or
+// nil if no token exists or the token has been revoked.
+func (p *projection) findToken(id uint) *projectedToken {
+	for i := range p.tokens {
+		if p.tokens[i].token.ID == id && p.tokens[i].token.Revoked == nil {
+			return &p.tokens[i]
+		}
+	}
+	return nil
+}
+
+// findReachableBalance returns a pointer to the projected balance with the
+// given id, or nil if no balance exists or either the balance or its account
+// has been deleted. Events recorded before balances were archived with their
+// accounts may refer to balances that are no longer reachable, so findBalance
+// must be used when applying Events.
+func (p *projection) findReachableBalance(id uint) *projectedBalance {
+	pb := p.findBalance(id)
+	if pb == nil || p.findAccount(pb.accountID) == nil {
+		return nil
+	}
+	return pb
+}
+
+// findDeletedAccount returns a pointer to the projected account with the
+// given id, or nil if no account exists or the account has not been deleted.
+func (p *projection) findDeletedAccount(id uint) *projectedAccount {
+	for i := range p.accounts {
+		if p.accounts[i].id == id && p.accounts[i].deleted != nil {
+			return &p.accounts[i]
+		}
+	}
+	return nil
+}
+
+// findDeletedBalance returns a pointer to the projected balance with the
+// given id, or nil if no balance exists or the balance has not been deleted.
+func (p *projection) findDeletedBalance(id uint) *projectedBalance {
+	for i := range p.balances {
+		if p.balances[i].id == id && p.balances[i].deleted != nil {
+			return &p.balances[i]
+		}
+	}
+	return nil
+}
+
+func (p *projection) selectDeletedAccounts() *storage.Accounts {
+	as := storage.Accounts{}
+	for _, pa := range p.accounts {
+		if pa.deleted != nil {
+			as = append(as, pa.storageAccount())
+		}
+	}
+	return &as
+}
+
+func (p *projection) selectDeletedBalances() *storage.Balances {
+	bs := storage.Balances{}
+	for _, pb := range p.balances {
+		if pb.deleted != nil {
+			bs = append(bs, pb.storageBalance())
+		}
+	}
+	return &bs
+}
+
+func (p *projection) selectAccounts() *storage.Accounts {
+	as := storage.Accounts{}
+	for _, pa := range p.accounts {
+		if pa.deleted == nil {
+			as = append(as, pa.storageAccount())
+		}
+	}
+	return &as
+}
+
+// selectAccountBalances returns the live balances of the account with the
+// given id, sorted by chronological order then by the id of the balance.
+func (p *projection) selectAccountBalances(accountID uint) *storage.Balances {
+	bs := storage.Balances{}
+	if p.findAccount(accountID) == nil {
+		// the balances of a deleted account are archived with it
+		return &bs
+	}
+	for _, pb := range p.balances {
+		if pb.accountID == accountID && pb.deleted == nil {
+			bs = append(bs, pb.storageBalance())
+		}
+	}
+	sort.Slice(bs, func(i, j int) bool {
+		if !bs[i].Date.Equal(bs[j].Date) {
+			return bs[i].Date.Before(bs[j].Date)
+		}
+		return bs[i].ID < bs[j].ID
+	})
+	return &bs
+}
+
+// sharedWith returns true if the projected account has been shared with the
+// given user.
+func (pa projectedAccount) sharedWith(user string) bool {
+	for _, u := range pa.shares {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+// storageAccount returns the projected account as a storage.Account. The
+// options used to mark an account as deleted never return an error.
+func (pa projectedAccount) storageAccount() storage.Account {
+	a := storage.Account{ID: pa.id, Version: pa.version, Account: pa.account}
+	if pa.deleted != nil {
+		_ = storage.DeletedAt(*pa.deleted)(&a)
+	}
+	return a
+}
+
+// storageBalance returns the projected balance as a storage.Balance. The
+// options used to mark a balance as deleted never return an error.
+func (pb projectedBalance) storageBalance() storage.Balance {
+	b := storage.Balance{
+		ID:        pb.id,
+		Version:   pb.version,
+		AccountID: pb.accountID,
+		Balance:   pb.balance,
+		Note:      pb.note,
+	}
+	if pb.deleted != nil {
+		_ = storage.BalanceDeletedAt(*pb.deleted)(&b)
+	}
+	return b
+}
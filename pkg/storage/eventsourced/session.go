@@ -0,0 +1,224 @@
+package eventsourced
+
+import (
+	"time"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// session is a storage.Storage that records each change as an Event, applying
+// it to a projection once it has been committed. The methods of session are
+// not safe for concurrent use.
+type session struct {
+	*projection
+	actor  string
+	commit func(Event) error
+}
+
+// Available returns true as a session is always available.
+func (s *session) Available() bool {
+	return true
+}
+
+// Close is a noop for a session.
+func (s *session) Close() error {
+	return nil
+}
+
+// InsertAccount records the insertion of an account.Account and returns it.
+func (s *session) InsertAccount(a account.Account) (*storage.Account, error) {
+	id := s.lastAccountID + 1
+	err := s.record(Event{Type: AccountInserted, AccountID: id, Account: &a})
+	if err != nil {
+		return nil, errors.Wrap(err, "recording account insertion")
+	}
+	return s.SelectAccount(id)
+}
+
+// SelectAccount returns the Account with the given id.
+func (s *session) SelectAccount(id uint) (*storage.Account, error) {
+	pa := s.findAccount(id)
+	if pa == nil {
+		return nil, storage.NotFoundf("no account with id %d", id)
+	}
+	a := pa.storageAccount()
+	return &a, nil
+}
+
+// SelectAccounts returns all of the Accounts that have not been deleted,
+// ordered by their ID.
+func (s *session) SelectAccounts() (*storage.Accounts, error) {
+	return s.selectAccounts(), nil
+}
+
+// UpdateAccount records the update of the account with the given id to the
+// values of the given account.Account.
+func (s *session) UpdateAccount(id uint, updates account.Account) (*storage.Account, error) {
+	if s.findAccount(id) == nil {
+		return nil, storage.NotFoundf("no account with id %d", id)
+	}
+	err := s.record(Event{Type: AccountUpdated, AccountID: id, Account: &updates})
+	if err != nil {
+		return nil, errors.Wrap(err, "recording account update")
+	}
+	return s.SelectAccount(id)
+}
+
+// DeleteAccount records the deletion of the account with the given id,
+// treating the live balances of the account according to the given
+// storage.DeletionPolicy. When balances are cascaded, the deletion of each is
+// recorded before the deletion of the account.
+func (s *session) DeleteAccount(id uint, policy storage.DeletionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if s.findAccount(id) == nil {
+		return storage.NotFoundf("no account with id %d", id)
+	}
+	bs := *s.selectAccountBalances(id)
+	switch {
+	case len(bs) > 0 && policy == storage.DeletionRefuse:
+		return storage.Conflictf("account %d has live balances", id)
+	case policy == storage.DeletionCascade:
+		for _, b := range bs {
+			if err := s.DeleteBalance(b.ID); err != nil {
+				return errors.Wrapf(err, "cascading deletion to balance %d", b.ID)
+			}
+		}
+	}
+	return errors.Wrap(
+		s.record(Event{Type: AccountDeleted, AccountID: id}),
+		"recording account deletion",
+	)
+}
+
+// SelectBalance returns the Balance with the given id.
+func (s *session) SelectBalance(id uint) (*storage.Balance, error) {
+	pb := s.findReachableBalance(id)
+	if pb == nil {
+		return nil, storage.NotFoundf("no balance with id %d", id)
+	}
+	b := pb.storageBalance()
+	return &b, nil
+}
+
+// InsertBalance records the insertion of a Balance for the account with the
+// given ID.
+func (s *session) InsertBalance(accountID uint, b balance.Balance, note string) (*storage.Balance, error) {
+	if s.findAccount(accountID) == nil {
+		return nil, storage.NotFoundf("no account with id %d", accountID)
+	}
+	id := s.lastBalanceID + 1
+	err := s.record(Event{
+		Type:      BalanceInserted,
+		AccountID: accountID,
+		BalanceID: id,
+		Balance:   &b,
+		Note:      note,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "recording balance insertion")
+	}
+	return s.SelectBalance(id)
+}
+
+// SelectAccountBalances returns all Balances for a given account ID. The
+// Balances are sorted by chronological order then by the id of the Balance.
+func (s *session) SelectAccountBalances(id uint) (*storage.Balances, error) {
+	return s.selectAccountBalances(id), nil
+}
+
+// UpdateBalance records the update of the balance with the given id, that
+// belongs to the account with the given accountID.
+func (s *session) UpdateBalance(accountID, id uint, b balance.Balance, note string) (*storage.Balance, error) {
+	pb := s.findReachableBalance(id)
+	if pb == nil || pb.accountID != accountID {
+		return nil, storage.NotFoundf("no balance with id %d for account %d", id, accountID)
+	}
+	err := s.record(Event{
+		Type:      BalanceUpdated,
+		AccountID: accountID,
+		BalanceID: id,
+		Balance:   &b,
+		Note:      note,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "recording balance update")
+	}
+	return s.SelectBalance(id)
+}
+
+// DeleteBalance records the deletion of the balance with the given id.
+func (s *session) DeleteBalance(id uint) error {
+	pb := s.findReachableBalance(id)
+	if pb == nil {
+		return storage.NotFoundf("no balance with id %d", id)
+	}
+	return errors.Wrap(
+		s.record(Event{Type: BalanceDeleted, AccountID: pb.accountID, BalanceID: id}),
+		"recording balance deletion",
+	)
+}
+
+// SelectDeletedAccounts returns all of the Accounts that have been deleted,
+// ordered by their ID.
+func (s *session) SelectDeletedAccounts() (*storage.Accounts, error) {
+	return s.selectDeletedAccounts(), nil
+}
+
+// UndeleteAccount records the restoration of the deleted account with the
+// given id.
+func (s *session) UndeleteAccount(id uint) (*storage.Account, error) {
+	if s.findDeletedAccount(id) == nil {
+		return nil, storage.NotFoundf("no deleted account with id %d", id)
+	}
+	err := s.record(Event{Type: AccountUndeleted, AccountID: id})
+	if err != nil {
+		return nil, errors.Wrap(err, "recording account undeletion")
+	}
+	return s.SelectAccount(id)
+}
+
+// SelectDeletedBalances returns all of the Balances that have been deleted,
+// ordered by their ID.
+func (s *session) SelectDeletedBalances() (*storage.Balances, error) {
+	return s.selectDeletedBalances(), nil
+}
+
+// UndeleteBalance records the restoration of the deleted balance with the
+// given id. A balance cannot be restored whilst its account is deleted.
+func (s *session) UndeleteBalance(id uint) (*storage.Balance, error) {
+	pb := s.findDeletedBalance(id)
+	if pb == nil {
+		return nil, storage.NotFoundf("no deleted balance with id %d", id)
+	}
+	if s.findAccount(pb.accountID) == nil {
+		return nil, storage.Conflictf("cannot undelete balance %d whilst account %d is deleted", id, pb.accountID)
+	}
+	err := s.record(Event{Type: BalanceUndeleted, AccountID: pb.accountID, BalanceID: id})
+	if err != nil {
+		return nil, errors.Wrap(err, "recording balance undeletion")
+	}
+	return s.SelectBalance(id)
+}
+
+// Atomic runs fn against the session itself, as a session already belongs to
+// a single set of changes.
+func (s *session) Atomic(fn func(storage.Storage) error) error {
+	return fn(s)
+}
+
+// record completes the given Event, commits it and then applies it to the
+// projection of the session.
+func (s *session) record(e Event) error {
+	e.Sequence = s.lastSequence + 1
+	e.Time = time.Now()
+	e.Actor = s.actor
+	if err := s.commit(e); err != nil {
+		return errors.Wrap(err, "committing event")
+	}
+	return s.apply(e)
+}
@@ -0,0 +1,115 @@
+package eventsourced
+
+import (
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// SetAccountOwner records the given user as the owner of the account with
+// the given id.
+func (es *eventsourced) SetAccountOwner(accountID uint, user string) error {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().SetAccountOwner(accountID, user)
+}
+
+// SelectUserAccountIDs returns the ids of the accounts that are owned by the
+// given user and the ids of those that have been shared with the user.
+func (es *eventsourced) SelectUserAccountIDs(user string) ([]uint, []uint, error) {
+	es.RLock()
+	defer es.RUnlock()
+	return es.session().SelectUserAccountIDs(user)
+}
+
+// ShareAccount records the sharing of the account with the given id with the
+// given user.
+func (es *eventsourced) ShareAccount(accountID uint, user string) error {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().ShareAccount(accountID, user)
+}
+
+// UnshareAccount records that the account with the given id is no longer
+// shared with the given user.
+func (es *eventsourced) UnshareAccount(accountID uint, user string) error {
+	es.Lock()
+	defer es.Unlock()
+	return es.session().UnshareAccount(accountID, user)
+}
+
+// SelectAccountShares returns the users that the account with the given id has
+// been shared with.
+func (es *eventsourced) SelectAccountShares(accountID uint) ([]string, error) {
+	es.RLock()
+	defer es.RUnlock()
+	return es.session().SelectAccountShares(accountID)
+}
+
+// SetAccountOwner records the given user as the owner of the account with
+// the given id.
+func (s *session) SetAccountOwner(accountID uint, user string) error {
+	if s.findAnyAccount(accountID) == nil {
+		return storage.NotFoundf("no account with id %d", accountID)
+	}
+	return errors.Wrap(
+		s.record(Event{Type: AccountOwnerSet, AccountID: accountID, User: user}),
+		"recording account owner",
+	)
+}
+
+// SelectUserAccountIDs returns the ids of the accounts that are owned by the
+// given user and the ids of those that have been shared with the user.
+func (s *session) SelectUserAccountIDs(user string) ([]uint, []uint, error) {
+	var owned, shared []uint
+	for _, pa := range s.accounts {
+		switch {
+		case pa.owner == user:
+			owned = append(owned, pa.id)
+		case pa.sharedWith(user):
+			shared = append(shared, pa.id)
+		}
+	}
+	return owned, shared, nil
+}
+
+// ShareAccount records the sharing of the account with the given id with the
+// given user, unless it has already been shared with them.
+func (s *session) ShareAccount(accountID uint, user string) error {
+	pa := s.findAnyAccount(accountID)
+	if pa == nil {
+		return storage.NotFoundf("no account with id %d", accountID)
+	}
+	if pa.sharedWith(user) {
+		return nil
+	}
+	return errors.Wrap(
+		s.record(Event{Type: AccountShared, AccountID: accountID, User: user}),
+		"recording account share",
+	)
+}
+
+// UnshareAccount records that the account with the given id is no longer
+// shared with the given user.
+func (s *session) UnshareAccount(accountID uint, user string) error {
+	pa := s.findAnyAccount(accountID)
+	if pa == nil {
+		return storage.NotFoundf("no account with id %d", accountID)
+	}
+	if !pa.sharedWith(user) {
+		return storage.NotFoundf("account %d is not shared with user %q", accountID, user)
+	}
+	return errors.Wrap(
+		s.record(Event{Type: AccountUnshared, AccountID: accountID, User: user}),
+		"recording account unshare",
+	)
+}
+
+// SelectAccountShares returns the users that the account with the given id has
+// been shared with.
+func (s *session) SelectAccountShares(accountID uint) ([]string, error) {
+	pa := s.findAnyAccount(accountID)
+	if pa == nil {
+		return nil, storage.NotFoundf("no account with id %d", accountID)
+	}
+	return append([]string{}, pa.shares...), nil
+}
@@ -0,0 +1,59 @@
+package eventsourced
+
+import (
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// InsertToken records the insertion of a Token with the given name, user,
+// role and hash of its secret, and returns it.
+func (es *eventsourced) InsertToken(name, user string, role storage.Role, hash string) (*storage.Token, error) {
+	es.Lock()
+	defer es.Unlock()
+	t := storage.Token{ID: es.state.lastTokenID + 1, Name: name, User: user, Role: role, Created: time.Now()}
+	err := es.session().record(Event{Type: TokenInserted, TokenID: t.ID, Token: &t, TokenHash: hash})
+	if err != nil {
+		return nil, errors.Wrap(err, "recording token insertion")
+	}
+	inserted := t
+	return &inserted, nil
+}
+
+// SelectTokens returns every Token, including those that have been revoked,
+// ordered by their ID.
+func (es *eventsourced) SelectTokens() (*storage.Tokens, error) {
+	es.RLock()
+	defer es.RUnlock()
+	ts := storage.Tokens{}
+	for _, pt := range es.state.tokens {
+		ts = append(ts, pt.token)
+	}
+	return &ts, nil
+}
+
+// SelectTokenByHash returns the Token that has the given hash and has not
+// been revoked.
+func (es *eventsourced) SelectTokenByHash(hash string) (*storage.Token, error) {
+	es.RLock()
+	defer es.RUnlock()
+	for _, pt := range es.state.tokens {
+		if pt.hash == hash && pt.token.Revoked == nil {
+			t := pt.token
+			return &t, nil
+		}
+	}
+	return nil, storage.NotFoundf("no token with the given hash")
+}
+
+// RevokeToken records the revocation of the Token with the given id.
+func (es *eventsourced) RevokeToken(id uint) error {
+	es.Lock()
+	defer es.Unlock()
+	if es.state.findToken(id) == nil {
+		return storage.NotFoundf("no token with id %d", id)
+	}
+	err := es.session().record(Event{Type: TokenRevoked, TokenID: id})
+	return errors.Wrap(err, "recording token revocation")
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IdempotentRequest is the record of a request that was made with an
+// idempotency key. Once the request has been served, it holds the response
+// to the request so that the same response can be given to any repeat of the
+// request that is made with the same key.
+type IdempotentRequest struct {
+	// User is the user that made the request and Key is the idempotency key
+	// that it was made with. There is at most one IdempotentRequest for each
+	// User and Key.
+	User string
+	Key  string
+	// Fingerprint identifies the method, path and body of the request, so
+	// that a key that is reused for a different request can be detected.
+	Fingerprint string
+	// Reservation is unique to each time that the request is reserved, so
+	// that a request whose reservation has been taken over by a repeat of
+	// the request can no longer complete or release it.
+	Reservation string
+	Created     time.Time
+	// Status is the status code of the response to the request, which is
+	// zero while the request is being served.
+	Status int
+	// Location and ETag hold the headers of the response, each of which is
+	// empty if the response did not have it, and Body holds its body.
+	Location string
+	ETag     string
+	Body     json.RawMessage `json:",omitempty"`
+}
+
+// IdempotencyStore is implemented by a Storage that is able to hold the
+// responses to requests that were made with idempotency keys.
+type IdempotencyStore interface {
+	// ReserveIdempotentRequest stores the given IdempotentRequest, with its
+	// Created time set and without a response, and returns it along with
+	// true. If there is already an IdempotentRequest with the same User and
+	// Key that was created after the given time, it is returned along with
+	// false instead, unless it is without a response and was created at or
+	// before the given reservedAfter time, in which case the request that it
+	// was reserved for is taken to have been abandoned and it is replaced.
+	// Every IdempotentRequest that was created at or before the given time is
+	// deleted.
+	ReserveIdempotentRequest(r IdempotentRequest, after, reservedAfter time.Time) (*IdempotentRequest, bool, error)
+	// CompleteIdempotentRequest stores the Status, Location, ETag and Body
+	// of the given IdempotentRequest as the response to the reserved
+	// IdempotentRequest with the same User, Key and Reservation. The error is
+	// of KindNotFound if there is no such IdempotentRequest that is without a
+	// response, which is the case once the reservation has been taken over.
+	CompleteIdempotentRequest(r IdempotentRequest) error
+	// ReleaseIdempotentRequest deletes the IdempotentRequest with the given
+	// user, key and reservation if it is without a response, so that the
+	// request can be made again with the same key. It should be used when a
+	// request fails.
+	ReleaseIdempotentRequest(user, key, reservation string) error
+}
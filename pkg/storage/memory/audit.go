@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// InsertAuditEntry stores the given AuditEntry, returning it with its ID and
+// Time set.
+func (m *memory) InsertAuditEntry(e storage.AuditEntry) (*storage.AuditEntry, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.lastAuditID++
+	e.ID = m.lastAuditID
+	e.Time = time.Now()
+	m.audit = append(m.audit, e)
+	return &e, nil
+}
+
+// SelectAuditEntries returns the AuditEntries that match the given
+// storage.AuditQuery.
+func (m *memory) SelectAuditEntries(q storage.AuditQuery) (*storage.AuditEntries, error) {
+	switch q.Order {
+	case "", storage.OrderAscending, storage.OrderDescending:
+	default:
+		return nil, storage.Invalidf("unsupported order: %q", q.Order)
+	}
+	m.RLock()
+	defer m.RUnlock()
+	matched := storage.AuditEntries{}
+	for _, e := range m.audit {
+		if matchesAuditQuery(e, q) {
+			matched = append(matched, e)
+		}
+	}
+	if q.Order == storage.OrderDescending {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+	if q.Offset >= uint(len(matched)) {
+		return &storage.AuditEntries{}, nil
+	}
+	matched = matched[q.Offset:]
+	if q.Limit > 0 && q.Limit < uint(len(matched)) {
+		matched = matched[:q.Limit]
+	}
+	return &matched, nil
+}
+
+// matchesAuditQuery returns true if the given AuditEntry matches the filters
+// of the storage.AuditQuery, ignoring its Order, Offset and Limit.
+func matchesAuditQuery(e storage.AuditEntry, q storage.AuditQuery) bool {
+	switch {
+	case q.User != nil && e.User != *q.User,
+		q.Route != "" && e.Route != q.Route,
+		q.AccountID != 0 && e.AccountID != q.AccountID,
+		q.BalanceID != 0 && e.BalanceID != q.BalanceID,
+		q.From != nil && e.Time.Before(*q.From),
+		q.To != nil && e.Time.After(*q.To):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// accountVersion is a previous version of a stored account, that was valid
+// from validFrom until validTo.
+type accountVersion struct {
+	version            uint
+	account            account.Account
+	validFrom, validTo time.Time
+}
+
+// balanceVersion is a previous version of a stored balance, that was valid
+// from validFrom until validTo.
+type balanceVersion struct {
+	version            uint
+	balance            balance.Balance
+	note               string
+	validFrom, validTo time.Time
+}
+
+// SelectAccountsAsOf returns the Accounts that were stored and not deleted at
+// the given time, as they were at that time, ordered by their ID.
+func (m *memory) SelectAccountsAsOf(t time.Time) (*storage.Accounts, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.data.selectAccountsAsOf(t)
+}
+
+// SelectAccountBalancesAsOf returns the Balances of the account with the given
+// id that were stored and not deleted at the given time, as they were at that
+// time. The Balances are sorted by chronological order then by their id.
+func (m *memory) SelectAccountBalancesAsOf(accountID uint, t time.Time) (*storage.Balances, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.data.selectAccountBalancesAsOf(accountID, t)
+}
+
+func (d *data) selectAccountsAsOf(t time.Time) (*storage.Accounts, error) {
+	as := storage.Accounts{}
+	for _, sa := range d.accounts {
+		a, ok := sa.accountAt(t)
+		if !ok {
+			continue
+		}
+		as = append(as, *a)
+	}
+	return &as, nil
+}
+
+func (d *data) selectAccountBalancesAsOf(accountID uint, t time.Time) (*storage.Balances, error) {
+	bs := storage.Balances{}
+	for _, sb := range d.balances {
+		if sb.accountID != accountID {
+			continue
+		}
+		b, ok := sb.balanceAt(t)
+		if !ok {
+			continue
+		}
+		bs = append(bs, *b)
+	}
+	sortBalances(bs)
+	return &bs, nil
+}
+
+// accountAt returns the version of the account that was valid at the given
+// time, or false if the account was not stored or had been deleted.
+func (sa storedAccount) accountAt(t time.Time) (*storage.Account, bool) {
+	if sa.deleted != nil && !sa.deleted.After(t) {
+		return nil, false
+	}
+	if !sa.validFrom.After(t) {
+		return &storage.Account{ID: sa.id, Version: sa.version, Account: sa.account}, true
+	}
+	for _, v := range sa.history {
+		if !v.validFrom.After(t) && v.validTo.After(t) {
+			return &storage.Account{ID: sa.id, Version: v.version, Account: v.account}, true
+		}
+	}
+	return nil, false
+}
+
+// balanceAt returns the version of the balance that was valid at the given
+// time, or false if the balance was not stored or had been deleted.
+func (sb storedBalance) balanceAt(t time.Time) (*storage.Balance, bool) {
+	if sb.deleted != nil && !sb.deleted.After(t) {
+		return nil, false
+	}
+	at := func(version uint, b balance.Balance, note string) *storage.Balance {
+		return &storage.Balance{ID: sb.id, Version: version, AccountID: sb.accountID, Balance: b, Note: note}
+	}
+	if !sb.validFrom.After(t) {
+		return at(sb.version, sb.balance, sb.note), true
+	}
+	for _, v := range sb.history {
+		if !v.validFrom.After(t) && v.validTo.After(t) {
+			return at(v.version, v.balance, v.note), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// ReserveIdempotentRequest stores the given IdempotentRequest without a
+// response, unless there is already an IdempotentRequest with the same User
+// and Key that was created after the given time, in which case that one is
+// returned along with false. An IdempotentRequest without a response that was
+// created at or before reservedAfter is replaced.
+func (m *memory) ReserveIdempotentRequest(r storage.IdempotentRequest, after, reservedAfter time.Time) (*storage.IdempotentRequest, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	live := m.idempotent[:0]
+	for _, ir := range m.idempotent {
+		if ir.Created.After(after) && (ir.Status != 0 || ir.Created.After(reservedAfter)) {
+			live = append(live, ir)
+		}
+	}
+	m.idempotent = live
+	if i := m.findIdempotentRequest(r.User, r.Key); i >= 0 {
+		existing := m.idempotent[i]
+		return &existing, false, nil
+	}
+	r.Created = time.Now()
+	r.Status, r.Location, r.ETag, r.Body = 0, "", "", nil
+	m.idempotent = append(m.idempotent, r)
+	return &r, true, nil
+}
+
+// CompleteIdempotentRequest stores the response of the given
+// IdempotentRequest for the reserved IdempotentRequest with the same User,
+// Key and Reservation.
+func (m *memory) CompleteIdempotentRequest(r storage.IdempotentRequest) error {
+	m.Lock()
+	defer m.Unlock()
+	i := m.findIdempotentRequest(r.User, r.Key)
+	if i < 0 || m.idempotent[i].Status != 0 || m.idempotent[i].Reservation != r.Reservation {
+		return storage.NotFoundf("no reserved request with key %q", r.Key)
+	}
+	ir := &m.idempotent[i]
+	ir.Status, ir.Location, ir.ETag, ir.Body = r.Status, r.Location, r.ETag, r.Body
+	return nil
+}
+
+// ReleaseIdempotentRequest deletes the IdempotentRequest with the given user,
+// key and reservation if it is without a response.
+func (m *memory) ReleaseIdempotentRequest(user, key, reservation string) error {
+	m.Lock()
+	defer m.Unlock()
+	if i := m.findIdempotentRequest(user, key); i >= 0 && m.idempotent[i].Status == 0 && m.idempotent[i].Reservation == reservation {
+		m.idempotent = append(m.idempotent[:i], m.idempotent[i+1:]...)
+	}
+	return nil
+}
+
+// findIdempotentRequest returns the index of the IdempotentRequest with the
+// given user and key, or -1 if there is none.
+func (d *data) findIdempotentRequest(user, key string) int {
+	for i, ir := range d.idempotent {
+		if ir.User == user && ir.Key == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,368 @@
+// Package memory provides a storage.Storage implementation that holds all of
+// its data in memory. It is useful for local runs and for tests but any data
+// held will be lost when the process exits.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// New returns a new, empty, in-memory Storage.
+func New() *memory {
+	return &memory{}
+}
+
+// memory is safe for concurrent use.
+type memory struct {
+	sync.RWMutex
+	data
+}
+
+type storedAccount struct {
+	id        uint
+	version   uint
+	account   account.Account
+	owner     string
+	shares    []string
+	validFrom time.Time
+	deleted   *time.Time
+	history   []accountVersion
+}
+
+type storedBalance struct {
+	id        uint
+	version   uint
+	accountID uint
+	balance   balance.Balance
+	note      string
+	validFrom time.Time
+	deleted   *time.Time
+	history   []balanceVersion
+}
+
+// data holds the state of a memory Storage. The methods of data are not safe
+// for concurrent use.
+type data struct {
+	accounts      []storedAccount
+	balances      []storedBalance
+	tokens        []storedToken
+	audit         storage.AuditEntries
+	idempotent    []storage.IdempotentRequest
+	lastAccountID uint
+	lastBalanceID uint
+	lastTokenID   uint
+	lastAuditID   uint
+}
+
+// Available returns true if the Storage is available. A memory Storage is
+// always available.
+func (m *memory) Available() bool {
+	return true
+}
+
+// Close is a noop as there are no resources to release for a memory Storage.
+func (m *memory) Close() error {
+	return nil
+}
+
+// InsertAccount inserts an account.Account in the Storage and returns it.
+func (m *memory) InsertAccount(a account.Account) (*storage.Account, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.insertAccount(a)
+}
+
+// SelectAccount returns an Account with the given id.
+func (m *memory) SelectAccount(id uint) (*storage.Account, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.data.selectAccount(id)
+}
+
+// UpdateAccount updates the account at a given id with the values from the
+// given account.Account
+func (m *memory) UpdateAccount(id uint, updates account.Account) (*storage.Account, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.updateAccount(id, updates)
+}
+
+// SelectAccounts returns all of the Accounts that have not been deleted,
+// ordered by their ID.
+func (m *memory) SelectAccounts() (*storage.Accounts, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.data.selectAccounts()
+}
+
+// DeleteAccount deletes an account with the given id, treating the live
+// balances of the account according to the given storage.DeletionPolicy.
+func (m *memory) DeleteAccount(id uint, policy storage.DeletionPolicy) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.deleteAccount(id, policy)
+}
+
+// SelectBalance returns the Balance with the given id.
+func (m *memory) SelectBalance(id uint) (*storage.Balance, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.data.selectBalance(id)
+}
+
+// InsertBalance inserts a Balance for the account with the given ID.
+func (m *memory) InsertBalance(accountID uint, b balance.Balance, note string) (*storage.Balance, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.insertBalance(accountID, b, note)
+}
+
+// SelectAccountBalances returns all Balances for a given account ID. The
+// Balances are sorted by chronological order then by the id of the Balance.
+func (m *memory) SelectAccountBalances(id uint) (*storage.Balances, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.data.selectAccountBalances(id)
+}
+
+// UpdateBalance updates the balance with the given id, that belongs to the
+// account with the given accountID, to hold the given balance.Balance and note.
+func (m *memory) UpdateBalance(accountID, id uint, b balance.Balance, note string) (*storage.Balance, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.updateBalance(accountID, id, b, note)
+}
+
+// DeleteBalance deletes a balance with the given id
+func (m *memory) DeleteBalance(id uint) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.deleteBalance(id)
+}
+
+// Atomic runs fn against a copy of the data held in the Storage. The copy
+// replaces the data held only if fn returns a nil error. All other operations
+// on the Storage are blocked until fn has returned.
+func (m *memory) Atomic(fn func(storage.Storage) error) error {
+	m.Lock()
+	defer m.Unlock()
+	d := m.data.copy()
+	err := fn(&transaction{data: &d})
+	if err != nil {
+		return err
+	}
+	m.data = d
+	return nil
+}
+
+// copy returns a copy of the data that can be modified without affecting the
+// original.
+func (d data) copy() data {
+	c := d
+	c.accounts = append([]storedAccount(nil), d.accounts...)
+	c.balances = append([]storedBalance(nil), d.balances...)
+	c.tokens = append([]storedToken(nil), d.tokens...)
+	c.audit = append(storage.AuditEntries(nil), d.audit...)
+	c.idempotent = append([]storage.IdempotentRequest(nil), d.idempotent...)
+	return c
+}
+
+func (d *data) insertAccount(a account.Account) (*storage.Account, error) {
+	d.lastAccountID++
+	sa := storedAccount{id: d.lastAccountID, version: 1, account: a, validFrom: time.Now()}
+	d.accounts = append(d.accounts, sa)
+	return sa.storageAccount()
+}
+
+func (d *data) selectAccount(id uint) (*storage.Account, error) {
+	sa := d.findAccount(id)
+	if sa == nil {
+		return nil, storage.NotFoundf("no account with id %d", id)
+	}
+	return sa.storageAccount()
+}
+
+func (d *data) updateAccount(id uint, updates account.Account) (*storage.Account, error) {
+	sa := d.findAccount(id)
+	if sa == nil {
+		return nil, storage.NotFoundf("no account with id %d", id)
+	}
+	now := time.Now()
+	sa.history = append(sa.history, accountVersion{
+		version:   sa.version,
+		account:   sa.account,
+		validFrom: sa.validFrom,
+		validTo:   now,
+	})
+	sa.version++
+	sa.account = updates
+	sa.validFrom = now
+	return sa.storageAccount()
+}
+
+func (d *data) selectAccounts() (*storage.Accounts, error) {
+	as := storage.Accounts{}
+	for _, sa := range d.accounts {
+		if sa.deleted != nil {
+			continue
+		}
+		a, err := sa.storageAccount()
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating storage account for id %d", sa.id)
+		}
+		as = append(as, *a)
+	}
+	return &as, nil
+}
+
+func (d *data) deleteAccount(id uint, policy storage.DeletionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	sa := d.findAccount(id)
+	if sa == nil {
+		return storage.NotFoundf("no account with id %d", id)
+	}
+	now := time.Now()
+	for i := range d.balances {
+		sb := &d.balances[i]
+		if sb.accountID != id || sb.deleted != nil {
+			continue
+		}
+		switch policy {
+		case storage.DeletionRefuse:
+			return storage.Conflictf("account %d has live balances", id)
+		case storage.DeletionCascade:
+			sb.deleted = &now
+		}
+	}
+	sa.deleted = &now
+	return nil
+}
+
+func (d *data) selectBalance(id uint) (*storage.Balance, error) {
+	sb := d.findBalance(id)
+	if sb == nil {
+		return nil, storage.NotFoundf("no balance with id %d", id)
+	}
+	return sb.storageBalance(), nil
+}
+
+func (d *data) insertBalance(accountID uint, b balance.Balance, note string) (*storage.Balance, error) {
+	if d.findAccount(accountID) == nil {
+		return nil, storage.NotFoundf("no account with id %d", accountID)
+	}
+	d.lastBalanceID++
+	sb := storedBalance{
+		id:        d.lastBalanceID,
+		version:   1,
+		accountID: accountID,
+		balance:   b,
+		note:      note,
+		validFrom: time.Now(),
+	}
+	d.balances = append(d.balances, sb)
+	return sb.storageBalance(), nil
+}
+
+func (d *data) selectAccountBalances(id uint) (*storage.Balances, error) {
+	bs := storage.Balances{}
+	if d.findAccount(id) == nil {
+		// the balances of a deleted account are archived with it
+		return &bs, nil
+	}
+	for _, sb := range d.balances {
+		if sb.accountID != id || sb.deleted != nil {
+			continue
+		}
+		bs = append(bs, *sb.storageBalance())
+	}
+	sortBalances(bs)
+	return &bs, nil
+}
+
+func (d *data) updateBalance(accountID, id uint, b balance.Balance, note string) (*storage.Balance, error) {
+	sb := d.findBalance(id)
+	if sb == nil || sb.accountID != accountID {
+		return nil, storage.NotFoundf("no balance with id %d for account %d", id, accountID)
+	}
+	now := time.Now()
+	sb.history = append(sb.history, balanceVersion{
+		version:   sb.version,
+		balance:   sb.balance,
+		note:      sb.note,
+		validFrom: sb.validFrom,
+		validTo:   now,
+	})
+	sb.version++
+	sb.balance = b
+	sb.note = note
+	sb.validFrom = now
+	return sb.storageBalance(), nil
+}
+
+func (d *data) deleteBalance(id uint) error {
+	sb := d.findBalance(id)
+	if sb == nil {
+		return storage.NotFoundf("no balance with id %d", id)
+	}
+	now := time.Now()
+	sb.deleted = &now
+	return nil
+}
+
+// findAccount returns a pointer to the stored account with the given id,
+// or nil if no account exists or the account has been deleted.
+func (d *data) findAccount(id uint) *storedAccount {
+	for i := range d.accounts {
+		if d.accounts[i].id == id && d.accounts[i].deleted == nil {
+			return &d.accounts[i]
+		}
+	}
+	return nil
+}
+
+// findBalance returns a pointer to the stored balance with the given id, or
+// nil if no balance exists or either the balance or its account has been
+// deleted.
+func (d *data) findBalance(id uint) *storedBalance {
+	for i := range d.balances {
+		sb := &d.balances[i]
+		if sb.id == id && sb.deleted == nil && d.findAccount(sb.accountID) != nil {
+			return sb
+		}
+	}
+	return nil
+}
+
+func (sa storedAccount) storageAccount() (*storage.Account, error) {
+	a := &storage.Account{ID: sa.id, Version: sa.version, Account: sa.account}
+	if sa.deleted != nil {
+		err := storage.DeletedAt(*sa.deleted)(a)
+		if err != nil {
+			return nil, errors.Wrap(err, "applying deleted time to account")
+		}
+	}
+	return a, nil
+}
+
+func (sb storedBalance) storageBalance() *storage.Balance {
+	b := &storage.Balance{
+		ID:        sb.id,
+		Version:   sb.version,
+		AccountID: sb.accountID,
+		Balance:   sb.balance,
+		Note:      sb.note,
+	}
+	if sb.deleted != nil {
+		// BalanceDeletedAt never returns an error
+		_ = storage.BalanceDeletedAt(*sb.deleted)(b)
+	}
+	return b
+}
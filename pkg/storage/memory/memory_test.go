@@ -0,0 +1,98 @@
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/memory"
+	"github.com/glynternet/mon/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// This line ensures that a memory Storage can be assigned to a
+// storage.Storage variable and, therefore, ensures that it satisfies the
+// storage.Storage interface
+var _ storage.Storage = memory.New()
+
+func TestSuite(t *testing.T) {
+	storagetest.Test(t, memory.New())
+}
+
+func TestAtomic(t *testing.T) {
+	storagetest.TestAtomic(t, memory.New())
+}
+
+func TestHistory(t *testing.T) {
+	storagetest.TestHistory(t, memory.New())
+}
+
+func TestVersions(t *testing.T) {
+	storagetest.TestVersions(t, memory.New())
+}
+
+func TestPurge(t *testing.T) {
+	storagetest.TestPurge(t, memory.New())
+}
+
+func TestTokens(t *testing.T) {
+	storagetest.TestTokens(t, memory.New())
+}
+
+func TestTenancy(t *testing.T) {
+	storagetest.TestTenancy(t, memory.New())
+}
+
+func TestAuditLog(t *testing.T) {
+	storagetest.TestAuditLog(t, memory.New())
+}
+
+func TestIdempotency(t *testing.T) {
+	storagetest.TestIdempotency(t, memory.New())
+}
+
+func TestMemory_DeletedAccount(t *testing.T) {
+	store := memory.New()
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	inserted, err := store.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+
+	common.FatalIfError(t, store.DeleteAccount(inserted.ID, storage.DeletionRefuse), "deleting account")
+
+	selected, err := store.SelectAccount(inserted.ID)
+	assert.Error(t, err)
+	assert.Nil(t, selected)
+
+	assert.Error(t, store.DeleteAccount(inserted.ID, storage.DeletionRefuse), "deleting an already deleted account")
+
+	_, err = store.InsertBalance(inserted.ID, balance.Balance{Date: a.Opened()}, "")
+	assert.Error(t, err, "inserting balance for deleted account")
+}
+
+func TestMemory_BalanceOrder(t *testing.T) {
+	store := memory.New()
+	now := time.Now()
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), now)
+	inserted, err := store.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+
+	for _, b := range []balance.Balance{
+		{Date: now.Add(2 * time.Hour), Amount: 1},
+		{Date: now.Add(time.Hour), Amount: 2},
+		{Date: now.Add(2 * time.Hour), Amount: 3},
+	} {
+		_, err := store.InsertBalance(inserted.ID, b, "")
+		common.FatalIfError(t, err, "inserting balance")
+	}
+
+	bs, err := store.SelectAccountBalances(inserted.ID)
+	common.FatalIfError(t, err, "selecting balances")
+	var amounts []int
+	for _, b := range *bs {
+		amounts = append(amounts, b.Amount)
+	}
+	assert.Equal(t, []int{2, 1, 3}, amounts)
+}
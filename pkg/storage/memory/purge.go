@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// Purge permanently removes the accounts and balances that were deleted
+// before the given time, along with every balance of a purged account.
+func (m *memory) Purge(before time.Time) (*storage.Purged, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.purge(before), nil
+}
+
+func (d *data) purge(before time.Time) *storage.Purged {
+	purgedAccounts := make(map[uint]bool)
+	var as []storedAccount
+	for _, sa := range d.accounts {
+		if deletedBefore(sa.deleted, before) {
+			purgedAccounts[sa.id] = true
+			continue
+		}
+		as = append(as, sa)
+	}
+	var bs []storedBalance
+	for _, sb := range d.balances {
+		if purgedAccounts[sb.accountID] || deletedBefore(sb.deleted, before) {
+			continue
+		}
+		bs = append(bs, sb)
+	}
+	p := &storage.Purged{
+		Accounts: len(d.accounts) - len(as),
+		Balances: len(d.balances) - len(bs),
+	}
+	d.accounts, d.balances = as, bs
+	return p
+}
+
+func deletedBefore(deleted *time.Time, t time.Time) bool {
+	return deleted != nil && deleted.Before(t)
+}
@@ -0,0 +1,18 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// sortBalances sorts Balances into chronological order, then by the id of the
+// Balance, matching the order that other storage implementations provide.
+func sortBalances(bs storage.Balances) {
+	sort.Slice(bs, func(i, j int) bool {
+		if !bs[i].Date.Equal(bs[j].Date) {
+			return bs[i].Date.Before(bs[j].Date)
+		}
+		return bs[i].ID < bs[j].ID
+	})
+}
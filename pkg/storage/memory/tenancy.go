@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// SetAccountOwner records the given user as the owner of the account with
+// the given id.
+func (m *memory) SetAccountOwner(accountID uint, user string) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.setAccountOwner(accountID, user)
+}
+
+// SelectUserAccountIDs returns the ids of the accounts that are owned by the
+// given user and the ids of those that have been shared with the user.
+func (m *memory) SelectUserAccountIDs(user string) ([]uint, []uint, error) {
+	m.RLock()
+	defer m.RUnlock()
+	owned, shared := m.data.selectUserAccountIDs(user)
+	return owned, shared, nil
+}
+
+// ShareAccount shares the account with the given id with the given user.
+func (m *memory) ShareAccount(accountID uint, user string) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.shareAccount(accountID, user)
+}
+
+// UnshareAccount stops sharing the account with the given id with the given
+// user.
+func (m *memory) UnshareAccount(accountID uint, user string) error {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.unshareAccount(accountID, user)
+}
+
+// SelectAccountShares returns the users that the account with the given id has
+// been shared with.
+func (m *memory) SelectAccountShares(accountID uint) ([]string, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.data.selectAccountShares(accountID)
+}
+
+// findAnyAccount returns the stored account with the given id, whether or not
+// it has been deleted.
+func (d *data) findAnyAccount(id uint) *storedAccount {
+	for i := range d.accounts {
+		if d.accounts[i].id == id {
+			return &d.accounts[i]
+		}
+	}
+	return nil
+}
+
+func (d *data) setAccountOwner(accountID uint, user string) error {
+	sa := d.findAnyAccount(accountID)
+	if sa == nil {
+		return storage.NotFoundf("no account with id %d", accountID)
+	}
+	sa.owner = user
+	return nil
+}
+
+func (d *data) selectUserAccountIDs(user string) ([]uint, []uint) {
+	var owned, shared []uint
+	for _, sa := range d.accounts {
+		if sa.owner == user {
+			owned = append(owned, sa.id)
+			continue
+		}
+		for _, u := range sa.shares {
+			if u == user {
+				shared = append(shared, sa.id)
+				break
+			}
+		}
+	}
+	return owned, shared
+}
+
+func (d *data) shareAccount(accountID uint, user string) error {
+	sa := d.findAnyAccount(accountID)
+	if sa == nil {
+		return storage.NotFoundf("no account with id %d", accountID)
+	}
+	for _, u := range sa.shares {
+		if u == user {
+			return nil
+		}
+	}
+	// shares is replaced rather than appended to in place so that it is not
+	// shared with copies of the data.
+	shares := append(append([]string(nil), sa.shares...), user)
+	sort.Strings(shares)
+	sa.shares = shares
+	return nil
+}
+
+func (d *data) unshareAccount(accountID uint, user string) error {
+	sa := d.findAnyAccount(accountID)
+	if sa == nil {
+		return storage.NotFoundf("no account with id %d", accountID)
+	}
+	var shares []string
+	for _, u := range sa.shares {
+		if u != user {
+			shares = append(shares, u)
+		}
+	}
+	if len(shares) == len(sa.shares) {
+		return storage.NotFoundf("account %d is not shared with user %q", accountID, user)
+	}
+	sa.shares = shares
+	return nil
+}
+
+func (d *data) selectAccountShares(accountID uint) ([]string, error) {
+	sa := d.findAnyAccount(accountID)
+	if sa == nil {
+		return nil, storage.NotFoundf("no account with id %d", accountID)
+	}
+	return append([]string{}, sa.shares...), nil
+}
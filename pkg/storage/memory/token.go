@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+type storedToken struct {
+	token storage.Token
+	hash  string
+}
+
+// InsertToken stores a Token with the given name, user, role and hash of its
+// secret.
+func (m *memory) InsertToken(name, user string, role storage.Role, hash string) (*storage.Token, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.lastTokenID++
+	t := storage.Token{ID: m.lastTokenID, Name: name, User: user, Role: role, Created: time.Now()}
+	m.tokens = append(m.tokens, storedToken{token: t, hash: hash})
+	return &t, nil
+}
+
+// SelectTokens returns every Token, including those that have been revoked,
+// ordered by their ID.
+func (m *memory) SelectTokens() (*storage.Tokens, error) {
+	m.RLock()
+	defer m.RUnlock()
+	ts := storage.Tokens{}
+	for _, st := range m.tokens {
+		ts = append(ts, st.token)
+	}
+	return &ts, nil
+}
+
+// SelectTokenByHash returns the Token that has the given hash and has not
+// been revoked.
+func (m *memory) SelectTokenByHash(hash string) (*storage.Token, error) {
+	m.RLock()
+	defer m.RUnlock()
+	for _, st := range m.tokens {
+		if st.hash == hash && st.token.Revoked == nil {
+			t := st.token
+			return &t, nil
+		}
+	}
+	return nil, storage.NotFoundf("no token with the given hash")
+}
+
+// RevokeToken revokes the Token with the given id.
+func (m *memory) RevokeToken(id uint) error {
+	m.Lock()
+	defer m.Unlock()
+	for i := range m.tokens {
+		t := &m.tokens[i].token
+		if t.ID == id && t.Revoked == nil {
+			now := time.Now()
+			t.Revoked = &now
+			return nil
+		}
+	}
+	return storage.NotFoundf("no token with id %d", id)
+}
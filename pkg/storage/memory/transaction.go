@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// transaction is the Storage provided to the function given to Atomic. The
+// memory Storage that created the transaction holds its lock for the lifetime
+// of the transaction, so the transaction itself does not need to lock.
+type transaction struct {
+	*data
+}
+
+// Available returns true if the Storage is available.
+func (t *transaction) Available() bool { return true }
+
+// Close is a noop for a transaction.
+func (t *transaction) Close() error { return nil }
+
+// InsertAccount inserts an account.Account in the Storage and returns it.
+func (t *transaction) InsertAccount(a account.Account) (*storage.Account, error) {
+	return t.data.insertAccount(a)
+}
+
+// SelectAccount returns an Account with the given id.
+func (t *transaction) SelectAccount(id uint) (*storage.Account, error) {
+	return t.data.selectAccount(id)
+}
+
+// UpdateAccount updates the account at a given id with the values from the
+// given account.Account
+func (t *transaction) UpdateAccount(id uint, updates account.Account) (*storage.Account, error) {
+	return t.data.updateAccount(id, updates)
+}
+
+// SelectAccounts returns all of the Accounts that have not been deleted.
+func (t *transaction) SelectAccounts() (*storage.Accounts, error) {
+	return t.data.selectAccounts()
+}
+
+// DeleteAccount deletes an account with the given id, treating the live
+// balances of the account according to the given storage.DeletionPolicy.
+func (t *transaction) DeleteAccount(id uint, policy storage.DeletionPolicy) error {
+	return t.data.deleteAccount(id, policy)
+}
+
+// SelectBalance returns the Balance with the given id.
+func (t *transaction) SelectBalance(id uint) (*storage.Balance, error) {
+	return t.data.selectBalance(id)
+}
+
+// InsertBalance inserts a Balance for the account with the given ID.
+func (t *transaction) InsertBalance(accountID uint, b balance.Balance, note string) (*storage.Balance, error) {
+	return t.data.insertBalance(accountID, b, note)
+}
+
+// SelectAccountBalances returns all Balances for a given account ID.
+func (t *transaction) SelectAccountBalances(id uint) (*storage.Balances, error) {
+	return t.data.selectAccountBalances(id)
+}
+
+// UpdateBalance updates the balance with the given id, that belongs to the
+// account with the given accountID.
+func (t *transaction) UpdateBalance(accountID, id uint, b balance.Balance, note string) (*storage.Balance, error) {
+	return t.data.updateBalance(accountID, id, b, note)
+}
+
+// DeleteBalance deletes a balance with the given id
+func (t *transaction) DeleteBalance(id uint) error {
+	return t.data.deleteBalance(id)
+}
+
+// Atomic runs fn as part of the existing transaction.
+func (t *transaction) Atomic(fn func(storage.Storage) error) error {
+	return fn(t)
+}
+
+// SelectDeletedAccounts returns all of the Accounts that have been deleted.
+func (t *transaction) SelectDeletedAccounts() (*storage.Accounts, error) {
+	return t.data.selectDeletedAccounts()
+}
+
+// UndeleteAccount restores the deleted account with the given id.
+func (t *transaction) UndeleteAccount(id uint) (*storage.Account, error) {
+	return t.data.undeleteAccount(id)
+}
+
+// SelectDeletedBalances returns all of the Balances that have been deleted.
+func (t *transaction) SelectDeletedBalances() (*storage.Balances, error) {
+	return t.data.selectDeletedBalances()
+}
+
+// UndeleteBalance restores the deleted balance with the given id.
+func (t *transaction) UndeleteBalance(id uint) (*storage.Balance, error) {
+	return t.data.undeleteBalance(id)
+}
+
+// SetAccountOwner records the given user as the owner of the account with
+// the given id.
+func (t *transaction) SetAccountOwner(accountID uint, user string) error {
+	return t.data.setAccountOwner(accountID, user)
+}
+
+// SelectUserAccountIDs returns the ids of the accounts that are owned by the
+// given user and the ids of those that have been shared with the user.
+func (t *transaction) SelectUserAccountIDs(user string) ([]uint, []uint, error) {
+	owned, shared := t.data.selectUserAccountIDs(user)
+	return owned, shared, nil
+}
+
+// ShareAccount shares the account with the given id with the given user.
+func (t *transaction) ShareAccount(accountID uint, user string) error {
+	return t.data.shareAccount(accountID, user)
+}
+
+// UnshareAccount stops sharing the account with the given id with the given
+// user.
+func (t *transaction) UnshareAccount(accountID uint, user string) error {
+	return t.data.unshareAccount(accountID, user)
+}
+
+// SelectAccountShares returns the users that the account with the given id has
+// been shared with.
+func (t *transaction) SelectAccountShares(accountID uint) ([]string, error) {
+	return t.data.selectAccountShares(accountID)
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+// SelectDeletedAccounts returns all of the Accounts that have been deleted,
+// ordered by their ID.
+func (m *memory) SelectDeletedAccounts() (*storage.Accounts, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.data.selectDeletedAccounts()
+}
+
+// UndeleteAccount restores the deleted account with the given id.
+func (m *memory) UndeleteAccount(id uint) (*storage.Account, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.undeleteAccount(id)
+}
+
+// SelectDeletedBalances returns all of the Balances that have been deleted,
+// ordered by their ID.
+func (m *memory) SelectDeletedBalances() (*storage.Balances, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return m.data.selectDeletedBalances()
+}
+
+// UndeleteBalance restores the deleted balance with the given id. A balance
+// cannot be restored whilst its account is deleted.
+func (m *memory) UndeleteBalance(id uint) (*storage.Balance, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.data.undeleteBalance(id)
+}
+
+func (d *data) selectDeletedAccounts() (*storage.Accounts, error) {
+	as := storage.Accounts{}
+	for _, sa := range d.accounts {
+		if sa.deleted == nil {
+			continue
+		}
+		a, err := sa.storageAccount()
+		if err != nil {
+			return nil, errors.Wrapf(err, "creating storage account for id %d", sa.id)
+		}
+		as = append(as, *a)
+	}
+	return &as, nil
+}
+
+// undeleteAccount restores a deleted account. The version of the account that
+// was deleted is kept in the history of the account as being valid only until
+// it was deleted.
+func (d *data) undeleteAccount(id uint) (*storage.Account, error) {
+	for i := range d.accounts {
+		sa := &d.accounts[i]
+		if sa.id != id || sa.deleted == nil {
+			continue
+		}
+		sa.history = append(sa.history, accountVersion{
+			version:   sa.version,
+			account:   sa.account,
+			validFrom: sa.validFrom,
+			validTo:   *sa.deleted,
+		})
+		sa.validFrom = time.Now()
+		sa.deleted = nil
+		return sa.storageAccount()
+	}
+	return nil, storage.NotFoundf("no deleted account with id %d", id)
+}
+
+func (d *data) selectDeletedBalances() (*storage.Balances, error) {
+	bs := storage.Balances{}
+	for _, sb := range d.balances {
+		if sb.deleted != nil {
+			bs = append(bs, *sb.storageBalance())
+		}
+	}
+	return &bs, nil
+}
+
+// undeleteBalance restores a deleted balance. The version of the balance that
+// was deleted is kept in the history of the balance as being valid only until
+// it was deleted.
+func (d *data) undeleteBalance(id uint) (*storage.Balance, error) {
+	for i := range d.balances {
+		sb := &d.balances[i]
+		if sb.id != id || sb.deleted == nil {
+			continue
+		}
+		if d.findAccount(sb.accountID) == nil {
+			return nil, storage.Conflictf("cannot undelete balance %d whilst account %d is deleted", id, sb.accountID)
+		}
+		sb.history = append(sb.history, balanceVersion{
+			version:   sb.version,
+			balance:   sb.balance,
+			note:      sb.note,
+			validFrom: sb.validFrom,
+			validTo:   *sb.deleted,
+		})
+		sb.validFrom = time.Now()
+		sb.deleted = nil
+		return sb.storageBalance(), nil
+	}
+	return nil, storage.NotFoundf("no deleted balance with id %d", id)
+}
@@ -0,0 +1,76 @@
+package storage
+
+// The optional interfaces of a Storage, such as History and AccountQuerier,
+// are found with the functions below rather than with a type assertion. A
+// Storage that wraps another, like the one returned by ForUser, can only
+// provide the optional interfaces that the wrapped Storage provides, so it
+// gives each of them through a method, such as AsHistory, instead of
+// implementing them itself.
+
+// AsHistory returns the History of the given Storage, which is either the
+// Storage itself or the History given by its own AsHistory method. The bool is
+// false if the Storage provides no History.
+func AsHistory(s Storage) (History, bool) {
+	if h, ok := s.(History); ok {
+		return h, true
+	}
+	if p, ok := s.(interface{ AsHistory() (History, bool) }); ok {
+		return p.AsHistory()
+	}
+	return nil, false
+}
+
+// AsAccountQuerier returns the AccountQuerier of the given Storage, which is
+// either the Storage itself or the AccountQuerier given by its own
+// AsAccountQuerier method. The bool is false if the Storage provides no
+// AccountQuerier.
+func AsAccountQuerier(s Storage) (AccountQuerier, bool) {
+	if aq, ok := s.(AccountQuerier); ok {
+		return aq, true
+	}
+	if p, ok := s.(interface{ AsAccountQuerier() (AccountQuerier, bool) }); ok {
+		return p.AsAccountQuerier()
+	}
+	return nil, false
+}
+
+// AsBalanceQuerier returns the BalanceQuerier of the given Storage, which is
+// either the Storage itself or the BalanceQuerier given by its own
+// AsBalanceQuerier method. The bool is false if the Storage provides no
+// BalanceQuerier.
+func AsBalanceQuerier(s Storage) (BalanceQuerier, bool) {
+	if bq, ok := s.(BalanceQuerier); ok {
+		return bq, true
+	}
+	if p, ok := s.(interface{ AsBalanceQuerier() (BalanceQuerier, bool) }); ok {
+		return p.AsBalanceQuerier()
+	}
+	return nil, false
+}
+
+// AsBalanceSummer returns the BalanceSummer of the given Storage, which is
+// either the Storage itself or the BalanceSummer given by its own
+// AsBalanceSummer method. The bool is false if the Storage provides no
+// BalanceSummer.
+func AsBalanceSummer(s Storage) (BalanceSummer, bool) {
+	if bs, ok := s.(BalanceSummer); ok {
+		return bs, true
+	}
+	if p, ok := s.(interface{ AsBalanceSummer() (BalanceSummer, bool) }); ok {
+		return p.AsBalanceSummer()
+	}
+	return nil, false
+}
+
+// AsEventLog returns the EventLog of the given Storage, which is either the
+// Storage itself or the EventLog given by its own AsEventLog method. The bool
+// is false if the Storage provides no EventLog.
+func AsEventLog(s Storage) (EventLog, bool) {
+	if el, ok := s.(EventLog); ok {
+		return el, true
+	}
+	if p, ok := s.(interface{ AsEventLog() (EventLog, bool) }); ok {
+		return p.AsEventLog()
+	}
+	return nil, false
+}
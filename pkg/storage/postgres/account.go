@@ -19,6 +19,7 @@ const (
 	fieldClosed   = "closed"
 	fieldCurrency = "currency"
 	fieldDeleted  = "deleted"
+	fieldVersion  = "version"
 	accountsTable = "accounts"
 )
 
@@ -31,13 +32,14 @@ var (
 		fieldCurrency)
 
 	accountsFieldsSelect = fmt.Sprintf(
-		"%s, %s, %s, %s, %s, %s",
+		"%s, %s, %s, %s, %s, %s, %s",
 		fieldID,
 		fieldName,
 		fieldOpened,
 		fieldClosed,
 		fieldCurrency,
-		fieldDeleted)
+		fieldDeleted,
+		fieldVersion)
 
 	accountsSelectPrefix = fmt.Sprintf(
 		`SELECT %s FROM %s WHERE %s IS NULL `,
@@ -62,20 +64,22 @@ var (
 		accountsFieldsSelect)
 
 	queryUpdateAccount = fmt.Sprintf(
-		`UPDATE %s SET %s = $1, %s = $2, %s = $3, %s = $4 WHERE %s = $5 returning %s`,
+		`UPDATE %s SET %s = $1, %s = $2, %s = $3, %s = $4, %[6]s = %[6]s + 1 WHERE %s = $5 returning %s`,
 		accountsTable,
 		fieldName,
 		fieldOpened,
 		fieldClosed,
 		fieldCurrency,
+		fieldVersion,
 		fieldID,
 		accountsFieldsSelect)
 
 	queryDeleteAccount = fmt.Sprintf(
-		`UPDATE %s SET %s = $1 WHERE %s = $2`,
+		`UPDATE %s SET %s = $1 WHERE %s = $2 AND %s IS NULL`,
 		accountsTable,
 		fieldDeleted,
 		fieldID,
+		fieldDeleted,
 	)
 )
 
@@ -83,25 +87,25 @@ var (
 // the given database along with any errors that occurred whilst attempting to
 // retrieve the Accounts.
 func (pg postgres) SelectAccounts() (*storage.Accounts, error) {
-	return queryAccounts(pg.db, querySelectAccounts)
+	return queryAccounts(pg.q, querySelectAccounts)
 }
 
 // SelectAccount returns an Account with the given id.
 func (pg postgres) SelectAccount(id uint) (*storage.Account, error) {
-	dba, err := queryAccount(pg.db, querySelectAccount, id)
+	dba, err := queryAccount(pg.q, querySelectAccount, id)
 	return dba, errors.Wrap(err, "querying Account")
 }
 
 // InsertAccount inserts an account.Account in the storage backend and returns it.
 func (pg postgres) InsertAccount(a account.Account) (*storage.Account, error) {
-	dba, err := queryAccount(pg.db, queryInsertAccount, a.Name(), a.Opened(), pq.NullTime(a.Closed()), a.CurrencyCode())
+	dba, err := queryAccount(pg.q, queryInsertAccount, a.Name(), a.Opened(), pq.NullTime(a.Closed()), a.CurrencyCode())
 	return dba, errors.Wrap(err, "querying Account")
 }
 
 // UpdateAccount updates the account at a given id with the values from the given account.Account
 func (pg postgres) UpdateAccount(id uint, updates account.Account) (*storage.Account, error) {
 	return queryAccount(
-		pg.db,
+		pg.q,
 		queryUpdateAccount,
 		updates.Name(),
 		updates.Opened(),
@@ -111,33 +115,54 @@ func (pg postgres) UpdateAccount(id uint, updates account.Account) (*storage.Acc
 	)
 }
 
-// DeleteAccount deletes an account with the given id
-func (pg postgres) DeleteAccount(id uint) error {
-	r, err := pg.db.Exec(queryDeleteAccount, time.Now(), id)
-	if err != nil {
-		return errors.Wrap(err, "executing query")
+// DeleteAccount deletes an account with the given id, treating the live
+// balances of the account according to the given storage.DeletionPolicy.
+func (pg postgres) DeleteAccount(id uint, policy storage.DeletionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
 	}
-	n, err := r.RowsAffected()
-	if err != nil {
-		return errors.Wrap(err, "getting number of rows affected ")
-	}
-	if n != 1 {
-		return fmt.Errorf("expected 1 affected row but got %d", n)
-	}
-	return nil
+	now := time.Now()
+	return pg.transact(func(q queryer) error {
+		switch policy {
+		case storage.DeletionRefuse:
+			bs, err := queryBalances(q, balancesSelectBalancesForAccountID, id)
+			if err != nil {
+				return errors.Wrap(err, "selecting account balances")
+			}
+			if len(*bs) > 0 {
+				return storage.Conflictf("account %d has live balances", id)
+			}
+		case storage.DeletionCascade:
+			if _, err := q.Exec(balancesDeleteAccountBalances, now, id); err != nil {
+				return errors.Wrap(err, "cascading deletion to balances")
+			}
+		}
+		r, err := q.Exec(queryDeleteAccount, now, id)
+		if err != nil {
+			return errors.Wrap(err, "executing query")
+		}
+		n, err := r.RowsAffected()
+		if err != nil {
+			return errors.Wrap(err, "getting number of rows affected ")
+		}
+		if n != 1 {
+			return storage.NotFoundf("no account with id %d", id)
+		}
+		return nil
+	})
 }
 
 // do not export this function.
 // The use of interface{} here is deemed to be acceptable here because it is only used within the
 // context of this package
-func queryAccount(db *sql.DB, queryString string, values ...interface{}) (*storage.Account, error) {
+func queryAccount(db queryer, queryString string, values ...interface{}) (*storage.Account, error) {
 	as, err := queryAccounts(db, queryString, values...)
 	if err != nil {
 		return nil, errors.Wrap(err, "querying accounts")
 	}
 	resLen := len(*as)
 	if resLen == 0 {
-		return nil, errors.New("query returned no accounts")
+		return nil, storage.NotFoundf("query returned no accounts")
 	}
 	if resLen > 1 {
 		return nil, fmt.Errorf("expected 1 account but query returned %d", resLen)
@@ -145,10 +170,10 @@ func queryAccount(db *sql.DB, queryString string, values ...interface{}) (*stora
 	return &(*as)[0], nil
 }
 
-func queryAccounts(db *sql.DB, queryString string, values ...interface{}) (*storage.Accounts, error) {
+func queryAccounts(db queryer, queryString string, values ...interface{}) (*storage.Accounts, error) {
 	rows, err := db.Query(queryString, values...)
 	if err != nil {
-		return nil, err
+		return nil, unavailable(err)
 	}
 	defer nonReturningCloseRows(rows)
 	return scanRowsForAccounts(rows)
@@ -159,12 +184,12 @@ func queryAccounts(db *sql.DB, queryString string, values ...interface{}) (*stor
 func scanRowsForAccounts(rows *sql.Rows) (*storage.Accounts, error) {
 	var openAccounts storage.Accounts
 	for rows.Next() {
-		var id uint
+		var id, version uint
 		var name, code string
 		var opened time.Time
 		var closed, deleted pq.NullTime
-		// 	fieldID, fieldName, fieldOpened, fieldClosed, fieldCurrency, fieldDeleted)
-		err := rows.Scan(&id, &name, &opened, &closed, &code, &deleted)
+		// 	fieldID, fieldName, fieldOpened, fieldClosed, fieldCurrency, fieldDeleted, fieldVersion)
+		err := rows.Scan(&id, &name, &opened, &closed, &code, &deleted, &version)
 		if err != nil {
 			return nil, errors.Wrap(err, "scanning row")
 		}
@@ -182,7 +207,7 @@ func scanRowsForAccounts(rows *sql.Rows) (*storage.Accounts, error) {
 				return nil, errors.Wrap(err, "applying closed time to inner account")
 			}
 		}
-		a := &storage.Account{ID: id, Account: *innerAccount}
+		a := &storage.Account{ID: id, Version: version, Account: *innerAccount}
 		if deleted.Valid {
 			err := storage.DeletedAt(deleted.Time)(a)
 			if err != nil {
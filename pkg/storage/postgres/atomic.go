@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// codeSerializationFailure is the code of the error that a transaction fails
+// with when it would change a row that has been changed by another
+// transaction since the transaction began.
+const codeSerializationFailure = "40001"
+
+// Atomic runs fn within a single database transaction. The transaction is
+// committed if fn returns a nil error and rolled back otherwise.
+// If the postgres is already within a transaction, fn is run as part of that
+// transaction.
+// The transaction sees the data as it was when the transaction began. If fn
+// changes a record that has been changed by another transaction since then,
+// the error is of storage.KindStale.
+func (pg postgres) Atomic(fn func(storage.Storage) error) error {
+	return pg.transact(func(q queryer) error {
+		return fn(&postgres{db: pg.db, q: q})
+	})
+}
+
+// transact runs fn with a queryer for a single database transaction, in the
+// same way as Atomic.
+func (pg postgres) transact(fn func(queryer) error) error {
+	if _, ok := pg.q.(*sql.Tx); ok {
+		return fn(pg.q)
+	}
+	tx, err := pg.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return errors.Wrap(unavailable(err), "beginning transaction")
+	}
+	err = fn(tx)
+	if err != nil {
+		return rollback(tx, stale(err))
+	}
+	return errors.Wrap(stale(tx.Commit()), "committing transaction")
+}
+
+// stale returns an Error of storage.KindStale if err was caused by a
+// serialization failure, or err otherwise.
+func stale(err error) error {
+	if pqErr, ok := errors.Cause(err).(*pq.Error); ok && pqErr.Code == codeSerializationFailure {
+		return storage.Stalef("changed by another transaction: %v", err)
+	}
+	return err
+}
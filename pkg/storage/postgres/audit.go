@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	auditTable          = "audit_entries"
+	auditFieldTime      = "time"
+	auditFieldTokenID   = "token_id"
+	auditFieldRoute     = "route"
+	auditFieldMethod    = "method"
+	auditFieldPath      = "path"
+	auditFieldAccountID = "account_id"
+	auditFieldBalanceID = "balance_id"
+	auditFieldRequest   = "request"
+	auditFieldBefore    = "before_snapshot"
+	auditFieldAfter     = "after_snapshot"
+	auditFieldStatus    = "status"
+	auditFieldError     = "error_message"
+)
+
+var (
+	auditFieldsInsert = []string{
+		fieldUsername,
+		auditFieldTokenID,
+		auditFieldRoute,
+		auditFieldMethod,
+		auditFieldPath,
+		auditFieldAccountID,
+		auditFieldBalanceID,
+		auditFieldRequest,
+		auditFieldBefore,
+		auditFieldAfter,
+		auditFieldStatus,
+		auditFieldError,
+	}
+
+	auditFieldsSelect = fmt.Sprintf(
+		"%s, %s, %s",
+		fieldID,
+		auditFieldTime,
+		strings.Join(auditFieldsInsert, ", "))
+
+	queryInsertAuditEntry = fmt.Sprintf(
+		`INSERT INTO %s (%s) VALUES (%s) returning %s;`,
+		auditTable,
+		strings.Join(auditFieldsInsert, ", "),
+		placeholders(len(auditFieldsInsert)),
+		auditFieldsSelect)
+)
+
+// auditOrders holds the ORDER BY clause for each order that can be given in a
+// storage.AuditQuery.
+var auditOrders = map[string]string{
+	"":                      fmt.Sprintf("%s ASC", fieldID),
+	storage.OrderAscending:  fmt.Sprintf("%s ASC", fieldID),
+	storage.OrderDescending: fmt.Sprintf("%s DESC", fieldID),
+}
+
+// InsertAuditEntry stores the given AuditEntry, returning it with its ID and
+// Time set.
+func (pg postgres) InsertAuditEntry(e storage.AuditEntry) (*storage.AuditEntry, error) {
+	es, err := queryAuditEntries(pg.q, queryInsertAuditEntry,
+		e.User,
+		e.TokenID,
+		e.Route,
+		e.Method,
+		e.Path,
+		e.AccountID,
+		e.BalanceID,
+		nullString(e.Request),
+		nullString(e.Before),
+		nullString(e.After),
+		e.Status,
+		e.Error)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying audit entries")
+	}
+	if len(*es) != 1 {
+		return nil, fmt.Errorf("expected 1 audit entry but query returned %d", len(*es))
+	}
+	return &(*es)[0], nil
+}
+
+// SelectAuditEntries returns the AuditEntries that match the given
+// storage.AuditQuery, filtering, ordering and paginating them within the
+// database.
+func (pg postgres) SelectAuditEntries(q storage.AuditQuery) (*storage.AuditEntries, error) {
+	query, args, err := auditEntriesQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	es, err := queryAuditEntries(pg.q, query, args...)
+	return es, errors.Wrap(err, "querying audit entries")
+}
+
+// auditEntriesQuery returns the query, along with its arguments, that selects
+// the AuditEntries that match the given storage.AuditQuery.
+func auditEntriesQuery(q storage.AuditQuery) (string, []interface{}, error) {
+	order, ok := auditOrders[q.Order]
+	if !ok {
+		return "", nil, storage.Invalidf("unsupported order: %q", q.Order)
+	}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	var conds []string
+	if q.User != nil {
+		conds = append(conds, fmt.Sprintf("%s = %s", fieldUsername, arg(*q.User)))
+	}
+	if q.Route != "" {
+		conds = append(conds, fmt.Sprintf("%s = %s", auditFieldRoute, arg(q.Route)))
+	}
+	if q.AccountID != 0 {
+		conds = append(conds, fmt.Sprintf("%s = %s", auditFieldAccountID, arg(q.AccountID)))
+	}
+	if q.BalanceID != 0 {
+		conds = append(conds, fmt.Sprintf("%s = %s", auditFieldBalanceID, arg(q.BalanceID)))
+	}
+	if q.From != nil {
+		conds = append(conds, fmt.Sprintf("%s >= %s", auditFieldTime, arg(*q.From)))
+	}
+	if q.To != nil {
+		conds = append(conds, fmt.Sprintf("%s <= %s", auditFieldTime, arg(*q.To)))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s ", auditFieldsSelect, auditTable)
+	if len(conds) > 0 {
+		query += "WHERE " + strings.Join(conds, " AND ") + " "
+	}
+	query += "ORDER BY " + order
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+	if q.Offset > 0 {
+		query += " OFFSET " + arg(q.Offset)
+	}
+	return query + ";", args, nil
+}
+
+func queryAuditEntries(db queryer, queryString string, values ...interface{}) (*storage.AuditEntries, error) {
+	rows, err := db.Query(queryString, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer nonReturningCloseRows(rows)
+	es := storage.AuditEntries{}
+	for rows.Next() {
+		var e storage.AuditEntry
+		var request, before, after sql.NullString
+		if err := rows.Scan(
+			&e.ID,
+			&e.Time,
+			&e.User,
+			&e.TokenID,
+			&e.Route,
+			&e.Method,
+			&e.Path,
+			&e.AccountID,
+			&e.BalanceID,
+			&request,
+			&before,
+			&after,
+			&e.Status,
+			&e.Error,
+		); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		e.Request = rawJSON(request)
+		e.Before = rawJSON(before)
+		e.After = rawJSON(after)
+		es = append(es, e)
+	}
+	return &es, rows.Err()
+}
+
+// placeholders returns a comma separated list of the first n positional
+// query parameters.
+func placeholders(n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return strings.Join(ps, ", ")
+}
+
+// nullString returns the given JSON as a sql.NullString that is NULL if the
+// JSON is empty.
+func nullString(raw []byte) sql.NullString {
+	return sql.NullString{String: string(raw), Valid: len(raw) > 0}
+}
+
+// rawJSON returns the JSON held by the given sql.NullString, which is nil if
+// the sql.NullString is NULL.
+func rawJSON(s sql.NullString) []byte {
+	if !s.Valid {
+		return nil
+	}
+	return []byte(s.String)
+}
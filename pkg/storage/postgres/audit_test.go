@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditEntriesQuery(t *testing.T) {
+	selectPrefix := "SELECT id, time, username, token_id, route, method, path, account_id, balance_id, " +
+		"request, before_snapshot, after_snapshot, status, error_message FROM audit_entries "
+
+	t.Run("zero query", func(t *testing.T) {
+		query, args, err := auditEntriesQuery(storage.AuditQuery{})
+		common.FatalIfError(t, err, "building query")
+		assert.Equal(t, selectPrefix+"ORDER BY id ASC;", query)
+		assert.Empty(t, args)
+	})
+
+	t.Run("unsupported order", func(t *testing.T) {
+		_, _, err := auditEntriesQuery(storage.AuditQuery{Order: "sideways"})
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+	})
+
+	t.Run("all conditions", func(t *testing.T) {
+		user := "alice"
+		from := time.Date(2018, 2, 3, 0, 0, 0, 0, time.UTC)
+		to := from.Add(time.Hour)
+		query, args, err := auditEntriesQuery(storage.AuditQuery{
+			User:      &user,
+			Route:     "AccountInsert",
+			AccountID: 4,
+			BalanceID: 6,
+			From:      &from,
+			To:        &to,
+			Order:     storage.OrderDescending,
+			Offset:    10,
+			Limit:     5,
+		})
+		common.FatalIfError(t, err, "building query")
+		assert.Equal(t, selectPrefix+
+			"WHERE username = $1 AND route = $2 AND account_id = $3 AND balance_id = $4 "+
+			"AND time >= $5 AND time <= $6 "+
+			"ORDER BY id DESC LIMIT $7 OFFSET $8;", query)
+		assert.Equal(t, []interface{}{user, "AccountInsert", uint(4), uint(6), from, to, uint(5), uint(10)}, args)
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/glynternet/go-accounting/balance"
 	"github.com/glynternet/mon/pkg/storage"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 )
 
@@ -21,17 +22,30 @@ const (
 
 var (
 	balancesSelectFields = fmt.Sprintf(
-		"%s, %s, %s, %s",
+		"%s, %s, %s, %s, %s, %s, %s",
 		balancesFieldID,
+		balancesFieldAccountID,
 		balancesFieldTime,
 		balancesFieldAmount,
-		balancesFieldNote)
+		balancesFieldNote,
+		fieldDeleted,
+		fieldVersion)
+
+	// balancesOfLiveAccounts matches only the balances whose account has not
+	// been deleted. The balances of a deleted account are archived with it.
+	balancesOfLiveAccounts = fmt.Sprintf(
+		`%s IN (SELECT %s FROM %s WHERE %s IS NULL)`,
+		balancesFieldAccountID,
+		fieldID,
+		accountsTable,
+		fieldDeleted)
 
 	balancesSelectPrefix = fmt.Sprintf(
-		`SELECT %s FROM %s WHERE %s IS NULL `,
+		`SELECT %s FROM %s WHERE %s IS NULL AND %s `,
 		balancesSelectFields,
 		balancesTable,
-		fieldDeleted)
+		fieldDeleted,
+		balancesOfLiveAccounts)
 
 	balancesSelectBalancesForAccountID = fmt.Sprintf(
 		"%sAND %s = $1 ORDER BY %s ASC, %s ASC;",
@@ -40,6 +54,20 @@ var (
 		balancesFieldTime,
 		balancesFieldID)
 
+	balancesSumAccountBalances = fmt.Sprintf(
+		`SELECT %[1]s, MAX(%[2]s), SUM(%[3]s) FROM %[4]s WHERE %[5]s IS NULL AND %[6]s AND %[1]s = ANY($1) AND %[2]s <= $2 GROUP BY %[1]s;`,
+		balancesFieldAccountID,
+		balancesFieldTime,
+		balancesFieldAmount,
+		balancesTable,
+		fieldDeleted,
+		balancesOfLiveAccounts)
+
+	balancesSelectBalance = fmt.Sprintf(
+		"%sAND %s = $1;",
+		balancesSelectPrefix,
+		balancesFieldID)
+
 	balancesInsertFields = fmt.Sprintf(
 		"%s, %s, %s, %s",
 		balancesFieldAccountID,
@@ -53,48 +81,92 @@ var (
 		balancesInsertFields,
 		balancesSelectFields)
 
+	balancesUpdateBalance = fmt.Sprintf(
+		`UPDATE %s SET %s = $1, %s = $2, %s = $3, %[5]s = %[5]s + 1 WHERE %s = $4 AND %s = $5 AND %s IS NULL AND %s RETURNING %s;`,
+		balancesTable,
+		balancesFieldTime,
+		balancesFieldAmount,
+		balancesFieldNote,
+		fieldVersion,
+		balancesFieldID,
+		balancesFieldAccountID,
+		fieldDeleted,
+		balancesOfLiveAccounts,
+		balancesSelectFields)
+
 	balancesDeleteBalance = fmt.Sprintf(
-		`UPDATE %s SET %s = $1 WHERE id = $2;`,
+		`UPDATE %s SET %s = $1 WHERE id = $2 AND %s IS NULL AND %s;`,
 		balancesTable,
 		fieldDeleted,
+		fieldDeleted,
+		balancesOfLiveAccounts,
 	)
+
+	balancesDeleteAccountBalances = fmt.Sprintf(
+		`UPDATE %s SET %s = $1 WHERE %s = $2 AND %s IS NULL;`,
+		balancesTable,
+		fieldDeleted,
+		balancesFieldAccountID,
+		fieldDeleted)
 )
 
 // SelectAccountBalances returns all Balances for a given account ID and any
 // errors that occur whilst attempting to retrieve the Balances. The Balances
 // are sorted by chronological order then by the id of the Balance in the DB
 func (pg postgres) SelectAccountBalances(id uint) (*storage.Balances, error) {
-	return queryBalances(pg.db, balancesSelectBalancesForAccountID, id)
+	return queryBalances(pg.q, balancesSelectBalancesForAccountID, id)
 }
 
-// SelectBalanceByAccountAndID selects a balance with a given ID within a given
-// account. An error will be returned if no balance can be found with the ID
-// for the given account.
-func (pg postgres) SelectBalanceByAccountAndID(a storage.Account, balanceID uint) (*storage.Balance, error) {
-	bs, err := pg.SelectAccountBalances(a.ID)
+// SelectBalance returns the Balance with the given id. An error will be
+// returned if no Balance exists with the id or if it has been deleted.
+func (pg postgres) SelectBalance(id uint) (*storage.Balance, error) {
+	b, err := queryBalance(pg.q, balancesSelectBalance, id)
 	if err != nil {
-		return nil, errors.Wrap(err, "selecting account balances for account %+v")
+		return nil, errors.Wrap(err, "querying balance")
 	}
-	for _, b := range *bs {
-		if b.ID == balanceID {
-			return &b, nil
-		}
+	if b == nil {
+		return nil, storage.NotFoundf("no balance with id %d", id)
 	}
-	return nil, fmt.Errorf("no balance with id %d for account", balanceID)
+	return b, nil
 }
 
 func (pg postgres) InsertBalance(accountID uint, b balance.Balance, note string) (*storage.Balance, error) {
-	return queryBalance(pg.db, balancesInsertBalance, accountID, b.Date, b.Amount, note)
+	return queryBalance(pg.q, balancesInsertBalance, accountID, b.Date, b.Amount, note)
 }
 
+// UpdateBalance updates the balance with the given id, that belongs to the
+// account with the given accountID, to hold the given balance.Balance and note.
+func (pg postgres) UpdateBalance(accountID, id uint, b balance.Balance, note string) (*storage.Balance, error) {
+	dbb, err := queryBalance(pg.q, balancesUpdateBalance, b.Date, b.Amount, note, id, accountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying balance")
+	}
+	if dbb == nil {
+		return nil, storage.NotFoundf("no balance with id %d for account %d", id, accountID)
+	}
+	return dbb, nil
+}
+
+// DeleteBalance deletes the balance with the given id. An error is returned
+// if no live balance of a live account has the id.
 func (pg postgres) DeleteBalance(id uint) error {
-	_, err := queryBalance(pg.db, balancesDeleteBalance, time.Now(), id)
-	return errors.Wrap(err, "querying balance")
+	r, err := pg.q.Exec(balancesDeleteBalance, time.Now(), id)
+	if err != nil {
+		return errors.Wrap(unavailable(err), "executing query")
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "getting number of rows affected")
+	}
+	if n != 1 {
+		return storage.NotFoundf("no balance with id %d", id)
+	}
+	return nil
 }
 
 // queryBalance returns an error if more than one result is returned from the query
 // queryBalance may or may not return an error if zero results are returned.
-func queryBalance(db *sql.DB, queryString string, values ...interface{}) (*storage.Balance, error) {
+func queryBalance(db queryer, queryString string, values ...interface{}) (*storage.Balance, error) {
 	bs, err := queryBalances(db, queryString, values...)
 	if err != nil {
 		return nil, errors.Wrap(err, "querying balances")
@@ -108,10 +180,10 @@ func queryBalance(db *sql.DB, queryString string, values ...interface{}) (*stora
 	return &(*bs)[0], nil
 }
 
-func queryBalances(db *sql.DB, queryString string, values ...interface{}) (*storage.Balances, error) {
+func queryBalances(db queryer, queryString string, values ...interface{}) (*storage.Balances, error) {
 	rows, err := db.Query(queryString, values...)
 	if err != nil {
-		return nil, errors.Wrap(err, "querying db")
+		return nil, errors.Wrap(unavailable(err), "querying db")
 	}
 	defer nonReturningCloseRows(rows)
 	return scanRowsForBalances(rows)
@@ -122,11 +194,13 @@ func queryBalances(db *sql.DB, queryString string, values ...interface{}) (*stor
 func scanRowsForBalances(rows *sql.Rows) (bs *storage.Balances, err error) {
 	bs = &storage.Balances{}
 	for rows.Next() {
-		var ID uint
+		var ID, version uint
 		var date time.Time
 		var amount int
+		var accountID uint
 		var note sql.NullString
-		err = rows.Scan(&ID, &date, &amount, &note)
+		var deleted pq.NullTime
+		err = rows.Scan(&ID, &accountID, &date, &amount, &note, &deleted, &version)
 		if err != nil {
 			return nil, errors.Wrap(err, "scanning rows")
 		}
@@ -135,14 +209,45 @@ func scanRowsForBalances(rows *sql.Rows) (bs *storage.Balances, err error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "creating new balance from scan results")
 		}
-		*bs = append(*bs, storage.Balance{
-			ID:      ID,
-			Balance: *innerB,
-			Note:    note.String,
-		})
+		b := storage.Balance{
+			ID:        ID,
+			Version:   version,
+			AccountID: accountID,
+			Balance:   *innerB,
+			Note:      note.String,
+		}
+		if deleted.Valid {
+			err = storage.BalanceDeletedAt(deleted.Time)(&b)
+			if err != nil {
+				return nil, errors.Wrap(err, "applying deleted time to balance")
+			}
+		}
+		*bs = append(*bs, b)
 	}
 	if err == nil {
 		err = errors.Wrap(rows.Err(), "rows error: ")
 	}
 	return
 }
+
+// SumAccountBalances returns a balance.Balance for each account with one of
+// the given ids that has live Balances at or before the given time, holding
+// the sum of their amounts and the date of the latest of them. The Balances
+// of every account are totalled with a single aggregate query.
+func (pg postgres) SumAccountBalances(ids []uint, at time.Time) (map[uint]balance.Balance, error) {
+	rows, err := pg.q.Query(balancesSumAccountBalances, pq.Array(int64s(ids)), at)
+	if err != nil {
+		return nil, errors.Wrap(unavailable(err), "querying db")
+	}
+	defer nonReturningCloseRows(rows)
+	sums := make(map[uint]balance.Balance)
+	for rows.Next() {
+		var id uint
+		var b balance.Balance
+		if err := rows.Scan(&id, &b.Date, &b.Amount); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		sums[id] = b
+	}
+	return sums, errors.Wrap(rows.Err(), "iterating rows")
+}
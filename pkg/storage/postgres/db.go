@@ -3,11 +3,14 @@ package postgres
 import (
 	"bytes"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"strings"
 
+	"github.com/glynternet/mon/pkg/storage"
 	"github.com/pkg/errors"
 )
 
@@ -21,11 +24,22 @@ func New(connectionString string) (*postgres, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "opening connection to backend")
 	}
-	return &postgres{db: db}, nil
+	return &postgres{db: db, q: db}, nil
 }
 
 type postgres struct {
 	db *sql.DB
+
+	// q is used to run all queries against the storage. q is the db itself
+	// unless the postgres is being used within a transaction.
+	q queryer
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 // NewConnectionString creates a new connection string for the postgres db
@@ -71,8 +85,8 @@ func (w *failSafeWriter) writef(format string, args ...interface{}) {
 	_, w.error = w.Writer.Write(bs)
 }
 
-// CreateStorage will create all the necessary tables to use postgres as a
-// backend.
+// CreateStorage will create the database and apply all migrations so that
+// the storage is ready to use postgres as a backend.
 func CreateStorage(host, user, password, dbname, sslmode string) error {
 	adminConnect, err := NewConnectionString(host, user, password, "", sslmode)
 	if err != nil {
@@ -86,11 +100,7 @@ func CreateStorage(host, user, password, dbname, sslmode string) error {
 	if err != nil {
 		return errors.Wrap(err, "creating database")
 	}
-	err = createAccountsTable(userConnect)
-	if err != nil {
-		return errors.Wrap(err, "creating accounts table")
-	}
-	return errors.Wrap(createBalancesTable(userConnect), "creating balances table")
+	return errors.Wrap(Migrate(userConnect, LatestSchemaVersion()), "migrating schema")
 }
 
 // TODO: functional tests
@@ -127,54 +137,6 @@ func createDatabase(connection, name, owner string) error {
 	return errors.Wrap(err, "executing create database query")
 }
 
-func createAccountsTable(connection string) error {
-	db, err := open(connection)
-	if err != nil {
-		return errors.Wrap(err, "opening DB connection")
-	}
-	defer nonReturningCloseDB(db)
-	query := fmt.Sprintf(`CREATE TABLE %s (
-	%s SERIAL PRIMARY KEY,
-	%s varchar(100) NOT NULL,
-	%s char(3) NOT NULL,
-	%s timestamp with time zone NOT NULL,
-	%s timestamp with time zone,
-	%s timestamp with time zone);`,
-		accountsTable,
-		fieldID,
-		fieldName,
-		fieldCurrency,
-		fieldOpened,
-		fieldClosed,
-		fieldDeleted)
-	_, err = db.Exec(query)
-	return err
-}
-
-func createBalancesTable(connection string) error {
-	db, err := open(connection)
-	if err != nil {
-		return errors.Wrap(err, "opening DB connection")
-	}
-	defer nonReturningCloseDB(db)
-	query := fmt.Sprintf(`CREATE TABLE %s (
-	%s SERIAL PRIMARY KEY,
-	%s integer NOT NULL,
-	%s timestamp with time zone NOT NULL,
-	%s bigint NOT NULL,
-	%s varchar(240),
-	%s timestamp with time zone);`,
-		balancesTable,
-		balancesFieldID,
-		balancesFieldAccountID,
-		balancesFieldTime,
-		balancesFieldAmount,
-		balancesFieldNote,
-		fieldDeleted)
-	_, err = db.Exec(query)
-	return errors.Wrap(err, "executing create Balances query")
-}
-
 // DeleteStorage deletes the database used for the backend.
 func DeleteStorage(host, user, password, name, sslmode string) error {
 	if len(strings.TrimSpace(name)) == 0 {
@@ -198,6 +160,16 @@ func open(connectionString string) (*sql.DB, error) {
 	return db, err
 }
 
+// unavailable returns err as an error of storage.KindUnavailable if err was
+// caused by a failure to communicate with the database.
+func unavailable(err error) error {
+	cause := errors.Cause(err)
+	if _, ok := cause.(net.Error); ok || cause == driver.ErrBadConn || cause == sql.ErrConnDone {
+		return storage.Unavailable(err)
+	}
+	return err
+}
+
 // Available returns true if the Storage is available
 func (pg *postgres) Available() bool {
 	return pg.db.Ping() == nil // Ping() returns an error if db  is unavailable
@@ -126,3 +126,30 @@ func adminConnectionString(t *testing.T) string {
 func nonReturningCloseStorage(s storage.Storage) {
 	nonReturningClose(s, "Storage")
 }
+
+func TestMigrate(t *testing.T) {
+	err := CreateStorage(host, user, password, testDBName, ssl)
+	common.FatalIfError(t, err, "creating storage")
+	defer deleteTestDB(t)
+
+	cs, err := NewConnectionString(host, user, password, testDBName, ssl)
+	common.FatalIfError(t, err, "creating connection string")
+
+	v, err := SchemaVersion(cs)
+	common.FatalIfError(t, err, "getting schema version")
+	assert.Equal(t, LatestSchemaVersion(), v)
+
+	err = MigrateDown(cs, uint(len(migrations)))
+	common.FatalIfError(t, err, "reverting all migrations")
+	v, err = SchemaVersion(cs)
+	common.FatalIfError(t, err, "getting schema version")
+	assert.Equal(t, uint(0), v)
+
+	assert.Error(t, MigrateDown(cs, 1), "reverting migrations when none are applied")
+
+	err = Migrate(cs, LatestSchemaVersion())
+	common.FatalIfError(t, err, "applying all migrations")
+	v, err = SchemaVersion(cs)
+	common.FatalIfError(t, err, "getting schema version")
+	assert.Equal(t, LatestSchemaVersion(), v)
+}
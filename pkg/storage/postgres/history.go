@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+const (
+	fieldValidFrom     = "valid_from"
+	fieldValidTo       = "valid_to"
+	historyTableSuffix = "_history"
+	historyTrigger     = "record_history"
+)
+
+var (
+	// accountsAsOfFields selects a NULL deleted time, as an account selected
+	// as of a given time had not been deleted at that time.
+	accountsAsOfFields = fmt.Sprintf(
+		"%s, %s, %s, %s, %s, NULL::timestamp with time zone, %s",
+		fieldID,
+		fieldName,
+		fieldOpened,
+		fieldClosed,
+		fieldCurrency,
+		fieldVersion)
+
+	// balancesAsOfFields selects a NULL deleted time, as a balance selected
+	// as of a given time had not been deleted at that time.
+	balancesAsOfFields = fmt.Sprintf(
+		"%s, %s, %s, %s, %s, NULL::timestamp with time zone, %s",
+		balancesFieldID,
+		balancesFieldAccountID,
+		balancesFieldTime,
+		balancesFieldAmount,
+		balancesFieldNote,
+		fieldVersion)
+
+	querySelectAccountsAsOf = fmt.Sprintf(
+		`SELECT %s FROM (%s) AS versions WHERE %s ORDER BY %s ASC;`,
+		accountsAsOfFields,
+		versionsQuery(accountsTable),
+		asOfCondition,
+		fieldID)
+
+	querySelectAccountBalancesAsOf = fmt.Sprintf(
+		`SELECT %s FROM (%s) AS versions WHERE %s = $2 AND %s ORDER BY %s ASC, %s ASC;`,
+		balancesAsOfFields,
+		versionsQuery(balancesTable),
+		balancesFieldAccountID,
+		asOfCondition,
+		balancesFieldTime,
+		balancesFieldID)
+
+	// asOfCondition matches the version of a record that was valid at the
+	// time given as the first query argument. Records deleted before history
+	// was recorded are matched using only their deleted time.
+	asOfCondition = fmt.Sprintf(
+		"%[1]s <= $1 AND %[2]s > $1 AND (%[3]s IS NULL OR %[3]s > $1)",
+		fieldValidFrom,
+		fieldValidTo,
+		fieldDeleted)
+)
+
+// versionsQuery returns a query selecting every version of the records of the
+// given table, with the current version of each record being valid until
+// infinity.
+func versionsQuery(table string) string {
+	return fmt.Sprintf(
+		`SELECT *, 'infinity'::timestamp with time zone AS %[2]s FROM %[1]s UNION ALL SELECT * FROM %[1]s%[3]s`,
+		table,
+		fieldValidTo,
+		historyTableSuffix)
+}
+
+// SelectAccountsAsOf returns the Accounts that were stored and not deleted at
+// the given time, as they were at that time, ordered by their ID.
+func (pg postgres) SelectAccountsAsOf(t time.Time) (*storage.Accounts, error) {
+	return queryAccounts(pg.q, querySelectAccountsAsOf, t)
+}
+
+// SelectAccountBalancesAsOf returns the Balances of the account with the given
+// id that were stored and not deleted at the given time, as they were at that
+// time. The Balances are sorted by chronological order then by their id.
+func (pg postgres) SelectAccountBalancesAsOf(accountID uint, t time.Time) (*storage.Balances, error) {
+	return queryBalances(pg.q, querySelectAccountBalancesAsOf, t, accountID)
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	idempotencyTable            = "idempotent_requests"
+	idempotencyFieldKey         = "idempotency_key"
+	idempotencyFieldFingerprint = "fingerprint"
+	idempotencyFieldReservation = "reservation"
+	idempotencyFieldCreated     = "created"
+	idempotencyFieldStatus      = "status"
+	idempotencyFieldLocation    = "location"
+	idempotencyFieldETag        = "etag"
+	idempotencyFieldBody        = "body"
+)
+
+var (
+	idempotencyFieldsSelect = fmt.Sprintf(
+		"%s, %s, %s, %s, %s, %s, %s, %s, %s",
+		fieldUsername,
+		idempotencyFieldKey,
+		idempotencyFieldFingerprint,
+		idempotencyFieldReservation,
+		idempotencyFieldCreated,
+		idempotencyFieldStatus,
+		idempotencyFieldLocation,
+		idempotencyFieldETag,
+		idempotencyFieldBody)
+
+	queryDeleteExpiredIdempotentRequests = fmt.Sprintf(
+		`DELETE FROM %s WHERE %s <= $1 OR (%s = 0 AND %[2]s <= $2);`,
+		idempotencyTable,
+		idempotencyFieldCreated,
+		idempotencyFieldStatus)
+
+	queryReserveIdempotentRequest = fmt.Sprintf(
+		`INSERT INTO %s (%s, %s, %s, %s) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING;`,
+		idempotencyTable,
+		fieldUsername,
+		idempotencyFieldKey,
+		idempotencyFieldFingerprint,
+		idempotencyFieldReservation)
+
+	querySelectIdempotentRequest = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = $1 AND %s = $2;`,
+		idempotencyFieldsSelect,
+		idempotencyTable,
+		fieldUsername,
+		idempotencyFieldKey)
+
+	queryCompleteIdempotentRequest = fmt.Sprintf(
+		`UPDATE %s SET %s = $1, %s = $2, %s = $3, %s = $4 WHERE %s = $5 AND %s = $6 AND %s = $7 AND %[2]s = 0;`,
+		idempotencyTable,
+		idempotencyFieldStatus,
+		idempotencyFieldLocation,
+		idempotencyFieldETag,
+		idempotencyFieldBody,
+		fieldUsername,
+		idempotencyFieldKey,
+		idempotencyFieldReservation)
+
+	queryReleaseIdempotentRequest = fmt.Sprintf(
+		`DELETE FROM %s WHERE %s = $1 AND %s = $2 AND %s = $3 AND %s = 0;`,
+		idempotencyTable,
+		fieldUsername,
+		idempotencyFieldKey,
+		idempotencyFieldReservation,
+		idempotencyFieldStatus)
+)
+
+// ReserveIdempotentRequest stores the given IdempotentRequest without a
+// response, unless there is already an IdempotentRequest with the same User
+// and Key that was created after the given time, in which case that one is
+// returned along with false. An IdempotentRequest without a response that was
+// created at or before reservedAfter is replaced.
+func (pg postgres) ReserveIdempotentRequest(r storage.IdempotentRequest, after, reservedAfter time.Time) (*storage.IdempotentRequest, bool, error) {
+	if _, err := pg.q.Exec(queryDeleteExpiredIdempotentRequests, after, reservedAfter); err != nil {
+		return nil, false, errors.Wrap(unavailable(err), "deleting expired requests")
+	}
+	res, err := pg.q.Exec(queryReserveIdempotentRequest, r.User, r.Key, r.Fingerprint, r.Reservation)
+	if err != nil {
+		return nil, false, errors.Wrap(unavailable(err), "executing query")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "getting number of rows affected")
+	}
+	ir, err := queryIdempotentRequest(pg.q, r.User, r.Key)
+	return ir, n == 1, errors.Wrap(err, "querying request")
+}
+
+// CompleteIdempotentRequest stores the response of the given
+// IdempotentRequest for the reserved IdempotentRequest with the same User,
+// Key and Reservation.
+func (pg postgres) CompleteIdempotentRequest(r storage.IdempotentRequest) error {
+	res, err := pg.q.Exec(queryCompleteIdempotentRequest,
+		r.Status, r.Location, r.ETag, nullString(r.Body), r.User, r.Key, r.Reservation)
+	if err != nil {
+		return errors.Wrap(unavailable(err), "executing query")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "getting number of rows affected")
+	}
+	if n != 1 {
+		return storage.NotFoundf("no reserved request with key %q", r.Key)
+	}
+	return nil
+}
+
+// ReleaseIdempotentRequest deletes the IdempotentRequest with the given user,
+// key and reservation if it is without a response.
+func (pg postgres) ReleaseIdempotentRequest(user, key, reservation string) error {
+	_, err := pg.q.Exec(queryReleaseIdempotentRequest, user, key, reservation)
+	return errors.Wrap(unavailable(err), "executing query")
+}
+
+func queryIdempotentRequest(db queryer, user, key string) (*storage.IdempotentRequest, error) {
+	rows, err := db.Query(querySelectIdempotentRequest, user, key)
+	if err != nil {
+		return nil, errors.Wrap(unavailable(err), "querying db")
+	}
+	defer nonReturningCloseRows(rows)
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, storage.NotFoundf("no request with key %q", key)
+	}
+	var ir storage.IdempotentRequest
+	var body sql.NullString
+	if err := rows.Scan(
+		&ir.User,
+		&ir.Key,
+		&ir.Fingerprint,
+		&ir.Reservation,
+		&ir.Created,
+		&ir.Status,
+		&ir.Location,
+		&ir.ETag,
+		&body); err != nil {
+		return nil, errors.Wrap(err, "scanning row")
+	}
+	ir.Body = rawJSON(body)
+	return &ir, rows.Err()
+}
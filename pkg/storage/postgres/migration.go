@@ -0,0 +1,505 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	migrationsTable              = "schema_migrations"
+	migrationsFieldVersion       = "version"
+	migrationsFieldDescription   = "description"
+	migrationsFieldApplied       = "applied"
+	migrationsDescriptionMaxSize = 240
+)
+
+var (
+	queryCreateMigrationsTable = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s integer PRIMARY KEY,
+	%s varchar(%d) NOT NULL,
+	%s timestamp with time zone NOT NULL DEFAULT now());`,
+		migrationsTable,
+		migrationsFieldVersion,
+		migrationsFieldDescription, migrationsDescriptionMaxSize,
+		migrationsFieldApplied)
+
+	querySelectSchemaVersion = fmt.Sprintf(
+		`SELECT COALESCE(MAX(%s), 0) FROM %s;`,
+		migrationsFieldVersion,
+		migrationsTable)
+
+	queryInsertMigration = fmt.Sprintf(
+		`INSERT INTO %s (%s, %s) VALUES ($1, $2);`,
+		migrationsTable,
+		migrationsFieldVersion,
+		migrationsFieldDescription)
+
+	queryDeleteMigration = fmt.Sprintf(
+		`DELETE FROM %s WHERE %s = $1;`,
+		migrationsTable,
+		migrationsFieldVersion)
+)
+
+// migration is a single, versioned change to the schema of the storage. Once
+// a migration has been released, it should never be changed; any further
+// changes to the schema should be made by adding a new migration.
+type migration struct {
+	version     uint
+	description string
+	up, down    string
+}
+
+// migrations holds every migration for the storage, in the order in which
+// they should be applied.
+// The initial tables are created only if they do not already exist, so that
+// storage created before migrations were introduced can be brought under
+// their management.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create accounts table",
+		up: fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s SERIAL PRIMARY KEY,
+	%s varchar(100) NOT NULL,
+	%s char(3) NOT NULL,
+	%s timestamp with time zone NOT NULL,
+	%s timestamp with time zone,
+	%s timestamp with time zone);`,
+			accountsTable,
+			fieldID,
+			fieldName,
+			fieldCurrency,
+			fieldOpened,
+			fieldClosed,
+			fieldDeleted),
+		down: fmt.Sprintf(`DROP TABLE %s;`, accountsTable),
+	},
+	{
+		version:     2,
+		description: "create balances table",
+		up: fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s SERIAL PRIMARY KEY,
+	%s integer NOT NULL,
+	%s timestamp with time zone NOT NULL,
+	%s bigint NOT NULL,
+	%s varchar(240),
+	%s timestamp with time zone);`,
+			balancesTable,
+			balancesFieldID,
+			balancesFieldAccountID,
+			balancesFieldTime,
+			balancesFieldAmount,
+			balancesFieldNote,
+			fieldDeleted),
+		down: fmt.Sprintf(`DROP TABLE %s;`, balancesTable),
+	},
+	{
+		// Records that existed before this migration are treated as having
+		// been valid for all time, as no history is available for them.
+		// Any column later added to the accounts or balances tables must also
+		// be added to the matching history table.
+		version:     3,
+		description: "record history of accounts and balances",
+		up: fmt.Sprintf(`ALTER TABLE %[1]s ADD COLUMN %[3]s timestamp with time zone NOT NULL DEFAULT '-infinity';
+ALTER TABLE %[1]s ALTER COLUMN %[3]s SET DEFAULT now();
+ALTER TABLE %[2]s ADD COLUMN %[3]s timestamp with time zone NOT NULL DEFAULT '-infinity';
+ALTER TABLE %[2]s ALTER COLUMN %[3]s SET DEFAULT now();
+CREATE TABLE %[1]s%[5]s (LIKE %[1]s);
+ALTER TABLE %[1]s%[5]s ADD COLUMN %[4]s timestamp with time zone NOT NULL;
+CREATE TABLE %[2]s%[5]s (LIKE %[2]s);
+ALTER TABLE %[2]s%[5]s ADD COLUMN %[4]s timestamp with time zone NOT NULL;
+CREATE FUNCTION %[6]s() RETURNS trigger AS $$
+BEGIN
+	EXECUTE format('INSERT INTO %%I SELECT ($1).*, now()', TG_TABLE_NAME || '%[5]s') USING OLD;
+	NEW.%[3]s := now();
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER %[6]s BEFORE UPDATE ON %[1]s FOR EACH ROW EXECUTE PROCEDURE %[6]s();
+CREATE TRIGGER %[6]s BEFORE UPDATE ON %[2]s FOR EACH ROW EXECUTE PROCEDURE %[6]s();`,
+			accountsTable,
+			balancesTable,
+			fieldValidFrom,
+			fieldValidTo,
+			historyTableSuffix,
+			historyTrigger),
+		down: fmt.Sprintf(`DROP TRIGGER %[6]s ON %[2]s;
+DROP TRIGGER %[6]s ON %[1]s;
+DROP FUNCTION %[6]s();
+DROP TABLE %[2]s%[5]s;
+DROP TABLE %[1]s%[5]s;
+ALTER TABLE %[2]s DROP COLUMN %[3]s;
+ALTER TABLE %[1]s DROP COLUMN %[3]s;`,
+			accountsTable,
+			balancesTable,
+			fieldValidFrom,
+			fieldValidTo,
+			historyTableSuffix,
+			historyTrigger),
+	},
+	{
+		version:     4,
+		description: "create tokens table",
+		up: fmt.Sprintf(`CREATE TABLE %s (
+	%s SERIAL PRIMARY KEY,
+	%s varchar(100) NOT NULL,
+	%s char(64) NOT NULL UNIQUE,
+	%s timestamp with time zone NOT NULL DEFAULT now(),
+	%s timestamp with time zone);`,
+			tokensTable,
+			fieldID,
+			fieldName,
+			tokensFieldHash,
+			tokensFieldCreated,
+			tokensFieldRevoked),
+		down: fmt.Sprintf(`DROP TABLE %s;`, tokensTable),
+	},
+	{
+		// Accounts that existed before this migration are owned by the
+		// default user. The accounts history table is rebuilt so that the
+		// owner column is in the same position as in the accounts table, as
+		// the history trigger and the versions query rely upon the columns of
+		// a table and its history table being in the same order.
+		version:     5,
+		description: "record owners of accounts and tokens and shared accounts",
+		up: fmt.Sprintf(`ALTER TABLE %[1]s ADD COLUMN %[2]s varchar(100) NOT NULL DEFAULT '';
+CREATE TABLE %[1]s%[3]s_new (LIKE %[1]s);
+ALTER TABLE %[1]s%[3]s_new ADD COLUMN %[4]s timestamp with time zone NOT NULL;
+INSERT INTO %[1]s%[3]s_new (%[5]s, %[2]s)
+	SELECT %[5]s, '' FROM %[1]s%[3]s;
+DROP TABLE %[1]s%[3]s;
+ALTER TABLE %[1]s%[3]s_new RENAME TO %[1]s%[3]s;
+ALTER TABLE %[6]s ADD COLUMN %[7]s varchar(100) NOT NULL DEFAULT '';
+CREATE TABLE %[8]s (
+	%[9]s integer NOT NULL REFERENCES %[1]s (%[10]s) ON DELETE CASCADE,
+	%[7]s varchar(100) NOT NULL,
+	PRIMARY KEY (%[9]s, %[7]s));`,
+			accountsTable,
+			accountsFieldOwner,
+			historyTableSuffix,
+			fieldValidTo,
+			fmt.Sprintf(
+				"%s, %s, %s, %s, %s, %s, %s, %s",
+				fieldID,
+				fieldName,
+				fieldCurrency,
+				fieldOpened,
+				fieldClosed,
+				fieldDeleted,
+				fieldValidFrom,
+				fieldValidTo),
+			tokensTable,
+			fieldUsername,
+			sharesTable,
+			sharesFieldAccountID,
+			fieldID),
+		down: fmt.Sprintf(`DROP TABLE %[6]s;
+ALTER TABLE %[5]s DROP COLUMN %[4]s;
+ALTER TABLE %[1]s%[3]s DROP COLUMN %[2]s;
+ALTER TABLE %[1]s DROP COLUMN %[2]s;`,
+			accountsTable,
+			accountsFieldOwner,
+			historyTableSuffix,
+			fieldUsername,
+			tokensTable,
+			sharesTable),
+	},
+	{
+		// Tokens that existed before this migration keep the access that
+		// they had, which was to every request.
+		version:     6,
+		description: "add role to tokens",
+		up: fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s varchar(20) NOT NULL DEFAULT '%s';`,
+			tokensTable,
+			tokensFieldRole,
+			storage.RoleAdmin),
+		down: fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s;`,
+			tokensTable,
+			tokensFieldRole),
+	},
+	{
+		version:     7,
+		description: "create audit entries table",
+		up: fmt.Sprintf(`CREATE TABLE %s (
+	%s SERIAL PRIMARY KEY,
+	%s timestamp with time zone NOT NULL DEFAULT now(),
+	%s varchar(100) NOT NULL,
+	%s integer NOT NULL,
+	%s varchar(100) NOT NULL,
+	%s varchar(10) NOT NULL,
+	%s text NOT NULL,
+	%s integer NOT NULL,
+	%s integer NOT NULL,
+	%s jsonb,
+	%s jsonb,
+	%s jsonb,
+	%s integer NOT NULL,
+	%s text NOT NULL);`,
+			auditTable,
+			fieldID,
+			auditFieldTime,
+			fieldUsername,
+			auditFieldTokenID,
+			auditFieldRoute,
+			auditFieldMethod,
+			auditFieldPath,
+			auditFieldAccountID,
+			auditFieldBalanceID,
+			auditFieldRequest,
+			auditFieldBefore,
+			auditFieldAfter,
+			auditFieldStatus,
+			auditFieldError),
+		down: fmt.Sprintf(`DROP TABLE %s;`, auditTable),
+	},
+	{
+		// Records that existed before this migration are at version 1. The
+		// history tables are rebuilt so that the version column is in the
+		// same position as in the accounts and balances tables, in the same
+		// way as migration 5. The versions of the records in the history
+		// tables were not recorded, so they are at version 0.
+		version:     8,
+		description: "record versions of accounts and balances",
+		up: fmt.Sprintf(`ALTER TABLE %[1]s ADD COLUMN %[3]s integer NOT NULL DEFAULT 1;
+CREATE TABLE %[1]s%[4]s_new (LIKE %[1]s);
+ALTER TABLE %[1]s%[4]s_new ADD COLUMN %[5]s timestamp with time zone NOT NULL;
+INSERT INTO %[1]s%[4]s_new (%[6]s, %[3]s)
+	SELECT %[6]s, 0 FROM %[1]s%[4]s;
+DROP TABLE %[1]s%[4]s;
+ALTER TABLE %[1]s%[4]s_new RENAME TO %[1]s%[4]s;
+ALTER TABLE %[2]s ADD COLUMN %[3]s integer NOT NULL DEFAULT 1;
+CREATE TABLE %[2]s%[4]s_new (LIKE %[2]s);
+ALTER TABLE %[2]s%[4]s_new ADD COLUMN %[5]s timestamp with time zone NOT NULL;
+INSERT INTO %[2]s%[4]s_new (%[7]s, %[3]s)
+	SELECT %[7]s, 0 FROM %[2]s%[4]s;
+DROP TABLE %[2]s%[4]s;
+ALTER TABLE %[2]s%[4]s_new RENAME TO %[2]s%[4]s;`,
+			accountsTable,
+			balancesTable,
+			fieldVersion,
+			historyTableSuffix,
+			fieldValidTo,
+			fmt.Sprintf(
+				"%s, %s, %s, %s, %s, %s, %s, %s, %s",
+				fieldID,
+				fieldName,
+				fieldCurrency,
+				fieldOpened,
+				fieldClosed,
+				fieldDeleted,
+				fieldValidFrom,
+				accountsFieldOwner,
+				fieldValidTo),
+			fmt.Sprintf(
+				"%s, %s, %s, %s, %s, %s, %s, %s",
+				balancesFieldID,
+				balancesFieldAccountID,
+				balancesFieldTime,
+				balancesFieldAmount,
+				balancesFieldNote,
+				fieldDeleted,
+				fieldValidFrom,
+				fieldValidTo)),
+		down: fmt.Sprintf(`ALTER TABLE %[2]s%[4]s DROP COLUMN %[3]s;
+ALTER TABLE %[2]s DROP COLUMN %[3]s;
+ALTER TABLE %[1]s%[4]s DROP COLUMN %[3]s;
+ALTER TABLE %[1]s DROP COLUMN %[3]s;`,
+			accountsTable,
+			balancesTable,
+			fieldVersion,
+			historyTableSuffix),
+	},
+	{
+		version:     9,
+		description: "create idempotent requests table",
+		up: fmt.Sprintf(`CREATE TABLE %s (
+	%s varchar(100) NOT NULL,
+	%s varchar(255) NOT NULL,
+	%s char(64) NOT NULL,
+	%s timestamp with time zone NOT NULL DEFAULT now(),
+	%s integer NOT NULL DEFAULT 0,
+	%s text NOT NULL DEFAULT '',
+	%s text NOT NULL DEFAULT '',
+	%s jsonb,
+	PRIMARY KEY (%s, %s));
+CREATE INDEX ON %[1]s (%[5]s);`,
+			idempotencyTable,
+			fieldUsername,
+			idempotencyFieldKey,
+			idempotencyFieldFingerprint,
+			idempotencyFieldCreated,
+			idempotencyFieldStatus,
+			idempotencyFieldLocation,
+			idempotencyFieldETag,
+			idempotencyFieldBody,
+			fieldUsername,
+			idempotencyFieldKey),
+		down: fmt.Sprintf(`DROP TABLE %s;`, idempotencyTable),
+	},
+	{
+		version:     10,
+		description: "record reservations of idempotent requests",
+		up: fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s varchar(64) NOT NULL DEFAULT '';`,
+			idempotencyTable,
+			idempotencyFieldReservation),
+		down: fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s;`,
+			idempotencyTable,
+			idempotencyFieldReservation),
+	},
+}
+
+// LatestSchemaVersion returns the version that the schema will be at once all
+// migrations have been applied.
+func LatestSchemaVersion() uint {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// SchemaVersion returns the version that the schema of the storage at the
+// given connection is currently at. A version of 0 means that no migrations
+// have been applied.
+func SchemaVersion(connectionString string) (uint, error) {
+	db, err := open(connectionString)
+	if err != nil {
+		return 0, errors.Wrap(err, "opening DB connection")
+	}
+	defer nonReturningCloseDB(db)
+	_, err = db.Exec(queryCreateMigrationsTable)
+	if err != nil {
+		return 0, errors.Wrap(err, "creating migrations table")
+	}
+	return schemaVersion(db)
+}
+
+// Migrate applies or reverts migrations against the storage at the given
+// connection until the schema is at the target version. Each migration is
+// applied within its own transaction, so a failing migration will leave the
+// schema at the version of the last successful migration.
+func Migrate(connectionString string, target uint) error {
+	db, err := open(connectionString)
+	if err != nil {
+		return errors.Wrap(err, "opening DB connection")
+	}
+	defer nonReturningCloseDB(db)
+	_, err = db.Exec(queryCreateMigrationsTable)
+	if err != nil {
+		return errors.Wrap(err, "creating migrations table")
+	}
+	current, err := schemaVersion(db)
+	if err != nil {
+		return errors.Wrap(err, "getting current schema version")
+	}
+	steps, err := planMigration(migrations, current, target)
+	if err != nil {
+		return errors.Wrapf(err, "planning migration from version %d to %d", current, target)
+	}
+	for _, s := range steps {
+		err := runMigrationStep(db, s)
+		if err != nil {
+			return errors.Wrapf(err, "running migration %d (%s)", s.version, s.description)
+		}
+	}
+	return nil
+}
+
+// MigrateDown reverts the given number of the most recently applied
+// migrations against the storage at the given connection.
+func MigrateDown(connectionString string, steps uint) error {
+	current, err := SchemaVersion(connectionString)
+	if err != nil {
+		return errors.Wrap(err, "getting current schema version")
+	}
+	var applied []uint
+	for _, m := range migrations {
+		if m.version <= current {
+			applied = append(applied, m.version)
+		}
+	}
+	if uint(len(applied)) < steps {
+		return fmt.Errorf("cannot revert %d migrations when only %d have been applied", steps, len(applied))
+	}
+	var target uint
+	if n := len(applied) - int(steps); n > 0 {
+		target = applied[n-1]
+	}
+	return Migrate(connectionString, target)
+}
+
+func schemaVersion(db *sql.DB) (uint, error) {
+	var v uint
+	err := db.QueryRow(querySelectSchemaVersion).Scan(&v)
+	return v, errors.Wrap(err, "querying schema version")
+}
+
+// migrationStep is a single migration to be either applied or reverted.
+type migrationStep struct {
+	migration
+	apply bool
+}
+
+// planMigration returns the steps that must be run, in order, to take a
+// schema from the current version to the target version.
+func planMigration(ms []migration, current, target uint) ([]migrationStep, error) {
+	var last uint
+	known := map[uint]bool{0: true}
+	for _, m := range ms {
+		if m.version <= last {
+			return nil, fmt.Errorf("migration versions must be strictly increasing but %d follows %d", m.version, last)
+		}
+		last = m.version
+		known[m.version] = true
+	}
+	if !known[current] {
+		return nil, fmt.Errorf("current schema version %d is unknown", current)
+	}
+	if !known[target] {
+		return nil, fmt.Errorf("target schema version %d is unknown", target)
+	}
+	var steps []migrationStep
+	if target >= current {
+		for _, m := range ms {
+			if m.version > current && m.version <= target {
+				steps = append(steps, migrationStep{migration: m, apply: true})
+			}
+		}
+		return steps, nil
+	}
+	for i := len(ms) - 1; i >= 0; i-- {
+		if ms[i].version <= current && ms[i].version > target {
+			steps = append(steps, migrationStep{migration: ms[i]})
+		}
+	}
+	return steps, nil
+}
+
+func runMigrationStep(db *sql.DB, s migrationStep) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	query, record, args := s.down, queryDeleteMigration, []interface{}{s.version}
+	if s.apply {
+		query, record, args = s.up, queryInsertMigration, []interface{}{s.version, s.description}
+	}
+	if _, err = tx.Exec(query); err != nil {
+		return rollback(tx, errors.Wrap(err, "executing migration query"))
+	}
+	if _, err = tx.Exec(record, args...); err != nil {
+		return rollback(tx, errors.Wrap(err, "recording migration"))
+	}
+	return errors.Wrap(tx.Commit(), "committing transaction")
+}
+
+// rollback rolls back a transaction, returning the given error along with
+// any error that occurred whilst attempting to roll back.
+func rollback(tx *sql.Tx, err error) error {
+	if rErr := tx.Rollback(); rErr != nil {
+		return errors.Wrapf(err, "rolling back transaction failed (%v) after error", rErr)
+	}
+	return err
+}
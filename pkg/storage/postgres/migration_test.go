@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations(t *testing.T) {
+	_, err := planMigration(migrations, 0, LatestSchemaVersion())
+	assert.NoError(t, err)
+	for _, m := range migrations {
+		assert.NotEmpty(t, m.description, "migration %d description", m.version)
+		assert.NotEmpty(t, m.up, "migration %d up", m.version)
+		assert.NotEmpty(t, m.down, "migration %d down", m.version)
+		assert.True(t, len(m.description) <= migrationsDescriptionMaxSize, "migration %d description length", m.version)
+	}
+}
+
+func TestPlanMigration(t *testing.T) {
+	ms := []migration{{version: 1}, {version: 2}, {version: 5}}
+	for _, test := range []struct {
+		name            string
+		ms              []migration
+		current, target uint
+		expected        []migrationStep
+		err             bool
+	}{
+		{
+			name: "no migrations",
+		},
+		{
+			name:   "up from empty",
+			ms:     ms,
+			target: 5,
+			expected: []migrationStep{
+				{migration: ms[0], apply: true},
+				{migration: ms[1], apply: true},
+				{migration: ms[2], apply: true},
+			},
+		},
+		{
+			name:    "up part way",
+			ms:      ms,
+			current: 1,
+			target:  2,
+			expected: []migrationStep{
+				{migration: ms[1], apply: true},
+			},
+		},
+		{
+			name:    "already at target",
+			ms:      ms,
+			current: 2,
+			target:  2,
+		},
+		{
+			name:    "down to empty",
+			ms:      ms,
+			current: 5,
+			expected: []migrationStep{
+				{migration: ms[2]},
+				{migration: ms[1]},
+				{migration: ms[0]},
+			},
+		},
+		{
+			name:    "down part way",
+			ms:      ms,
+			current: 5,
+			target:  1,
+			expected: []migrationStep{
+				{migration: ms[2]},
+				{migration: ms[1]},
+			},
+		},
+		{
+			name:    "unknown current",
+			ms:      ms,
+			current: 3,
+			target:  5,
+			err:     true,
+		},
+		{
+			name:   "unknown target",
+			ms:     ms,
+			target: 4,
+			err:    true,
+		},
+		{
+			name:   "unordered migrations",
+			ms:     []migration{{version: 2}, {version: 1}},
+			target: 2,
+			err:    true,
+		},
+		{
+			name:   "duplicate migrations",
+			ms:     []migration{{version: 1}, {version: 1}},
+			target: 1,
+			err:    true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			steps, err := planMigration(test.ms, test.current, test.target)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, steps)
+		})
+	}
+}
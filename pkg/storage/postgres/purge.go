@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+var (
+	// queryPurgeBalances removes the balances, and their history, that were
+	// deleted before the time given as the first query argument or that
+	// belong to an account that was. The number of balances removed is
+	// returned.
+	queryPurgeBalances = fmt.Sprintf(
+		`WITH purged AS (DELETE FROM %[1]s WHERE (%[2]s IS NOT NULL AND %[2]s < $1) OR %[3]s IN (SELECT %[4]s FROM %[5]s WHERE %[2]s IS NOT NULL AND %[2]s < $1) RETURNING %[6]s),
+history AS (DELETE FROM %[1]s%[7]s WHERE %[6]s IN (SELECT %[6]s FROM purged))
+SELECT COUNT(*) FROM purged;`,
+		balancesTable,
+		fieldDeleted,
+		balancesFieldAccountID,
+		fieldID,
+		accountsTable,
+		balancesFieldID,
+		historyTableSuffix)
+
+	// queryPurgeAccounts removes the accounts, and their history, that were
+	// deleted before the time given as the first query argument. The number
+	// of accounts removed is returned.
+	queryPurgeAccounts = fmt.Sprintf(
+		`WITH purged AS (DELETE FROM %[1]s WHERE %[2]s IS NOT NULL AND %[2]s < $1 RETURNING %[3]s),
+history AS (DELETE FROM %[1]s%[4]s WHERE %[3]s IN (SELECT %[3]s FROM purged))
+SELECT COUNT(*) FROM purged;`,
+		accountsTable,
+		fieldDeleted,
+		fieldID,
+		historyTableSuffix)
+)
+
+// Purge permanently removes the accounts and balances that were deleted
+// before the given time, along with every balance of a purged account. The
+// history of every purged record is also removed.
+func (pg postgres) Purge(before time.Time) (*storage.Purged, error) {
+	var p storage.Purged
+	err := pg.transact(func(q queryer) error {
+		// balances are purged first as they are selected by the deleted
+		// time of their accounts.
+		err := q.QueryRow(queryPurgeBalances, before).Scan(&p.Balances)
+		if err != nil {
+			return errors.Wrap(err, "purging balances")
+		}
+		return errors.Wrap(
+			q.QueryRow(queryPurgeAccounts, before).Scan(&p.Accounts),
+			"purging accounts",
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// accountsSortOrders holds the ORDER BY clause for each sort key that can be
+// given in a storage.AccountQuery.
+var accountsSortOrders = map[string]string{
+	"":                      fmt.Sprintf("%s ASC", fieldID),
+	storage.AccountSortID:   fmt.Sprintf("%s ASC", fieldID),
+	storage.AccountSortName: fmt.Sprintf("%s ASC, %s ASC", fieldName, fieldID),
+}
+
+// QueryAccounts returns the Accounts that have not been deleted and that
+// match the given storage.AccountQuery, filtering, sorting and paginating
+// them within the database.
+func (pg postgres) QueryAccounts(q storage.AccountQuery) (*storage.Accounts, error) {
+	query, args, err := accountsQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	as, err := queryAccounts(pg.q, query, args...)
+	return as, errors.Wrap(err, "querying accounts")
+}
+
+// accountsQuery returns the query, along with its arguments, that selects the
+// Accounts that match the given storage.AccountQuery.
+func accountsQuery(q storage.AccountQuery) (string, []interface{}, error) {
+	order, ok := accountsSortOrders[q.SortBy]
+	if !ok {
+		return "", nil, storage.Invalidf("unsupported sort key: %q", q.SortBy)
+	}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	var conds []string
+	if q.ExistedAt != nil {
+		conds = append(conds, fmt.Sprintf("%s <= %s", fieldOpened, arg(*q.ExistedAt)))
+	}
+	if q.OpenAt != nil {
+		at := arg(*q.OpenAt)
+		conds = append(conds, fmt.Sprintf(
+			"%[1]s <= %[3]s AND (%[2]s IS NULL OR %[2]s > %[3]s)",
+			fieldOpened, fieldClosed, at))
+	}
+	if len(q.IDs) > 0 {
+		conds = append(conds, fmt.Sprintf("%s = ANY(%s)", fieldID, arg(pq.Array(int64s(q.IDs)))))
+	}
+	if len(q.ExcludeIDs) > 0 {
+		conds = append(conds, fmt.Sprintf("NOT (%s = ANY(%s))", fieldID, arg(pq.Array(int64s(q.ExcludeIDs)))))
+	}
+	if len(q.Currencies) > 0 {
+		cs := make([]string, len(q.Currencies))
+		for i, c := range q.Currencies {
+			cs[i] = c.String()
+		}
+		conds = append(conds, fmt.Sprintf("%s = ANY(%s)", fieldCurrency, arg(pq.Array(cs))))
+	}
+	query := accountsSelectPrefix
+	for _, c := range conds {
+		query += "AND " + c + " "
+	}
+	query += "ORDER BY " + order
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+	if q.Offset > 0 {
+		query += " OFFSET " + arg(q.Offset)
+	}
+	return strings.TrimSpace(query) + ";", args, nil
+}
+
+// balancesOrders holds the ORDER BY clause for each order that can be given in
+// a storage.BalanceQuery.
+var balancesOrders = map[string]string{
+	"":                      fmt.Sprintf("%s ASC, %s ASC", balancesFieldTime, balancesFieldID),
+	storage.OrderAscending:  fmt.Sprintf("%s ASC, %s ASC", balancesFieldTime, balancesFieldID),
+	storage.OrderDescending: fmt.Sprintf("%s DESC, %s DESC", balancesFieldTime, balancesFieldID),
+}
+
+// QueryAccountBalances returns the live Balances of the account with the given
+// id that match the given storage.BalanceQuery, filtering, ordering and
+// paginating them within the database.
+func (pg postgres) QueryAccountBalances(accountID uint, q storage.BalanceQuery) (*storage.Balances, error) {
+	query, args, err := accountBalancesQuery(accountID, q)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := queryBalances(pg.q, query, args...)
+	return bs, errors.Wrap(err, "querying balances")
+}
+
+// accountBalancesQuery returns the query, along with its arguments, that
+// selects the Balances of the account with the given id that match the given
+// storage.BalanceQuery.
+func accountBalancesQuery(accountID uint, q storage.BalanceQuery) (string, []interface{}, error) {
+	order, ok := balancesOrders[q.Order]
+	if !ok {
+		return "", nil, storage.Invalidf("unsupported order: %q", q.Order)
+	}
+	args := []interface{}{accountID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	query := fmt.Sprintf("%sAND %s = $1 ", balancesSelectPrefix, balancesFieldAccountID)
+	if q.From != nil {
+		query += fmt.Sprintf("AND %s >= %s ", balancesFieldTime, arg(*q.From))
+	}
+	if q.To != nil {
+		query += fmt.Sprintf("AND %s <= %s ", balancesFieldTime, arg(*q.To))
+	}
+	query += "ORDER BY " + order
+	if q.Limit > 0 {
+		query += " LIMIT " + arg(q.Limit)
+	}
+	if q.Offset > 0 {
+		query += " OFFSET " + arg(q.Offset)
+	}
+	return query + ";", args, nil
+}
+
+func int64s(us []uint) []int64 {
+	is := make([]int64, len(us))
+	for i, u := range us {
+		is[i] = int64(u)
+	}
+	return is
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/go-money/currency"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ storage.AccountQuerier = postgres{}
+	_ storage.BalanceQuerier = postgres{}
+	_ storage.BalanceSummer  = postgres{}
+	_ storage.TokenStore     = postgres{}
+	_ storage.Tenancy        = postgres{}
+	_ storage.AuditLog       = postgres{}
+)
+
+func TestAccountsQuery(t *testing.T) {
+	t.Run("zero query", func(t *testing.T) {
+		query, args, err := accountsQuery(storage.AccountQuery{})
+		common.FatalIfError(t, err, "building query")
+		assert.Equal(t, querySelectAccounts, query)
+		assert.Empty(t, args)
+	})
+
+	t.Run("unsupported sort key", func(t *testing.T) {
+		_, _, err := accountsQuery(storage.AccountQuery{SortBy: "balance"})
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+	})
+
+	t.Run("all conditions", func(t *testing.T) {
+		at := time.Date(2018, 2, 3, 0, 0, 0, 0, time.UTC)
+		gbp, err := currency.NewCode("GBP")
+		common.FatalIfError(t, err, "creating currency code")
+		query, args, err := accountsQuery(storage.AccountQuery{
+			ExistedAt:  &at,
+			OpenAt:     &at,
+			IDs:        []uint{1, 2},
+			ExcludeIDs: []uint{3},
+			Currencies: []currency.Code{*gbp},
+			SortBy:     storage.AccountSortName,
+			Offset:     10,
+			Limit:      5,
+		})
+		common.FatalIfError(t, err, "building query")
+		assert.Equal(t, accountsSelectPrefix+
+			"AND opened <= $1 "+
+			"AND opened <= $2 AND (closed IS NULL OR closed > $2) "+
+			"AND id = ANY($3) "+
+			"AND NOT (id = ANY($4)) "+
+			"AND currency = ANY($5) "+
+			"ORDER BY name ASC, id ASC LIMIT $6 OFFSET $7;", query)
+		assert.Equal(t, []interface{}{
+			at,
+			at,
+			pq.Array([]int64{1, 2}),
+			pq.Array([]int64{3}),
+			pq.Array([]string{"GBP"}),
+			uint(5),
+			uint(10),
+		}, args)
+	})
+}
+
+func TestAccountBalancesQuery(t *testing.T) {
+	t.Run("zero query", func(t *testing.T) {
+		query, args, err := accountBalancesQuery(4, storage.BalanceQuery{})
+		common.FatalIfError(t, err, "building query")
+		assert.Equal(t, balancesSelectBalancesForAccountID, query)
+		assert.Equal(t, []interface{}{uint(4)}, args)
+	})
+
+	t.Run("unsupported order", func(t *testing.T) {
+		_, _, err := accountBalancesQuery(4, storage.BalanceQuery{Order: "sideways"})
+		assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+	})
+
+	t.Run("range and page", func(t *testing.T) {
+		from := time.Date(2018, 2, 3, 0, 0, 0, 0, time.UTC)
+		to := from.Add(time.Hour)
+		query, args, err := accountBalancesQuery(4, storage.BalanceQuery{
+			From:   &from,
+			To:     &to,
+			Order:  storage.OrderDescending,
+			Offset: 10,
+			Limit:  5,
+		})
+		common.FatalIfError(t, err, "building query")
+		assert.Equal(t, balancesSelectPrefix+
+			"AND account_id = $1 "+
+			"AND time >= $2 "+
+			"AND time <= $3 "+
+			"ORDER BY time DESC, id DESC LIMIT $4 OFFSET $5;", query)
+		assert.Equal(t, []interface{}{uint(4), from, to, uint(5), uint(10)}, args)
+	})
+}
+
+func TestBalancesSumAccountBalances(t *testing.T) {
+	assert.Equal(t,
+		"SELECT account_id, MAX(time), SUM(amount) FROM balances "+
+			"WHERE deleted IS NULL AND account_id IN (SELECT id FROM accounts WHERE deleted IS NULL) "+
+			"AND account_id = ANY($1) AND time <= $2 GROUP BY account_id;",
+		balancesSumAccountBalances)
+}
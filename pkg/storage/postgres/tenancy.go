@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	accountsFieldOwner   = "owner"
+	fieldUsername        = "username"
+	sharesTable          = "account_shares"
+	sharesFieldAccountID = "account_id"
+)
+
+var (
+	querySetAccountOwner = fmt.Sprintf(
+		`UPDATE %s SET %s = $1 WHERE %s = $2;`,
+		accountsTable,
+		accountsFieldOwner,
+		fieldID)
+
+	querySelectOwnedAccountIDs = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = $1 ORDER BY %s ASC;`,
+		fieldID,
+		accountsTable,
+		accountsFieldOwner,
+		fieldID)
+
+	querySelectSharedAccountIDs = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = $1 ORDER BY %s ASC;`,
+		sharesFieldAccountID,
+		sharesTable,
+		fieldUsername,
+		sharesFieldAccountID)
+
+	queryShareAccount = fmt.Sprintf(
+		`INSERT INTO %s (%s, %s) VALUES ($1, $2) ON CONFLICT DO NOTHING;`,
+		sharesTable,
+		sharesFieldAccountID,
+		fieldUsername)
+
+	queryUnshareAccount = fmt.Sprintf(
+		`DELETE FROM %s WHERE %s = $1 AND %s = $2;`,
+		sharesTable,
+		sharesFieldAccountID,
+		fieldUsername)
+
+	// querySelectAccountShares selects the users that an account has been
+	// shared with, along with a row with a NULL user for the account itself
+	// so that a missing account can be distinguished from an unshared one.
+	querySelectAccountShares = fmt.Sprintf(
+		`SELECT %[1]s FROM %[2]s WHERE %[3]s = $1 UNION ALL SELECT NULL FROM %[4]s WHERE %[5]s = $1 ORDER BY 1 ASC NULLS FIRST;`,
+		fieldUsername,
+		sharesTable,
+		sharesFieldAccountID,
+		accountsTable,
+		fieldID)
+)
+
+// SetAccountOwner records the given user as the owner of the account with
+// the given id.
+func (pg postgres) SetAccountOwner(accountID uint, user string) error {
+	r, err := pg.q.Exec(querySetAccountOwner, user, accountID)
+	if err != nil {
+		return errors.Wrap(unavailable(err), "executing query")
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "getting number of rows affected")
+	}
+	if n != 1 {
+		return storage.NotFoundf("no account with id %d", accountID)
+	}
+	return nil
+}
+
+// SelectUserAccountIDs returns the ids of the accounts that are owned by the
+// given user and the ids of those that have been shared with the user.
+func (pg postgres) SelectUserAccountIDs(user string) ([]uint, []uint, error) {
+	owned, err := queryIDs(pg.q, querySelectOwnedAccountIDs, user)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "querying owned account ids")
+	}
+	shared, err := queryIDs(pg.q, querySelectSharedAccountIDs, user)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "querying shared account ids")
+	}
+	return owned, shared, nil
+}
+
+// ShareAccount shares the account with the given id with the given user.
+func (pg postgres) ShareAccount(accountID uint, user string) error {
+	_, err := pg.q.Exec(queryShareAccount, accountID, user)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+		return storage.NotFoundf("no account with id %d", accountID)
+	}
+	return errors.Wrap(unavailable(err), "executing query")
+}
+
+// UnshareAccount stops sharing the account with the given id with the given
+// user.
+func (pg postgres) UnshareAccount(accountID uint, user string) error {
+	r, err := pg.q.Exec(queryUnshareAccount, accountID, user)
+	if err != nil {
+		return errors.Wrap(unavailable(err), "executing query")
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "getting number of rows affected")
+	}
+	if n != 1 {
+		return storage.NotFoundf("account %d is not shared with user %q", accountID, user)
+	}
+	return nil
+}
+
+// SelectAccountShares returns the users that the account with the given id has
+// been shared with, in alphabetical order.
+func (pg postgres) SelectAccountShares(accountID uint) ([]string, error) {
+	rows, err := pg.q.Query(querySelectAccountShares, accountID)
+	if err != nil {
+		return nil, errors.Wrap(unavailable(err), "querying db")
+	}
+	defer nonReturningCloseRows(rows)
+	var found bool
+	users := []string{}
+	for rows.Next() {
+		var u *string
+		if err := rows.Scan(&u); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		if u == nil {
+			found = true
+			continue
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "iterating rows")
+	}
+	if !found {
+		return nil, storage.NotFoundf("no account with id %d", accountID)
+	}
+	return users, nil
+}
+
+func queryIDs(db queryer, queryString string, values ...interface{}) ([]uint, error) {
+	rows, err := db.Query(queryString, values...)
+	if err != nil {
+		return nil, errors.Wrap(unavailable(err), "querying db")
+	}
+	defer nonReturningCloseRows(rows)
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
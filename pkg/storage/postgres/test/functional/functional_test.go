@@ -54,9 +54,27 @@ func TestMain(m *testing.M) {
 func TestSuite(t *testing.T) {
 	store := createStorage(t)
 	storagetest.Test(t, store)
+	storagetest.TestAtomic(t, store)
+	storagetest.TestHistory(t, store)
+	storagetest.TestVersions(t, store)
+	storagetest.TestPurge(t, store)
+	storagetest.TestTokens(t, store)
+	storagetest.TestTenancy(t, store)
+	storagetest.TestAuditLog(t, store)
+	storagetest.TestIdempotency(t, store)
 }
 
-func createStorage(t *testing.T) storage.Storage {
+// testStorage is the set of capabilities of a postgres Storage that are tested.
+type testStorage interface {
+	storagetest.HistoryStorage
+	storage.Purger
+	storage.TokenStore
+	storage.Tenancy
+	storage.AuditLog
+	storage.IdempotencyStore
+}
+
+func createStorage(t *testing.T) testStorage {
 	cs, err := postgres.NewConnectionString(
 		os.Getenv(keyDBHost),
 		os.Getenv(keyDBUser),
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	tokensTable        = "tokens"
+	tokensFieldHash    = "hash"
+	tokensFieldCreated = "created"
+	tokensFieldRevoked = "revoked"
+	tokensFieldRole    = "role"
+)
+
+var (
+	tokensFieldsSelect = fmt.Sprintf(
+		"%s, %s, %s, %s, %s, %s",
+		fieldID,
+		fieldName,
+		fieldUsername,
+		tokensFieldRole,
+		tokensFieldCreated,
+		tokensFieldRevoked)
+
+	queryInsertToken = fmt.Sprintf(
+		`INSERT INTO %s (%s, %s, %s, %s) VALUES ($1, $2, $3, $4) returning %s;`,
+		tokensTable,
+		fieldName,
+		fieldUsername,
+		tokensFieldRole,
+		tokensFieldHash,
+		tokensFieldsSelect)
+
+	querySelectTokens = fmt.Sprintf(
+		`SELECT %s FROM %s ORDER BY %s ASC;`,
+		tokensFieldsSelect,
+		tokensTable,
+		fieldID)
+
+	querySelectTokenByHash = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = $1 AND %s IS NULL;`,
+		tokensFieldsSelect,
+		tokensTable,
+		tokensFieldHash,
+		tokensFieldRevoked)
+
+	queryRevokeToken = fmt.Sprintf(
+		`UPDATE %[1]s SET %[2]s = $1 WHERE %[3]s = $2 AND %[2]s IS NULL;`,
+		tokensTable,
+		tokensFieldRevoked,
+		fieldID)
+)
+
+// InsertToken stores a Token with the given name, user, role and hash of its
+// secret.
+func (pg postgres) InsertToken(name, user string, role storage.Role, hash string) (*storage.Token, error) {
+	t, err := queryToken(pg.q, queryInsertToken, name, user, role, hash)
+	return t, errors.Wrap(err, "querying Token")
+}
+
+// SelectTokens returns every Token, including those that have been revoked,
+// ordered by their ID.
+func (pg postgres) SelectTokens() (*storage.Tokens, error) {
+	return queryTokens(pg.q, querySelectTokens)
+}
+
+// SelectTokenByHash returns the Token that has the given hash and has not
+// been revoked.
+func (pg postgres) SelectTokenByHash(hash string) (*storage.Token, error) {
+	t, err := queryToken(pg.q, querySelectTokenByHash, hash)
+	return t, errors.Wrap(err, "querying Token")
+}
+
+// RevokeToken revokes the Token with the given id.
+func (pg postgres) RevokeToken(id uint) error {
+	r, err := pg.q.Exec(queryRevokeToken, time.Now(), id)
+	if err != nil {
+		return errors.Wrap(unavailable(err), "executing query")
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "getting number of rows affected")
+	}
+	if n != 1 {
+		return storage.NotFoundf("no unrevoked token with id %d", id)
+	}
+	return nil
+}
+
+func queryToken(db queryer, queryString string, values ...interface{}) (*storage.Token, error) {
+	ts, err := queryTokens(db, queryString, values...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying tokens")
+	}
+	if len(*ts) == 0 {
+		return nil, storage.NotFoundf("query returned no tokens")
+	}
+	if len(*ts) > 1 {
+		return nil, fmt.Errorf("expected 1 token but query returned %d", len(*ts))
+	}
+	return &(*ts)[0], nil
+}
+
+func queryTokens(db queryer, queryString string, values ...interface{}) (*storage.Tokens, error) {
+	rows, err := db.Query(queryString, values...)
+	if err != nil {
+		return nil, errors.Wrap(unavailable(err), "querying db")
+	}
+	defer nonReturningCloseRows(rows)
+	ts := storage.Tokens{}
+	for rows.Next() {
+		var t storage.Token
+		var revoked pq.NullTime
+		if err := rows.Scan(&t.ID, &t.Name, &t.User, &t.Role, &t.Created, &revoked); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		if revoked.Valid {
+			t.Revoked = &revoked.Time
+		}
+		ts = append(ts, t)
+	}
+	return &ts, rows.Err()
+}
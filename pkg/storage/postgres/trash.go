@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+var (
+	querySelectDeletedAccounts = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s IS NOT NULL ORDER BY %s ASC;`,
+		accountsFieldsSelect,
+		accountsTable,
+		fieldDeleted,
+		fieldID)
+
+	queryUndeleteAccount = fmt.Sprintf(
+		`UPDATE %s SET %s = NULL WHERE %s = $1 AND %s IS NOT NULL RETURNING %s;`,
+		accountsTable,
+		fieldDeleted,
+		fieldID,
+		fieldDeleted,
+		accountsFieldsSelect)
+
+	balancesSelectDeletedBalances = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s IS NOT NULL ORDER BY %s ASC;`,
+		balancesSelectFields,
+		balancesTable,
+		fieldDeleted,
+		balancesFieldID)
+
+	// balancesUndeleteBalance will only restore a balance whose account has
+	// not been deleted.
+	balancesUndeleteBalance = fmt.Sprintf(
+		`UPDATE %[1]s SET %[2]s = NULL WHERE %[3]s = $1 AND %[2]s IS NOT NULL AND %[4]s IN (SELECT %[5]s FROM %[6]s WHERE %[2]s IS NULL) RETURNING %[7]s;`,
+		balancesTable,
+		fieldDeleted,
+		balancesFieldID,
+		balancesFieldAccountID,
+		fieldID,
+		accountsTable,
+		balancesSelectFields)
+)
+
+// SelectDeletedAccounts returns all of the Accounts that have been deleted,
+// ordered by their ID.
+func (pg postgres) SelectDeletedAccounts() (*storage.Accounts, error) {
+	return queryAccounts(pg.q, querySelectDeletedAccounts)
+}
+
+// UndeleteAccount restores the deleted account with the given id.
+func (pg postgres) UndeleteAccount(id uint) (*storage.Account, error) {
+	a, err := queryAccount(pg.q, queryUndeleteAccount, id)
+	return a, errors.Wrapf(err, "undeleting account with id %d", id)
+}
+
+// SelectDeletedBalances returns all of the Balances that have been deleted,
+// ordered by their ID.
+func (pg postgres) SelectDeletedBalances() (*storage.Balances, error) {
+	return queryBalances(pg.q, balancesSelectDeletedBalances)
+}
+
+// UndeleteBalance restores the deleted balance with the given id. A balance
+// cannot be restored whilst its account is deleted.
+func (pg postgres) UndeleteBalance(id uint) (*storage.Balance, error) {
+	b, err := queryBalance(pg.q, balancesUndeleteBalance, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying balance")
+	}
+	if b == nil {
+		return nil, storage.NotFoundf("no deleted balance with id %d belonging to an existing account", id)
+	}
+	return b, nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/glynternet/go-money/currency"
+)
+
+// The keys that an AccountQuery can be sorted by.
+const (
+	// AccountSortID sorts Accounts by their ID, which is the default order.
+	AccountSortID = "id"
+	// AccountSortName sorts Accounts by their name, then by their ID.
+	AccountSortName = "name"
+)
+
+// AccountQuery describes a subset of the Accounts of a Storage. The zero
+// AccountQuery describes every Account, ordered by ID.
+type AccountQuery struct {
+	// ExistedAt, if not nil, matches only the Accounts that were opened at
+	// or before the given time.
+	ExistedAt *time.Time
+	// OpenAt, if not nil, matches only the Accounts that were open at the
+	// given time.
+	OpenAt *time.Time
+	// IDs, if not empty, matches only the Accounts with one of the IDs.
+	IDs []uint
+	// ExcludeIDs matches only the Accounts without any of the IDs.
+	ExcludeIDs []uint
+	// Currencies, if not empty, matches only the Accounts with one of the
+	// currencies.
+	Currencies []currency.Code
+	// SortBy is the key that the matching Accounts are ordered by. It should
+	// be empty or one of AccountSortID or AccountSortName.
+	SortBy string
+	// Offset is the number of matching Accounts to skip.
+	Offset uint
+	// Limit, if not zero, is the maximum number of Accounts to return.
+	Limit uint
+}
+
+// AccountQuerier is implemented by a Storage that is able to select the
+// Accounts that match an AccountQuery itself, rather than having every
+// Account selected and filtered by the caller.
+type AccountQuerier interface {
+	// QueryAccounts returns the Accounts that have not been deleted and that
+	// match the given AccountQuery.
+	QueryAccounts(q AccountQuery) (*Accounts, error)
+}
+
+// The orders that the Balances that match a BalanceQuery can be returned in.
+const (
+	// OrderAscending orders Balances chronologically, then by their ID. It is
+	// the default order.
+	OrderAscending = "asc"
+	// OrderDescending orders Balances reverse chronologically, then by their
+	// ID in descending order.
+	OrderDescending = "desc"
+)
+
+// BalanceQuery describes a subset of the Balances of an Account. The zero
+// BalanceQuery describes every Balance of the Account, in chronological order.
+type BalanceQuery struct {
+	// From, if not nil, matches only the Balances at or after the given time.
+	From *time.Time
+	// To, if not nil, matches only the Balances at or before the given time.
+	To *time.Time
+	// Order is the order that the matching Balances are returned in. It
+	// should be empty or one of OrderAscending or OrderDescending.
+	Order string
+	// Offset is the number of matching Balances to skip.
+	Offset uint
+	// Limit, if not zero, is the maximum number of Balances to return.
+	Limit uint
+}
+
+// BalanceQuerier is implemented by a Storage that is able to select the
+// Balances of an Account that match a BalanceQuery itself, rather than having
+// every Balance of the Account selected and filtered by the caller.
+type BalanceQuerier interface {
+	// QueryAccountBalances returns the live Balances of the account with the
+	// given id that match the given BalanceQuery.
+	QueryAccountBalances(accountID uint, q BalanceQuery) (*Balances, error)
+}
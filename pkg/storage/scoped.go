@@ -0,0 +1,562 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/pkg/errors"
+)
+
+// TenantStorage is a Storage that is able to record the owners of its
+// Accounts.
+type TenantStorage interface {
+	Storage
+	Tenancy
+}
+
+// ForUser returns a Storage that holds only the Accounts of the given store
+// that are owned by or shared with the given user, along with their Balances.
+// Accounts that are inserted through the returned Storage are owned by the
+// user. Accounts that have been shared with the user, and their Balances,
+// cannot be changed through the returned Storage, with any attempt resulting
+// in an error of KindForbidden. Any other Account that is not owned by the
+// user is reported as not found. The owner of an Account is checked in the
+// same transaction of the store as any change that is made to it.
+//
+// The returned Storage also implements AccountSharer, allowing the user to
+// share the Accounts that they own. Each of History, AccountQuerier,
+// BalanceQuerier, BalanceSummer and EventLog that the given store provides is
+// given, scoped to the user, by AsHistory, AsAccountQuerier, AsBalanceQuerier,
+// AsBalanceSummer and AsEventLog. Closing the returned Storage does not close
+// the given store.
+func ForUser(store TenantStorage, user string) Storage {
+	return scoped{store: store, user: user}
+}
+
+// scoped is the Storage returned by ForUser.
+type scoped struct {
+	store TenantStorage
+	user  string
+}
+
+// visibility holds the ids of the Accounts that a user owns and the ids of
+// the Accounts that have been shared with them.
+type visibility struct {
+	owned, shared map[uint]bool
+}
+
+func (s scoped) visibility() (*visibility, error) {
+	owned, shared, err := s.store.SelectUserAccountIDs(s.user)
+	if err != nil {
+		return nil, errors.Wrapf(err, "selecting account ids of user %q", s.user)
+	}
+	v := visibility{owned: make(map[uint]bool), shared: make(map[uint]bool)}
+	for _, id := range owned {
+		v.owned[id] = true
+	}
+	for _, id := range shared {
+		v.shared[id] = true
+	}
+	return &v, nil
+}
+
+func (v visibility) readable(id uint) bool {
+	return v.owned[id] || v.shared[id]
+}
+
+// readableIDs returns those of the given ids that are of readable Accounts.
+func (v visibility) readableIDs(ids []uint) []uint {
+	var readable []uint
+	for _, id := range ids {
+		if v.readable(id) {
+			readable = append(readable, id)
+		}
+	}
+	return readable
+}
+
+func (v visibility) ids() []uint {
+	ids := make([]uint, 0, len(v.owned)+len(v.shared))
+	for id := range v.owned {
+		ids = append(ids, id)
+	}
+	for id := range v.shared {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// checkReadable returns an error if the user can not read the Account with the
+// given id.
+func (s scoped) checkReadable(id uint) error {
+	v, err := s.visibility()
+	if err != nil {
+		return err
+	}
+	if !v.readable(id) {
+		return NotFoundf("no account with id %d", id)
+	}
+	return nil
+}
+
+// checkOwned returns an error if the user does not own the Account with the
+// given id.
+func (s scoped) checkOwned(id uint) error {
+	v, err := s.visibility()
+	if err != nil {
+		return err
+	}
+	if v.shared[id] && !v.owned[id] {
+		return Forbiddenf("account %d has been shared read-only", id)
+	}
+	if !v.owned[id] {
+		return NotFoundf("no account with id %d", id)
+	}
+	return nil
+}
+
+func (s scoped) filterAccounts(as *Accounts, err error) (*Accounts, error) {
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.visibility()
+	if err != nil {
+		return nil, err
+	}
+	filtered := Accounts{}
+	for _, a := range *as {
+		if v.readable(a.ID) {
+			filtered = append(filtered, a)
+		}
+	}
+	return &filtered, nil
+}
+
+func (s scoped) filterBalances(bs *Balances, err error) (*Balances, error) {
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.visibility()
+	if err != nil {
+		return nil, err
+	}
+	filtered := Balances{}
+	for _, b := range *bs {
+		if v.readable(b.AccountID) {
+			filtered = append(filtered, b)
+		}
+	}
+	return &filtered, nil
+}
+
+// Available returns true if the underlying store is available.
+func (s scoped) Available() bool {
+	return s.store.Available()
+}
+
+// Close is a noop, as the underlying store may still be used by other users.
+func (s scoped) Close() error {
+	return nil
+}
+
+// transact runs fn with a Storage that is scoped to the user in the same way
+// as s, but that uses a single transaction of the store, as given by its
+// Atomic method.
+func (s scoped) transact(fn func(tx scoped) error) error {
+	return s.store.Atomic(func(tx Storage) error {
+		ts, ok := tx.(TenantStorage)
+		if !ok {
+			return errors.New("atomic storage does not record account owners")
+		}
+		return fn(scoped{store: ts, user: s.user})
+	})
+}
+
+// owned runs fn with a Storage that is scoped to the user in the same way as
+// s, but that uses a single transaction of the store, once it has checked in
+// that transaction that the user owns the Account with the given id.
+func (s scoped) owned(accountID uint, fn func(tx scoped) error) error {
+	return s.transact(func(tx scoped) error {
+		if err := tx.checkOwned(accountID); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// InsertAccount inserts an account.Account that is owned by the user.
+func (s scoped) InsertAccount(a account.Account) (*Account, error) {
+	var inserted *Account
+	err := s.transact(func(tx scoped) error {
+		var err error
+		inserted, err = tx.store.InsertAccount(a)
+		if err != nil {
+			return err
+		}
+		return errors.Wrap(tx.store.SetAccountOwner(inserted.ID, s.user), "setting account owner")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inserted, nil
+}
+
+// SelectAccount returns the Account with the given id if the user can read it.
+func (s scoped) SelectAccount(id uint) (*Account, error) {
+	if err := s.checkReadable(id); err != nil {
+		return nil, err
+	}
+	return s.store.SelectAccount(id)
+}
+
+// UpdateAccount updates the Account with the given id if the user owns it.
+func (s scoped) UpdateAccount(id uint, updates account.Account) (*Account, error) {
+	var updated *Account
+	err := s.owned(id, func(tx scoped) error {
+		var err error
+		updated, err = tx.store.UpdateAccount(id, updates)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// SelectAccounts returns the Accounts that the user can read.
+func (s scoped) SelectAccounts() (*Accounts, error) {
+	return s.filterAccounts(s.store.SelectAccounts())
+}
+
+// DeleteAccount deletes the Account with the given id if the user owns it.
+func (s scoped) DeleteAccount(id uint, policy DeletionPolicy) error {
+	return s.owned(id, func(tx scoped) error {
+		return tx.store.DeleteAccount(id, policy)
+	})
+}
+
+// SelectDeletedAccounts returns the deleted Accounts that the user can read.
+func (s scoped) SelectDeletedAccounts() (*Accounts, error) {
+	return s.filterAccounts(s.store.SelectDeletedAccounts())
+}
+
+// UndeleteAccount restores the deleted Account with the given id if the user
+// owns it.
+func (s scoped) UndeleteAccount(id uint) (*Account, error) {
+	var undeleted *Account
+	err := s.owned(id, func(tx scoped) error {
+		var err error
+		undeleted, err = tx.store.UndeleteAccount(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return undeleted, nil
+}
+
+// SelectBalance returns the Balance with the given id if the user can read
+// its Account.
+func (s scoped) SelectBalance(id uint) (*Balance, error) {
+	b, err := s.store.SelectBalance(id)
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkReadable(b.AccountID)
+	if KindOf(err) == KindNotFound {
+		return nil, NotFoundf("no balance with id %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// InsertBalance inserts a Balance for the Account with the given id if the
+// user owns it.
+func (s scoped) InsertBalance(accountID uint, b balance.Balance, note string) (*Balance, error) {
+	var inserted *Balance
+	err := s.owned(accountID, func(tx scoped) error {
+		var err error
+		inserted, err = tx.store.InsertBalance(accountID, b, note)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inserted, nil
+}
+
+// SelectAccountBalances returns the Balances of the Account with the given id
+// if the user can read it.
+func (s scoped) SelectAccountBalances(id uint) (*Balances, error) {
+	if err := s.checkReadable(id); err != nil {
+		return nil, err
+	}
+	return s.store.SelectAccountBalances(id)
+}
+
+// UpdateBalance updates a Balance of the Account with the given id if the user
+// owns the Account.
+func (s scoped) UpdateBalance(accountID, id uint, b balance.Balance, note string) (*Balance, error) {
+	var updated *Balance
+	err := s.owned(accountID, func(tx scoped) error {
+		var err error
+		updated, err = tx.store.UpdateBalance(accountID, id, b, note)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteBalance deletes the Balance with the given id if the user owns its
+// Account.
+func (s scoped) DeleteBalance(id uint) error {
+	return s.transact(func(tx scoped) error {
+		b, err := tx.SelectBalance(id)
+		if err != nil {
+			return err
+		}
+		if err := tx.checkOwned(b.AccountID); err != nil {
+			return err
+		}
+		return tx.store.DeleteBalance(id)
+	})
+}
+
+// SelectDeletedBalances returns the deleted Balances of the Accounts that the
+// user can read.
+func (s scoped) SelectDeletedBalances() (*Balances, error) {
+	return s.filterBalances(s.store.SelectDeletedBalances())
+}
+
+// UndeleteBalance restores the deleted Balance with the given id if the user
+// owns its Account.
+func (s scoped) UndeleteBalance(id uint) (*Balance, error) {
+	var undeleted *Balance
+	err := s.transact(func(tx scoped) error {
+		bs, err := tx.SelectDeletedBalances()
+		if err != nil {
+			return errors.Wrap(err, "selecting deleted balances")
+		}
+		for _, b := range *bs {
+			if b.ID != id {
+				continue
+			}
+			if err := tx.checkOwned(b.AccountID); err != nil {
+				return err
+			}
+			undeleted, err = tx.store.UndeleteBalance(id)
+			return err
+		}
+		return NotFoundf("no deleted balance with id %d", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return undeleted, nil
+}
+
+// Atomic runs fn against a Storage that is scoped to the user in the same way
+// as s, whose operations are committed together if fn returns nil, or
+// discarded together otherwise.
+func (s scoped) Atomic(fn func(Storage) error) error {
+	return s.transact(func(tx scoped) error {
+		return fn(tx)
+	})
+}
+
+// ShareAccount shares the Account with the given id with the given user if
+// the user of s owns the Account.
+func (s scoped) ShareAccount(accountID uint, user string) error {
+	return s.owned(accountID, func(tx scoped) error {
+		if user == s.user {
+			return Invalidf("account %d cannot be shared with its owner", accountID)
+		}
+		return tx.store.ShareAccount(accountID, user)
+	})
+}
+
+// UnshareAccount stops sharing the Account with the given id with the given
+// user if the user of s owns the Account.
+func (s scoped) UnshareAccount(accountID uint, user string) error {
+	return s.owned(accountID, func(tx scoped) error {
+		return tx.store.UnshareAccount(accountID, user)
+	})
+}
+
+// SelectAccountShares returns the users that the Account with the given id has
+// been shared with if the user of s owns the Account.
+func (s scoped) SelectAccountShares(accountID uint) ([]string, error) {
+	if err := s.checkOwned(accountID); err != nil {
+		return nil, err
+	}
+	return s.store.SelectAccountShares(accountID)
+}
+
+// AsHistory returns a History that holds only the Accounts that the user can
+// read, if the store provides a History.
+func (s scoped) AsHistory() (History, bool) {
+	h, ok := AsHistory(s.store)
+	if !ok {
+		return nil, false
+	}
+	return scopedHistory{scoped: s, history: h}, true
+}
+
+// AsAccountQuerier returns an AccountQuerier that queries only the Accounts
+// that the user can read, if the store provides an AccountQuerier.
+func (s scoped) AsAccountQuerier() (AccountQuerier, bool) {
+	aq, ok := AsAccountQuerier(s.store)
+	if !ok {
+		return nil, false
+	}
+	return scopedAccountQuerier{scoped: s, querier: aq}, true
+}
+
+// AsBalanceQuerier returns a BalanceQuerier that queries only the Balances of
+// the Accounts that the user can read, if the store provides a
+// BalanceQuerier.
+func (s scoped) AsBalanceQuerier() (BalanceQuerier, bool) {
+	bq, ok := AsBalanceQuerier(s.store)
+	if !ok {
+		return nil, false
+	}
+	return scopedBalanceQuerier{scoped: s, querier: bq}, true
+}
+
+// AsBalanceSummer returns a BalanceSummer that totals only the Balances of the
+// Accounts that the user can read, if the store provides a BalanceSummer.
+func (s scoped) AsBalanceSummer() (BalanceSummer, bool) {
+	bs, ok := AsBalanceSummer(s.store)
+	if !ok {
+		return nil, false
+	}
+	return scopedBalanceSummer{scoped: s, summer: bs}, true
+}
+
+// AsEventLog returns an EventLog that holds only the Events of the Accounts
+// that the user can read, if the store provides an EventLog.
+func (s scoped) AsEventLog() (EventLog, bool) {
+	el, ok := AsEventLog(s.store)
+	if !ok {
+		return nil, false
+	}
+	return scopedEventLog{scoped: s, log: el}, true
+}
+
+// scopedHistory implements History for the Storage returned by ForUser.
+type scopedHistory struct {
+	scoped  scoped
+	history History
+}
+
+// SelectAccountsAsOf returns the Accounts that the user can read, as they were
+// at the given time.
+func (s scopedHistory) SelectAccountsAsOf(t time.Time) (*Accounts, error) {
+	return s.scoped.filterAccounts(s.history.SelectAccountsAsOf(t))
+}
+
+// SelectAccountBalancesAsOf returns the Balances of the Account with the
+// given id, as they were at the given time, if the user can read the Account.
+func (s scopedHistory) SelectAccountBalancesAsOf(accountID uint, t time.Time) (*Balances, error) {
+	if err := s.scoped.checkReadable(accountID); err != nil {
+		return nil, err
+	}
+	return s.history.SelectAccountBalancesAsOf(accountID, t)
+}
+
+// scopedAccountQuerier implements AccountQuerier for the Storage returned by
+// ForUser.
+type scopedAccountQuerier struct {
+	scoped  scoped
+	querier AccountQuerier
+}
+
+// QueryAccounts returns the Accounts that the user can read and that match
+// the given AccountQuery.
+func (s scopedAccountQuerier) QueryAccounts(q AccountQuery) (*Accounts, error) {
+	v, err := s.scoped.visibility()
+	if err != nil {
+		return nil, err
+	}
+	ids := v.ids()
+	if len(q.IDs) > 0 {
+		ids = v.readableIDs(q.IDs)
+	}
+	if len(ids) == 0 {
+		return &Accounts{}, nil
+	}
+	q.IDs = ids
+	return s.querier.QueryAccounts(q)
+}
+
+// scopedBalanceQuerier implements BalanceQuerier for the Storage returned by
+// ForUser.
+type scopedBalanceQuerier struct {
+	scoped  scoped
+	querier BalanceQuerier
+}
+
+// QueryAccountBalances returns the Balances of the Account with the given id
+// that match the given BalanceQuery, if the user can read the Account.
+func (s scopedBalanceQuerier) QueryAccountBalances(accountID uint, q BalanceQuery) (*Balances, error) {
+	if err := s.scoped.checkReadable(accountID); err != nil {
+		return nil, err
+	}
+	return s.querier.QueryAccountBalances(accountID, q)
+}
+
+// scopedBalanceSummer implements BalanceSummer for the Storage returned by
+// ForUser.
+type scopedBalanceSummer struct {
+	scoped scoped
+	summer BalanceSummer
+}
+
+// SumAccountBalances totals the Balances of the Accounts with the given ids
+// that the user can read.
+func (s scopedBalanceSummer) SumAccountBalances(ids []uint, at time.Time) (map[uint]balance.Balance, error) {
+	v, err := s.scoped.visibility()
+	if err != nil {
+		return nil, err
+	}
+	readable := v.readableIDs(ids)
+	if len(readable) == 0 {
+		return map[uint]balance.Balance{}, nil
+	}
+	return s.summer.SumAccountBalances(readable, at)
+}
+
+// scopedEventLog implements EventLog for the Storage returned by ForUser.
+type scopedEventLog struct {
+	scoped scoped
+	log    EventLog
+}
+
+// SelectEvents returns the Events of the Accounts that the user can read that
+// have a Sequence greater than after. If limit is not zero, no more than limit
+// Events are returned.
+func (s scopedEventLog) SelectEvents(after uint64, limit uint) (*Events, error) {
+	v, err := s.scoped.visibility()
+	if err != nil {
+		return nil, err
+	}
+	es, err := s.log.SelectEvents(after, 0)
+	if err != nil {
+		return nil, err
+	}
+	selected := Events{}
+	for _, e := range *es {
+		if limit > 0 && uint(len(selected)) == limit {
+			break
+		}
+		if v.readable(e.AccountID) {
+			selected = append(selected, e)
+		}
+	}
+	return &selected, nil
+}
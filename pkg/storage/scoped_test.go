@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForUser_optionalInterfaces(t *testing.T) {
+	type (
+		plain   struct{ TenantStorage }
+		history struct {
+			TenantStorage
+			History
+		}
+		queriers struct {
+			TenantStorage
+			AccountQuerier
+			BalanceQuerier
+		}
+		summer struct {
+			TenantStorage
+			BalanceSummer
+		}
+		eventLog struct {
+			TenantStorage
+			EventLog
+		}
+		all struct {
+			TenantStorage
+			History
+			AccountQuerier
+			BalanceQuerier
+			BalanceSummer
+			EventLog
+		}
+	)
+
+	for _, store := range []TenantStorage{
+		plain{},
+		history{},
+		queriers{},
+		summer{},
+		eventLog{},
+		all{},
+	} {
+		t.Run(fmt.Sprintf("%T", store), func(t *testing.T) {
+			scoped := ForUser(store, "user")
+			for _, check := range []struct {
+				name       string
+				implements func(Storage) bool
+			}{
+				{"History", func(s Storage) bool { _, ok := AsHistory(s); return ok }},
+				{"AccountQuerier", func(s Storage) bool { _, ok := AsAccountQuerier(s); return ok }},
+				{"BalanceQuerier", func(s Storage) bool { _, ok := AsBalanceQuerier(s); return ok }},
+				{"BalanceSummer", func(s Storage) bool { _, ok := AsBalanceSummer(s); return ok }},
+				{"EventLog", func(s Storage) bool { _, ok := AsEventLog(s); return ok }},
+			} {
+				assert.Equal(t, check.implements(store), check.implements(scoped), check.name)
+			}
+			_, ok := scoped.(AccountSharer)
+			assert.True(t, ok, "AccountSharer")
+		})
+	}
+}
+
+// changedOwner is a TenantStorage whose Accounts are owned by a user outside of
+// a transaction but not within one, as if they had been given to another user
+// between the two.
+type changedOwner struct {
+	TenantStorage
+	owned []uint
+}
+
+func (s changedOwner) SelectUserAccountIDs(string) ([]uint, []uint, error) {
+	return s.owned, nil, nil
+}
+
+func (s changedOwner) Atomic(fn func(Storage) error) error {
+	return fn(changedOwner{TenantStorage: s.TenantStorage})
+}
+
+func TestForUser_checksOwnerInTransaction(t *testing.T) {
+	// the embedded TenantStorage is nil, so any change that is made without
+	// the owner being checked in the transaction panics.
+	scoped := ForUser(changedOwner{owned: []uint{1}}, "user")
+	_, err := scoped.UpdateAccount(1, account.Account{})
+	assert.Equal(t, KindNotFound, KindOf(err), "updating account")
+	_, err = scoped.InsertBalance(1, balance.Balance{}, "")
+	assert.Equal(t, KindNotFound, KindOf(err), "inserting balance")
+	assert.Equal(t, KindNotFound, KindOf(scoped.DeleteAccount(1, DeletionCascade)), "deleting account")
+	assert.Equal(t, KindNotFound, KindOf(scoped.(AccountSharer).ShareAccount(1, "other")), "sharing account")
+}
@@ -0,0 +1,249 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/glynternet/go-accounting/account"
+	"github.com/glynternet/go-money/currency"
+	gtime "github.com/glynternet/go-time"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	fieldID       = "id"
+	fieldName     = "name"
+	fieldOpened   = "opened"
+	fieldClosed   = "closed"
+	fieldCurrency = "currency"
+	fieldDeleted  = "deleted"
+	fieldVersion  = "version"
+	accountsTable = "accounts"
+)
+
+var (
+	accountsFieldsInsert = fmt.Sprintf(
+		"%s, %s, %s, %s",
+		fieldName,
+		fieldOpened,
+		fieldClosed,
+		fieldCurrency)
+
+	accountsFieldsSelect = fmt.Sprintf(
+		"%s, %s, %s, %s, %s, %s, %s",
+		fieldID,
+		fieldName,
+		fieldOpened,
+		fieldClosed,
+		fieldCurrency,
+		fieldDeleted,
+		fieldVersion)
+
+	accountsSelectPrefix = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s IS NULL `,
+		accountsFieldsSelect,
+		accountsTable,
+		fieldDeleted)
+
+	querySelectAccounts = fmt.Sprintf(
+		"%sORDER BY %s ASC;",
+		accountsSelectPrefix,
+		fieldID)
+
+	querySelectAccount = fmt.Sprintf(
+		"%sAND %s = ?;",
+		accountsSelectPrefix,
+		fieldID)
+
+	queryInsertAccount = fmt.Sprintf(
+		`INSERT INTO %s (%s) VALUES (?, ?, ?, ?);`,
+		accountsTable,
+		accountsFieldsInsert)
+
+	queryUpdateAccount = fmt.Sprintf(
+		`UPDATE %s SET %s = ?, %s = ?, %s = ?, %s = ?, %[6]s = %[6]s + 1 WHERE %s = ? AND %s IS NULL;`,
+		accountsTable,
+		fieldName,
+		fieldOpened,
+		fieldClosed,
+		fieldCurrency,
+		fieldVersion,
+		fieldID,
+		fieldDeleted)
+
+	queryDeleteAccount = fmt.Sprintf(
+		`UPDATE %s SET %s = ? WHERE %s = ? AND %s IS NULL;`,
+		accountsTable,
+		fieldDeleted,
+		fieldID,
+		fieldDeleted)
+)
+
+// SelectAccounts returns an Accounts item holding all Account entries within
+// the given database along with any errors that occurred whilst attempting to
+// retrieve the Accounts.
+func (s *sqlite) SelectAccounts() (*storage.Accounts, error) {
+	return queryAccounts(s.q, querySelectAccounts)
+}
+
+// SelectAccount returns an Account with the given id.
+func (s *sqlite) SelectAccount(id uint) (*storage.Account, error) {
+	a, err := queryAccount(s.q, querySelectAccount, id)
+	return a, errors.Wrap(err, "querying Account")
+}
+
+// InsertAccount inserts an account.Account in the storage backend and returns it.
+func (s *sqlite) InsertAccount(a account.Account) (*storage.Account, error) {
+	r, err := s.q.Exec(
+		queryInsertAccount,
+		a.Name(),
+		a.Opened().UTC(),
+		nullTimeUTC(a.Closed()),
+		a.CurrencyCode().String(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "executing query")
+	}
+	id, err := r.LastInsertId()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting inserted account id")
+	}
+	return s.SelectAccount(uint(id))
+}
+
+// UpdateAccount updates the account at a given id with the values from the given account.Account
+func (s *sqlite) UpdateAccount(id uint, updates account.Account) (*storage.Account, error) {
+	err := execSingleRow(
+		s.q,
+		queryUpdateAccount,
+		updates.Name(),
+		updates.Opened().UTC(),
+		nullTimeUTC(updates.Closed()),
+		updates.CurrencyCode().String(),
+		id,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "updating account")
+	}
+	return s.SelectAccount(id)
+}
+
+// DeleteAccount deletes an account with the given id, treating the live
+// balances of the account according to the given storage.DeletionPolicy.
+func (s *sqlite) DeleteAccount(id uint, policy storage.DeletionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	return s.transact(func(q queryer) error {
+		switch policy {
+		case storage.DeletionRefuse:
+			bs, err := queryBalances(q, balancesSelectBalancesForAccountID, id)
+			if err != nil {
+				return errors.Wrap(err, "selecting account balances")
+			}
+			if len(*bs) > 0 {
+				return storage.Conflictf("account %d has live balances", id)
+			}
+		case storage.DeletionCascade:
+			if _, err := execCount(q, balancesDeleteAccountBalances, now, id); err != nil {
+				return errors.Wrap(err, "cascading deletion to balances")
+			}
+		}
+		return execSingleRow(q, queryDeleteAccount, now, id)
+	})
+}
+
+// execSingleRow executes the given query and returns an error if exactly one
+// row was not affected. The error will be of storage.KindNotFound if no rows
+// were affected.
+func execSingleRow(db queryer, query string, args ...interface{}) error {
+	r, err := db.Exec(query, args...)
+	if err != nil {
+		return errors.Wrap(err, "executing query")
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "getting number of rows affected")
+	}
+	if n == 0 {
+		return storage.NotFoundf("no rows were affected")
+	}
+	if n != 1 {
+		return fmt.Errorf("expected 1 affected row but got %d", n)
+	}
+	return nil
+}
+
+// nullTimeUTC converts a nullable time into a sql.NullTime with its time in
+// UTC. Times are stored as text in SQLite, so storing them all in UTC keeps
+// their ordering consistent.
+func nullTimeUTC(t gtime.NullTime) sql.NullTime {
+	t.Time = t.Time.UTC()
+	return sql.NullTime(t)
+}
+
+func queryAccount(db queryer, queryString string, values ...interface{}) (*storage.Account, error) {
+	as, err := queryAccounts(db, queryString, values...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying accounts")
+	}
+	resLen := len(*as)
+	if resLen == 0 {
+		return nil, storage.NotFoundf("query returned no accounts")
+	}
+	if resLen > 1 {
+		return nil, fmt.Errorf("expected 1 account but query returned %d", resLen)
+	}
+	return &(*as)[0], nil
+}
+
+func queryAccounts(db queryer, queryString string, values ...interface{}) (*storage.Accounts, error) {
+	rows, err := db.Query(queryString, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer nonReturningCloseRows(rows)
+	return scanRowsForAccounts(rows)
+}
+
+// scanRowsForAccounts scans an sql.Rows object for storage.Accounts objects
+// and returns then along with any error that occurs whilst attempting to scan.
+func scanRowsForAccounts(rows *sql.Rows) (*storage.Accounts, error) {
+	as := storage.Accounts{}
+	for rows.Next() {
+		var id, version uint
+		var name, code string
+		var opened time.Time
+		var closed, deleted sql.NullTime
+		err := rows.Scan(&id, &name, &opened, &closed, &code, &deleted, &version)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		c, err := currency.NewCode(code)
+		if err != nil {
+			return nil, errors.Wrap(err, "generating new currency code")
+		}
+		innerAccount, err := account.New(name, *c, opened)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating new inner account")
+		}
+		if closed.Valid {
+			err = account.CloseTime(closed.Time)(innerAccount)
+			if err != nil {
+				return nil, errors.Wrap(err, "applying closed time to inner account")
+			}
+		}
+		a := &storage.Account{ID: id, Version: version, Account: *innerAccount}
+		if deleted.Valid {
+			err := storage.DeletedAt(deleted.Time)(a)
+			if err != nil {
+				return nil, errors.Wrap(err, "applying deleted time to inner account")
+			}
+		}
+		as = append(as, *a)
+	}
+	return &as, rows.Err()
+}
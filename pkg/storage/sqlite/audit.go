@@ -0,0 +1,244 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	auditTable          = "audit_entries"
+	auditFieldTime      = "time"
+	auditFieldTokenID   = "token_id"
+	auditFieldRoute     = "route"
+	auditFieldMethod    = "method"
+	auditFieldPath      = "path"
+	auditFieldAccountID = "account_id"
+	auditFieldBalanceID = "balance_id"
+	auditFieldRequest   = "request"
+	auditFieldBefore    = "before_snapshot"
+	auditFieldAfter     = "after_snapshot"
+	auditFieldStatus    = "status"
+	auditFieldError     = "error_message"
+)
+
+var (
+	auditFieldsInsert = []string{
+		auditFieldTime,
+		fieldUsername,
+		auditFieldTokenID,
+		auditFieldRoute,
+		auditFieldMethod,
+		auditFieldPath,
+		auditFieldAccountID,
+		auditFieldBalanceID,
+		auditFieldRequest,
+		auditFieldBefore,
+		auditFieldAfter,
+		auditFieldStatus,
+		auditFieldError,
+	}
+
+	auditFieldsSelect = fieldID + ", " + strings.Join(auditFieldsInsert, ", ")
+
+	queryCreateAuditTable = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s INTEGER PRIMARY KEY AUTOINCREMENT,
+	%s timestamp NOT NULL,
+	%s varchar(100) NOT NULL,
+	%s integer NOT NULL,
+	%s varchar(100) NOT NULL,
+	%s varchar(10) NOT NULL,
+	%s text NOT NULL,
+	%s integer NOT NULL,
+	%s integer NOT NULL,
+	%s text,
+	%s text,
+	%s text,
+	%s integer NOT NULL,
+	%s text NOT NULL);`,
+		auditTable,
+		fieldID,
+		auditFieldTime,
+		fieldUsername,
+		auditFieldTokenID,
+		auditFieldRoute,
+		auditFieldMethod,
+		auditFieldPath,
+		auditFieldAccountID,
+		auditFieldBalanceID,
+		auditFieldRequest,
+		auditFieldBefore,
+		auditFieldAfter,
+		auditFieldStatus,
+		auditFieldError)
+
+	queryInsertAuditEntry = fmt.Sprintf(
+		`INSERT INTO %s (%s) VALUES (?%s);`,
+		auditTable,
+		strings.Join(auditFieldsInsert, ", "),
+		strings.Repeat(", ?", len(auditFieldsInsert)-1))
+
+	querySelectAuditEntry = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = ?;`,
+		auditFieldsSelect,
+		auditTable,
+		fieldID)
+)
+
+// auditOrders holds the ORDER BY clause for each order that can be given in a
+// storage.AuditQuery.
+var auditOrders = map[string]string{
+	"":                      fmt.Sprintf("%s ASC", fieldID),
+	storage.OrderAscending:  fmt.Sprintf("%s ASC", fieldID),
+	storage.OrderDescending: fmt.Sprintf("%s DESC", fieldID),
+}
+
+// InsertAuditEntry stores the given AuditEntry, returning it with its ID and
+// Time set.
+func (s *sqlite) InsertAuditEntry(e storage.AuditEntry) (*storage.AuditEntry, error) {
+	r, err := s.q.Exec(queryInsertAuditEntry,
+		time.Now().UTC(),
+		e.User,
+		e.TokenID,
+		e.Route,
+		e.Method,
+		e.Path,
+		e.AccountID,
+		e.BalanceID,
+		nullString(e.Request),
+		nullString(e.Before),
+		nullString(e.After),
+		e.Status,
+		e.Error)
+	if err != nil {
+		return nil, errors.Wrap(err, "executing query")
+	}
+	id, err := r.LastInsertId()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting inserted audit entry id")
+	}
+	es, err := queryAuditEntries(s.q, querySelectAuditEntry, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying audit entries")
+	}
+	if len(*es) != 1 {
+		return nil, fmt.Errorf("expected 1 audit entry but query returned %d", len(*es))
+	}
+	return &(*es)[0], nil
+}
+
+// SelectAuditEntries returns the AuditEntries that match the given
+// storage.AuditQuery, filtering, ordering and paginating them within the
+// database.
+func (s *sqlite) SelectAuditEntries(q storage.AuditQuery) (*storage.AuditEntries, error) {
+	query, args, err := auditEntriesQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	es, err := queryAuditEntries(s.q, query, args...)
+	return es, errors.Wrap(err, "querying audit entries")
+}
+
+// auditEntriesQuery returns the query, along with its arguments, that selects
+// the AuditEntries that match the given storage.AuditQuery.
+func auditEntriesQuery(q storage.AuditQuery) (string, []interface{}, error) {
+	order, ok := auditOrders[q.Order]
+	if !ok {
+		return "", nil, storage.Invalidf("unsupported order: %q", q.Order)
+	}
+	var conds []string
+	var args []interface{}
+	cond := func(c string, v interface{}) {
+		conds = append(conds, c)
+		args = append(args, v)
+	}
+	if q.User != nil {
+		cond(fieldUsername+" = ?", *q.User)
+	}
+	if q.Route != "" {
+		cond(auditFieldRoute+" = ?", q.Route)
+	}
+	if q.AccountID != 0 {
+		cond(auditFieldAccountID+" = ?", q.AccountID)
+	}
+	if q.BalanceID != 0 {
+		cond(auditFieldBalanceID+" = ?", q.BalanceID)
+	}
+	if q.From != nil {
+		cond(auditFieldTime+" >= ?", q.From.UTC())
+	}
+	if q.To != nil {
+		cond(auditFieldTime+" <= ?", q.To.UTC())
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s ", auditFieldsSelect, auditTable)
+	if len(conds) > 0 {
+		query += "WHERE " + strings.Join(conds, " AND ") + " "
+	}
+	query += "ORDER BY " + order
+	if q.Limit > 0 || q.Offset > 0 {
+		// SQLite only supports an OFFSET along with a LIMIT, where a
+		// negative LIMIT has no upper bound.
+		limit := int64(-1)
+		if q.Limit > 0 {
+			limit = int64(q.Limit)
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, q.Offset)
+	}
+	return query + ";", args, nil
+}
+
+func queryAuditEntries(db queryer, queryString string, values ...interface{}) (*storage.AuditEntries, error) {
+	rows, err := db.Query(queryString, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer nonReturningCloseRows(rows)
+	es := storage.AuditEntries{}
+	for rows.Next() {
+		var e storage.AuditEntry
+		var request, before, after sql.NullString
+		if err := rows.Scan(
+			&e.ID,
+			&e.Time,
+			&e.User,
+			&e.TokenID,
+			&e.Route,
+			&e.Method,
+			&e.Path,
+			&e.AccountID,
+			&e.BalanceID,
+			&request,
+			&before,
+			&after,
+			&e.Status,
+			&e.Error,
+		); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		e.Request = rawJSON(request)
+		e.Before = rawJSON(before)
+		e.After = rawJSON(after)
+		es = append(es, e)
+	}
+	return &es, rows.Err()
+}
+
+// nullString returns the given JSON as a sql.NullString that is NULL if the
+// JSON is empty.
+func nullString(raw []byte) sql.NullString {
+	return sql.NullString{String: string(raw), Valid: len(raw) > 0}
+}
+
+// rawJSON returns the JSON held by the given sql.NullString, which is nil if
+// the sql.NullString is NULL.
+func rawJSON(s sql.NullString) []byte {
+	if !s.Valid {
+		return nil
+	}
+	return []byte(s.String)
+}
@@ -0,0 +1,206 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	balancesFieldAccountID = "account_id"
+	balancesFieldAmount    = "amount"
+	balancesFieldID        = "id"
+	balancesFieldTime      = "time"
+	balancesFieldNote      = "note"
+	balancesTable          = "balances"
+)
+
+var (
+	balancesSelectFields = fmt.Sprintf(
+		"%s, %s, %s, %s, %s, %s, %s",
+		balancesFieldID,
+		balancesFieldAccountID,
+		balancesFieldTime,
+		balancesFieldAmount,
+		balancesFieldNote,
+		fieldDeleted,
+		fieldVersion)
+
+	// balancesOfLiveAccounts matches only the balances whose account has not
+	// been deleted. The balances of a deleted account are archived with it.
+	balancesOfLiveAccounts = fmt.Sprintf(
+		`%s IN (SELECT %s FROM %s WHERE %s IS NULL)`,
+		balancesFieldAccountID,
+		fieldID,
+		accountsTable,
+		fieldDeleted)
+
+	balancesSelectPrefix = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s IS NULL AND %s `,
+		balancesSelectFields,
+		balancesTable,
+		fieldDeleted,
+		balancesOfLiveAccounts)
+
+	balancesSelectBalancesForAccountID = fmt.Sprintf(
+		"%sAND %s = ? ORDER BY %s ASC, %s ASC;",
+		balancesSelectPrefix,
+		balancesFieldAccountID,
+		balancesFieldTime,
+		balancesFieldID)
+
+	balancesSelectBalance = fmt.Sprintf(
+		"%sAND %s = ?;",
+		balancesSelectPrefix,
+		balancesFieldID)
+
+	balancesInsertFields = fmt.Sprintf(
+		"%s, %s, %s, %s",
+		balancesFieldAccountID,
+		balancesFieldTime,
+		balancesFieldAmount,
+		balancesFieldNote)
+
+	balancesInsertBalance = fmt.Sprintf(
+		`INSERT INTO %s (%s) VALUES (?, ?, ?, ?);`,
+		balancesTable,
+		balancesInsertFields)
+
+	balancesUpdateBalance = fmt.Sprintf(
+		`UPDATE %s SET %s = ?, %s = ?, %s = ?, %[5]s = %[5]s + 1 WHERE %s = ? AND %s = ? AND %s IS NULL AND %s;`,
+		balancesTable,
+		balancesFieldTime,
+		balancesFieldAmount,
+		balancesFieldNote,
+		fieldVersion,
+		balancesFieldID,
+		balancesFieldAccountID,
+		fieldDeleted,
+		balancesOfLiveAccounts)
+
+	balancesDeleteBalance = fmt.Sprintf(
+		`UPDATE %s SET %s = ? WHERE %s = ? AND %s IS NULL AND %s;`,
+		balancesTable,
+		fieldDeleted,
+		balancesFieldID,
+		fieldDeleted,
+		balancesOfLiveAccounts)
+
+	balancesDeleteAccountBalances = fmt.Sprintf(
+		`UPDATE %s SET %s = ? WHERE %s = ? AND %s IS NULL;`,
+		balancesTable,
+		fieldDeleted,
+		balancesFieldAccountID,
+		fieldDeleted)
+)
+
+// SelectAccountBalances returns all Balances for a given account ID and any
+// errors that occur whilst attempting to retrieve the Balances. The Balances
+// are sorted by chronological order then by the id of the Balance in the DB
+func (s *sqlite) SelectAccountBalances(id uint) (*storage.Balances, error) {
+	return queryBalances(s.q, balancesSelectBalancesForAccountID, id)
+}
+
+// SelectBalance returns the Balance with the given id. An error will be
+// returned if no Balance exists with the id or if it has been deleted.
+func (s *sqlite) SelectBalance(id uint) (*storage.Balance, error) {
+	b, err := queryBalance(s.q, balancesSelectBalance, id)
+	return b, errors.Wrapf(err, "selecting balance with id %d", id)
+}
+
+// InsertBalance inserts a Balance for the account with the given ID.
+func (s *sqlite) InsertBalance(accountID uint, b balance.Balance, note string) (*storage.Balance, error) {
+	if _, err := s.SelectAccount(accountID); err != nil {
+		return nil, errors.Wrapf(err, "selecting account with id %d", accountID)
+	}
+	r, err := s.q.Exec(balancesInsertBalance, accountID, b.Date.UTC(), b.Amount, note)
+	if err != nil {
+		return nil, errors.Wrap(err, "executing query")
+	}
+	id, err := r.LastInsertId()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting inserted balance id")
+	}
+	return queryBalance(s.q, balancesSelectBalance, id)
+}
+
+// UpdateBalance updates the balance with the given id, that belongs to the
+// account with the given accountID, to hold the given balance.Balance and note.
+func (s *sqlite) UpdateBalance(accountID, id uint, b balance.Balance, note string) (*storage.Balance, error) {
+	err := execSingleRow(s.q, balancesUpdateBalance, b.Date.UTC(), b.Amount, note, id, accountID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "updating balance with id %d for account %d", id, accountID)
+	}
+	return queryBalance(s.q, balancesSelectBalance, id)
+}
+
+// DeleteBalance deletes a balance with the given id
+func (s *sqlite) DeleteBalance(id uint) error {
+	return execSingleRow(s.q, balancesDeleteBalance, time.Now().UTC(), id)
+}
+
+// queryBalance returns an error if anything other than exactly one result is
+// returned from the query.
+func queryBalance(db queryer, queryString string, values ...interface{}) (*storage.Balance, error) {
+	bs, err := queryBalances(db, queryString, values...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying balances")
+	}
+	if len(*bs) == 0 {
+		return nil, storage.NotFoundf("query returned no balances")
+	}
+	if len(*bs) != 1 {
+		return nil, fmt.Errorf("expected 1 balance but query returned %d", len(*bs))
+	}
+	return &(*bs)[0], nil
+}
+
+func queryBalances(db queryer, queryString string, values ...interface{}) (*storage.Balances, error) {
+	rows, err := db.Query(queryString, values...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying db")
+	}
+	defer nonReturningCloseRows(rows)
+	return scanRowsForBalances(rows)
+}
+
+// scanRowsForBalances scans a sql.Rows for a Balances object and returns any
+// error occurring along the way.
+func scanRowsForBalances(rows *sql.Rows) (*storage.Balances, error) {
+	bs := &storage.Balances{}
+	for rows.Next() {
+		var id, version uint
+		var date time.Time
+		var amount int
+		var accountID uint
+		var note sql.NullString
+		var deleted sql.NullTime
+		err := rows.Scan(&id, &accountID, &date, &amount, &note, &deleted, &version)
+		if err != nil {
+			return nil, errors.Wrap(err, "scanning rows")
+		}
+		innerB, err := balance.New(date, balance.Amount(amount))
+		if err != nil {
+			return nil, errors.Wrap(err, "creating new balance from scan results")
+		}
+		b := storage.Balance{
+			ID:        id,
+			Version:   version,
+			AccountID: accountID,
+			Balance:   *innerB,
+			Note:      note.String,
+		}
+		if deleted.Valid {
+			err = storage.BalanceDeletedAt(deleted.Time)(&b)
+			if err != nil {
+				return nil, errors.Wrap(err, "applying deleted time to balance")
+			}
+		}
+		*bs = append(*bs, b)
+	}
+	return bs, errors.Wrap(rows.Err(), "rows error")
+}
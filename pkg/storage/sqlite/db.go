@@ -0,0 +1,222 @@
+// Package sqlite provides a storage.Storage implementation backed by a SQLite
+// database file. It is intended for single-user deployments where running a
+// postgres server would be unnecessarily heavy.
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/glynternet/mon/pkg/storage"
+	// registers the sqlite3 driver with database/sql
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// New opens a SQLite database at the given path, creating the file and any
+// tables that do not already exist, and returns it as a Storage.
+// The special path ":memory:" can be used to create a Storage that is held
+// only in memory.
+func New(path string) (*sqlite, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening connection to backend")
+	}
+	// SQLite only supports a single writer and an in-memory database only
+	// exists for the connection that created it, so all queries are made
+	// through a single connection.
+	db.SetMaxOpenConns(1)
+	err = createTables(db)
+	if err != nil {
+		nonReturningCloseDB(db)
+		return nil, errors.Wrap(err, "creating tables")
+	}
+	return &sqlite{db: db, q: db}, nil
+}
+
+type sqlite struct {
+	db *sql.DB
+
+	// q is used to run all queries against the storage. q is the db itself
+	// unless the sqlite is being used within a transaction.
+	q queryer
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func createTables(db *sql.DB) error {
+	_, err := db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s INTEGER PRIMARY KEY AUTOINCREMENT,
+	%s varchar(100) NOT NULL,
+	%s char(3) NOT NULL,
+	%s timestamp NOT NULL,
+	%s timestamp,
+	%s timestamp,
+	%s varchar(100) NOT NULL DEFAULT '',
+	%s integer NOT NULL DEFAULT 1);`,
+		accountsTable,
+		fieldID,
+		fieldName,
+		fieldCurrency,
+		fieldOpened,
+		fieldClosed,
+		fieldDeleted,
+		accountsFieldOwner,
+		fieldVersion))
+	if err != nil {
+		return errors.Wrap(err, "executing create accounts query")
+	}
+	_, err = db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s INTEGER PRIMARY KEY AUTOINCREMENT,
+	%s integer NOT NULL,
+	%s timestamp NOT NULL,
+	%s bigint NOT NULL,
+	%s varchar(240),
+	%s timestamp,
+	%s integer NOT NULL DEFAULT 1);`,
+		balancesTable,
+		balancesFieldID,
+		balancesFieldAccountID,
+		balancesFieldTime,
+		balancesFieldAmount,
+		balancesFieldNote,
+		fieldDeleted,
+		fieldVersion))
+	if err != nil {
+		return errors.Wrap(err, "executing create balances query")
+	}
+	_, err = db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s INTEGER PRIMARY KEY AUTOINCREMENT,
+	%s varchar(100) NOT NULL,
+	%s char(64) NOT NULL UNIQUE,
+	%s timestamp NOT NULL,
+	%s timestamp,
+	%s varchar(100) NOT NULL DEFAULT '',
+	%s varchar(20) NOT NULL DEFAULT '%s');`,
+		tokensTable,
+		fieldID,
+		fieldName,
+		tokensFieldHash,
+		tokensFieldCreated,
+		tokensFieldRevoked,
+		fieldUsername,
+		tokensFieldRole,
+		storage.RoleAdmin))
+	if err != nil {
+		return errors.Wrap(err, "executing create tokens query")
+	}
+	_, err = db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s integer NOT NULL,
+	%s varchar(100) NOT NULL,
+	PRIMARY KEY (%s, %s));`,
+		sharesTable,
+		sharesFieldAccountID,
+		fieldUsername,
+		sharesFieldAccountID,
+		fieldUsername))
+	if err != nil {
+		return errors.Wrap(err, "executing create shares query")
+	}
+	_, err = db.Exec(queryCreateAuditTable)
+	if err != nil {
+		return errors.Wrap(err, "executing create audit entries query")
+	}
+	_, err = db.Exec(queryCreateIdempotencyTable)
+	if err != nil {
+		return errors.Wrap(err, "executing create idempotent requests query")
+	}
+	return upgradeTables(db)
+}
+
+// upgradeTables adds the columns that are missing from the tables of a
+// database that was created by an earlier version of the package. Tokens
+// that were created before Tokens had roles keep the access that they had,
+// which was to every request.
+func upgradeTables(db *sql.DB) error {
+	for _, c := range []struct {
+		table, column, definition string
+	}{
+		{table: accountsTable, column: accountsFieldOwner, definition: "varchar(100) NOT NULL DEFAULT ''"},
+		{table: accountsTable, column: fieldVersion, definition: "integer NOT NULL DEFAULT 1"},
+		{table: balancesTable, column: fieldVersion, definition: "integer NOT NULL DEFAULT 1"},
+		{table: tokensTable, column: fieldUsername, definition: "varchar(100) NOT NULL DEFAULT ''"},
+		{table: tokensTable, column: tokensFieldRole, definition: fmt.Sprintf("varchar(20) NOT NULL DEFAULT '%s'", storage.RoleAdmin)},
+		{table: idempotencyTable, column: idempotencyFieldReservation, definition: "varchar(64) NOT NULL DEFAULT ''"},
+	} {
+		_, err := db.Exec(fmt.Sprintf(
+			`ALTER TABLE %s ADD COLUMN %s %s;`,
+			c.table,
+			c.column,
+			c.definition))
+		if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return errors.Wrapf(err, "adding %s column to %s table", c.column, c.table)
+		}
+	}
+	return nil
+}
+
+// Atomic runs fn within a single database transaction. The transaction is
+// committed if fn returns a nil error and rolled back otherwise.
+// If the sqlite is already within a transaction, fn is run as part of that
+// transaction.
+func (s *sqlite) Atomic(fn func(storage.Storage) error) error {
+	return s.transact(func(q queryer) error {
+		return fn(&sqlite{db: s.db, q: q})
+	})
+}
+
+// transact runs fn with a queryer for a single database transaction, in the
+// same way as Atomic.
+func (s *sqlite) transact(fn func(queryer) error) error {
+	if _, ok := s.q.(*sql.Tx); ok {
+		return fn(s.q)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	err = fn(tx)
+	if err != nil {
+		if rErr := tx.Rollback(); rErr != nil {
+			return errors.Wrapf(err, "rolling back transaction failed (%v) after error", rErr)
+		}
+		return err
+	}
+	return errors.Wrap(tx.Commit(), "committing transaction")
+}
+
+// Available returns true if the Storage is available
+func (s *sqlite) Available() bool {
+	return s.db.Ping() == nil
+}
+
+// Close closes the underlying database
+func (s *sqlite) Close() error {
+	return s.db.Close()
+}
+
+func nonReturningClose(c io.Closer, name string) {
+	if c == nil {
+		log.Printf("Attempted to close io.Closer (%s) but it was nil.", name)
+		return
+	}
+	err := c.Close()
+	if err != nil {
+		log.Printf("Error closing io.Closer (%s) %v", name, c)
+	}
+}
+
+func nonReturningCloseDB(db *sql.DB) {
+	nonReturningClose(db, "DB")
+}
+
+func nonReturningCloseRows(rows *sql.Rows) {
+	nonReturningClose(rows, "Rows")
+}
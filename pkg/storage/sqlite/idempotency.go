@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	idempotencyTable            = "idempotent_requests"
+	idempotencyFieldKey         = "idempotency_key"
+	idempotencyFieldFingerprint = "fingerprint"
+	idempotencyFieldReservation = "reservation"
+	idempotencyFieldCreated     = "created"
+	idempotencyFieldStatus      = "status"
+	idempotencyFieldLocation    = "location"
+	idempotencyFieldETag        = "etag"
+	idempotencyFieldBody        = "body"
+)
+
+var (
+	idempotencyFieldsSelect = fmt.Sprintf(
+		"%s, %s, %s, %s, %s, %s, %s, %s, %s",
+		fieldUsername,
+		idempotencyFieldKey,
+		idempotencyFieldFingerprint,
+		idempotencyFieldReservation,
+		idempotencyFieldCreated,
+		idempotencyFieldStatus,
+		idempotencyFieldLocation,
+		idempotencyFieldETag,
+		idempotencyFieldBody)
+
+	queryCreateIdempotencyTable = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	%s varchar(100) NOT NULL,
+	%s varchar(255) NOT NULL,
+	%s char(64) NOT NULL,
+	%s varchar(64) NOT NULL DEFAULT '',
+	%s timestamp NOT NULL,
+	%s integer NOT NULL DEFAULT 0,
+	%s text NOT NULL DEFAULT '',
+	%s text NOT NULL DEFAULT '',
+	%s text,
+	PRIMARY KEY (%s, %s));`,
+		idempotencyTable,
+		fieldUsername,
+		idempotencyFieldKey,
+		idempotencyFieldFingerprint,
+		idempotencyFieldReservation,
+		idempotencyFieldCreated,
+		idempotencyFieldStatus,
+		idempotencyFieldLocation,
+		idempotencyFieldETag,
+		idempotencyFieldBody,
+		fieldUsername,
+		idempotencyFieldKey)
+
+	queryDeleteExpiredIdempotentRequests = fmt.Sprintf(
+		`DELETE FROM %s WHERE %s <= ? OR (%s = 0 AND %[2]s <= ?);`,
+		idempotencyTable,
+		idempotencyFieldCreated,
+		idempotencyFieldStatus)
+
+	queryReserveIdempotentRequest = fmt.Sprintf(
+		`INSERT OR IGNORE INTO %s (%s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?);`,
+		idempotencyTable,
+		fieldUsername,
+		idempotencyFieldKey,
+		idempotencyFieldFingerprint,
+		idempotencyFieldReservation,
+		idempotencyFieldCreated)
+
+	querySelectIdempotentRequest = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = ? AND %s = ?;`,
+		idempotencyFieldsSelect,
+		idempotencyTable,
+		fieldUsername,
+		idempotencyFieldKey)
+
+	queryCompleteIdempotentRequest = fmt.Sprintf(
+		`UPDATE %s SET %s = ?, %s = ?, %s = ?, %s = ? WHERE %s = ? AND %s = ? AND %s = ? AND %[2]s = 0;`,
+		idempotencyTable,
+		idempotencyFieldStatus,
+		idempotencyFieldLocation,
+		idempotencyFieldETag,
+		idempotencyFieldBody,
+		fieldUsername,
+		idempotencyFieldKey,
+		idempotencyFieldReservation)
+
+	queryReleaseIdempotentRequest = fmt.Sprintf(
+		`DELETE FROM %s WHERE %s = ? AND %s = ? AND %s = ? AND %s = 0;`,
+		idempotencyTable,
+		fieldUsername,
+		idempotencyFieldKey,
+		idempotencyFieldReservation,
+		idempotencyFieldStatus)
+)
+
+// ReserveIdempotentRequest stores the given IdempotentRequest without a
+// response, unless there is already an IdempotentRequest with the same User
+// and Key that was created after the given time, in which case that one is
+// returned along with false. An IdempotentRequest without a response that was
+// created at or before reservedAfter is replaced.
+func (s *sqlite) ReserveIdempotentRequest(r storage.IdempotentRequest, after, reservedAfter time.Time) (*storage.IdempotentRequest, bool, error) {
+	if _, err := s.q.Exec(queryDeleteExpiredIdempotentRequests, after.UTC(), reservedAfter.UTC()); err != nil {
+		return nil, false, errors.Wrap(err, "deleting expired requests")
+	}
+	res, err := s.q.Exec(queryReserveIdempotentRequest, r.User, r.Key, r.Fingerprint, r.Reservation, time.Now().UTC())
+	if err != nil {
+		return nil, false, errors.Wrap(err, "executing query")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "getting number of rows affected")
+	}
+	ir, err := queryIdempotentRequest(s.q, r.User, r.Key)
+	return ir, n == 1, errors.Wrap(err, "querying request")
+}
+
+// CompleteIdempotentRequest stores the response of the given
+// IdempotentRequest for the reserved IdempotentRequest with the same User,
+// Key and Reservation.
+func (s *sqlite) CompleteIdempotentRequest(r storage.IdempotentRequest) error {
+	return errors.Wrapf(
+		execSingleRow(s.q, queryCompleteIdempotentRequest,
+			r.Status, r.Location, r.ETag, nullString(r.Body), r.User, r.Key, r.Reservation),
+		"completing request with key %q", r.Key,
+	)
+}
+
+// ReleaseIdempotentRequest deletes the IdempotentRequest with the given user,
+// key and reservation if it is without a response.
+func (s *sqlite) ReleaseIdempotentRequest(user, key, reservation string) error {
+	_, err := s.q.Exec(queryReleaseIdempotentRequest, user, key, reservation)
+	return errors.Wrap(err, "executing query")
+}
+
+func queryIdempotentRequest(db queryer, user, key string) (*storage.IdempotentRequest, error) {
+	rows, err := db.Query(querySelectIdempotentRequest, user, key)
+	if err != nil {
+		return nil, err
+	}
+	defer nonReturningCloseRows(rows)
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, storage.NotFoundf("no request with key %q", key)
+	}
+	var ir storage.IdempotentRequest
+	var body sql.NullString
+	if err := rows.Scan(
+		&ir.User,
+		&ir.Key,
+		&ir.Fingerprint,
+		&ir.Reservation,
+		&ir.Created,
+		&ir.Status,
+		&ir.Location,
+		&ir.ETag,
+		&body); err != nil {
+		return nil, errors.Wrap(err, "scanning row")
+	}
+	ir.Body = rawJSON(body)
+	return &ir, rows.Err()
+}
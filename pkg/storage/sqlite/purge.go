@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+var (
+	queryPurgeShares = fmt.Sprintf(
+		`DELETE FROM %[1]s WHERE %[2]s IN (SELECT %[3]s FROM %[4]s WHERE %[5]s IS NOT NULL AND %[5]s < ?);`,
+		sharesTable,
+		sharesFieldAccountID,
+		fieldID,
+		accountsTable,
+		fieldDeleted)
+
+	queryPurgeBalances = fmt.Sprintf(
+		`DELETE FROM %[1]s WHERE (%[2]s IS NOT NULL AND %[2]s < ?) OR %[3]s IN (SELECT %[4]s FROM %[5]s WHERE %[2]s IS NOT NULL AND %[2]s < ?);`,
+		balancesTable,
+		fieldDeleted,
+		balancesFieldAccountID,
+		fieldID,
+		accountsTable)
+
+	queryPurgeAccounts = fmt.Sprintf(
+		`DELETE FROM %[1]s WHERE %[2]s IS NOT NULL AND %[2]s < ?;`,
+		accountsTable,
+		fieldDeleted)
+)
+
+// Purge permanently removes the accounts and balances that were deleted
+// before the given time, along with every balance of a purged account.
+func (s *sqlite) Purge(before time.Time) (*storage.Purged, error) {
+	before = before.UTC()
+	var p storage.Purged
+	err := s.transact(func(q queryer) error {
+		// shares and balances are purged first as they are selected by the
+		// deleted time of their accounts.
+		if _, err := q.Exec(queryPurgeShares, before); err != nil {
+			return errors.Wrap(err, "purging shares")
+		}
+		var err error
+		p.Balances, err = execCount(q, queryPurgeBalances, before, before)
+		if err != nil {
+			return errors.Wrap(err, "purging balances")
+		}
+		p.Accounts, err = execCount(q, queryPurgeAccounts, before)
+		return errors.Wrap(err, "purging accounts")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// execCount executes the given query and returns the number of rows affected.
+func execCount(db queryer, query string, args ...interface{}) (int, error) {
+	r, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, errors.Wrap(err, "executing query")
+	}
+	n, err := r.RowsAffected()
+	return int(n), errors.Wrap(err, "getting number of rows affected")
+}
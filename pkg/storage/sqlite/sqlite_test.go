@@ -0,0 +1,122 @@
+package sqlite_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/glynternet/mon/pkg/storage/sqlite"
+	"github.com/glynternet/mon/pkg/storage/storagetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// testStorage is the set of capabilities of a sqlite Storage that are tested.
+type testStorage interface {
+	storagetest.PurgeStorage
+	storage.TokenStore
+	storage.Tenancy
+	storage.AuditLog
+	storage.IdempotencyStore
+}
+
+func newTestStorage(t *testing.T) testStorage {
+	store, err := sqlite.New(":memory:")
+	common.FatalIfError(t, err, "creating storage")
+	return store
+}
+
+func TestSuite(t *testing.T) {
+	store := newTestStorage(t)
+	defer func() {
+		common.FatalIfError(t, store.Close(), "closing storage")
+	}()
+	storagetest.Test(t, store)
+}
+
+func TestAtomic(t *testing.T) {
+	store := newTestStorage(t)
+	defer func() {
+		common.FatalIfError(t, store.Close(), "closing storage")
+	}()
+	storagetest.TestAtomic(t, store)
+}
+
+func TestVersions(t *testing.T) {
+	store := newTestStorage(t)
+	defer func() {
+		common.FatalIfError(t, store.Close(), "closing storage")
+	}()
+	storagetest.TestVersions(t, store)
+}
+
+func TestPurge(t *testing.T) {
+	store := newTestStorage(t)
+	defer func() {
+		common.FatalIfError(t, store.Close(), "closing storage")
+	}()
+	storagetest.TestPurge(t, store)
+}
+
+func TestTokens(t *testing.T) {
+	store := newTestStorage(t)
+	defer func() {
+		common.FatalIfError(t, store.Close(), "closing storage")
+	}()
+	storagetest.TestTokens(t, store)
+}
+
+func TestTenancy(t *testing.T) {
+	store := newTestStorage(t)
+	defer func() {
+		common.FatalIfError(t, store.Close(), "closing storage")
+	}()
+	storagetest.TestTenancy(t, store)
+}
+
+func TestAuditLog(t *testing.T) {
+	store := newTestStorage(t)
+	defer func() {
+		common.FatalIfError(t, store.Close(), "closing storage")
+	}()
+	storagetest.TestAuditLog(t, store)
+}
+
+func TestIdempotency(t *testing.T) {
+	store := newTestStorage(t)
+	defer func() {
+		common.FatalIfError(t, store.Close(), "closing storage")
+	}()
+	storagetest.TestIdempotency(t, store)
+}
+
+func TestNew_PersistsToFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mon-sqlite")
+	common.FatalIfError(t, err, "creating temp dir")
+	defer func() {
+		common.FatalIfError(t, os.RemoveAll(dir), "removing temp dir")
+	}()
+	path := filepath.Join(dir, "mon.db")
+
+	store, err := sqlite.New(path)
+	common.FatalIfError(t, err, "creating storage")
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	inserted, err := store.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+	common.FatalIfError(t, store.Close(), "closing storage")
+
+	reopened, err := sqlite.New(path)
+	common.FatalIfError(t, err, "reopening storage")
+	defer func() {
+		common.FatalIfError(t, reopened.Close(), "closing storage")
+	}()
+	selected, err := reopened.SelectAccount(inserted.ID)
+	common.FatalIfError(t, err, "selecting account")
+	equal, err := inserted.Equal(*selected)
+	common.FatalIfError(t, err, "equaling inserted and selected")
+	assert.True(t, equal)
+}
@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	accountsFieldOwner   = "owner"
+	fieldUsername        = "username"
+	sharesTable          = "account_shares"
+	sharesFieldAccountID = "account_id"
+)
+
+var (
+	querySetAccountOwner = fmt.Sprintf(
+		`UPDATE %s SET %s = ? WHERE %s = ?;`,
+		accountsTable,
+		accountsFieldOwner,
+		fieldID)
+
+	querySelectOwnedAccountIDs = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = ? ORDER BY %s ASC;`,
+		fieldID,
+		accountsTable,
+		accountsFieldOwner,
+		fieldID)
+
+	querySelectSharedAccountIDs = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = ? ORDER BY %s ASC;`,
+		sharesFieldAccountID,
+		sharesTable,
+		fieldUsername,
+		sharesFieldAccountID)
+
+	queryShareAccount = fmt.Sprintf(
+		`INSERT OR IGNORE INTO %s (%s, %s) VALUES (?, ?);`,
+		sharesTable,
+		sharesFieldAccountID,
+		fieldUsername)
+
+	queryUnshareAccount = fmt.Sprintf(
+		`DELETE FROM %s WHERE %s = ? AND %s = ?;`,
+		sharesTable,
+		sharesFieldAccountID,
+		fieldUsername)
+
+	querySelectAccountShares = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = ? ORDER BY %s ASC;`,
+		fieldUsername,
+		sharesTable,
+		sharesFieldAccountID,
+		fieldUsername)
+
+	queryAccountExists = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = ?;`,
+		fieldID,
+		accountsTable,
+		fieldID)
+)
+
+// SetAccountOwner records the given user as the owner of the account with
+// the given id.
+func (s *sqlite) SetAccountOwner(accountID uint, user string) error {
+	return errors.Wrapf(
+		execSingleRow(s.q, querySetAccountOwner, user, accountID),
+		"setting owner of account with id %d", accountID,
+	)
+}
+
+// SelectUserAccountIDs returns the ids of the accounts that are owned by the
+// given user and the ids of those that have been shared with the user.
+func (s *sqlite) SelectUserAccountIDs(user string) ([]uint, []uint, error) {
+	owned, err := queryIDs(s.q, querySelectOwnedAccountIDs, user)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "querying owned account ids")
+	}
+	shared, err := queryIDs(s.q, querySelectSharedAccountIDs, user)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "querying shared account ids")
+	}
+	return owned, shared, nil
+}
+
+// ShareAccount shares the account with the given id with the given user.
+func (s *sqlite) ShareAccount(accountID uint, user string) error {
+	if err := s.checkAccountExists(accountID); err != nil {
+		return err
+	}
+	_, err := s.q.Exec(queryShareAccount, accountID, user)
+	return errors.Wrap(err, "executing query")
+}
+
+// UnshareAccount stops sharing the account with the given id with the given
+// user.
+func (s *sqlite) UnshareAccount(accountID uint, user string) error {
+	return errors.Wrapf(
+		execSingleRow(s.q, queryUnshareAccount, accountID, user),
+		"unsharing account with id %d with user %q", accountID, user,
+	)
+}
+
+// SelectAccountShares returns the users that the account with the given id has
+// been shared with.
+func (s *sqlite) SelectAccountShares(accountID uint) ([]string, error) {
+	if err := s.checkAccountExists(accountID); err != nil {
+		return nil, err
+	}
+	rows, err := s.q.Query(querySelectAccountShares, accountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying account shares")
+	}
+	defer nonReturningCloseRows(rows)
+	users := []string{}
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// checkAccountExists returns an error of kind storage.KindNotFound if there
+// is no account with the given id, whether deleted or not.
+func (s *sqlite) checkAccountExists(id uint) error {
+	ids, err := queryIDs(s.q, queryAccountExists, id)
+	if err != nil {
+		return errors.Wrap(err, "querying account")
+	}
+	if len(ids) == 0 {
+		return storage.NotFoundf("no account with id %d", id)
+	}
+	return nil
+}
+
+func queryIDs(db queryer, queryString string, values ...interface{}) ([]uint, error) {
+	rows, err := db.Query(queryString, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer nonReturningCloseRows(rows)
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,134 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+const (
+	tokensTable        = "tokens"
+	tokensFieldHash    = "hash"
+	tokensFieldCreated = "created"
+	tokensFieldRevoked = "revoked"
+	tokensFieldRole    = "role"
+)
+
+var (
+	tokensFieldsSelect = fmt.Sprintf(
+		"%s, %s, %s, %s, %s, %s",
+		fieldID,
+		fieldName,
+		fieldUsername,
+		tokensFieldRole,
+		tokensFieldCreated,
+		tokensFieldRevoked)
+
+	queryInsertToken = fmt.Sprintf(
+		`INSERT INTO %s (%s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?);`,
+		tokensTable,
+		fieldName,
+		fieldUsername,
+		tokensFieldRole,
+		tokensFieldHash,
+		tokensFieldCreated)
+
+	querySelectTokens = fmt.Sprintf(
+		`SELECT %s FROM %s ORDER BY %s ASC;`,
+		tokensFieldsSelect,
+		tokensTable,
+		fieldID)
+
+	querySelectToken = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = ?;`,
+		tokensFieldsSelect,
+		tokensTable,
+		fieldID)
+
+	querySelectTokenByHash = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s = ? AND %s IS NULL;`,
+		tokensFieldsSelect,
+		tokensTable,
+		tokensFieldHash,
+		tokensFieldRevoked)
+
+	queryRevokeToken = fmt.Sprintf(
+		`UPDATE %[1]s SET %[2]s = ? WHERE %[3]s = ? AND %[2]s IS NULL;`,
+		tokensTable,
+		tokensFieldRevoked,
+		fieldID)
+)
+
+// InsertToken stores a Token with the given name, user, role and hash of its
+// secret.
+func (s *sqlite) InsertToken(name, user string, role storage.Role, hash string) (*storage.Token, error) {
+	r, err := s.q.Exec(queryInsertToken, name, user, role, hash, time.Now().UTC())
+	if err != nil {
+		return nil, errors.Wrap(err, "executing query")
+	}
+	id, err := r.LastInsertId()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting inserted token id")
+	}
+	t, err := queryToken(s.q, querySelectToken, id)
+	return t, errors.Wrap(err, "querying Token")
+}
+
+// SelectTokens returns every Token, including those that have been revoked,
+// ordered by their ID.
+func (s *sqlite) SelectTokens() (*storage.Tokens, error) {
+	return queryTokens(s.q, querySelectTokens)
+}
+
+// SelectTokenByHash returns the Token that has the given hash and has not
+// been revoked.
+func (s *sqlite) SelectTokenByHash(hash string) (*storage.Token, error) {
+	t, err := queryToken(s.q, querySelectTokenByHash, hash)
+	return t, errors.Wrap(err, "querying Token")
+}
+
+// RevokeToken revokes the Token with the given id.
+func (s *sqlite) RevokeToken(id uint) error {
+	return errors.Wrapf(
+		execSingleRow(s.q, queryRevokeToken, time.Now().UTC(), id),
+		"revoking token with id %d", id,
+	)
+}
+
+func queryToken(db queryer, queryString string, values ...interface{}) (*storage.Token, error) {
+	ts, err := queryTokens(db, queryString, values...)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying tokens")
+	}
+	if len(*ts) == 0 {
+		return nil, storage.NotFoundf("query returned no tokens")
+	}
+	if len(*ts) > 1 {
+		return nil, fmt.Errorf("expected 1 token but query returned %d", len(*ts))
+	}
+	return &(*ts)[0], nil
+}
+
+func queryTokens(db queryer, queryString string, values ...interface{}) (*storage.Tokens, error) {
+	rows, err := db.Query(queryString, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer nonReturningCloseRows(rows)
+	ts := storage.Tokens{}
+	for rows.Next() {
+		var t storage.Token
+		var revoked sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Name, &t.User, &t.Role, &t.Created, &revoked); err != nil {
+			return nil, errors.Wrap(err, "scanning row")
+		}
+		if revoked.Valid {
+			t.Revoked = &revoked.Time
+		}
+		ts = append(ts, t)
+	}
+	return &ts, rows.Err()
+}
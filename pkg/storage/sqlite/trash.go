@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+)
+
+var (
+	querySelectDeletedAccounts = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s IS NOT NULL ORDER BY %s ASC;`,
+		accountsFieldsSelect,
+		accountsTable,
+		fieldDeleted,
+		fieldID)
+
+	queryUndeleteAccount = fmt.Sprintf(
+		`UPDATE %s SET %s = NULL WHERE %s = ? AND %s IS NOT NULL;`,
+		accountsTable,
+		fieldDeleted,
+		fieldID,
+		fieldDeleted)
+
+	balancesSelectDeletedBalances = fmt.Sprintf(
+		`SELECT %s FROM %s WHERE %s IS NOT NULL ORDER BY %s ASC;`,
+		balancesSelectFields,
+		balancesTable,
+		fieldDeleted,
+		balancesFieldID)
+
+	// balancesUndeleteBalance will only restore a balance whose account has
+	// not been deleted.
+	balancesUndeleteBalance = fmt.Sprintf(
+		`UPDATE %[1]s SET %[2]s = NULL WHERE %[3]s = ? AND %[2]s IS NOT NULL AND %[4]s IN (SELECT %[5]s FROM %[6]s WHERE %[2]s IS NULL);`,
+		balancesTable,
+		fieldDeleted,
+		balancesFieldID,
+		balancesFieldAccountID,
+		fieldID,
+		accountsTable)
+)
+
+// SelectDeletedAccounts returns all of the Accounts that have been deleted,
+// ordered by their ID.
+func (s *sqlite) SelectDeletedAccounts() (*storage.Accounts, error) {
+	return queryAccounts(s.q, querySelectDeletedAccounts)
+}
+
+// UndeleteAccount restores the deleted account with the given id.
+func (s *sqlite) UndeleteAccount(id uint) (*storage.Account, error) {
+	err := execSingleRow(s.q, queryUndeleteAccount, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "undeleting account with id %d", id)
+	}
+	return s.SelectAccount(id)
+}
+
+// SelectDeletedBalances returns all of the Balances that have been deleted,
+// ordered by their ID.
+func (s *sqlite) SelectDeletedBalances() (*storage.Balances, error) {
+	return queryBalances(s.q, balancesSelectDeletedBalances)
+}
+
+// UndeleteBalance restores the deleted balance with the given id. A balance
+// cannot be restored whilst its account is deleted.
+func (s *sqlite) UndeleteBalance(id uint) (*storage.Balance, error) {
+	err := execSingleRow(s.q, balancesUndeleteBalance, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "undeleting balance with id %d", id)
+	}
+	return s.SelectBalance(id)
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/glynternet/go-accounting/account"
 	"github.com/glynternet/go-accounting/balance"
 )
@@ -13,10 +15,59 @@ type Storage interface {
 	SelectAccount(id uint) (*Account, error)
 	UpdateAccount(id uint, updates account.Account) (*Account, error)
 	SelectAccounts() (*Accounts, error)
-	DeleteAccount(id uint) error
+	DeleteAccount(id uint, policy DeletionPolicy) error
+	SelectDeletedAccounts() (*Accounts, error)
+	UndeleteAccount(id uint) (*Account, error)
 	//
+	SelectBalance(id uint) (*Balance, error)
 	InsertBalance(accountID uint, b balance.Balance, note string) (*Balance, error)
 	SelectAccountBalances(id uint) (*Balances, error)
-	//UpdateBalance(a Account, b *Balance, us balance.Balance) error
+	UpdateBalance(accountID, id uint, b balance.Balance, note string) (*Balance, error)
 	DeleteBalance(id uint) error
+	SelectDeletedBalances() (*Balances, error)
+	UndeleteBalance(id uint) (*Balance, error)
+	//
+	// Atomic runs fn against a Storage whose operations are committed together
+	// if fn returns nil, or discarded together otherwise.
+	Atomic(fn func(Storage) error) error
+}
+
+// History is implemented by a Storage that retains every version of its
+// Accounts and Balances, allowing its data to be retrieved as it was stored at
+// a past moment. History is concerned with when records were stored, not with
+// the dates that Accounts were open or that Balances were recorded for.
+type History interface {
+	// SelectAccountsAsOf returns the Accounts that were stored and not
+	// deleted at the given time, as they were at that time.
+	SelectAccountsAsOf(t time.Time) (*Accounts, error)
+	// SelectAccountBalancesAsOf returns the Balances of the account with the
+	// given id that were stored and not deleted at the given time, as they
+	// were at that time.
+	SelectAccountBalancesAsOf(accountID uint, t time.Time) (*Balances, error)
+}
+
+// Purger is implemented by a Storage that is able to permanently remove
+// records that have been deleted.
+type Purger interface {
+	// Purge permanently removes the Accounts and Balances that were deleted
+	// before the given time. Every Balance of a purged Account is also
+	// removed, whether or not the Balance has been deleted itself.
+	Purge(before time.Time) (*Purged, error)
+}
+
+// Purged holds the number of records that were permanently removed by a Purge.
+type Purged struct {
+	Accounts int
+	Balances int
+}
+
+// BalanceSummer is implemented by a Storage that is able to total the
+// Balances of many Accounts at once, rather than having the Balances of each
+// Account selected and totalled by the caller.
+type BalanceSummer interface {
+	// SumAccountBalances returns a balance.Balance for each account with one
+	// of the given ids that has live Balances at or before the given time.
+	// The Amount of each balance.Balance is the sum of the amounts of those
+	// Balances and its Date is the date of the latest of them.
+	SumAccountBalances(ids []uint, at time.Time) (map[uint]balance.Balance, error)
 }
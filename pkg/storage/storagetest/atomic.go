@@ -0,0 +1,129 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAtomic will run a suite of tests against the Atomic method of a given
+// Storage. The Storage is expected to contain no accounts when TestAtomic is
+// called.
+func TestAtomic(t *testing.T, store storage.Storage) {
+	tests := []struct {
+		title string
+		run   func(t *testing.T, c storage.Storage)
+	}{
+		{
+			title: "committed operations",
+			run:   atomicCommit,
+		},
+		{
+			title: "discarded operations",
+			run:   atomicRollback,
+		},
+		{
+			title: "nested operations",
+			run:   atomicNested,
+		},
+	}
+	for _, test := range tests {
+		success := t.Run(test.title, func(t *testing.T) {
+			test.run(t, store)
+		})
+		if !success {
+			t.Fail()
+			return
+		}
+	}
+}
+
+func atomicCommit(t *testing.T, store storage.Storage) {
+	before := selectAccounts(t, store)
+	a := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	var inserted *storage.Account
+	var insertedB *storage.Balance
+	err := store.Atomic(func(s storage.Storage) error {
+		var err error
+		inserted, err = s.InsertAccount(*a)
+		if err != nil {
+			return errors.Wrap(err, "inserting account")
+		}
+		insertedB, err = s.InsertBalance(inserted.ID, newTestBalance(t, inserted.Account.Opened()), "atomic")
+		return errors.Wrap(err, "inserting balance")
+	})
+	common.FatalIfError(t, err, "running atomic operations")
+
+	after := selectAccounts(t, store)
+	assert.Len(t, *after, len(*before)+1)
+
+	selected, err := store.SelectAccount(inserted.ID)
+	common.FatalIfError(t, err, "selecting account")
+	equal, err := inserted.Equal(*selected)
+	common.FatalIfError(t, err, "equaling inserted and selected")
+	assert.True(t, equal)
+
+	bs, err := store.SelectAccountBalances(inserted.ID)
+	common.FatalIfError(t, err, "selecting account balances")
+	if assert.Len(t, *bs, 1) {
+		assert.True(t, insertedB.Equal((*bs)[0]))
+	}
+}
+
+func atomicRollback(t *testing.T, store storage.Storage) {
+	before := selectAccounts(t, store)
+	if !assert.NotEmpty(t, *before) {
+		t.FailNow()
+	}
+	existing := (*before)[0]
+	balancesBefore, err := store.SelectAccountBalances(existing.ID)
+	common.FatalIfError(t, err, "selecting account balances")
+
+	expected := errors.New("atomic error")
+	err = store.Atomic(func(s storage.Storage) error {
+		a := accountingtest.NewAccount(t, "B", accountingtest.NewCurrencyCode(t, "EUR"), time.Now())
+		if _, err := s.InsertAccount(*a); err != nil {
+			return errors.Wrap(err, "inserting account")
+		}
+		if _, err := s.InsertBalance(existing.ID, newTestBalance(t, existing.Account.Opened()), "discarded"); err != nil {
+			return errors.Wrap(err, "inserting balance")
+		}
+		if err := s.DeleteAccount(existing.ID, storage.DeletionCascade); err != nil {
+			return errors.Wrap(err, "deleting account")
+		}
+		return expected
+	})
+	assert.Equal(t, expected, errors.Cause(err))
+
+	after := selectAccounts(t, store)
+	assert.Equal(t, *before, *after)
+
+	balancesAfter, err := store.SelectAccountBalances(existing.ID)
+	common.FatalIfError(t, err, "selecting account balances")
+	assert.Equal(t, *balancesBefore, *balancesAfter)
+}
+
+func atomicNested(t *testing.T, store storage.Storage) {
+	before := selectAccounts(t, store)
+	expected := errors.New("outer error")
+	err := store.Atomic(func(outer storage.Storage) error {
+		err := outer.Atomic(func(inner storage.Storage) error {
+			a := accountingtest.NewAccount(t, "C", accountingtest.NewCurrencyCode(t, "YEN"), time.Now())
+			_, err := inner.InsertAccount(*a)
+			return err
+		})
+		if err != nil {
+			return errors.Wrap(err, "running inner operation")
+		}
+		return expected
+	})
+	assert.Equal(t, expected, errors.Cause(err))
+
+	after := selectAccounts(t, store)
+	assert.Equal(t, *before, *after, "inner operation should be discarded with outer operation")
+}
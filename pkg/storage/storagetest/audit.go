@@ -0,0 +1,141 @@
+package storagetest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// AuditStorage is a Storage that is able to hold an audit log.
+type AuditStorage interface {
+	storage.Storage
+	storage.AuditLog
+}
+
+// TestAuditLog will test that a given AuditStorage stores AuditEntries and
+// selects those that match an AuditQuery.
+func TestAuditLog(t *testing.T, store AuditStorage) {
+	start := time.Now().Add(-time.Second)
+	first, err := store.InsertAuditEntry(storage.AuditEntry{
+		User:      "auditor",
+		TokenID:   3,
+		Route:     "AccountUpdate",
+		Method:    "POST",
+		Path:      "/account/7/update",
+		AccountID: 7,
+		Request:   json.RawMessage(`{"Name":"after"}`),
+		Before:    json.RawMessage(`{"Name":"before"}`),
+		After:     json.RawMessage(`{"Name":"after"}`),
+		Status:    200,
+	})
+	common.FatalIfError(t, err, "inserting first audit entry")
+	assert.NotZero(t, first.ID)
+	assert.False(t, first.Time.Before(start), "time should be set by the storage")
+	assert.Equal(t, "auditor", first.User)
+	assert.Equal(t, uint(3), first.TokenID)
+	assert.Equal(t, "AccountUpdate", first.Route)
+	assert.Equal(t, "POST", first.Method)
+	assert.Equal(t, "/account/7/update", first.Path)
+	assert.Equal(t, uint(7), first.AccountID)
+	assert.Zero(t, first.BalanceID)
+	assert.JSONEq(t, `{"Name":"after"}`, string(first.Request))
+	assert.JSONEq(t, `{"Name":"before"}`, string(first.Before))
+	assert.JSONEq(t, `{"Name":"after"}`, string(first.After))
+	assert.Equal(t, 200, first.Status)
+	assert.Empty(t, first.Error)
+
+	second, err := store.InsertAuditEntry(storage.AuditEntry{
+		User:      "auditor",
+		Route:     "BalanceDelete",
+		Method:    "DELETE",
+		Path:      "/balance/9",
+		AccountID: 7,
+		BalanceID: 9,
+		Status:    404,
+		Error:     "no balance with id 9",
+	})
+	common.FatalIfError(t, err, "inserting second audit entry")
+	assert.True(t, second.ID > first.ID, "ids should increase")
+	assert.Nil(t, second.Request)
+	assert.Nil(t, second.Before)
+	assert.Nil(t, second.After)
+	assert.Equal(t, "no balance with id 9", second.Error)
+
+	other := "other-auditor"
+	_, err = store.InsertAuditEntry(storage.AuditEntry{User: other, Route: "AccountInsert", Method: "POST", Path: "/account/insert"})
+	common.FatalIfError(t, err, "inserting other user's audit entry")
+
+	user := "auditor"
+	for _, test := range []struct {
+		name     string
+		query    storage.AuditQuery
+		expected []uint
+	}{
+		{
+			name:     "user",
+			query:    storage.AuditQuery{User: &user},
+			expected: []uint{first.ID, second.ID},
+		},
+		{
+			name:     "descending",
+			query:    storage.AuditQuery{User: &user, Order: storage.OrderDescending},
+			expected: []uint{second.ID, first.ID},
+		},
+		{
+			name:     "route",
+			query:    storage.AuditQuery{User: &user, Route: "BalanceDelete"},
+			expected: []uint{second.ID},
+		},
+		{
+			name:     "account",
+			query:    storage.AuditQuery{User: &user, AccountID: 7},
+			expected: []uint{first.ID, second.ID},
+		},
+		{
+			name:     "balance",
+			query:    storage.AuditQuery{User: &user, BalanceID: 9},
+			expected: []uint{second.ID},
+		},
+		{
+			name:     "from",
+			query:    storage.AuditQuery{User: &user, From: &start},
+			expected: []uint{first.ID, second.ID},
+		},
+		{
+			name:     "to",
+			query:    storage.AuditQuery{User: &user, To: &start},
+			expected: []uint{},
+		},
+		{
+			name:     "page",
+			query:    storage.AuditQuery{User: &user, Offset: 1, Limit: 1},
+			expected: []uint{second.ID},
+		},
+		{
+			name:     "offset only",
+			query:    storage.AuditQuery{User: &user, Offset: 1},
+			expected: []uint{second.ID},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			es, err := store.SelectAuditEntries(test.query)
+			common.FatalIfError(t, err, "selecting audit entries")
+			assert.Equal(t, test.expected, auditEntryIDs(*es))
+		})
+	}
+
+	_, err = store.SelectAuditEntries(storage.AuditQuery{Order: "sideways"})
+	assert.Equal(t, storage.KindInvalid, storage.KindOf(err))
+}
+
+func auditEntryIDs(es storage.AuditEntries) []uint {
+	ids := []uint{}
+	for _, e := range es {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
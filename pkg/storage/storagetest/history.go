@@ -0,0 +1,121 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// HistoryStorage is a Storage that retains the history of its data.
+type HistoryStorage interface {
+	storage.Storage
+	storage.History
+}
+
+// TestHistory will test that a given HistoryStorage provides its Accounts and
+// Balances as they were stored at different moments in time.
+func TestHistory(t *testing.T, store HistoryStorage) {
+	// pause ensures that each moment is distinguishable from the changes made
+	// either side of it, even for storage that holds times at a coarse grain.
+	pause := func() time.Time {
+		time.Sleep(10 * time.Millisecond)
+		now := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		return now
+	}
+
+	beforeInsert := pause()
+	a := accountingtest.NewAccount(t, "history A", accountingtest.NewCurrencyCode(t, "GBP"), time.Now().Truncate(time.Second))
+	inserted, err := store.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+	b, err := store.InsertBalance(inserted.ID, newTestBalance(t, a.Opened(), balance.Amount(1)), "first")
+	common.FatalIfError(t, err, "inserting balance")
+
+	afterInsert := pause()
+	updates := accountingtest.NewAccount(t, "history B", accountingtest.NewCurrencyCode(t, "EUR"), a.Opened())
+	_, err = store.UpdateAccount(inserted.ID, *updates)
+	common.FatalIfError(t, err, "updating account")
+	_, err = store.UpdateBalance(inserted.ID, b.ID, newTestBalance(t, a.Opened(), balance.Amount(2)), "second")
+	common.FatalIfError(t, err, "updating balance")
+
+	afterUpdate := pause()
+	common.FatalIfError(t, store.DeleteBalance(b.ID), "deleting balance")
+	common.FatalIfError(t, store.DeleteAccount(inserted.ID, storage.DeletionRefuse), "deleting account")
+
+	afterDelete := pause()
+
+	for _, test := range []struct {
+		name    string
+		at      time.Time
+		account *storage.Account
+		balance *storage.Balance
+	}{
+		{
+			name: "before insert",
+			at:   beforeInsert,
+		},
+		{
+			name:    "after insert",
+			at:      afterInsert,
+			account: &storage.Account{ID: inserted.ID, Version: 1, Account: *a},
+			balance: &storage.Balance{
+				ID:        b.ID,
+				Version:   1,
+				AccountID: inserted.ID,
+				Balance:   newTestBalance(t, a.Opened(), balance.Amount(1)),
+				Note:      "first",
+			},
+		},
+		{
+			name:    "after update",
+			at:      afterUpdate,
+			account: &storage.Account{ID: inserted.ID, Version: 2, Account: *updates},
+			balance: &storage.Balance{
+				ID:        b.ID,
+				Version:   2,
+				AccountID: inserted.ID,
+				Balance:   newTestBalance(t, a.Opened(), balance.Amount(2)),
+				Note:      "second",
+			},
+		},
+		{
+			name: "after delete",
+			at:   afterDelete,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			as, err := store.SelectAccountsAsOf(test.at)
+			common.FatalIfError(t, err, "selecting accounts")
+			var found *storage.Account
+			for _, sa := range *as {
+				if sa.ID == inserted.ID {
+					found = &sa
+					break
+				}
+			}
+			if test.account == nil {
+				assert.Nil(t, found)
+			} else if assert.NotNil(t, found) {
+				equal, err := test.account.Equal(*found)
+				common.FatalIfError(t, err, "equaling accounts")
+				assert.True(t, equal, "expected: %+v\nactual: %+v", *test.account, *found)
+				assert.Equal(t, test.account.Version, found.Version)
+			}
+
+			bs, err := store.SelectAccountBalancesAsOf(inserted.ID, test.at)
+			common.FatalIfError(t, err, "selecting balances")
+			if test.balance == nil {
+				assert.Len(t, *bs, 0)
+			} else if assert.Len(t, *bs, 1) {
+				assert.True(t, test.balance.Equal((*bs)[0]), "expected: %+v\nactual: %+v", *test.balance, (*bs)[0])
+				assert.Equal(t, test.balance.AccountID, (*bs)[0].AccountID)
+				assert.Equal(t, test.balance.Version, (*bs)[0].Version)
+			}
+		})
+	}
+}
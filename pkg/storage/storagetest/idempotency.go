@@ -0,0 +1,111 @@
+package storagetest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// IdempotencyStorage is a Storage that is able to hold the responses to
+// requests that were made with idempotency keys.
+type IdempotencyStorage interface {
+	storage.Storage
+	storage.IdempotencyStore
+}
+
+// TestIdempotency will test that a given IdempotencyStorage reserves each key
+// once for each user, stores the responses of reserved requests, releases
+// requests without a response and replaces requests that have expired or been
+// abandoned without a response.
+func TestIdempotency(t *testing.T, store IdempotencyStorage) {
+	key := "key-" + time.Now().Format(time.RFC3339Nano)
+	expired := time.Now().Add(-time.Hour)
+	request := storage.IdempotentRequest{User: "idempotent", Key: key, Fingerprint: "first", Reservation: "first"}
+
+	reserved, ok, err := store.ReserveIdempotentRequest(request, expired, expired)
+	common.FatalIfError(t, err, "reserving request")
+	assert.True(t, ok)
+	assert.Equal(t, request.User, reserved.User)
+	assert.Equal(t, request.Key, reserved.Key)
+	assert.Equal(t, request.Fingerprint, reserved.Fingerprint)
+	assert.Equal(t, request.Reservation, reserved.Reservation)
+	assert.False(t, reserved.Created.IsZero())
+	assert.Zero(t, reserved.Status)
+
+	repeat := request
+	repeat.Fingerprint = "repeat"
+	repeat.Reservation = "repeat"
+	existing, ok, err := store.ReserveIdempotentRequest(repeat, expired, expired)
+	common.FatalIfError(t, err, "reserving reserved request")
+	assert.False(t, ok)
+	assert.Equal(t, request.Fingerprint, existing.Fingerprint, "existing request should be returned")
+	assert.Zero(t, existing.Status)
+
+	other := request
+	other.User = "other-idempotent"
+	_, ok, err = store.ReserveIdempotentRequest(other, expired, expired)
+	common.FatalIfError(t, err, "reserving request of other user")
+	assert.True(t, ok, "keys should be reserved separately for each user")
+
+	completed := request
+	completed.Status = 201
+	completed.Reservation = repeat.Reservation
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(store.CompleteIdempotentRequest(completed)), "completing request of other reservation")
+	completed.Reservation = request.Reservation
+	completed.Location = "/account/1"
+	completed.ETag = `"1"`
+	completed.Body = json.RawMessage(`{"ID":1}`)
+	common.FatalIfError(t, store.CompleteIdempotentRequest(completed), "completing request")
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(store.CompleteIdempotentRequest(completed)), "completing completed request")
+	unknown := request
+	unknown.Key = key + "-unknown"
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(store.CompleteIdempotentRequest(unknown)), "completing unknown request")
+
+	common.FatalIfError(t, store.ReleaseIdempotentRequest(request.User, request.Key, request.Reservation), "releasing completed request")
+	existing, ok, err = store.ReserveIdempotentRequest(repeat, expired, expired)
+	common.FatalIfError(t, err, "reserving completed request")
+	assert.False(t, ok, "completed request should not be released")
+	assert.Equal(t, completed.Status, existing.Status)
+	assert.Equal(t, completed.Location, existing.Location)
+	assert.Equal(t, completed.ETag, existing.ETag)
+	assert.JSONEq(t, string(completed.Body), string(existing.Body))
+
+	common.FatalIfError(t, store.ReleaseIdempotentRequest(other.User, other.Key, repeat.Reservation), "releasing request of other reservation")
+	_, ok, err = store.ReserveIdempotentRequest(other, expired, expired)
+	common.FatalIfError(t, err, "reserving request released by other reservation")
+	assert.False(t, ok, "request should only be released by its reservation")
+	common.FatalIfError(t, store.ReleaseIdempotentRequest(other.User, other.Key, other.Reservation), "releasing request")
+	_, ok, err = store.ReserveIdempotentRequest(other, expired, expired)
+	common.FatalIfError(t, err, "reserving released request")
+	assert.True(t, ok, "released request should be reserved again")
+	common.FatalIfError(t, store.ReleaseIdempotentRequest(other.User, unknown.Key, other.Reservation), "releasing unknown request")
+
+	abandoned := time.Now().Add(time.Second)
+	takeover := other
+	takeover.Fingerprint = "takeover"
+	takeover.Reservation = "takeover"
+	reserved, ok, err = store.ReserveIdempotentRequest(takeover, expired, abandoned)
+	common.FatalIfError(t, err, "reserving abandoned request")
+	assert.True(t, ok, "abandoned request should be replaced")
+	assert.Equal(t, takeover.Fingerprint, reserved.Fingerprint)
+	abandonedResponse := other
+	abandonedResponse.Status = 201
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(store.CompleteIdempotentRequest(abandonedResponse)), "completing request that was taken over")
+	common.FatalIfError(t, store.ReleaseIdempotentRequest(other.User, other.Key, other.Reservation), "releasing request that was taken over")
+	takeoverResponse := takeover
+	takeoverResponse.Status = 202
+	common.FatalIfError(t, store.CompleteIdempotentRequest(takeoverResponse), "completing request that took over")
+	_, ok, err = store.ReserveIdempotentRequest(repeat, expired, abandoned)
+	common.FatalIfError(t, err, "reserving completed request after abandoned time")
+	assert.False(t, ok, "completed request should not be replaced before it expires")
+
+	reserved, ok, err = store.ReserveIdempotentRequest(repeat, time.Now().Add(time.Second), expired)
+	common.FatalIfError(t, err, "reserving expired request")
+	assert.True(t, ok, "expired request should be replaced")
+	assert.Equal(t, repeat.Fingerprint, reserved.Fingerprint)
+	assert.Zero(t, reserved.Status)
+}
@@ -0,0 +1,100 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// PurgeStorage is a Storage that is able to permanently remove deleted
+// records.
+type PurgeStorage interface {
+	storage.Storage
+	storage.Purger
+}
+
+// TestPurge will test that a given PurgeStorage permanently removes only the
+// records that were deleted before a given time, cascading from purged
+// Accounts to their Balances.
+func TestPurge(t *testing.T, store PurgeStorage) {
+	// pause ensures that each moment is distinguishable from the changes made
+	// either side of it, even for storage that holds times at a coarse grain.
+	pause := func() time.Time {
+		time.Sleep(10 * time.Millisecond)
+		now := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		return now
+	}
+
+	insertAccount := func(name string) *storage.Account {
+		a := accountingtest.NewAccount(t, name, accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+		inserted, err := store.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+		return inserted
+	}
+	insertBalance := func(a *storage.Account, note string) *storage.Balance {
+		b, err := store.InsertBalance(a.ID, newTestBalance(t, a.Account.Opened(), balance.Amount(1)), note)
+		common.FatalIfError(t, err, "inserting balance")
+		return b
+	}
+
+	// records deleted before the test are purged so that the counts of purged
+	// records concern only the records created by the test.
+	_, err := store.Purge(time.Now())
+	common.FatalIfError(t, err, "purging records deleted before test")
+
+	kept := insertAccount("kept")
+	keptBalance := insertBalance(kept, "kept")
+	purgedBalance := insertBalance(kept, "purged")
+	purgedAccount := insertAccount("purged")
+	cascadedBalance := insertBalance(purgedAccount, "cascaded")
+	retainedAccount := insertAccount("retained")
+	retainedBalance := insertBalance(kept, "retained")
+
+	beforeDeletes := pause()
+	common.FatalIfError(t, store.DeleteBalance(purgedBalance.ID), "deleting balance")
+	common.FatalIfError(t, store.DeleteAccount(purgedAccount.ID, storage.DeletionArchive), "deleting account")
+
+	cutoff := pause()
+	common.FatalIfError(t, store.DeleteBalance(retainedBalance.ID), "deleting balance")
+	common.FatalIfError(t, store.DeleteAccount(retainedAccount.ID, storage.DeletionRefuse), "deleting account")
+
+	p, err := store.Purge(beforeDeletes)
+	common.FatalIfError(t, err, "purging before any deletes")
+	assert.Equal(t, storage.Purged{}, *p)
+
+	p, err = store.Purge(cutoff)
+	common.FatalIfError(t, err, "purging before cutoff")
+	assert.Equal(t, storage.Purged{Accounts: 1, Balances: 2}, *p)
+
+	das, err := store.SelectDeletedAccounts()
+	common.FatalIfError(t, err, "selecting deleted accounts")
+	assert.Nil(t, findAccount(*das, purgedAccount.ID), "purged account should not be listed")
+	assert.NotNil(t, findAccount(*das, retainedAccount.ID), "account deleted after cutoff should be listed")
+
+	dbs, err := store.SelectDeletedBalances()
+	common.FatalIfError(t, err, "selecting deleted balances")
+	assert.Nil(t, findBalance(*dbs, purgedBalance.ID), "purged balance should not be listed")
+	assert.Nil(t, findBalance(*dbs, cascadedBalance.ID), "balance of purged account should not be listed")
+	assert.NotNil(t, findBalance(*dbs, retainedBalance.ID), "balance deleted after cutoff should be listed")
+
+	_, err = store.UndeleteAccount(purgedAccount.ID)
+	assert.Error(t, err, "undeleting purged account")
+	_, err = store.UndeleteBalance(purgedBalance.ID)
+	assert.Error(t, err, "undeleting purged balance")
+
+	bs, err := store.SelectAccountBalances(kept.ID)
+	common.FatalIfError(t, err, "selecting balances of kept account")
+	if assert.Len(t, *bs, 1) {
+		assert.Equal(t, keptBalance.ID, (*bs)[0].ID)
+	}
+
+	p, err = store.Purge(cutoff)
+	common.FatalIfError(t, err, "purging before cutoff again")
+	assert.Equal(t, storage.Purged{}, *p)
+}
@@ -23,8 +23,10 @@ type Storage struct {
 	*storage.Balances
 	BalancesErr error
 
-	LastAccountID   uint
-	LastBalanceNote string
+	LastAccountID      uint
+	LastBalanceID      uint
+	LastBalanceNote    string
+	LastDeletionPolicy storage.DeletionPolicy
 }
 
 // Available stubs storage.Available method
@@ -54,11 +56,27 @@ func (s *Storage) SelectAccount(id uint) (*storage.Account, error) {
 func (s *Storage) SelectAccounts() (*storage.Accounts, error) { return s.Accounts, s.Err }
 
 // DeleteAccount stubs the storage.DeleteAccount method
-func (s *Storage) DeleteAccount(id uint) error {
+func (s *Storage) DeleteAccount(id uint, policy storage.DeletionPolicy) error {
 	s.LastAccountID = id
+	s.LastDeletionPolicy = policy
 	return s.AccountErr
 }
 
+// SelectDeletedAccounts stubs the storage.SelectDeletedAccounts method
+func (s *Storage) SelectDeletedAccounts() (*storage.Accounts, error) { return s.Accounts, s.Err }
+
+// UndeleteAccount stubs the storage.UndeleteAccount method
+func (s *Storage) UndeleteAccount(id uint) (*storage.Account, error) {
+	s.LastAccountID = id
+	return s.Account, s.AccountErr
+}
+
+// SelectBalance stubs the storage.SelectBalance method
+func (s *Storage) SelectBalance(id uint) (*storage.Balance, error) {
+	s.LastBalanceID = id
+	return s.Balance, s.BalanceErr
+}
+
 // InsertBalance stubs the storage.InsertBalance method
 func (s *Storage) InsertBalance(accountID uint, _ balance.Balance, note string) (*storage.Balance, error) {
 	s.LastAccountID = accountID
@@ -66,11 +84,34 @@ func (s *Storage) InsertBalance(accountID uint, _ balance.Balance, note string)
 	return s.Balance, s.BalanceErr
 }
 
+// UpdateBalance stubs the storage.UpdateBalance method
+func (s *Storage) UpdateBalance(accountID, id uint, _ balance.Balance, note string) (*storage.Balance, error) {
+	s.LastAccountID = accountID
+	s.LastBalanceID = id
+	s.LastBalanceNote = note
+	return s.Balance, s.BalanceErr
+}
+
 // DeleteBalance stubs the storage.DeleteBalance method
 func (s *Storage) DeleteBalance(_ uint) error { return s.Err }
 
+// SelectDeletedBalances stubs the storage.SelectDeletedBalances method
+func (s *Storage) SelectDeletedBalances() (*storage.Balances, error) {
+	return s.Balances, s.BalancesErr
+}
+
+// UndeleteBalance stubs the storage.UndeleteBalance method
+func (s *Storage) UndeleteBalance(id uint) (*storage.Balance, error) {
+	s.LastBalanceID = id
+	return s.Balance, s.BalanceErr
+}
+
 // SelectAccountBalances mocks the storage.SelectAccountBalances method
 func (s *Storage) SelectAccountBalances(id uint) (*storage.Balances, error) {
 	s.LastAccountID = id
 	return s.Balances, s.BalancesErr
 }
+
+// Atomic stubs the storage.Atomic method by running fn against the stub
+// itself.
+func (s *Storage) Atomic(fn func(storage.Storage) error) error { return fn(s) }
@@ -0,0 +1,100 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTenancy will test that a given storage.TenantStorage records the owners
+// of Accounts and the users that they have been shared with, such that the
+// Storage returned by storage.ForUser holds only the Accounts of a user.
+func TestTenancy(t *testing.T, store storage.TenantStorage) {
+	owner := storage.ForUser(store, "tenancy-owner")
+	other := storage.ForUser(store, "tenancy-other")
+
+	a := accountingtest.NewAccount(t, "owned", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	owned, err := owner.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+	b, err := owner.InsertBalance(owned.ID, balance.Balance{Date: time.Now(), Amount: 10}, "")
+	common.FatalIfError(t, err, "inserting balance")
+
+	_, err = owner.SelectAccount(owned.ID)
+	assert.NoError(t, err, "selecting owned account")
+	_, err = other.SelectAccount(owned.ID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "selecting account of other user")
+	_, err = other.SelectBalance(b.ID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "selecting balance of other user")
+	_, err = other.InsertBalance(owned.ID, balance.Balance{Date: time.Now()}, "")
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "inserting balance for account of other user")
+	as, err := other.SelectAccounts()
+	common.FatalIfError(t, err, "selecting accounts")
+	assert.NotContains(t, accountIDs(*as), owned.ID)
+	as, err = storage.ForUser(store, storage.DefaultUser).SelectAccounts()
+	common.FatalIfError(t, err, "selecting accounts of default user")
+	assert.NotContains(t, accountIDs(*as), owned.ID)
+
+	sharer := owner.(storage.AccountSharer)
+	assert.Equal(t, storage.KindInvalid, storage.KindOf(sharer.ShareAccount(owned.ID, "tenancy-owner")), "sharing account with owner")
+	common.FatalIfError(t, sharer.ShareAccount(owned.ID, "tenancy-other"), "sharing account")
+	common.FatalIfError(t, sharer.ShareAccount(owned.ID, "tenancy-other"), "sharing account again")
+	shares, err := sharer.SelectAccountShares(owned.ID)
+	common.FatalIfError(t, err, "selecting account shares")
+	assert.Equal(t, []string{"tenancy-other"}, shares)
+
+	_, err = other.SelectAccount(owned.ID)
+	assert.NoError(t, err, "selecting shared account")
+	_, err = other.SelectBalance(b.ID)
+	assert.NoError(t, err, "selecting balance of shared account")
+	bs, err := other.SelectAccountBalances(owned.ID)
+	common.FatalIfError(t, err, "selecting balances of shared account")
+	assert.Len(t, *bs, 1)
+	_, err = other.InsertBalance(owned.ID, balance.Balance{Date: time.Now()}, "")
+	assert.Equal(t, storage.KindForbidden, storage.KindOf(err), "inserting balance for shared account")
+	_, err = other.UpdateAccount(owned.ID, *a)
+	assert.Equal(t, storage.KindForbidden, storage.KindOf(err), "updating shared account")
+	assert.Equal(t, storage.KindForbidden, storage.KindOf(other.DeleteBalance(b.ID)), "deleting balance of shared account")
+	assert.Equal(t, storage.KindForbidden, storage.KindOf(other.DeleteAccount(owned.ID, storage.DeletionCascade)), "deleting shared account")
+	assert.Equal(t, storage.KindForbidden, storage.KindOf(other.(storage.AccountSharer).ShareAccount(owned.ID, "tenancy-third")), "sharing shared account")
+
+	common.FatalIfError(t, sharer.UnshareAccount(owned.ID, "tenancy-other"), "unsharing account")
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(sharer.UnshareAccount(owned.ID, "tenancy-other")), "unsharing unshared account")
+	_, err = other.SelectAccount(owned.ID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "selecting unshared account")
+
+	var atomic *storage.Account
+	err = owner.Atomic(func(tx storage.Storage) error {
+		var err error
+		atomic, err = tx.InsertAccount(*a)
+		return err
+	})
+	common.FatalIfError(t, err, "inserting account atomically")
+	ownedIDs, _, err := store.SelectUserAccountIDs("tenancy-owner")
+	common.FatalIfError(t, err, "selecting account ids of owner")
+	assert.Equal(t, []uint{owned.ID, atomic.ID}, ownedIDs)
+
+	unowned, err := store.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account without owner")
+	_, err = storage.ForUser(store, storage.DefaultUser).SelectAccount(unowned.ID)
+	assert.NoError(t, err, "selecting account of default user")
+	_, err = owner.SelectAccount(unowned.ID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "selecting account of default user")
+
+	const missing = 1 << 30
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(store.ShareAccount(missing, "tenancy-other")), "sharing missing account")
+	_, err = store.SelectAccountShares(missing)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "selecting shares of missing account")
+}
+
+func accountIDs(as storage.Accounts) []uint {
+	var ids []uint
+	for _, a := range as {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
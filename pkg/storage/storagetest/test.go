@@ -30,6 +30,14 @@ func Test(t *testing.T, store storage.Storage) {
 			title: "inserting and retrieving balances",
 			run:   insertDeleteAndRetrieveBalances,
 		},
+		{
+			title: "select balance",
+			run:   selectBalance,
+		},
+		{
+			title: "update balance",
+			run:   updateBalance,
+		},
 		{
 			title: "update account",
 			run:   updateAccount,
@@ -38,6 +46,18 @@ func Test(t *testing.T, store storage.Storage) {
 			title: "insert and delete accounts",
 			run:   insertAndDeleteAccounts,
 		},
+		{
+			title: "undelete accounts and balances",
+			run:   undeleteAccountsAndBalances,
+		},
+		{
+			title: "delete accounts with balances",
+			run:   deleteAccountsWithBalances,
+		},
+		{
+			title: "error kinds",
+			run:   errorKinds,
+		},
 	}
 	for _, test := range tests {
 		success := t.Run(test.title, func(t *testing.T) {
@@ -184,6 +204,69 @@ func insertDeleteAndRetrieveBalances(t *testing.T, store storage.Storage) {
 	}
 }
 
+func selectBalance(t *testing.T, store storage.Storage) {
+	as := selectAccounts(t, store)
+	if !assert.True(t, len(*as) > 0) {
+		t.FailNow()
+	}
+	a := (*as)[0]
+
+	inserted, err := store.InsertBalance(a.ID, newTestBalance(t, a.Account.Opened(), balance.Amount(123)), "selected note")
+	common.FatalIfError(t, err, "inserting Balance")
+	assert.Equal(t, a.ID, inserted.AccountID)
+
+	selected, err := store.SelectBalance(inserted.ID)
+	common.FatalIfError(t, err, "selecting Balance")
+	assert.Equal(t, inserted.ID, selected.ID)
+	assert.Equal(t, a.ID, selected.AccountID)
+	assert.True(t, inserted.Balance.Equal(selected.Balance), "inserted: %+v\nselected: %+v", inserted.Balance, selected.Balance)
+	assert.Equal(t, "selected note", selected.Note)
+
+	_, err = store.SelectBalance(inserted.ID + 1000)
+	assert.Error(t, err, "selecting non-existent balance")
+
+	err = store.DeleteBalance(inserted.ID)
+	common.FatalIfError(t, err, "deleting Balance")
+
+	_, err = store.SelectBalance(inserted.ID)
+	assert.Error(t, err, "selecting deleted balance")
+}
+
+func updateBalance(t *testing.T, store storage.Storage) {
+	as := selectAccounts(t, store)
+	if !assert.True(t, len(*as) > 0) {
+		t.FailNow()
+	}
+	a := (*as)[0]
+
+	inserted, err := store.InsertBalance(a.ID, newTestBalance(t, a.Account.Opened()), "original note")
+	common.FatalIfError(t, err, "inserting Balance")
+
+	updates := newTestBalance(t, a.Account.Opened(), balance.Amount(987))
+	updated, err := store.UpdateBalance(a.ID, inserted.ID, updates, "updated note")
+	common.FatalIfError(t, err, "updating Balance")
+	assert.Equal(t, inserted.ID, updated.ID)
+	assert.True(t, updates.Equal(updated.Balance), "updates: %+v\nupdated: %+v", updates, updated.Balance)
+	assert.Equal(t, "updated note", updated.Note)
+
+	bs, err := store.SelectAccountBalances(a.ID)
+	common.FatalIfError(t, err, "selecting account balances")
+	if assert.Len(t, *bs, 1) {
+		assert.Equal(t, inserted.ID, (*bs)[0].ID)
+		assert.True(t, updates.Equal((*bs)[0].Balance))
+		assert.Equal(t, "updated note", (*bs)[0].Note)
+	}
+
+	_, err = store.UpdateBalance(a.ID+1000, inserted.ID, updates, "")
+	assert.Error(t, err, "updating balance of a different account")
+
+	err = store.DeleteBalance(inserted.ID)
+	common.FatalIfError(t, err, "deleting Balance")
+
+	_, err = store.UpdateBalance(a.ID, inserted.ID, updates, "")
+	assert.Error(t, err, "updating deleted balance")
+}
+
 func updateAccount(t *testing.T, store storage.Storage) {
 	initial := accountingtest.NewAccount(t, "A", accountingtest.NewCurrencyCode(t, "YEN"), time.Now())
 
@@ -248,7 +331,7 @@ func insertAndDeleteAccounts(t *testing.T, store storage.Storage) {
 
 	for i, a := range as {
 		t.Run("deleting account (i:"+strconv.Itoa(i)+") should reduce accounts count by 1", func(t *testing.T) {
-			err := store.DeleteAccount(a.ID)
+			err := store.DeleteAccount(a.ID, storage.DeletionRefuse)
 			selectedAfter = selectAccounts(t, store)
 			common.FatalIfError(t, err, "deleting account")
 			// Accounts count should be the number of originals, with the
@@ -273,6 +356,183 @@ func insertAndDeleteAccounts(t *testing.T, store storage.Storage) {
 	})
 }
 
+func undeleteAccountsAndBalances(t *testing.T, store storage.Storage) {
+	a := accountingtest.NewAccount(t, "TO RESTORE", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	ia, err := store.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+	ib, err := store.InsertBalance(ia.ID, newTestBalance(t, a.Opened(), balance.Amount(42)), "restored note")
+	common.FatalIfError(t, err, "inserting balance")
+
+	_, err = store.UndeleteAccount(ia.ID)
+	assert.Error(t, err, "undeleting account that is not deleted")
+	_, err = store.UndeleteBalance(ib.ID)
+	assert.Error(t, err, "undeleting balance that is not deleted")
+
+	common.FatalIfError(t, store.DeleteBalance(ib.ID), "deleting balance")
+	common.FatalIfError(t, store.DeleteAccount(ia.ID, storage.DeletionRefuse), "deleting account")
+
+	das, err := store.SelectDeletedAccounts()
+	common.FatalIfError(t, err, "selecting deleted accounts")
+	da := findAccount(*das, ia.ID)
+	if assert.NotNil(t, da, "deleted account should be listed") {
+		assert.True(t, da.Deleted().Valid)
+	}
+	dbs, err := store.SelectDeletedBalances()
+	common.FatalIfError(t, err, "selecting deleted balances")
+	db := findBalance(*dbs, ib.ID)
+	if assert.NotNil(t, db, "deleted balance should be listed") {
+		assert.True(t, db.Deleted().Valid)
+		assert.Equal(t, ia.ID, db.AccountID)
+	}
+
+	_, err = store.UndeleteBalance(ib.ID)
+	assert.Error(t, err, "undeleting balance of deleted account")
+
+	ua, err := store.UndeleteAccount(ia.ID)
+	common.FatalIfError(t, err, "undeleting account")
+	assert.Equal(t, ia.ID, ua.ID)
+	assert.False(t, ua.Deleted().Valid)
+	assert.True(t, ia.Account.Equal(ua.Account))
+
+	ub, err := store.UndeleteBalance(ib.ID)
+	common.FatalIfError(t, err, "undeleting balance")
+	assert.Equal(t, ib.ID, ub.ID)
+	assert.False(t, ub.Deleted().Valid)
+	assert.True(t, ib.Equal(*ub))
+
+	bs, err := store.SelectAccountBalances(ia.ID)
+	common.FatalIfError(t, err, "selecting account balances")
+	assert.NotNil(t, findBalance(*bs, ib.ID), "restored balance should be listed")
+
+	das, err = store.SelectDeletedAccounts()
+	common.FatalIfError(t, err, "selecting deleted accounts")
+	assert.Nil(t, findAccount(*das, ia.ID), "restored account should not be listed as deleted")
+	dbs, err = store.SelectDeletedBalances()
+	common.FatalIfError(t, err, "selecting deleted balances")
+	assert.Nil(t, findBalance(*dbs, ib.ID), "restored balance should not be listed as deleted")
+
+	common.FatalIfError(t, store.DeleteAccount(ia.ID, storage.DeletionCascade), "deleting restored account")
+}
+
+func deleteAccountsWithBalances(t *testing.T, store storage.Storage) {
+	insert := func() (*storage.Account, *storage.Balance) {
+		a := accountingtest.NewAccount(t, "WITH BALANCE", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+		ia, err := store.InsertAccount(*a)
+		common.FatalIfError(t, err, "inserting account")
+		ib, err := store.InsertBalance(ia.ID, newTestBalance(t, a.Opened(), balance.Amount(7)), "")
+		common.FatalIfError(t, err, "inserting balance")
+		return ia, ib
+	}
+
+	t.Run("invalid policy", func(t *testing.T) {
+		a, _ := insert()
+		assert.Error(t, store.DeleteAccount(a.ID, storage.DeletionPolicy("shred")))
+		_, err := store.SelectAccount(a.ID)
+		assert.NoError(t, err, "account should not be deleted")
+	})
+
+	t.Run("refuse", func(t *testing.T) {
+		a, b := insert()
+		assert.Error(t, store.DeleteAccount(a.ID, storage.DeletionRefuse))
+		_, err := store.SelectAccount(a.ID)
+		assert.NoError(t, err, "account should not be deleted")
+		_, err = store.SelectBalance(b.ID)
+		assert.NoError(t, err, "balance should not be deleted")
+	})
+
+	t.Run("cascade", func(t *testing.T) {
+		a, b := insert()
+		common.FatalIfError(t, store.DeleteAccount(a.ID, storage.DeletionCascade), "deleting account")
+		_, err := store.SelectBalance(b.ID)
+		assert.Error(t, err, "selecting balance of deleted account")
+
+		dbs, err := store.SelectDeletedBalances()
+		common.FatalIfError(t, err, "selecting deleted balances")
+		assert.NotNil(t, findBalance(*dbs, b.ID), "cascaded balance should be listed as deleted")
+
+		_, err = store.UndeleteAccount(a.ID)
+		common.FatalIfError(t, err, "undeleting account")
+		bs, err := store.SelectAccountBalances(a.ID)
+		common.FatalIfError(t, err, "selecting account balances")
+		assert.Len(t, *bs, 0, "cascaded balances should remain deleted")
+		common.FatalIfError(t, store.DeleteAccount(a.ID, storage.DeletionRefuse), "deleting restored account")
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		a, b := insert()
+		common.FatalIfError(t, store.DeleteAccount(a.ID, storage.DeletionArchive), "deleting account")
+		_, err := store.SelectBalance(b.ID)
+		assert.Error(t, err, "selecting archived balance")
+		_, err = store.UpdateBalance(a.ID, b.ID, b.Balance, "")
+		assert.Error(t, err, "updating archived balance")
+		assert.Error(t, store.DeleteBalance(b.ID), "deleting archived balance")
+		// a Storage may refuse to select the balances of a deleted account
+		if bs, err := store.SelectAccountBalances(a.ID); err == nil {
+			assert.Len(t, *bs, 0, "archived balances should not be listed")
+		}
+
+		dbs, err := store.SelectDeletedBalances()
+		common.FatalIfError(t, err, "selecting deleted balances")
+		assert.Nil(t, findBalance(*dbs, b.ID), "archived balance should not be listed as deleted")
+
+		_, err = store.UndeleteAccount(a.ID)
+		common.FatalIfError(t, err, "undeleting account")
+		selected, err := store.SelectBalance(b.ID)
+		common.FatalIfError(t, err, "selecting restored balance")
+		assert.True(t, b.Equal(*selected))
+		common.FatalIfError(t, store.DeleteAccount(a.ID, storage.DeletionCascade), "deleting restored account")
+	})
+}
+
+func errorKinds(t *testing.T, store storage.Storage) {
+	const missingID = 1 << 30
+	_, err := store.SelectAccount(missingID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "selecting missing account: %v", err)
+	_, err = store.SelectBalance(missingID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "selecting missing balance: %v", err)
+	err = store.DeleteBalance(missingID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "deleting missing balance: %v", err)
+	_, err = store.UndeleteAccount(missingID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "undeleting missing account: %v", err)
+	_, err = store.UndeleteBalance(missingID)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "undeleting missing balance: %v", err)
+
+	a := accountingtest.NewAccount(t, "ERROR KINDS", accountingtest.NewCurrencyCode(t, "GBP"), time.Now())
+	ia, err := store.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+	ib, err := store.InsertBalance(ia.ID, newTestBalance(t, a.Opened(), balance.Amount(1)), "")
+	common.FatalIfError(t, err, "inserting balance")
+
+	_, err = store.UpdateBalance(ia.ID, missingID, ib.Balance, "")
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "updating missing balance: %v", err)
+	err = store.DeleteAccount(ia.ID, storage.DeletionPolicy("shred"))
+	assert.Equal(t, storage.KindInvalid, storage.KindOf(err), "deleting with invalid policy: %v", err)
+	err = store.DeleteAccount(ia.ID, storage.DeletionRefuse)
+	assert.Equal(t, storage.KindConflict, storage.KindOf(err), "refusing to delete account: %v", err)
+
+	common.FatalIfError(t, store.DeleteAccount(ia.ID, storage.DeletionCascade), "deleting account")
+	err = store.DeleteAccount(ia.ID, storage.DeletionRefuse)
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "deleting deleted account: %v", err)
+}
+
+func findAccount(as storage.Accounts, id uint) *storage.Account {
+	for _, a := range as {
+		if a.ID == id {
+			return &a
+		}
+	}
+	return nil
+}
+
+func findBalance(bs storage.Balances, id uint) *storage.Balance {
+	for _, b := range bs {
+		if b.ID == id {
+			return &b
+		}
+	}
+	return nil
+}
+
 func selectAccounts(t *testing.T, store storage.Storage) *storage.Accounts {
 	as, err := store.SelectAccounts()
 	common.FatalIfError(t, err, "selecting accounts after inserting one")
@@ -0,0 +1,62 @@
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// TokenStorage is a Storage that is able to hold API tokens.
+type TokenStorage interface {
+	storage.Storage
+	storage.TokenStore
+}
+
+// TestTokens will test that a given TokenStorage stores Tokens, selects only
+// those that have not been revoked by their hash and revokes them.
+func TestTokens(t *testing.T, store TokenStorage) {
+	before, err := store.SelectTokens()
+	common.FatalIfError(t, err, "selecting tokens before test")
+
+	first, err := store.InsertToken("first", "first-user", storage.RoleReadOnly, "first-hash")
+	common.FatalIfError(t, err, "inserting first token")
+	assert.Equal(t, "first", first.Name)
+	assert.Equal(t, "first-user", first.User)
+	assert.Equal(t, storage.RoleReadOnly, first.Role)
+	assert.False(t, first.Created.IsZero())
+	assert.Nil(t, first.Revoked)
+	second, err := store.InsertToken("second", storage.DefaultUser, storage.RoleAdmin, "second-hash")
+	common.FatalIfError(t, err, "inserting second token")
+	assert.NotEqual(t, first.ID, second.ID)
+
+	selected, err := store.SelectTokenByHash("first-hash")
+	common.FatalIfError(t, err, "selecting token by hash")
+	assert.Equal(t, first.ID, selected.ID)
+	assert.Equal(t, first.Name, selected.Name)
+	assert.Equal(t, first.User, selected.User)
+	assert.Equal(t, first.Role, selected.Role)
+
+	_, err = store.SelectTokenByHash("unknown-hash")
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err))
+
+	common.FatalIfError(t, store.RevokeToken(first.ID), "revoking token")
+	_, err = store.SelectTokenByHash("first-hash")
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(err), "revoked token should not be selected by hash")
+	assert.Equal(t, storage.KindNotFound, storage.KindOf(store.RevokeToken(first.ID)), "revoking revoked token")
+
+	selected, err = store.SelectTokenByHash("second-hash")
+	common.FatalIfError(t, err, "selecting unrevoked token by hash")
+	assert.Equal(t, second.ID, selected.ID)
+
+	ts, err := store.SelectTokens()
+	common.FatalIfError(t, err, "selecting tokens")
+	if assert.Len(t, *ts, len(*before)+2) {
+		revoked, unrevoked := (*ts)[len(*before)], (*ts)[len(*before)+1]
+		assert.Equal(t, first.ID, revoked.ID)
+		assert.NotNil(t, revoked.Revoked)
+		assert.Equal(t, second.ID, unrevoked.ID)
+		assert.Nil(t, unrevoked.Revoked)
+	}
+}
@@ -0,0 +1,52 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glynternet/go-accounting/accountingtest"
+	"github.com/glynternet/go-accounting/balance"
+	"github.com/glynternet/go-money/common"
+	"github.com/glynternet/mon/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVersions will test that a given Storage starts Accounts and Balances at
+// version 1 and increases their version each time that they are updated.
+func TestVersions(t *testing.T, store storage.Storage) {
+	a := accountingtest.NewAccount(t, "versions", accountingtest.NewCurrencyCode(t, "GBP"), time.Now().Truncate(time.Second))
+	inserted, err := store.InsertAccount(*a)
+	common.FatalIfError(t, err, "inserting account")
+	assert.Equal(t, uint(1), inserted.Version, "inserted account")
+
+	updated, err := store.UpdateAccount(inserted.ID, *accountingtest.NewAccount(t, "versions updated", accountingtest.NewCurrencyCode(t, "GBP"), a.Opened()))
+	common.FatalIfError(t, err, "updating account")
+	assert.Equal(t, uint(2), updated.Version, "updated account")
+	selected, err := store.SelectAccount(inserted.ID)
+	common.FatalIfError(t, err, "selecting account")
+	assert.Equal(t, uint(2), selected.Version, "selected account")
+
+	b, err := store.InsertBalance(inserted.ID, newTestBalance(t, a.Opened(), balance.Amount(1)), "first")
+	common.FatalIfError(t, err, "inserting balance")
+	assert.Equal(t, uint(1), b.Version, "inserted balance")
+
+	ub, err := store.UpdateBalance(inserted.ID, b.ID, newTestBalance(t, a.Opened(), balance.Amount(2)), "second")
+	common.FatalIfError(t, err, "updating balance")
+	assert.Equal(t, uint(2), ub.Version, "updated balance")
+	sb, err := store.SelectBalance(b.ID)
+	common.FatalIfError(t, err, "selecting balance")
+	assert.Equal(t, uint(2), sb.Version, "selected balance")
+	bs, err := store.SelectAccountBalances(inserted.ID)
+	common.FatalIfError(t, err, "selecting account balances")
+	if assert.Len(t, *bs, 1) {
+		assert.Equal(t, uint(2), (*bs)[0].Version, "selected account balances")
+	}
+
+	as, err := store.SelectAccounts()
+	common.FatalIfError(t, err, "selecting accounts")
+	for _, sa := range *as {
+		if sa.ID == inserted.ID {
+			assert.Equal(t, uint(2), sa.Version, "selected accounts")
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package storage
+
+// DefaultUser is the user that owns the Accounts that were stored before
+// Accounts had owners, and that makes any request that is not authenticated.
+const DefaultUser = ""
+
+// Tenancy is implemented by a Storage that is able to record which user owns
+// each of its Accounts and which other users each Account has been shared
+// with. An Account that has no recorded owner is owned by the DefaultUser.
+// ForUser uses a Tenancy to provide a Storage that is scoped to a single user.
+type Tenancy interface {
+	// SetAccountOwner records the given user as the owner of the Account
+	// with the given id.
+	SetAccountOwner(accountID uint, user string) error
+	// SelectUserAccountIDs returns the ids of the Accounts, including those
+	// that have been deleted, that are owned by the given user, along with
+	// the ids of those that have been shared with the user.
+	SelectUserAccountIDs(user string) (owned, shared []uint, err error)
+	AccountSharer
+}
+
+// AccountSharer is implemented by a Storage that allows Accounts to be
+// shared with users other than their owner. A user that an Account has been
+// shared with can read the Account and its Balances but cannot change them.
+type AccountSharer interface {
+	// ShareAccount shares the Account with the given id with the given user.
+	ShareAccount(accountID uint, user string) error
+	// UnshareAccount stops sharing the Account with the given id with the
+	// given user. The error is of KindNotFound if the Account was not shared
+	// with the user.
+	UnshareAccount(accountID uint, user string) error
+	// SelectAccountShares returns the users that the Account with the given
+	// id has been shared with, in alphabetical order.
+	SelectAccountShares(accountID uint) ([]string, error)
+}
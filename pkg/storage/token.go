@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"strings"
+	"time"
+)
+
+// Role determines which requests a Token is permitted to authenticate.
+type Role string
+
+// The Roles that a Token can have, from least to most permitted.
+const (
+	// RoleReadOnly permits requests that only read Accounts and Balances.
+	RoleReadOnly Role = "read-only"
+	// RoleEditor permits requests that read, change or delete Accounts and
+	// Balances.
+	RoleEditor Role = "editor"
+	// RoleAdmin permits every request, including those that share Accounts
+	// with other users. Tokens that were created before Tokens had Roles have
+	// RoleAdmin.
+	RoleAdmin Role = "admin"
+)
+
+// Roles holds every Role, from least to most permitted.
+var Roles = []Role{RoleReadOnly, RoleEditor, RoleAdmin}
+
+// ParseRole returns the Role with the given name. The error is of KindInvalid
+// if there is no such Role.
+func ParseRole(name string) (Role, error) {
+	names := make([]string, len(Roles))
+	for i, r := range Roles {
+		if string(r) == name {
+			return r, nil
+		}
+		names[i] = string(r)
+	}
+	return "", Invalidf("unknown role %q, must be one of %s", name, strings.Join(names, ","))
+}
+
+// Token is an API token that is held within a Storage. Only a hash of the
+// secret of a Token is held, so the secret cannot be retrieved once the Token
+// has been created. Requests authenticated with a Token are made as its User
+// and are permitted according to its Role.
+type Token struct {
+	ID      uint
+	Name    string
+	User    string
+	Role    Role
+	Created time.Time
+	Revoked *time.Time
+}
+
+// Tokens holds multiple Token items.
+type Tokens []Token
+
+// TokenStore is implemented by a Storage that is able to hold the API tokens
+// that are used to authenticate requests.
+type TokenStore interface {
+	// InsertToken stores a Token with the given name, user, role and hash of
+	// its secret.
+	InsertToken(name, user string, role Role, hash string) (*Token, error)
+	// SelectTokens returns every Token, including those that have been
+	// revoked, ordered by their ID.
+	SelectTokens() (*Tokens, error)
+	// SelectTokenByHash returns the Token that has the given hash and has
+	// not been revoked. The error is of KindNotFound if there is no such
+	// Token.
+	SelectTokenByHash(hash string) (*Token, error)
+	// RevokeToken revokes the Token with the given id, after which it can no
+	// longer be selected by its hash. The error is of KindNotFound if there
+	// is no Token with the id that has not been revoked.
+	RevokeToken(id uint) error
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRole(t *testing.T) {
+	for _, r := range Roles {
+		parsed, err := ParseRole(string(r))
+		assert.NoError(t, err)
+		assert.Equal(t, r, parsed)
+	}
+
+	_, err := ParseRole("owner")
+	assert.Equal(t, KindInvalid, KindOf(err))
+	_, err = ParseRole("")
+	assert.Equal(t, KindInvalid, KindOf(err))
+}
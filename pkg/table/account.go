@@ -11,7 +11,10 @@ import (
 	"github.com/olekukonko/tablewriter"
 )
 
-const dateFormat = `02-01-2006`
+const (
+	dateFormat     = `02-01-2006`
+	dateTimeFormat = `02-01-2006 15:04:05`
+)
 
 // Accounts writes a table for a set of Accounts to a given io.Writer
 func Accounts(as storage.Accounts, w io.Writer) {
@@ -69,6 +72,44 @@ func Balances(bs storage.Balances, w io.Writer) {
 	t.Render()
 }
 
+// DeletedAccounts writes a table for a set of deleted Accounts, including the
+// time that each was deleted, to a given io.Writer
+func DeletedAccounts(as storage.Accounts, w io.Writer) {
+	t := newDefaultTable(w)
+	t.SetHeader([]string{"ID", "Name", "Opened", "Closed", "Currency", "Deleted"})
+
+	for _, a := range as {
+		t.Append([]string{
+			strconv.FormatUint(uint64(a.ID), 10),
+			a.Account.Name(),
+			a.Account.Opened().Format(dateFormat),
+			closedString(a.Account.Closed()),
+			a.Account.CurrencyCode().String(),
+			deletedString(a.Deleted()),
+		})
+	}
+	t.Render()
+}
+
+// DeletedBalances writes a table for a set of deleted Balances, including the
+// time that each was deleted, to a given io.Writer
+func DeletedBalances(bs storage.Balances, w io.Writer) {
+	t := newDefaultTable(w)
+	t.SetHeader([]string{"ID", "Account ID", "Amount", "Date", "Note", "Deleted"})
+
+	for _, b := range bs {
+		t.Append([]string{
+			strconv.FormatUint(uint64(b.ID), 10),
+			strconv.FormatUint(uint64(b.AccountID), 10),
+			strconv.Itoa(b.Amount),
+			b.Date.Format(dateFormat),
+			b.Note,
+			deletedString(b.Deleted()),
+		})
+	}
+	t.Render()
+}
+
 // Basic writes grid of string data to a given io.Writer
 func Basic(data [][]string, w io.Writer) error {
 	if len(data) < 2 {
@@ -95,3 +136,10 @@ func closedString(t time.NullTime) string {
 	}
 	return t.Time.Format(dateFormat)
 }
+
+func deletedString(t time.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format(dateTimeFormat)
+}
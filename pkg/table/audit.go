@@ -0,0 +1,38 @@
+package table
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// AuditEntries writes a table for a set of AuditEntries to a given io.Writer
+func AuditEntries(es storage.AuditEntries, w io.Writer) {
+	t := newDefaultTable(w)
+	t.SetHeader([]string{"ID", "Time", "User", "Token ID", "Route", "Account ID", "Balance ID", "Status", "Error"})
+
+	for _, e := range es {
+		t.Append([]string{
+			strconv.FormatUint(uint64(e.ID), 10),
+			e.Time.Format(dateTimeFormat),
+			e.User,
+			idString(e.TokenID),
+			e.Route,
+			idString(e.AccountID),
+			idString(e.BalanceID),
+			strconv.Itoa(e.Status),
+			e.Error,
+		})
+	}
+	t.Render()
+}
+
+// idString returns the given ID as a string, or an empty string if the ID is
+// zero.
+func idString(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,27 @@
+package table
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/glynternet/mon/pkg/storage"
+)
+
+// Events writes a table for a set of Events to a given io.Writer
+func Events(es storage.Events, w io.Writer) {
+	t := newDefaultTable(w)
+	t.SetHeader([]string{"Sequence", "Time", "Actor", "Type", "Account ID", "Balance ID", "Note"})
+
+	for _, e := range es {
+		t.Append([]string{
+			strconv.FormatUint(e.Sequence, 10),
+			e.Time.Format(dateTimeFormat),
+			e.Actor,
+			string(e.Type),
+			idString(e.AccountID),
+			idString(e.BalanceID),
+			e.Note,
+		})
+	}
+	t.Render()
+}
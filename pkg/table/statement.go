@@ -0,0 +1,31 @@
+package table
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/glynternet/mon/pkg/statement"
+)
+
+// StatementEntries writes a table for a set of statement.Entries that are yet
+// to be inserted to a given io.Writer, along with the error of each Entry that
+// cannot be inserted. The errors are given by the index of their Entry.
+func StatementEntries(es []statement.Entry, errs map[int]error, w io.Writer) {
+	t := newDefaultTable(w)
+	t.SetHeader([]string{"#", "Amount", "Date", "Note", "Error"})
+
+	for i, e := range es {
+		var errString string
+		if err := errs[i]; err != nil {
+			errString = err.Error()
+		}
+		t.Append([]string{
+			strconv.Itoa(i + 1),
+			strconv.Itoa(e.Balance.Amount),
+			e.Balance.Date.Format(dateFormat),
+			e.Note,
+			errString,
+		})
+	}
+	t.Render()
+}